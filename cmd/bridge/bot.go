@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/anthropics/feishu-codex-bridge/internal/api"
+	"github.com/anthropics/feishu-codex-bridge/internal/biz/usecase"
+	"github.com/anthropics/feishu-codex-bridge/internal/conf"
+	"github.com/anthropics/feishu-codex-bridge/internal/data"
+	"github.com/anthropics/feishu-codex-bridge/internal/infra/feishu"
+	"github.com/anthropics/feishu-codex-bridge/internal/infra/openai"
+	"github.com/anthropics/feishu-codex-bridge/internal/server"
+	"github.com/anthropics/feishu-codex-bridge/internal/service"
+)
+
+// bot is one Feishu app served by the bridge
+type bot struct {
+	id         string
+	srv        *server.FeishuServer
+	apiServer  *api.Server
+	cronRunner *service.CronRunner
+}
+
+// newBot wires the repositories, services and servers of one bot
+// cfg is the bot's view of the configuration, see conf.Config.ForBot
+func newBot(
+	id string,
+	cfg *conf.Config,
+	apiPort int,
+	codex *data.CodexShare,
+	moonshotClient *openai.Client,
+	mcpConfigured bool,
+) (*bot, error) {
+	feishuClient := feishu.NewClient(cfg.Feishu.AppID, cfg.Feishu.AppSecret)
+	feishuClient.SetBaseURL(cfg.Feishu.ToBaseURL())
+	feishuClient.SetOutbound(cfg.Outbound.ToOutboundConfig())
+	if cfg.Feishu.EventMode == conf.EventModeWebhook {
+		feishuClient.SetWebhook(cfg.Feishu.ToWebhookConfig())
+	}
+
+	// Threads run in the bot's working directory, MCP tools call the bot's API
+	threadOpts := data.CodexThreadOptions{}
+	if workingDir, err := filepath.Abs(cfg.Codex.WorkingDir); err == nil {
+		threadOpts.Cwd = workingDir
+	}
+	if mcpConfigured {
+		threadOpts.Config = map[string]interface{}{
+			"mcp_servers.feishu.env.BRIDGE_API_URL": fmt.Sprintf("http://127.0.0.1:%d", apiPort),
+		}
+	}
+
+	// Initialize repository layer
+	repos, err := data.NewRepositories(id, feishuClient, codex.ForBot(id, threadOpts), moonshotClient, cfg.Session.DBPath, cfg.Feishu.BotName, cfg.Prompts,
+		time.Duration(cfg.Directory.TTLMinutes)*time.Minute)
+	if err != nil {
+		return nil, fmt.Errorf("create repositories: %w", err)
+	}
+
+	// Initialize usecase layer
+	sessionCfg := cfg.Session.ToSessionConfig()
+	promptCfg := cfg.ToPromptConfig()
+
+	contextUC := usecase.NewContextBuilderUsecase(repos.Message)
+	sessionUC := usecase.NewSessionUsecase(repos.Session, repos.Codex, sessionCfg)
+	filterUC := usecase.NewFilterUsecase(repos.Filter, repos.Message, contextUC)
+	convUC := usecase.NewConversationUsecase(sessionUC, contextUC, repos.Codex, promptCfg)
+
+	// Initialize service layer
+	convSvc := service.NewConversationService(convUC, filterUC, repos.Message, repos.Codex)
+
+	// Queue follow-up messages while a chat has a turn in flight
+	queueUC := usecase.NewQueueUsecase(repos.Queue, cfg.Queue.ToQueueConfig())
+	convSvc.SetQueueUsecase(queueUC)
+
+	// Per-chat settings, e.g. streaming replies into an updating card
+	settingsUC := usecase.NewSettingsUsecase(repos.Settings, cfg.ToChatSettings())
+	sessionUC.SetSettingsUsecase(settingsUC)
+	streamCfg := service.DefaultStreamConfig()
+	if cfg.Stream.IntervalMs > 0 {
+		streamCfg.Interval = time.Duration(cfg.Stream.IntervalMs) * time.Millisecond
+	}
+	streamCfg.Feedback = cfg.Stream.Feedback
+	convSvc.SetStreaming(settingsUC, streamCfg)
+
+	// Initialize Buffer usecase
+	bufferCfg := usecase.DefaultBufferConfig()
+	bufferUC := usecase.NewBufferUsecase(repos.Buffer, bufferCfg)
+
+	// Initialize Memory usecase
+	memoryUC := usecase.NewMemoryUsecase(repos.Memory)
+
+	// Initialize HTTP API server for feishu-mcp
+	apiServer := api.NewServer(repos.Message, bufferUC, memoryUC, repos.Codex, apiPort)
+	apiServer.AddStats("feishu_api", func() interface{} { return feishuClient.OutboundStats() })
+	go func() {
+		if err := apiServer.Start(); err != nil {
+			fmt.Printf("[Bridge] [%s] API server error: %v\n", id, err)
+		}
+	}()
+	fmt.Printf("[Bridge] [%s] HTTP API server started on port %d\n", id, apiPort)
+
+	// Initialize server
+	// Pass codexRepo and filterUC to enable Codex smart digest + Moonshot filtering
+	srv := server.NewFeishuServer(feishuClient, repos.Message, convSvc, bufferUC, repos.Codex, filterUC, apiServer)
+
+	// Slash commands like /reset and /status, answered without Codex
+	commands := service.NewCommandRouter(repos.Message)
+	commands.SetAdmins(cfg.Command.Admins)
+	convSvc.RegisterCommands(commands)
+	service.RegisterBufferCommands(commands, bufferUC, srv.DigestScheduler())
+	srv.SetCommandRouter(commands)
+	srv.SetAttachmentDir(cfg.Attachment.Dir)
+	srv.SetRecallReplies(cfg.Reply.RecallReplies)
+
+	// Journal received messages, so restarts and dropped connections lose none
+	if cfg.Journal.Enabled {
+		srv.SetJournal(usecase.NewJournalUsecase(repos.Journal, repos.Session, repos.Message, cfg.Journal.ToJournalConfig()))
+	}
+
+	// Greet groups the bot joins and clean up after groups it leaves
+	lifecycleUC := usecase.NewLifecycleUsecase(repos.Session, repos.Buffer, repos.Memory)
+	srv.SetUserRepo(repos.User)
+	srv.SetLifecycleService(service.NewLifecycleService(lifecycleUC, convSvc, repos.Message, cfg.Lifecycle.OnboardingMessage))
+
+	// Card buttons, routed by the "action" in their value
+	cards := service.NewCardRouter()
+	cards.SetAdmins(cfg.Command.Admins)
+	cards.Register(service.FeedbackCardAction, service.PermissionAnyone, service.NewFeedbackHandler(repos.Feedback))
+	if scheduler := srv.DigestScheduler(); scheduler != nil {
+		// The /buffer card lists every chat, replying from it posts into other chats
+		cards.Register(service.DigestCardAction, service.PermissionAdmin, scheduler)
+	}
+	srv.SetCardRouter(cards)
+
+	// Approval flow for Codex tool calls (auto-accept everything when disabled)
+	if cfg.Approval.Enabled {
+		approvalUC := usecase.NewApprovalUsecase(repos.Approval, cfg.Approval.ToApprovalPolicy())
+		approvalSvc := service.NewApprovalService(approvalUC, repos.Message, repos.Codex)
+		approvalSvc.SetSessionResolver(convSvc.SessionForThread)
+		approvalSvc.Start()
+		cards.Register(service.ApprovalCardAction, service.PermissionAnyone, approvalSvc)
+		apiServer.SetApprovalUsecase(approvalUC)
+	}
+	apiServer.SetSettingsUsecase(settingsUC)
+	apiServer.SetUserRepo(repos.User)
+	apiServer.SetFeedbackRepo(repos.Feedback)
+
+	// Let Codex post files from its workspace to the chat
+	artifactUC := usecase.NewArtifactUsecase(repos.Message, cfg.ToArtifactConfig())
+	apiServer.SetArtifactUsecase(artifactUC)
+
+	// Initialize and start CronRunner for scheduled tasks and heartbeats
+	cronRunner := service.NewCronRunner(memoryUC, repos.Message, repos.Codex)
+	cronRunner.RegisterCommands(commands)
+	cards.Register(service.TaskCardAction, service.PermissionAdmin, cronRunner)
+	cronRunner.Start()
+	fmt.Printf("[Bridge] [%s] CronRunner started\n", id)
+
+	return &bot{id: id, srv: srv, apiServer: apiServer, cronRunner: cronRunner}, nil
+}
+
+// stop stops the bot's servers, the shared Codex client is left running
+func (b *bot) stop() {
+	b.cronRunner.Stop()
+	b.srv.Stop()
+	b.apiServer.Stop()
+}
@@ -10,15 +10,10 @@ import (
 	"path/filepath"
 	"syscall"
 
-	"github.com/anthropics/feishu-codex-bridge/internal/api"
-	"github.com/anthropics/feishu-codex-bridge/internal/biz/usecase"
 	"github.com/anthropics/feishu-codex-bridge/internal/conf"
 	"github.com/anthropics/feishu-codex-bridge/internal/data"
 	"github.com/anthropics/feishu-codex-bridge/internal/infra/acp"
-	"github.com/anthropics/feishu-codex-bridge/internal/infra/feishu"
 	"github.com/anthropics/feishu-codex-bridge/internal/infra/openai"
-	"github.com/anthropics/feishu-codex-bridge/internal/server"
-	"github.com/anthropics/feishu-codex-bridge/internal/service"
 	"github.com/joho/godotenv"
 )
 
@@ -36,9 +31,20 @@ func main() {
 		log.Fatalf("Invalid config: %v", err)
 	}
 
-	// Initialize clients
-	feishuClient := feishu.NewClient(cfg.Feishu.AppID, cfg.Feishu.AppSecret)
+	// All bots share one Codex app-server
 	codexClient := acp.NewClient(cfg.Codex.WorkingDir, cfg.Codex.Model)
+	if cfg.Codex.Path != "" {
+		codexClient.SetExecutable(cfg.Codex.Path)
+	}
+
+	// Bots without an API port take the next one after the default
+	apiPorts := make([]int, len(cfg.Bots))
+	for i, botCfg := range cfg.Bots {
+		apiPorts[i] = botCfg.APIPort
+		if apiPorts[i] == 0 {
+			apiPorts[i] = defaultAPIPort + i
+		}
+	}
 
 	// Configure MCP server BEFORE starting Codex client
 	mcpPath, err := findMCPServerPath()
@@ -46,19 +52,26 @@ func main() {
 		fmt.Printf("[Bridge] Warning: MCP server not found: %v\n", err)
 	} else {
 		// Pass Bridge API URL to MCP server via environment variable
+		// Threads of other bots override it with their own API's URL
 		mcpEnvVars := map[string]string{
-			"BRIDGE_API_URL": fmt.Sprintf("http://127.0.0.1:%d", defaultAPIPort),
+			"BRIDGE_API_URL": fmt.Sprintf("http://127.0.0.1:%d", apiPorts[0]),
 		}
 		codexClient.SetMCPServer(mcpPath, mcpEnvVars)
 		fmt.Printf("[Bridge] MCP server configured: %s\n", mcpPath)
 	}
 
+	// Codex must ask before running commands for the approval flow to see them
+	if cfg.Approval.Enabled {
+		codexClient.SetApprovalPolicy(cfg.Approval.CodexPolicy)
+	}
+
 	// Start Codex client (will configure MCP if path was set above)
 	ctx := context.Background()
 	if err := codexClient.Start(ctx); err != nil {
 		log.Fatalf("Failed to start Codex client: %v", err)
 	}
 	fmt.Println("[Bridge] Codex client started")
+	codex := data.NewCodexShare(codexClient)
 
 	var moonshotClient *openai.Client
 	if cfg.Moonshot.APIKey != "" {
@@ -66,50 +79,17 @@ func main() {
 		fmt.Println("[Bridge] Moonshot pre-filter enabled")
 	}
 
-	// Initialize repository layer
-	repos, err := data.NewRepositories(feishuClient, codexClient, moonshotClient, cfg.Session.DBPath, cfg.Feishu.BotName, cfg.Prompts)
-	if err != nil {
-		log.Fatalf("Failed to create repositories: %v", err)
-	}
-
 	fmt.Printf("[Bridge] Session DB: %s\n", cfg.Session.DBPath)
 
-	// Initialize usecase layer
-	sessionCfg := cfg.Session.ToSessionConfig()
-	promptCfg := cfg.ToPromptConfig()
-
-	contextUC := usecase.NewContextBuilderUsecase(repos.Message)
-	sessionUC := usecase.NewSessionUsecase(repos.Session, repos.Codex, sessionCfg)
-	filterUC := usecase.NewFilterUsecase(repos.Filter, repos.Message, contextUC)
-	convUC := usecase.NewConversationUsecase(sessionUC, contextUC, repos.Codex, promptCfg)
-
-	// Initialize service layer
-	convSvc := service.NewConversationService(convUC, filterUC, repos.Message, repos.Codex)
-
-	// Initialize Buffer usecase
-	bufferCfg := usecase.DefaultBufferConfig()
-	bufferUC := usecase.NewBufferUsecase(repos.Buffer, bufferCfg)
-
-	// Initialize Memory usecase
-	memoryUC := usecase.NewMemoryUsecase(repos.Memory)
-
-	// Initialize HTTP API server for feishu-mcp
-	apiServer := api.NewServer(repos.Message, bufferUC, memoryUC, repos.Codex, defaultAPIPort)
-	go func() {
-		if err := apiServer.Start(); err != nil {
-			fmt.Printf("[Bridge] API server error: %v\n", err)
+	// Each bot has its own Feishu app, services and API server
+	var bots []*bot
+	for i, botCfg := range cfg.Bots {
+		b, err := newBot(botCfg.ID, cfg.ForBot(botCfg), apiPorts[i], codex, moonshotClient, mcpPath != "")
+		if err != nil {
+			log.Fatalf("Failed to set up bot %s: %v", botCfg.ID, err)
 		}
-	}()
-	fmt.Printf("[Bridge] HTTP API server started on port %d\n", defaultAPIPort)
-
-	// Initialize server
-	// Pass codexRepo and filterUC to enable Codex smart digest + Moonshot filtering
-	srv := server.NewFeishuServer(feishuClient, repos.Message, convSvc, bufferUC, repos.Codex, filterUC, apiServer)
-
-	// Initialize and start CronRunner for scheduled tasks and heartbeats
-	cronRunner := service.NewCronRunner(memoryUC, repos.Message, repos.Codex)
-	cronRunner.Start()
-	fmt.Println("[Bridge] CronRunner started")
+		bots = append(bots, b)
+	}
 
 	// Graceful shutdown
 	sigCh := make(chan os.Signal, 1)
@@ -118,17 +98,23 @@ func main() {
 	go func() {
 		<-sigCh
 		fmt.Println("\nShutting down...")
-		cronRunner.Stop()
-		srv.Stop()
-		apiServer.Stop()
+		for _, b := range bots {
+			b.stop()
+		}
 		codexClient.Stop()
 		os.Exit(0)
 	}()
 
-	fmt.Println("Starting Feishu-Codex Bridge (Kratos style)...")
-	if err := srv.Start(); err != nil {
-		log.Fatalf("Server error: %v", err)
+	fmt.Printf("Starting Feishu-Codex Bridge (Kratos style) with %d bot(s)...\n", len(bots))
+	errCh := make(chan error, len(bots))
+	for _, b := range bots {
+		go func(b *bot) {
+			if err := b.srv.Start(); err != nil {
+				errCh <- fmt.Errorf("bot %s: %w", b.id, err)
+			}
+		}(b)
 	}
+	log.Fatalf("Server error: %v", <-errCh)
 }
 
 // findMCPServerPath finds the feishu-mcp binary
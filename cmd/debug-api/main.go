@@ -8,6 +8,8 @@ import (
 	"os"
 	"strings"
 	"time"
+
+	"github.com/anthropics/feishu-codex-bridge/internal/infra/feishu"
 )
 
 func main() {
@@ -19,8 +21,15 @@ func main() {
 		chatID = os.Args[1]
 	}
 
+	// Feishu, Lark or a custom base URL
+	baseURL, err := feishu.ResolveBaseURL(os.Getenv("FEISHU_DOMAIN"))
+	if err != nil {
+		fmt.Printf("Invalid FEISHU_DOMAIN: %v\n", err)
+		return
+	}
+
 	// 1. Get access token
-	token, err := getTenantToken(appID, appSecret)
+	token, err := getTenantToken(baseURL, appID, appSecret)
 	if err != nil {
 		fmt.Printf("Failed to get token: %v\n", err)
 		return
@@ -29,13 +38,13 @@ func main() {
 
 	// Test the fix: fetch in descending order then reverse
 	fmt.Println("=== Simulating fixed behavior: ByCreateTimeDesc + Reverse ===")
-	testGetMessagesWithReverse(token, chatID, 10)
+	testGetMessagesWithReverse(baseURL, token, chatID, 10)
 }
 
-func getTenantToken(appID, appSecret string) (string, error) {
+func getTenantToken(baseURL, appID, appSecret string) (string, error) {
 	body := fmt.Sprintf(`{"app_id":"%s","app_secret":"%s"}`, appID, appSecret)
 	resp, err := http.Post(
-		"https://open.feishu.cn/open-apis/auth/v3/tenant_access_token/internal",
+		baseURL+"/open-apis/auth/v3/tenant_access_token/internal",
 		"application/json",
 		strings.NewReader(body),
 	)
@@ -54,9 +63,9 @@ func getTenantToken(appID, appSecret string) (string, error) {
 	return result.TenantAccessToken, nil
 }
 
-func testGetMessagesWithReverse(token, chatID string, pageSize int) {
-	url := fmt.Sprintf("https://open.feishu.cn/open-apis/im/v1/messages?container_id_type=chat&container_id=%s&page_size=%d&sort_type=ByCreateTimeDesc",
-		chatID, pageSize)
+func testGetMessagesWithReverse(baseURL, token, chatID string, pageSize int) {
+	url := fmt.Sprintf("%s/open-apis/im/v1/messages?container_id_type=chat&container_id=%s&page_size=%d&sort_type=ByCreateTimeDesc",
+		baseURL, chatID, pageSize)
 
 	req, _ := http.NewRequest("GET", url, nil)
 	req.Header.Set("Authorization", "Bearer "+token)
@@ -0,0 +1,9 @@
+package main
+
+import "github.com/anthropics/feishu-codex-bridge/internal/infra/acp/acptest"
+
+// fake-codex stands in for the codex executable: run the bridge with
+// CODEX_PATH pointing at it and FAKE_CODEX_SCRIPT at a JSON script
+func main() {
+	acptest.Main()
+}
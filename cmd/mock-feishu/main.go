@@ -0,0 +1,32 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/anthropics/feishu-codex-bridge/internal/infra/feishu/feishutest"
+)
+
+// mock-feishu runs a fake Feishu Open Platform for local end-to-end testing
+// Start the bridge with FEISHU_DOMAIN=http://<addr>, FEISHU_EVENT_MODE=webhook
+// and the same verification token, then drive it through /mock/
+func main() {
+	addr := flag.String("addr", "127.0.0.1:9990", "address to listen on")
+	webhook := flag.String("webhook", "http://127.0.0.1:8080/webhook/event", "bridge webhook URL events are delivered to")
+	token := flag.String("token", "mock-verification-token", "verification token sent with events")
+	botOpenID := flag.String("bot-open-id", feishutest.DefaultBotOpenID, "open_id of the bot")
+	botName := flag.String("bot-name", "Mock Bot", "name of the bot")
+	flag.Parse()
+
+	srv := feishutest.New()
+	srv.SetWebhook(*webhook, *token)
+	srv.SetBot(*botOpenID, *botName)
+
+	fmt.Printf("[MockFeishu] Listening on %s, delivering events to %s\n", *addr, *webhook)
+	if err := http.ListenAndServe(*addr, srv); err != nil {
+		fmt.Printf("[MockFeishu] Server error: %v\n", err)
+		os.Exit(1)
+	}
+}
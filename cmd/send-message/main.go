@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/anthropics/feishu-codex-bridge/internal/infra/feishu"
 	lark "github.com/larksuite/oapi-sdk-go/v3"
 	larkim "github.com/larksuite/oapi-sdk-go/v3/service/im/v1"
 )
@@ -28,8 +29,15 @@ func main() {
 	userID := os.Args[2]
 	message := os.Args[3]
 
+	// Feishu, Lark or a custom base URL
+	baseURL, err := feishu.ResolveBaseURL(os.Getenv("FEISHU_DOMAIN"))
+	if err != nil {
+		fmt.Printf("Error: invalid FEISHU_DOMAIN: %v\n", err)
+		os.Exit(1)
+	}
+
 	// Create Lark client
-	client := lark.NewClient(appID, appSecret)
+	client := lark.NewClient(appID, appSecret, lark.WithOpenBaseUrl(baseURL))
 
 	// Build message with mention
 	mentionText := fmt.Sprintf("<at user_id=\"%s\">@User</at> %s", userID, message)
@@ -17,10 +17,16 @@ import (
 
 // Server provides HTTP API for feishu-mcp to call back into Bridge
 type Server struct {
-	messageRepo repo.MessageRepo
-	bufferUC    *usecase.BufferUsecase
-	memoryUC    *usecase.MemoryUsecase
-	codexRepo   repo.CodexRepo
+	messageRepo  repo.MessageRepo
+	bufferUC     *usecase.BufferUsecase
+	memoryUC     *usecase.MemoryUsecase
+	codexRepo    repo.CodexRepo
+	approvalUC   *usecase.ApprovalUsecase
+	settingsUC   *usecase.SettingsUsecase
+	artifactUC   *usecase.ArtifactUsecase
+	userRepo     repo.UserRepo
+	feedbackRepo repo.FeedbackRepo
+	stats        map[string]func() interface{} // Counters reported by /health
 
 	// Current chat context (updated when processing messages)
 	currentContext *ChatContext
@@ -52,10 +58,42 @@ func NewServer(messageRepo repo.MessageRepo, bufferUC *usecase.BufferUsecase, me
 		memoryUC:       memoryUC,
 		codexRepo:      codexRepo,
 		currentContext: &ChatContext{},
+		stats:          make(map[string]func() interface{}),
 		port:           port,
 	}
 }
 
+// SetApprovalUsecase enables the approval log endpoint
+func (s *Server) SetApprovalUsecase(approvalUC *usecase.ApprovalUsecase) {
+	s.approvalUC = approvalUC
+}
+
+// SetSettingsUsecase enables the per-chat settings endpoint
+func (s *Server) SetSettingsUsecase(settingsUC *usecase.SettingsUsecase) {
+	s.settingsUC = settingsUC
+}
+
+// SetArtifactUsecase enables posting workspace files into chats
+func (s *Server) SetArtifactUsecase(artifactUC *usecase.ArtifactUsecase) {
+	s.artifactUC = artifactUC
+}
+
+// SetUserRepo enables looking up user profiles
+func (s *Server) SetUserRepo(userRepo repo.UserRepo) {
+	s.userRepo = userRepo
+}
+
+// SetFeedbackRepo enables the reply rating endpoint
+func (s *Server) SetFeedbackRepo(feedbackRepo repo.FeedbackRepo) {
+	s.feedbackRepo = feedbackRepo
+}
+
+// AddStats reports the counters returned by fn under name in /health
+// Must be called before Start
+func (s *Server) AddStats(name string, fn func() interface{}) {
+	s.stats[name] = fn
+}
+
 // Start starts the HTTP server
 func (s *Server) Start() error {
 	mux := http.NewServeMux()
@@ -95,14 +133,28 @@ func (s *Server) Start() error {
 	// Context
 	mux.HandleFunc("/api/context", s.handleContext)
 
+	// Approval log
+	mux.HandleFunc("/api/approvals", s.handleApprovals)
+	mux.HandleFunc("/api/feedback", s.handleFeedback)
+
+	// Per-chat settings
+	mux.HandleFunc("/api/settings/", s.handleSettings)
+
+	// User profiles
+	mux.HandleFunc("/api/users/", s.handleUser)
+
+	// Member and chat info cache
+	mux.HandleFunc("/api/directory", s.handleDirectory)
+	mux.HandleFunc("/api/directory/", s.handleDirectoryItem)
+
+	// Workspace file upload
+	mux.HandleFunc("/api/files", s.handleFiles)
+
 	// Debug endpoint for direct Codex communication
 	mux.HandleFunc("/api/debug/codex", s.handleDebugCodex)
 
 	// Health check
-	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte("ok"))
-	})
+	mux.HandleFunc("/health", s.handleHealth)
 
 	s.server = &http.Server{
 		Addr:    fmt.Sprintf("127.0.0.1:%d", s.port),
@@ -574,6 +626,254 @@ func (s *Server) handleMemorySearch(w http.ResponseWriter, r *http.Request) {
 	s.writeJSON(w, map[string]interface{}{"results": entries})
 }
 
+// ============ Approval Handlers ============
+
+func (s *Server) handleApprovals(w http.ResponseWriter, r *http.Request) {
+	if s.approvalUC == nil {
+		http.Error(w, "approval flow not enabled", http.StatusServiceUnavailable)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ctx := r.Context()
+	query := r.URL.Query()
+	limit := 50
+	if l := query.Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil {
+			limit = parsed
+		}
+	}
+
+	var records []*domain.ApprovalRecord
+	var err error
+	if userID := query.Get("user_id"); userID != "" {
+		records, err = s.approvalUC.ListByUser(ctx, userID, limit)
+	} else {
+		chatID := query.Get("chat_id")
+		if chatID == "" {
+			chatID = s.GetContext().ChatID
+		}
+		if chatID == "" {
+			http.Error(w, "chat_id or user_id is required", http.StatusBadRequest)
+			return
+		}
+		records, err = s.approvalUC.ListByChat(ctx, chatID, limit)
+	}
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+	s.writeJSON(w, map[string]interface{}{"approvals": records})
+}
+
+// handleFeedback lists the ratings of a chat's replies: GET /api/feedback?chat_id=...
+func (s *Server) handleFeedback(w http.ResponseWriter, r *http.Request) {
+	if s.feedbackRepo == nil {
+		http.Error(w, "feedback not enabled", http.StatusServiceUnavailable)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	limit := 50
+	if l := query.Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil {
+			limit = parsed
+		}
+	}
+	chatID := query.Get("chat_id")
+	if chatID == "" {
+		chatID = s.GetContext().ChatID
+	}
+	if chatID == "" {
+		http.Error(w, "chat_id is required", http.StatusBadRequest)
+		return
+	}
+
+	feedback, err := s.feedbackRepo.ListByChat(r.Context(), chatID, limit)
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+	s.writeJSON(w, map[string]interface{}{"feedback": feedback})
+}
+
+// ============ Directory Handlers ============
+
+// handleUser returns a user's profile: GET /api/users/{open_id}
+func (s *Server) handleUser(w http.ResponseWriter, r *http.Request) {
+	if s.userRepo == nil {
+		http.Error(w, "user directory not enabled", http.StatusServiceUnavailable)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID := strings.TrimPrefix(r.URL.Path, "/api/users/")
+	if userID == "" {
+		http.Error(w, "open_id is required", http.StatusBadRequest)
+		return
+	}
+
+	profile, err := s.userRepo.GetUser(r.Context(), userID)
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+	if profile == nil {
+		http.Error(w, "user not found", http.StatusNotFound)
+		return
+	}
+	s.writeJSON(w, map[string]interface{}{
+		"open_id":    profile.UserID,
+		"union_id":   profile.UnionID,
+		"name":       profile.Name,
+		"department": profile.Department,
+		"updated_at": profile.UpdatedAt,
+	})
+}
+
+// handleDirectory returns the member and chat info cache counters
+func (s *Server) handleDirectory(w http.ResponseWriter, r *http.Request) {
+	cache, ok := s.messageRepo.(repo.DirectoryCache)
+	if !ok {
+		http.Error(w, "directory cache not enabled", http.StatusServiceUnavailable)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	s.writeJSON(w, cache.DirectoryStats())
+}
+
+// handleDirectoryItem drops the cached members and info of a chat: DELETE /api/directory/{chat_id}
+func (s *Server) handleDirectoryItem(w http.ResponseWriter, r *http.Request) {
+	cache, ok := s.messageRepo.(repo.DirectoryCache)
+	if !ok {
+		http.Error(w, "directory cache not enabled", http.StatusServiceUnavailable)
+		return
+	}
+	if r.Method != http.MethodDelete {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	chatID := strings.TrimPrefix(r.URL.Path, "/api/directory/")
+	if chatID == "" {
+		http.Error(w, "chat_id is required", http.StatusBadRequest)
+		return
+	}
+	cache.InvalidateMembers(chatID)
+	s.writeJSON(w, map[string]interface{}{"success": true})
+}
+
+// ============ Settings Handlers ============
+
+func (s *Server) handleSettings(w http.ResponseWriter, r *http.Request) {
+	if s.settingsUC == nil {
+		http.Error(w, "settings not initialized", http.StatusServiceUnavailable)
+		return
+	}
+
+	chatID := strings.TrimPrefix(r.URL.Path, "/api/settings/")
+	if chatID == "" {
+		chatID = s.GetContext().ChatID
+	}
+	if chatID == "" {
+		http.Error(w, "chat_id is required", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	settings, err := s.settingsUC.Get(ctx, chatID)
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		s.writeJSON(w, settings)
+
+	case http.MethodPut:
+		// Only fields present in the body are changed
+		var req struct {
+			StreamReplies *bool             `json:"stream_replies"`
+			ReplyMode     *domain.ReplyMode `json:"reply_mode"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if req.ReplyMode != nil && !req.ReplyMode.Valid() {
+			http.Error(w, "reply_mode must be quote or plain", http.StatusBadRequest)
+			return
+		}
+		if req.StreamReplies != nil {
+			settings.StreamReplies = *req.StreamReplies
+		}
+		if req.ReplyMode != nil {
+			settings.ReplyMode = *req.ReplyMode
+		}
+		if err := s.settingsUC.Save(ctx, settings); err != nil {
+			s.writeError(w, err)
+			return
+		}
+		s.writeJSON(w, settings)
+
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// ============ File Handlers ============
+
+func (s *Server) handleFiles(w http.ResponseWriter, r *http.Request) {
+	if s.artifactUC == nil {
+		http.Error(w, "file upload not enabled", http.StatusServiceUnavailable)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		ChatID string `json:"chat_id"`
+		Path   string `json:"path"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.ChatID == "" {
+		req.ChatID = s.GetContext().ChatID
+	}
+	if req.ChatID == "" {
+		http.Error(w, "chat_id is required", http.StatusBadRequest)
+		return
+	}
+
+	// Sandbox and size errors are the caller's to fix
+	artifact, err := s.artifactUC.Resolve(req.Path)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := s.artifactUC.Post(r.Context(), req.ChatID, artifact); err != nil {
+		s.writeError(w, err)
+		return
+	}
+	s.writeJSON(w, map[string]interface{}{"success": true, "artifact": artifact})
+}
+
 // ============ Task Handlers ============
 
 func (s *Server) handleTasks(w http.ResponseWriter, r *http.Request) {
@@ -763,6 +1063,32 @@ func (s *Server) handleHeartbeatItem(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// ============ Health Handler ============
+
+// handleHealth reports bridge health, including the Codex app-server supervisor status
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	result := map[string]interface{}{"status": "ok"}
+	if len(s.stats) > 0 {
+		stats := make(map[string]interface{}, len(s.stats))
+		for name, fn := range s.stats {
+			stats[name] = fn()
+		}
+		result["stats"] = stats
+	}
+	if s.codexRepo != nil {
+		codex := s.codexRepo.Status()
+		result["codex"] = codex
+		if !codex.Running {
+			result["status"] = "degraded"
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			json.NewEncoder(w).Encode(result)
+			return
+		}
+	}
+	s.writeJSON(w, result)
+}
+
 // ============ Helpers ============
 
 func (s *Server) writeJSON(w http.ResponseWriter, data interface{}) {
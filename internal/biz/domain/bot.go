@@ -0,0 +1,5 @@
+package domain
+
+// DefaultBotID identifies the bot of a single-bot setup
+// Records stored before records were tagged with a bot belong to it
+const DefaultBotID = "default"
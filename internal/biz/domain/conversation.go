@@ -17,6 +17,7 @@ type Conversation struct {
 	Members  []Member
 	History  []Message
 	Current  *Message
+	Parent   *Message // Message the current one replies to, if any
 }
 
 // HistorySince gets history messages after specified time
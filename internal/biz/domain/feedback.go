@@ -0,0 +1,25 @@
+package domain
+
+import "time"
+
+// FeedbackRating is a rating from the buttons under a streamed reply
+type FeedbackRating string
+
+const (
+	FeedbackUp   FeedbackRating = "up"   // Helpful
+	FeedbackDown FeedbackRating = "down" // Not helpful
+)
+
+// Valid reports whether r is a known rating
+func (r FeedbackRating) Valid() bool {
+	return r == FeedbackUp || r == FeedbackDown
+}
+
+// Feedback is one user's rating of a reply, rating again replaces it
+type Feedback struct {
+	MsgID      string         `json:"msg_id"` // Reply card that was rated
+	ChatID     string         `json:"chat_id"`
+	OperatorID string         `json:"operator_id"`
+	Rating     FeedbackRating `json:"rating"`
+	CreatedAt  time.Time      `json:"created_at"`
+}
@@ -0,0 +1,38 @@
+package domain
+
+import "time"
+
+// JournalState is how far the handling of a received message got
+type JournalState string
+
+const (
+	JournalReceived   JournalState = "received"   // Accepted, not routed yet
+	JournalQueued     JournalState = "queued"     // Waiting behind its session's current turn
+	JournalProcessing JournalState = "processing" // Its turn is starting or running
+	JournalReplied    JournalState = "replied"    // Handled: answered, or deliberately left unanswered (filtered, buffered)
+	JournalFailed     JournalState = "failed"     // Given up on, see Error
+)
+
+// Finished checks whether nothing is left to do for the message
+func (s JournalState) Finished() bool {
+	return s == JournalReplied || s == JournalFailed
+}
+
+// JournalEntry records a received message and how far its handling got
+// Entries survive restarts, so unfinished messages can be resumed
+type JournalEntry struct {
+	ChatID     string
+	MsgID      string
+	TopicID    string // Feishu topic thread of the message, if any
+	State      JournalState
+	Error      string    // Why the message failed
+	Recovered  bool      // Already resumed once after a restart, never resumed again
+	CreateTime time.Time // When the message was sent
+	ReceivedAt time.Time
+	UpdatedAt  time.Time
+}
+
+// Key returns the key of the session the message belongs to
+func (e *JournalEntry) Key() SessionKey {
+	return SessionKey{ChatID: e.ChatID, TopicID: e.TopicID}
+}
@@ -11,7 +11,9 @@ type Message struct {
 	SenderName string
 	MsgType    string // text, image, post, etc.
 	CreateTime time.Time
-	IsBot      bool // Whether the message was sent by the bot
+	IsBot      bool   // Whether the message was sent by the bot
+	ParentID   string // Message this one replies to, if any
+	RootID     string // First message of the reply chain, if any
 }
 
 // IsFromBot checks if the message is from the bot
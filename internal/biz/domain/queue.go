@@ -0,0 +1,66 @@
+package domain
+
+import (
+	"strings"
+	"time"
+)
+
+// QueuedMessage is a message waiting for its session's current turn to finish
+type QueuedMessage struct {
+	ID            int64
+	ChatID        string
+	MsgID         string
+	Content       string
+	SenderID      string
+	SenderName    string
+	ChatType      ChatType
+	MentionsBot   bool
+	ImagePaths    []string
+	MsgCreateTime int64    // Feishu message creation time (milliseconds)
+	TopicID       string   // Feishu topic thread of the message, if any
+	Parent        *Message // Message it replies to, if any
+	MergedMsgIDs  []string // Earlier messages coalesced into this one, not stored
+	EnqueuedAt    time.Time
+}
+
+// Key returns the key of the session the message belongs to
+func (m *QueuedMessage) Key() SessionKey {
+	return SessionKey{ChatID: m.ChatID, TopicID: m.TopicID}
+}
+
+// CoalesceMessages combines queued messages into one, oldest first
+// The result takes the ID and time of the newest message so replies
+// and the processed-message anchor point at the end of the batch
+func CoalesceMessages(msgs []*QueuedMessage) *QueuedMessage {
+	if len(msgs) == 0 {
+		return nil
+	}
+	if len(msgs) == 1 {
+		return msgs[0]
+	}
+
+	last := msgs[len(msgs)-1]
+	combined := *last
+	combined.ImagePaths = nil
+	combined.MentionsBot = false
+
+	var sb strings.Builder
+	for i, msg := range msgs {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		sender := msg.SenderName
+		if sender == "" {
+			sender = msg.SenderID
+		}
+		sb.WriteString("[" + sender + "]: " + msg.Content)
+
+		combined.ImagePaths = append(combined.ImagePaths, msg.ImagePaths...)
+		combined.MentionsBot = combined.MentionsBot || msg.MentionsBot
+		if msg != last {
+			combined.MergedMsgIDs = append(combined.MergedMsgIDs, msg.MsgID)
+		}
+	}
+	combined.Content = sb.String()
+	return &combined
+}
@@ -0,0 +1,41 @@
+package domain
+
+import "testing"
+
+func TestCoalesceMessages(t *testing.T) {
+	msgs := []*QueuedMessage{
+		{ID: 1, MsgID: "m1", Content: "first", SenderName: "Alice", ImagePaths: []string{"a.png"}, MsgCreateTime: 1},
+		{ID: 2, MsgID: "m2", Content: "second", SenderID: "ou_bob", MentionsBot: true, MsgCreateTime: 2},
+	}
+
+	combined := CoalesceMessages(msgs)
+
+	if combined.MsgID != "m2" || combined.MsgCreateTime != 2 {
+		t.Errorf("Expected newest message identity, got %s/%d", combined.MsgID, combined.MsgCreateTime)
+	}
+	if combined.Content != "[Alice]: first\n[ou_bob]: second" {
+		t.Errorf("Unexpected content: %q", combined.Content)
+	}
+	if !combined.MentionsBot {
+		t.Error("Expected MentionsBot when any message mentions the bot")
+	}
+	if len(combined.ImagePaths) != 1 || combined.ImagePaths[0] != "a.png" {
+		t.Errorf("Expected images to be merged, got %v", combined.ImagePaths)
+	}
+	if len(combined.MergedMsgIDs) != 1 || combined.MergedMsgIDs[0] != "m1" {
+		t.Errorf("Expected the earlier message to be listed as merged, got %v", combined.MergedMsgIDs)
+	}
+	if msgs[1].Content != "second" {
+		t.Error("Input messages must not be modified")
+	}
+}
+
+func TestCoalesceMessages_Single(t *testing.T) {
+	msg := &QueuedMessage{MsgID: "m1", Content: "only"}
+	if got := CoalesceMessages([]*QueuedMessage{msg}); got != msg {
+		t.Error("Expected single message to be returned as is")
+	}
+	if CoalesceMessages(nil) != nil {
+		t.Error("Expected nil for empty input")
+	}
+}
@@ -0,0 +1,26 @@
+package domain
+
+import "strings"
+
+// reactionTypes lists Feishu emoji types usable as message reactions
+// See https://open.feishu.cn/document/server-docs/im-v1/message-reaction/emojis-introduce
+var reactionTypes = []string{
+	"OK", "THUMBSUP", "THANKS", "MUSCLE", "FINGERHEART", "APPLAUSE", "FISTBUMP", "JIAYI", "DONE",
+	"SMILE", "BLUSH", "LAUGH", "SMIRK", "LOL", "FACEPALM", "LOVE", "WINK", "PROUD", "WITTY", "SMART",
+	"THINKING", "SOB", "CRY", "ERROR", "JOYFUL", "WOW", "YEAH", "TEARS", "EMBARRASSED", "SURPRISED",
+	"CLAP", "PRAISE", "STRIVE", "SHY", "WAVE", "WHAT", "HUG", "SHOCKED", "SWEAT", "SPEECHLESS",
+	"SALUTE", "HIGHFIVE", "APPRECIATE", "HEART", "PARTY", "ROSE", "BEER", "CAKE", "GIFT", "FIREWORKS",
+	"HEARTBROKEN", "ThumbsDown", "Yes", "No", "CheckMark", "CrossMark", "Hundred", "Fire", "Trophy",
+	"Pin", "Alarm", "Get", "LGTM", "OnIt", "OneSecond", "Typing", "Coffee", "YouAreTheBest",
+}
+
+// NormalizeReaction maps an emoji type to its canonical spelling, ignoring case
+// Returns false if Feishu does not know the type
+func NormalizeReaction(emojiType string) (string, bool) {
+	for _, t := range reactionTypes {
+		if strings.EqualFold(t, emojiType) {
+			return t, true
+		}
+	}
+	return "", false
+}
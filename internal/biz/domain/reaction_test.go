@@ -0,0 +1,25 @@
+package domain
+
+import "testing"
+
+func TestNormalizeReaction(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+		ok    bool
+	}{
+		{"THUMBSUP", "THUMBSUP", true},
+		{"ThumbsUp", "THUMBSUP", true},
+		{"lgtm", "LGTM", true},
+		{"onit", "OnIt", true},
+		{"NOT_AN_EMOJI", "", false},
+		{"", "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := NormalizeReaction(tt.input)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("NormalizeReaction(%q) = %q, %v; want %q, %v", tt.input, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -2,9 +2,24 @@ package domain
 
 import "time"
 
+// SessionKey identifies a session: a chat, or one topic thread of a chat
+type SessionKey struct {
+	ChatID  string
+	TopicID string // Feishu topic thread ID, empty for the chat itself
+}
+
+// String returns the chat ID, followed by the topic ID for a topic session
+func (k SessionKey) String() string {
+	if k.TopicID == "" {
+		return k.ChatID
+	}
+	return k.ChatID + "/" + k.TopicID
+}
+
 // Session represents a session entity
 type Session struct {
 	ChatID             string
+	TopicID            string // Feishu topic thread the session belongs to, empty for the chat
 	ThreadID           string
 	CreatedAt          time.Time
 	UpdatedAt          time.Time
@@ -13,6 +28,11 @@ type Session struct {
 	LastProcessedMsgID string    // Last processed message ID (for reliable message recovery)
 }
 
+// Key returns the key of the session
+func (s *Session) Key() SessionKey {
+	return SessionKey{ChatID: s.ChatID, TopicID: s.TopicID}
+}
+
 // SessionConfig represents session configuration (value object)
 type SessionConfig struct {
 	IdleTimeout time.Duration // Idle timeout
@@ -143,3 +143,12 @@ func TestSession_MarkReplied(t *testing.T) {
 		t.Error("Expected LastReplyAt to be updated")
 	}
 }
+
+func TestSessionKey_String(t *testing.T) {
+	if got := (SessionKey{ChatID: "chat-123"}).String(); got != "chat-123" {
+		t.Errorf("Expected chat-123, got %s", got)
+	}
+	if got := (SessionKey{ChatID: "chat-123", TopicID: "omt_1"}).String(); got != "chat-123/omt_1" {
+		t.Errorf("Expected chat-123/omt_1, got %s", got)
+	}
+}
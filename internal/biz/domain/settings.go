@@ -0,0 +1,25 @@
+package domain
+
+import "time"
+
+// ReplyMode controls how replies are posted
+type ReplyMode string
+
+const (
+	ReplyModeQuote ReplyMode = "quote" // Reply to the triggering message
+	ReplyModePlain ReplyMode = "plain" // Post a new message to the chat
+)
+
+// Valid reports whether m is a known reply mode
+func (m ReplyMode) Valid() bool {
+	return m == ReplyModeQuote || m == ReplyModePlain
+}
+
+// ChatSettings holds per-chat reply preferences
+type ChatSettings struct {
+	ChatID        string    `json:"chat_id"`
+	StreamReplies bool      `json:"stream_replies"` // Update a card as Codex generates the reply
+	ReplyMode     ReplyMode `json:"reply_mode"`
+	Model         string    `json:"model,omitempty"` // Codex model for new threads, empty for the default
+	UpdatedAt     time.Time `json:"updated_at"`
+}
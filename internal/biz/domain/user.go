@@ -0,0 +1,12 @@
+package domain
+
+import "time"
+
+// UserProfile is what the bridge knows about a Feishu user
+type UserProfile struct {
+	UserID     string // open_id, specific to the app
+	UnionID    string // Same across the tenant's apps
+	Name       string
+	Department string
+	UpdatedAt  time.Time
+}
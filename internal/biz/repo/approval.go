@@ -0,0 +1,21 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/anthropics/feishu-codex-bridge/internal/biz/domain"
+)
+
+// ApprovalRepo is the approval audit log repository interface
+type ApprovalRepo interface {
+	// SaveRecord saves a decided approval request
+	SaveRecord(ctx context.Context, record *domain.ApprovalRecord) error
+
+	// ListByChat lists approval records for a chat (newest first)
+	ListByChat(ctx context.Context, chatID string, limit int) ([]*domain.ApprovalRecord, error)
+
+	// ListByUser lists approval records decided by a user (newest first)
+	ListByUser(ctx context.Context, userID string, limit int) ([]*domain.ApprovalRecord, error)
+
+	Close() error
+}
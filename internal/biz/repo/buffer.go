@@ -17,6 +17,9 @@ type BufferRepo interface {
 	GetBufferSummary(ctx context.Context) ([]*domain.BufferSummary, error)
 	CleanupOld(ctx context.Context, before time.Time) (int64, error)
 
+	// DeleteChat deletes a chat's buffered messages and whitelist entry
+	DeleteChat(ctx context.Context, chatID string) error
+
 	// Whitelist operations
 	AddToWhitelist(ctx context.Context, entry *domain.WhitelistEntry) error
 	RemoveFromWhitelist(ctx context.Context, chatID string) error
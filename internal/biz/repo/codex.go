@@ -3,12 +3,14 @@ package repo
 import (
 	"context"
 	"time"
+
+	"github.com/anthropics/feishu-codex-bridge/internal/biz/domain"
 )
 
 // CodexRepo is the Codex interaction interface
 type CodexRepo interface {
-	// CreateThread creates a new Thread
-	CreateThread(ctx context.Context) (threadID string, err error)
+	// CreateThread creates a new Thread, using the app-server's model when model is empty
+	CreateThread(ctx context.Context, model string) (threadID string, err error)
 
 	// StartTurn starts a conversation turn
 	StartTurn(ctx context.Context, threadID, prompt string, images []string) (turnID string, err error)
@@ -16,16 +18,79 @@ type CodexRepo interface {
 	// ResumeThread resumes a Thread (checks if it exists)
 	ResumeThread(ctx context.Context, threadID string) error
 
+	// InterruptTurn interrupts the running turn of a Thread
+	InterruptTurn(ctx context.Context, threadID string) error
+
 	// Stop stops the Codex client
 	Stop()
 
-	// Events gets the event channel
-	Events() <-chan Event
+	// Subscribe delivers every event matching the filter until the subscription is closed
+	Subscribe(filter EventFilter) Subscription
+
+	// SubscribeTurn delivers every event of one turn and closes once the turn completes
+	// An empty turnID binds to the first turn seen in the thread, so subscribe before StartTurn
+	SubscribeTurn(threadID, turnID string) Subscription
+
+	// Status gets the app-server supervisor status
+	Status() CodexStatus
+
+	// SetApprovalHandler sets the handler for Codex approval requests
+	// Without a handler, all requests are auto-accepted
+	SetApprovalHandler(handler ApprovalHandler)
+
+	// RespondToApproval sends the decision for an approval request back to Codex
+	RespondToApproval(requestID int64, decision domain.ApprovalDecision) error
 
 	// DebugConversation runs a complete conversation synchronously for debugging
 	DebugConversation(ctx context.Context, prompt string, timeout time.Duration) (response string, threadID string, err error)
 }
 
+// CodexStatus represents the health of the Codex app-server
+type CodexStatus struct {
+	Running     bool      `json:"running"`
+	Restarting  bool      `json:"restarting"`
+	Restarts    int       `json:"restarts"`
+	LastRestart time.Time `json:"last_restart,omitempty"`
+	LastExitErr string    `json:"last_exit_error,omitempty"`
+}
+
+// EventFilter selects the events a subscription receives
+// Empty fields match everything
+type EventFilter struct {
+	ThreadID string
+	TurnID   string
+	Types    []EventType
+}
+
+// Matches reports whether the event passes the filter
+func (f EventFilter) Matches(event Event) bool {
+	if f.ThreadID != "" && event.ThreadID != f.ThreadID {
+		return false
+	}
+	if f.TurnID != "" && event.TurnID != f.TurnID {
+		return false
+	}
+	if len(f.Types) == 0 {
+		return true
+	}
+	for _, t := range f.Types {
+		if t == event.Type {
+			return true
+		}
+	}
+	return false
+}
+
+// Subscription is a stream of Codex events
+// Events are never dropped; the channel is closed when the subscription ends
+type Subscription interface {
+	Events() <-chan Event
+	Close()
+}
+
+// ApprovalHandler is called for each approval request from Codex
+type ApprovalHandler func(req *domain.ApprovalRequest)
+
 // Event represents a Codex event
 type Event struct {
 	Type     EventType
@@ -38,10 +103,12 @@ type Event struct {
 type EventType string
 
 const (
-	EventTypeAgentDelta    EventType = "agent_delta"
-	EventTypeTurnComplete  EventType = "turn_complete"
-	EventTypeItemCompleted EventType = "item_completed"
-	EventTypeError         EventType = "error"
+	EventTypeAgentDelta     EventType = "agent_delta"
+	EventTypeTurnComplete   EventType = "turn_complete"
+	EventTypeItemCompleted  EventType = "item_completed"
+	EventTypeError          EventType = "error"
+	EventTypeCodexExited    EventType = "codex_exited"    // app-server died, in-flight turns are lost
+	EventTypeCodexRestarted EventType = "codex_restarted" // app-server is back and initialized
 )
 
 // AgentDeltaData represents delta/incremental data
@@ -54,6 +121,11 @@ type TurnCompleteData struct {
 	Response string
 }
 
+// CodexRestartData represents restart data
+type CodexRestartData struct {
+	Restarts int
+}
+
 // ErrorData represents error data
 type ErrorData struct {
 	Error error
@@ -0,0 +1,18 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/anthropics/feishu-codex-bridge/internal/biz/domain"
+)
+
+// FeedbackRepo is the reply rating repository interface
+type FeedbackRepo interface {
+	// Save records a rating, replacing the operator's earlier rating of the same reply
+	Save(ctx context.Context, feedback *domain.Feedback) error
+
+	// ListByChat lists the ratings of a chat's replies, newest first
+	ListByChat(ctx context.Context, chatID string, limit int) ([]*domain.Feedback, error)
+
+	Close() error
+}
@@ -0,0 +1,34 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/anthropics/feishu-codex-bridge/internal/biz/domain"
+)
+
+// JournalRepo is the inbound message journal repository interface
+// Every received message is recorded once, with the state of its handling
+type JournalRepo interface {
+	// Receive records a message in state received
+	// Returns false if it was recorded before, e.g. an event delivered twice
+	Receive(ctx context.Context, entry *domain.JournalEntry) (bool, error)
+
+	// Get gets a message's entry, nil if it was never received
+	Get(ctx context.Context, msgID string) (*domain.JournalEntry, error)
+
+	// SetState moves messages to a state, errMsg says why they failed
+	SetState(ctx context.Context, msgIDs []string, state domain.JournalState, errMsg string) error
+
+	// ListUnfinished lists messages that are not replied or failed, oldest first
+	ListUnfinished(ctx context.Context) ([]*domain.JournalEntry, error)
+
+	// ClaimRecovery marks an unfinished message as resumed after a restart
+	// Returns false if it was resumed before
+	ClaimRecovery(ctx context.Context, msgID string) (bool, error)
+
+	// Prune deletes entries received before the given time
+	Prune(ctx context.Context, before time.Time) (int64, error)
+
+	Close() error
+}
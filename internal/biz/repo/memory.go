@@ -32,4 +32,8 @@ type MemoryRepo interface {
 	ListHeartbeats(ctx context.Context, enabledOnly bool) ([]*domain.HeartbeatConfig, error)
 	UpdateHeartbeatTime(ctx context.Context, chatID string, lastHeartbeat time.Time) error
 	DeleteHeartbeat(ctx context.Context, chatID string) error
+
+	// DeleteChat deletes the scheduled tasks and heartbeat of a chat
+	// Memories are knowledge rather than chat state and are kept
+	DeleteChat(ctx context.Context, chatID string) error
 }
@@ -13,6 +13,12 @@ type ChatInfo struct {
 	ChatType domain.ChatType
 }
 
+// ReplyTo identifies the message a reply answers
+type ReplyTo struct {
+	MsgID   string
+	InTopic bool // The message is in a topic thread, keep the reply there
+}
+
 // MessageRepo is the message repository interface
 // Responsible for fetching message data from Feishu API
 type MessageRepo interface {
@@ -20,6 +26,9 @@ type MessageRepo interface {
 	// Fetches in real-time from Feishu API, does not rely on local storage
 	GetChatHistory(ctx context.Context, chatID string, limit int) ([]domain.Message, error)
 
+	// GetThreadHistory gets the history of a topic thread in a chat
+	GetThreadHistory(ctx context.Context, chatID, threadID string, limit int) ([]domain.Message, error)
+
 	// GetChatMembers gets the list of chat members
 	GetChatMembers(ctx context.Context, chatID string) ([]domain.Member, error)
 
@@ -32,6 +41,60 @@ type MessageRepo interface {
 	// SendTextWithMentions sends a text message with @ mentions
 	SendTextWithMentions(ctx context.Context, chatID, text string, mentions []domain.Member) error
 
+	// SendTextMentionAll sends a text message that @ mentions everyone
+	SendTextMentionAll(ctx context.Context, chatID, text string) error
+
+	// SendMarkdown renders Markdown as rich text, splitting long replies into ordered messages
+	SendMarkdown(ctx context.Context, chatID, markdown string, mentions []domain.Member) error
+
+	// ReplyText replies to a message with text
+	ReplyText(ctx context.Context, to ReplyTo, text string) error
+
+	// ReplyMarkdown replies to a message with rich text, like SendMarkdown
+	ReplyMarkdown(ctx context.Context, to ReplyTo, markdown string, mentions []domain.Member) error
+
+	// ReplyCard replies to a message with an interactive card and returns the message ID
+	ReplyCard(ctx context.Context, to ReplyTo, card string) (string, error)
+
+	// SendCard sends an interactive card (JSON) and returns the message ID
+	SendCard(ctx context.Context, chatID, card string) (string, error)
+
+	// UpdateCard replaces the content of a sent card
+	UpdateCard(ctx context.Context, msgID, card string) error
+
+	// SendImage uploads a local image and posts it to a chat
+	SendImage(ctx context.Context, chatID, path string) error
+
+	// SendFile uploads a local file and posts it to a chat as an attachment
+	SendFile(ctx context.Context, chatID, path string) error
+
 	// AddReaction adds an emoji reaction
 	AddReaction(ctx context.Context, msgID, reactionType string) error
+
+	// RecallReplies recalls the bot's replies to a message and returns how many
+	RecallReplies(ctx context.Context, msgID string) (int, error)
+}
+
+// MemberCache is implemented by message repos that cache chat members
+type MemberCache interface {
+	// InvalidateMembers drops the cached members of a chat
+	InvalidateMembers(chatID string)
+}
+
+// DirectoryStats counts lookups of a cached member and chat directory
+type DirectoryStats struct {
+	Hits          int64 `json:"hits"`
+	Misses        int64 `json:"misses"`        // Lookups that called the Feishu API
+	Shared        int64 `json:"shared"`        // Lookups that waited for a concurrent identical call
+	Invalidations int64 `json:"invalidations"` // Chats dropped after member changes
+	Members       int   `json:"members"`       // Chats with cached members
+	Chats         int   `json:"chats"`         // Chats with cached info
+}
+
+// DirectoryCache is implemented by message repos that cache members and chat info
+type DirectoryCache interface {
+	MemberCache
+
+	// DirectoryStats returns the cache counters
+	DirectoryStats() DirectoryStats
 }
@@ -0,0 +1,39 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/anthropics/feishu-codex-bridge/internal/biz/domain"
+)
+
+// QueueRepo is the per-session message queue repository interface
+// A chat and each of its topic threads have their own queue
+type QueueRepo interface {
+	// Enqueue appends a message to its session's queue
+	Enqueue(ctx context.Context, msg *domain.QueuedMessage) error
+
+	// ListPending lists a session's queued messages, oldest first
+	ListPending(ctx context.Context, key domain.SessionKey, limit int) ([]*domain.QueuedMessage, error)
+
+	// Count returns the number of queued messages of a session
+	Count(ctx context.Context, key domain.SessionKey) (int, error)
+
+	// Remove deletes messages from the queue
+	Remove(ctx context.Context, ids []int64) error
+
+	// RemoveMessage deletes a queued message by its Feishu message ID
+	// Returns false if the message is not queued
+	RemoveMessage(ctx context.Context, msgID string) (bool, error)
+
+	// UpdateMessage replaces the content and images of a queued message
+	// Returns false if the message is not queued
+	UpdateMessage(ctx context.Context, msgID, content string, imagePaths []string) (bool, error)
+
+	// RemoveChat deletes the queued messages of a chat and all its topics
+	RemoveChat(ctx context.Context, chatID string) (int64, error)
+
+	// ListSessions lists sessions that have queued messages
+	ListSessions(ctx context.Context) ([]domain.SessionKey, error)
+
+	Close() error
+}
@@ -9,27 +9,31 @@ import (
 
 // SessionRepo is the session repository interface
 // Responsible for session persistence (SQLite)
+// Sessions are keyed by chat, and by topic thread within topic chats
 type SessionRepo interface {
-	// GetByChat gets a session by ChatID
-	GetByChat(ctx context.Context, chatID string) (*domain.Session, error)
+	// Get gets a session by key, nil if there is none
+	Get(ctx context.Context, key domain.SessionKey) (*domain.Session, error)
 
 	// Save saves a session (create or update)
 	Save(ctx context.Context, session *domain.Session) error
 
 	// Delete deletes a session
-	Delete(ctx context.Context, chatID string) error
+	Delete(ctx context.Context, key domain.SessionKey) error
+
+	// DeleteChat deletes the sessions of a chat and all its topics
+	DeleteChat(ctx context.Context, chatID string) error
 
 	// Touch updates session active time
-	Touch(ctx context.Context, chatID string) error
+	Touch(ctx context.Context, key domain.SessionKey) error
 
 	// MarkReplied marks session as replied
-	MarkReplied(ctx context.Context, chatID string) error
+	MarkReplied(ctx context.Context, key domain.SessionKey) error
 
 	// UpdateLastMsgTime updates the last processed message time
-	UpdateLastMsgTime(ctx context.Context, chatID string, msgTime time.Time) error
+	UpdateLastMsgTime(ctx context.Context, key domain.SessionKey, msgTime time.Time) error
 
 	// UpdateLastProcessedMsg updates the last processed message ID and time
-	UpdateLastProcessedMsg(ctx context.Context, chatID string, msgID string, msgTime time.Time) error
+	UpdateLastProcessedMsg(ctx context.Context, key domain.SessionKey, msgID string, msgTime time.Time) error
 
 	// CleanupStale cleans up stale sessions
 	CleanupStale(ctx context.Context, before time.Time) (int64, error)
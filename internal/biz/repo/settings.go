@@ -0,0 +1,18 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/anthropics/feishu-codex-bridge/internal/biz/domain"
+)
+
+// SettingsRepo is the per-chat settings repository interface
+type SettingsRepo interface {
+	// Get returns a chat's settings, or nil if the chat has none
+	Get(ctx context.Context, chatID string) (*domain.ChatSettings, error)
+
+	// Save creates or replaces a chat's settings
+	Save(ctx context.Context, settings *domain.ChatSettings) error
+
+	Close() error
+}
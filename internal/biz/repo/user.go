@@ -0,0 +1,14 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/anthropics/feishu-codex-bridge/internal/biz/domain"
+)
+
+// UserRepo resolves Feishu users by open_id
+// Profiles come from the contact API and are cached locally
+type UserRepo interface {
+	// GetUser returns a user's profile, nil if the user cannot be resolved
+	GetUser(ctx context.Context, userID string) (*domain.UserProfile, error)
+}
@@ -0,0 +1,70 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/anthropics/feishu-codex-bridge/internal/biz/domain"
+	"github.com/anthropics/feishu-codex-bridge/internal/biz/repo"
+)
+
+// ApprovalUsecase handles approval policy evaluation and the audit log
+type ApprovalUsecase struct {
+	approvalRepo repo.ApprovalRepo
+	policy       *domain.ApprovalPolicy
+}
+
+// NewApprovalUsecase creates a new approval usecase
+func NewApprovalUsecase(approvalRepo repo.ApprovalRepo, policy *domain.ApprovalPolicy) *ApprovalUsecase {
+	if policy.DefaultDecision != domain.ApprovalAccept {
+		policy.DefaultDecision = domain.ApprovalDecline
+	}
+	return &ApprovalUsecase{
+		approvalRepo: approvalRepo,
+		policy:       policy,
+	}
+}
+
+// Policy returns the approval policy
+func (uc *ApprovalUsecase) Policy() *domain.ApprovalPolicy {
+	return uc.policy
+}
+
+// Evaluate decides a request by policy. Returns ApprovalAsk when a human must decide.
+func (uc *ApprovalUsecase) Evaluate(req *domain.ApprovalRequest) domain.ApprovalDecision {
+	return uc.policy.Evaluate(req)
+}
+
+// Record saves a decision to the audit log
+func (uc *ApprovalUsecase) Record(ctx context.Context, req *domain.ApprovalRequest, decision domain.ApprovalDecision, source, decidedBy string) error {
+	record := &domain.ApprovalRecord{
+		RequestID: req.RequestID,
+		ChatID:    req.ChatID,
+		Kind:      req.Kind,
+		Summary:   req.Summary(),
+		Decision:  decision,
+		Source:    source,
+		DecidedBy: decidedBy,
+		CreatedAt: req.CreatedAt,
+		DecidedAt: time.Now(),
+	}
+	if record.CreatedAt.IsZero() {
+		record.CreatedAt = record.DecidedAt
+	}
+	if err := uc.approvalRepo.SaveRecord(ctx, record); err != nil {
+		return err
+	}
+	fmt.Printf("[Approval] %s %s (source=%s, by=%s, chat=%s)\n", decision, record.Summary, source, decidedBy, req.ChatID)
+	return nil
+}
+
+// ListByChat lists approval records for a chat
+func (uc *ApprovalUsecase) ListByChat(ctx context.Context, chatID string, limit int) ([]*domain.ApprovalRecord, error) {
+	return uc.approvalRepo.ListByChat(ctx, chatID, limit)
+}
+
+// ListByUser lists approval records decided by a user
+func (uc *ApprovalUsecase) ListByUser(ctx context.Context, userID string, limit int) ([]*domain.ApprovalRecord, error) {
+	return uc.approvalRepo.ListByUser(ctx, userID, limit)
+}
@@ -0,0 +1,131 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/anthropics/feishu-codex-bridge/internal/biz/domain"
+	"github.com/anthropics/feishu-codex-bridge/internal/biz/repo"
+)
+
+// ErrOutsideWorkspace is returned for paths that resolve outside the workspace
+var ErrOutsideWorkspace = errors.New("path is outside the workspace")
+
+// ArtifactConfig contains artifact upload configuration
+type ArtifactConfig struct {
+	Root          string // Workspace directory files must live in
+	MaxFileBytes  int64
+	MaxImageBytes int64
+}
+
+// DefaultArtifactConfig returns default artifact configuration for a workspace
+// The limits match what Feishu accepts for uploads
+func DefaultArtifactConfig(root string) ArtifactConfig {
+	return ArtifactConfig{
+		Root:          root,
+		MaxFileBytes:  30 << 20,
+		MaxImageBytes: 10 << 20,
+	}
+}
+
+// ArtifactUsecase posts files from the Codex workspace into chats
+type ArtifactUsecase struct {
+	messageRepo repo.MessageRepo
+	config      ArtifactConfig
+}
+
+// NewArtifactUsecase creates a new artifact usecase
+func NewArtifactUsecase(messageRepo repo.MessageRepo, config ArtifactConfig) *ArtifactUsecase {
+	return &ArtifactUsecase{
+		messageRepo: messageRepo,
+		config:      config,
+	}
+}
+
+// Send uploads a workspace file to a chat, as an image if it looks like one
+// Relative paths are resolved against the workspace root
+func (uc *ArtifactUsecase) Send(ctx context.Context, chatID, path string) (*domain.Artifact, error) {
+	artifact, err := uc.Resolve(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := uc.Post(ctx, chatID, artifact); err != nil {
+		return nil, err
+	}
+	return artifact, nil
+}
+
+// Post uploads an artifact returned by Resolve to a chat
+func (uc *ArtifactUsecase) Post(ctx context.Context, chatID string, artifact *domain.Artifact) error {
+	var err error
+	if artifact.Kind == domain.ArtifactImage {
+		err = uc.messageRepo.SendImage(ctx, chatID, artifact.Path)
+	} else {
+		err = uc.messageRepo.SendFile(ctx, chatID, artifact.Path)
+	}
+	if err != nil {
+		return fmt.Errorf("send %s: %w", artifact.Name, err)
+	}
+	fmt.Printf("[Artifact] Sent %s %s (%d bytes) to %s\n", artifact.Kind, artifact.Name, artifact.Size, chatID)
+	return nil
+}
+
+// Resolve checks that a path is a regular file inside the workspace and
+// within the size limit. Symlinks are followed before the check, so a link
+// cannot point the upload outside the workspace
+func (uc *ArtifactUsecase) Resolve(path string) (*domain.Artifact, error) {
+	if uc.config.Root == "" {
+		return nil, fmt.Errorf("no workspace configured")
+	}
+	if path == "" {
+		return nil, fmt.Errorf("path is required")
+	}
+
+	root, err := filepath.EvalSymlinks(uc.config.Root)
+	if err != nil {
+		return nil, fmt.Errorf("resolve workspace: %w", err)
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(root, path)
+	}
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return nil, fmt.Errorf("file not found: %s", path)
+	}
+
+	rel, err := filepath.Rel(root, resolved)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return nil, ErrOutsideWorkspace
+	}
+
+	info, err := os.Stat(resolved)
+	if err != nil {
+		return nil, fmt.Errorf("stat file: %w", err)
+	}
+	if !info.Mode().IsRegular() {
+		return nil, fmt.Errorf("not a regular file: %s", rel)
+	}
+
+	artifact := &domain.Artifact{
+		Path: resolved,
+		Name: filepath.Base(resolved),
+		Kind: domain.ArtifactKindFor(resolved),
+		Size: info.Size(),
+	}
+
+	limit := uc.config.MaxFileBytes
+	if artifact.Kind == domain.ArtifactImage {
+		limit = uc.config.MaxImageBytes
+	}
+	if artifact.Size == 0 {
+		return nil, fmt.Errorf("file is empty: %s", rel)
+	}
+	if limit > 0 && artifact.Size > limit {
+		return nil, fmt.Errorf("%s is %d bytes, over the %d byte limit for %s uploads", rel, artifact.Size, limit, artifact.Kind)
+	}
+	return artifact, nil
+}
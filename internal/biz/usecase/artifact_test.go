@@ -0,0 +1,90 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newArtifactWorkspace(t *testing.T) (string, string) {
+	t.Helper()
+	base := t.TempDir()
+	root := filepath.Join(base, "workspace")
+	if err := os.MkdirAll(filepath.Join(root, "out"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	write := func(path, content string) {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(filepath.Join(root, "out", "report.csv"), "a,b\n1,2\n")
+	write(filepath.Join(root, "chart.png"), "not really a png")
+	write(filepath.Join(base, "secret.txt"), "top secret")
+	return base, root
+}
+
+func TestArtifactSend(t *testing.T) {
+	_, root := newArtifactWorkspace(t)
+	msgRepo := &mockMessageRepo{}
+	uc := NewArtifactUsecase(msgRepo, DefaultArtifactConfig(root))
+
+	if _, err := uc.Send(context.Background(), "chat-1", "out/report.csv"); err != nil {
+		t.Fatalf("Send csv failed: %v", err)
+	}
+	if _, err := uc.Send(context.Background(), "chat-1", filepath.Join(root, "chart.png")); err != nil {
+		t.Fatalf("Send png failed: %v", err)
+	}
+
+	if len(msgRepo.sentFiles) != 2 ||
+		!strings.HasPrefix(msgRepo.sentFiles[0], "file:") ||
+		!strings.HasPrefix(msgRepo.sentFiles[1], "image:") {
+		t.Errorf("Expected a file then an image, got %v", msgRepo.sentFiles)
+	}
+}
+
+func TestArtifactResolveSandbox(t *testing.T) {
+	base, root := newArtifactWorkspace(t)
+	uc := NewArtifactUsecase(&mockMessageRepo{}, DefaultArtifactConfig(root))
+
+	// A symlink inside the workspace pointing out of it
+	if err := os.Symlink(filepath.Join(base, "secret.txt"), filepath.Join(root, "link.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{
+		"../secret.txt",
+		filepath.Join(base, "secret.txt"),
+		"out/../../secret.txt",
+		"link.txt",
+	} {
+		if _, err := uc.Resolve(path); !errors.Is(err, ErrOutsideWorkspace) {
+			t.Errorf("Resolve(%q) = %v, want ErrOutsideWorkspace", path, err)
+		}
+	}
+
+	if _, err := uc.Resolve("out"); err == nil {
+		t.Error("Expected directories to be rejected")
+	}
+	if _, err := uc.Resolve("missing.txt"); err == nil {
+		t.Error("Expected missing files to be rejected")
+	}
+}
+
+func TestArtifactResolveSizeLimit(t *testing.T) {
+	_, root := newArtifactWorkspace(t)
+	cfg := DefaultArtifactConfig(root)
+	cfg.MaxFileBytes = 4
+	uc := NewArtifactUsecase(&mockMessageRepo{}, cfg)
+
+	if _, err := uc.Resolve("out/report.csv"); err == nil || !strings.Contains(err.Error(), "limit") {
+		t.Errorf("Expected a size limit error, got %v", err)
+	}
+	// Images have their own limit
+	if _, err := uc.Resolve("chart.png"); err != nil {
+		t.Errorf("Expected the image to pass, got %v", err)
+	}
+}
@@ -21,15 +21,24 @@ func NewContextBuilderUsecase(messageRepo repo.MessageRepo) *ContextBuilderUseca
 }
 
 // BuildConversation builds conversation context (from Feishu API)
+// A topic session only sees the history of its topic thread
 func (uc *ContextBuilderUsecase) BuildConversation(
 	ctx context.Context,
-	chatID string,
+	key domain.SessionKey,
 	chatType domain.ChatType,
 	current *domain.Message,
 	historyLimit int,
 ) (*domain.Conversation, error) {
+	chatID := key.ChatID
+
 	// Get history from Feishu API
-	history, err := uc.messageRepo.GetChatHistory(ctx, chatID, historyLimit)
+	var history []domain.Message
+	var err error
+	if key.TopicID != "" {
+		history, err = uc.messageRepo.GetThreadHistory(ctx, chatID, key.TopicID, historyLimit)
+	} else {
+		history, err = uc.messageRepo.GetChatHistory(ctx, chatID, historyLimit)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("get chat history: %w", err)
 	}
@@ -54,6 +63,7 @@ type PromptConfig struct {
 	SystemPrompt        string // System prompt
 	HistoryMarker       string // History message marker
 	CurrentMarker       string // Current message marker
+	ReplyMarker         string // Marker of the message the current one replies to
 	MemberListHeader    string // Member list header
 	ChatContextTemplate string // Chat context template (supports {{chat_id}}, {{chat_type}})
 
@@ -157,6 +167,9 @@ These topics are used by the Filter Model to decide if a message needs response:
 - feishu_get_chat_members: Get member list for @mentioning
 - feishu_get_chat_history: Get more history messages
 
+### Files
+- feishu_send_file: Post a file or image from your workspace to the chat (e.g. a generated chart or report)
+
 ## Common Scenarios and Actions
 
 ### Scenario 1: "Watch this chat" / "This chat is important"
@@ -201,6 +214,7 @@ Before responding, read the "Recent chat messages" section. Users often refer to
 - Prefer concise responses`,
 	HistoryMarker:    "[Recent chat messages - for reference]",
 	CurrentMarker:    "[Current message]",
+	ReplyMarker:      "[Replying to - the current message quotes this message]",
 	MemberListHeader: "## Chat Members\nHere are the members of this chat. You can use [MENTION:user_id:name] to @ them:",
 	ChatContextTemplate: `## Current Chat Context
 - chat_id: {{chat_id}}
@@ -244,7 +258,10 @@ func (uc *ContextBuilderUsecase) FormatForNewThread(conv *domain.Conversation, c
 		parts = append(parts, historyText)
 	}
 
-	// 4. Current message
+	// 4. Quoted message and current message
+	if conv.Parent != nil {
+		parts = append(parts, uc.formatParentMessage(conv.Parent, cfg.ReplyMarker))
+	}
 	if conv.Current != nil {
 		currentText := uc.formatCurrentMessage(conv.Current, cfg.CurrentMarker)
 		parts = append(parts, currentText)
@@ -351,7 +368,10 @@ func (uc *ContextBuilderUsecase) FormatForResumedThread(
 		parts = append(parts, historyText)
 	}
 
-	// 2. Current message
+	// 2. Quoted message and current message
+	if conv.Parent != nil {
+		parts = append(parts, uc.formatParentMessage(conv.Parent, cfg.ReplyMarker))
+	}
 	if conv.Current != nil {
 		currentText := uc.formatCurrentMessage(conv.Current, cfg.CurrentMarker)
 		parts = append(parts, currentText)
@@ -424,6 +444,18 @@ func (uc *ContextBuilderUsecase) formatCurrentMessage(msg *domain.Message, marke
 	return fmt.Sprintf("%s\n[Message from %s]:\n%s", marker, name, msg.Content)
 }
 
+// formatParentMessage formats the message the current one quote-replies to
+func (uc *ContextBuilderUsecase) formatParentMessage(msg *domain.Message, marker string) string {
+	name := msg.SenderName
+	if name == "" {
+		name = msg.SenderID
+	}
+	if msg.IsBot {
+		name = "You (bot)"
+	}
+	return fmt.Sprintf("%s\n[Message from %s (msg_id: %s)]:\n%s", marker, name, msg.ID, msg.Content)
+}
+
 // FormatHistoryForFilter formats history messages for filter
 func (uc *ContextBuilderUsecase) FormatHistoryForFilter(messages []domain.Message) string {
 	var sb strings.Builder
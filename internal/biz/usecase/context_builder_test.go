@@ -11,8 +11,10 @@ import (
 )
 
 type mockMessageRepo struct {
-	history []domain.Message
-	members []domain.Member
+	history   []domain.Message
+	threads   map[string][]domain.Message // Topic thread ID -> history
+	members   []domain.Member
+	sentFiles []string // "image:<path>" or "file:<path>"
 }
 
 func (m *mockMessageRepo) GetChatHistory(ctx context.Context, chatID string, limit int) ([]domain.Message, error) {
@@ -22,6 +24,10 @@ func (m *mockMessageRepo) GetChatHistory(ctx context.Context, chatID string, lim
 	return m.history[:limit], nil
 }
 
+func (m *mockMessageRepo) GetThreadHistory(ctx context.Context, chatID, threadID string, limit int) ([]domain.Message, error) {
+	return m.threads[threadID], nil
+}
+
 func (m *mockMessageRepo) GetChatMembers(ctx context.Context, chatID string) ([]domain.Member, error) {
 	return m.members, nil
 }
@@ -38,10 +44,52 @@ func (m *mockMessageRepo) SendTextWithMentions(ctx context.Context, chatID, text
 	return nil
 }
 
+func (m *mockMessageRepo) SendTextMentionAll(ctx context.Context, chatID, text string) error {
+	return nil
+}
+
+func (m *mockMessageRepo) SendMarkdown(ctx context.Context, chatID, markdown string, mentions []domain.Member) error {
+	return nil
+}
+
+func (m *mockMessageRepo) ReplyText(ctx context.Context, to repo.ReplyTo, text string) error {
+	return nil
+}
+
+func (m *mockMessageRepo) ReplyMarkdown(ctx context.Context, to repo.ReplyTo, markdown string, mentions []domain.Member) error {
+	return nil
+}
+
+func (m *mockMessageRepo) ReplyCard(ctx context.Context, to repo.ReplyTo, card string) (string, error) {
+	return "", nil
+}
+
+func (m *mockMessageRepo) SendImage(ctx context.Context, chatID, path string) error {
+	m.sentFiles = append(m.sentFiles, "image:"+path)
+	return nil
+}
+
+func (m *mockMessageRepo) SendFile(ctx context.Context, chatID, path string) error {
+	m.sentFiles = append(m.sentFiles, "file:"+path)
+	return nil
+}
+
 func (m *mockMessageRepo) AddReaction(ctx context.Context, msgID, reactionType string) error {
 	return nil
 }
 
+func (m *mockMessageRepo) RecallReplies(ctx context.Context, msgID string) (int, error) {
+	return 0, nil
+}
+
+func (m *mockMessageRepo) SendCard(ctx context.Context, chatID, card string) (string, error) {
+	return "", nil
+}
+
+func (m *mockMessageRepo) UpdateCard(ctx context.Context, msgID, card string) error {
+	return nil
+}
+
 func TestBuildConversation(t *testing.T) {
 	now := time.Now()
 	msgRepo := &mockMessageRepo{
@@ -64,7 +112,7 @@ func TestBuildConversation(t *testing.T) {
 		CreateTime: now,
 	}
 
-	conv, err := uc.BuildConversation(context.Background(), "chat-123", domain.ChatTypeGroup, currentMsg, 10)
+	conv, err := uc.BuildConversation(context.Background(), domain.SessionKey{ChatID: "chat-123"}, domain.ChatTypeGroup, currentMsg, 10)
 	if err != nil {
 		t.Fatalf("Unexpected error: %v", err)
 	}
@@ -83,6 +131,28 @@ func TestBuildConversation(t *testing.T) {
 	}
 }
 
+func TestBuildConversation_TopicUsesThreadHistory(t *testing.T) {
+	msgRepo := &mockMessageRepo{
+		history: []domain.Message{{ID: "1", Content: "Chat message"}},
+		threads: map[string][]domain.Message{
+			"omt_1": {{ID: "2", Content: "Topic message"}},
+		},
+	}
+	uc := NewContextBuilderUsecase(msgRepo)
+
+	key := domain.SessionKey{ChatID: "chat-123", TopicID: "omt_1"}
+	conv, err := uc.BuildConversation(context.Background(), key, domain.ChatTypeGroup, &domain.Message{ID: "3"}, 10)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if conv.ChatID != "chat-123" {
+		t.Errorf("Expected chat-123, got %s", conv.ChatID)
+	}
+	if len(conv.History) != 1 || conv.History[0].Content != "Topic message" {
+		t.Errorf("Expected only the topic's history, got %+v", conv.History)
+	}
+}
+
 func TestFormatForNewThread(t *testing.T) {
 	uc := &ContextBuilderUsecase{}
 
@@ -166,6 +236,37 @@ func TestFormatForResumedThread(t *testing.T) {
 	}
 }
 
+func TestFormatForResumedThread_ReplyingToOldMessage(t *testing.T) {
+	uc := &ContextBuilderUsecase{}
+
+	now := time.Now()
+	conv := &domain.Conversation{
+		ChatID:   "chat-123",
+		ChatType: domain.ChatTypeGroup,
+		History: []domain.Message{
+			{ID: "1", Content: "panic: nil map", SenderName: "Bob", CreateTime: now.Add(-time.Hour)},
+		},
+		Current: &domain.Message{ID: "3", Content: "what does this error mean?", SenderName: "Alice", ParentID: "1", CreateTime: now},
+		Parent:  &domain.Message{ID: "1", Content: "panic: nil map", SenderName: "Bob", CreateTime: now.Add(-time.Hour)},
+	}
+
+	cfg := DefaultPromptConfig
+	// The parent was processed long ago, so it is not in the recent history
+	prompt := uc.FormatForResumedThread(conv, "", now.Add(-10*time.Minute), now.Add(-10*time.Minute), cfg)
+
+	quoted := strings.Index(prompt, cfg.ReplyMarker+"\n[Message from Bob (msg_id: 1)]:\npanic: nil map")
+	current := strings.Index(prompt, cfg.CurrentMarker)
+	if quoted < 0 {
+		t.Fatalf("Expected a section for the quoted message, got:\n%s", prompt)
+	}
+	if current < quoted {
+		t.Error("Expected the quoted message before the current message")
+	}
+	if strings.Contains(prompt, cfg.HistoryMarker) {
+		t.Error("Did not expect the quoted message to be repeated as history")
+	}
+}
+
 func TestFormatForResumedThread_NoRecentHistory(t *testing.T) {
 	uc := &ContextBuilderUsecase{}
 
@@ -41,7 +41,14 @@ type TriggerRequest struct {
 	SenderName    string
 	ChatType      domain.ChatType
 	ImagePaths    []string
-	MsgCreateTime int64 // Message creation time (milliseconds Unix timestamp from Feishu)
+	MsgCreateTime int64           // Message creation time (milliseconds Unix timestamp from Feishu)
+	TopicID       string          // Feishu topic thread of the message, which gets its own session
+	Parent        *domain.Message // Message it quote-replies to, if any
+}
+
+// Key returns the key of the session the request belongs to
+func (r *TriggerRequest) Key() domain.SessionKey {
+	return domain.SessionKey{ChatID: r.ChatID, TopicID: r.TopicID}
 }
 
 // TriggerResponse represents a trigger response
@@ -49,12 +56,13 @@ type TriggerResponse struct {
 	ThreadID string
 	TurnID   string
 	IsNew    bool
+	Events   repo.Subscription // Events of the started turn, closed when it completes
 }
 
 // Trigger triggers a conversation (core method)
 func (uc *ConversationUsecase) Trigger(ctx context.Context, req *TriggerRequest) (*TriggerResponse, error) {
 	// 1. Resolve Thread
-	decision, err := uc.sessionUC.ResolveThread(ctx, req.ChatID)
+	decision, err := uc.sessionUC.ResolveThread(ctx, req.Key())
 	if err != nil {
 		return nil, fmt.Errorf("resolve thread: %w", err)
 	}
@@ -74,6 +82,9 @@ func (uc *ConversationUsecase) Trigger(ctx context.Context, req *TriggerRequest)
 		SenderName: req.SenderName,
 		CreateTime: msgTime,
 	}
+	if req.Parent != nil {
+		current.ParentID = req.Parent.ID
+	}
 
 	// 3. Build conversation context from Feishu API
 	historyLimit := 20
@@ -81,10 +92,12 @@ func (uc *ConversationUsecase) Trigger(ctx context.Context, req *TriggerRequest)
 		historyLimit = 30 // Get more history for new Thread
 	}
 
-	conv, err := uc.contextUC.BuildConversation(ctx, req.ChatID, req.ChatType, current, historyLimit)
+	conv, err := uc.contextUC.BuildConversation(ctx, req.Key(), req.ChatType, current, historyLimit)
 	if err != nil {
 		return nil, fmt.Errorf("build conversation: %w", err)
 	}
+	// The parent may be older than the fetched history, it gets its own section
+	conv.Parent = req.Parent
 
 	// 4. Format Prompt
 	var prompt string
@@ -103,15 +116,18 @@ func (uc *ConversationUsecase) Trigger(ctx context.Context, req *TriggerRequest)
 	fmt.Printf("[ConvUC] ========== FULL PROMPT START ==========\n%s\n[ConvUC] ========== FULL PROMPT END ==========\n", prompt)
 
 	// 5. Send to Codex
+	// Subscribe first so no event of the new turn is missed
+	events := uc.codexRepo.SubscribeTurn(decision.ThreadID, "")
 	turnID, err := uc.codexRepo.StartTurn(ctx, decision.ThreadID, prompt, req.ImagePaths)
 	if err != nil {
+		events.Close()
 		return nil, fmt.Errorf("start turn: %w", err)
 	}
 
 	// 6. Update LastProcessedMsgID and LastMsgTime to current message
 	// Use message ID as primary anchor, timestamp as fallback
 	// This ensures accurate "where to continue" regardless of bridge restart
-	if err := uc.sessionUC.UpdateLastProcessedMsg(ctx, req.ChatID, req.MsgID, current.CreateTime); err != nil {
+	if err := uc.sessionUC.UpdateLastProcessedMsg(ctx, req.Key(), req.MsgID, current.CreateTime); err != nil {
 		fmt.Printf("[ConvUC] Warning: failed to update last processed msg: %v\n", err)
 	}
 
@@ -119,15 +135,36 @@ func (uc *ConversationUsecase) Trigger(ctx context.Context, req *TriggerRequest)
 		ThreadID: decision.ThreadID,
 		TurnID:   turnID,
 		IsNew:    decision.IsNew,
+		Events:   events,
 	}, nil
 }
 
+// ResumeActiveThreads re-attaches active sessions after a Codex restart
+func (uc *ConversationUsecase) ResumeActiveThreads(ctx context.Context) (int, error) {
+	return uc.sessionUC.ResumeActiveThreads(ctx)
+}
+
 // OnReplyComplete callback when reply is complete
-func (uc *ConversationUsecase) OnReplyComplete(ctx context.Context, chatID string) error {
-	return uc.sessionUC.MarkReplied(ctx, chatID)
+func (uc *ConversationUsecase) OnReplyComplete(ctx context.Context, key domain.SessionKey) error {
+	return uc.sessionUC.MarkReplied(ctx, key)
+}
+
+// ResetSession drops a session so its next message starts a new thread
+func (uc *ConversationUsecase) ResetSession(ctx context.Context, key domain.SessionKey) error {
+	return uc.sessionUC.Reset(ctx, key)
+}
+
+// GetSession gets a session, nil if there is none
+func (uc *ConversationUsecase) GetSession(ctx context.Context, key domain.SessionKey) (*domain.Session, error) {
+	return uc.sessionUC.GetSession(ctx, key)
+}
+
+// FindSessionByThread returns the session bound to a Codex thread, nil if there is none
+func (uc *ConversationUsecase) FindSessionByThread(ctx context.Context, threadID string) (*domain.Session, error) {
+	return uc.sessionUC.FindByThread(ctx, threadID)
 }
 
 // Touch updates session active time
-func (uc *ConversationUsecase) Touch(ctx context.Context, chatID string) error {
-	return uc.sessionUC.Touch(ctx, chatID)
+func (uc *ConversationUsecase) Touch(ctx context.Context, key domain.SessionKey) error {
+	return uc.sessionUC.Touch(ctx, key)
 }
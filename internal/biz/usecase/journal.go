@@ -0,0 +1,190 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/anthropics/feishu-codex-bridge/internal/biz/domain"
+	"github.com/anthropics/feishu-codex-bridge/internal/biz/repo"
+)
+
+// JournalConfig contains inbound message journal configuration
+type JournalConfig struct {
+	CatchUpWindow time.Duration // Only sessions and messages this recent are caught up on
+	HistoryLimit  int           // Messages listed per session when catching up
+	Retention     time.Duration // Entries older than this are pruned
+}
+
+// DefaultJournalConfig returns default journal configuration
+func DefaultJournalConfig() JournalConfig {
+	return JournalConfig{
+		CatchUpWindow: 24 * time.Hour,
+		HistoryLimit:  50,
+		Retention:     7 * 24 * time.Hour,
+	}
+}
+
+// JournalUsecase records received messages and finds the ones left unanswered
+// by a restart or a dropped connection
+type JournalUsecase struct {
+	journalRepo repo.JournalRepo
+	sessionRepo repo.SessionRepo
+	messageRepo repo.MessageRepo
+	config      JournalConfig
+}
+
+// NewJournalUsecase creates a new journal usecase
+func NewJournalUsecase(journalRepo repo.JournalRepo, sessionRepo repo.SessionRepo, messageRepo repo.MessageRepo, config JournalConfig) *JournalUsecase {
+	if config.HistoryLimit <= 0 {
+		config.HistoryLimit = DefaultJournalConfig().HistoryLimit
+	}
+	if config.Retention < config.CatchUpWindow {
+		config.Retention = config.CatchUpWindow
+	}
+	return &JournalUsecase{
+		journalRepo: journalRepo,
+		sessionRepo: sessionRepo,
+		messageRepo: messageRepo,
+		config:      config,
+	}
+}
+
+// Receive records a received message
+// Returns false if it was received before, the caller should drop it
+func (uc *JournalUsecase) Receive(ctx context.Context, key domain.SessionKey, msgID string, createTime time.Time) (bool, error) {
+	return uc.journalRepo.Receive(ctx, &domain.JournalEntry{
+		ChatID:     key.ChatID,
+		TopicID:    key.TopicID,
+		MsgID:      msgID,
+		CreateTime: createTime,
+	})
+}
+
+// Mark moves messages to a state
+// Failures are only logged, the journal never holds up a reply
+func (uc *JournalUsecase) Mark(ctx context.Context, state domain.JournalState, msgIDs ...string) {
+	if err := uc.journalRepo.SetState(ctx, msgIDs, state, ""); err != nil {
+		fmt.Printf("[Journal] Failed to mark %v %s: %v\n", msgIDs, state, err)
+	}
+}
+
+// Fail marks messages as given up on
+func (uc *JournalUsecase) Fail(ctx context.Context, reason string, msgIDs ...string) {
+	if err := uc.journalRepo.SetState(ctx, msgIDs, domain.JournalFailed, reason); err != nil {
+		fmt.Printf("[Journal] Failed to mark %v failed: %v\n", msgIDs, err)
+	}
+}
+
+// Unfinished returns the messages a restart interrupted, each one only once
+// Queued messages are left to the persistent queue. Messages that were resumed
+// before, or are older than the catch-up window, are marked failed instead
+func (uc *JournalUsecase) Unfinished(ctx context.Context) ([]*domain.JournalEntry, error) {
+	entries, err := uc.journalRepo.ListUnfinished(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cutoff := time.Now().Add(-uc.config.CatchUpWindow)
+	var resume []*domain.JournalEntry
+	for _, entry := range entries {
+		switch {
+		case entry.State == domain.JournalQueued:
+			continue
+		case entry.Recovered:
+			uc.Fail(ctx, "interrupted again after being resumed", entry.MsgID)
+		case entry.ReceivedAt.Before(cutoff):
+			uc.Fail(ctx, "too old to resume", entry.MsgID)
+		default:
+			claimed, err := uc.journalRepo.ClaimRecovery(ctx, entry.MsgID)
+			if err != nil {
+				fmt.Printf("[Journal] Failed to claim %s: %v\n", entry.MsgID, err)
+				continue
+			}
+			if claimed {
+				resume = append(resume, entry)
+			}
+		}
+	}
+	return resume, nil
+}
+
+// Missed lists messages posted to recently active sessions after their last
+// processed message that never reached the bridge, oldest first
+func (uc *JournalUsecase) Missed(ctx context.Context) ([]domain.Message, error) {
+	sessions, err := uc.sessionRepo.ListAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cutoff := time.Now().Add(-uc.config.CatchUpWindow)
+	seen := make(map[string]bool)
+	var missed []domain.Message
+	for _, session := range sessions {
+		if session.UpdatedAt.Before(cutoff) {
+			continue
+		}
+		if session.LastProcessedMsgID == "" && session.LastMsgTime.Unix() <= 0 {
+			continue // Nothing processed yet, no anchor to continue from
+		}
+
+		var history []domain.Message
+		if session.TopicID != "" {
+			history, err = uc.messageRepo.GetThreadHistory(ctx, session.ChatID, session.TopicID, uc.config.HistoryLimit)
+		} else {
+			history, err = uc.messageRepo.GetChatHistory(ctx, session.ChatID, uc.config.HistoryLimit)
+		}
+		if err != nil {
+			fmt.Printf("[Journal] Failed to list history of %s: %v\n", session.Key(), err)
+			continue
+		}
+
+		for _, msg := range messagesAfter(history, session) {
+			if msg.IsBot || seen[msg.ID] || msg.CreateTime.Before(cutoff) {
+				continue
+			}
+			entry, err := uc.journalRepo.Get(ctx, msg.ID)
+			if err != nil || entry != nil {
+				continue
+			}
+			seen[msg.ID] = true
+			missed = append(missed, msg)
+		}
+	}
+
+	sort.SliceStable(missed, func(i, j int) bool {
+		return missed[i].CreateTime.Before(missed[j].CreateTime)
+	})
+	return missed, nil
+}
+
+// Prune deletes entries older than the retention period
+func (uc *JournalUsecase) Prune(ctx context.Context) (int64, error) {
+	return uc.journalRepo.Prune(ctx, time.Now().Add(-uc.config.Retention))
+}
+
+// messagesAfter returns the messages of history, oldest first, that follow
+// the session's last processed message
+// Falls back to the last processed time when the message scrolled out of history
+func messagesAfter(history []domain.Message, session *domain.Session) []domain.Message {
+	if session.LastProcessedMsgID != "" {
+		for i, msg := range history {
+			if msg.ID == session.LastProcessedMsgID {
+				return history[i+1:]
+			}
+		}
+	}
+	if session.LastMsgTime.Unix() <= 0 {
+		return nil
+	}
+
+	// Stored times have second precision, the journal filters the anchor's neighbours
+	var after []domain.Message
+	for _, msg := range history {
+		if msg.ID != session.LastProcessedMsgID && msg.CreateTime.Unix() >= session.LastMsgTime.Unix() {
+			after = append(after, msg)
+		}
+	}
+	return after
+}
@@ -0,0 +1,161 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/anthropics/feishu-codex-bridge/internal/biz/domain"
+)
+
+type mockJournalRepo struct {
+	entries map[string]*domain.JournalEntry
+}
+
+func newMockJournalRepo() *mockJournalRepo {
+	return &mockJournalRepo{entries: make(map[string]*domain.JournalEntry)}
+}
+
+func (m *mockJournalRepo) Receive(ctx context.Context, entry *domain.JournalEntry) (bool, error) {
+	if _, ok := m.entries[entry.MsgID]; ok {
+		return false, nil
+	}
+	if entry.State == "" {
+		entry.State = domain.JournalReceived
+	}
+	if entry.ReceivedAt.IsZero() {
+		entry.ReceivedAt = time.Now()
+	}
+	m.entries[entry.MsgID] = entry
+	return true, nil
+}
+
+func (m *mockJournalRepo) Get(ctx context.Context, msgID string) (*domain.JournalEntry, error) {
+	return m.entries[msgID], nil
+}
+
+func (m *mockJournalRepo) SetState(ctx context.Context, msgIDs []string, state domain.JournalState, errMsg string) error {
+	for _, id := range msgIDs {
+		if entry, ok := m.entries[id]; ok {
+			entry.State = state
+			entry.Error = errMsg
+		}
+	}
+	return nil
+}
+
+func (m *mockJournalRepo) ListUnfinished(ctx context.Context) ([]*domain.JournalEntry, error) {
+	var result []*domain.JournalEntry
+	for _, entry := range m.entries {
+		if !entry.State.Finished() {
+			result = append(result, entry)
+		}
+	}
+	return result, nil
+}
+
+func (m *mockJournalRepo) ClaimRecovery(ctx context.Context, msgID string) (bool, error) {
+	entry, ok := m.entries[msgID]
+	if !ok || entry.Recovered {
+		return false, nil
+	}
+	entry.Recovered = true
+	return true, nil
+}
+
+func (m *mockJournalRepo) Prune(ctx context.Context, before time.Time) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockJournalRepo) Close() error {
+	return nil
+}
+
+func TestJournalUsecase_UnfinishedResumesOnce(t *testing.T) {
+	journalRepo := newMockJournalRepo()
+	uc := NewJournalUsecase(journalRepo, &mockSessionRepo{}, &mockMessageRepo{}, DefaultJournalConfig())
+	ctx := context.Background()
+
+	key := domain.SessionKey{ChatID: "oc_1"}
+	for _, id := range []string{"om_received", "om_processing", "om_queued", "om_replied", "om_stale"} {
+		_, _ = uc.Receive(ctx, key, id, time.Now())
+	}
+	uc.Mark(ctx, domain.JournalProcessing, "om_processing")
+	uc.Mark(ctx, domain.JournalQueued, "om_queued")
+	uc.Mark(ctx, domain.JournalReplied, "om_replied")
+	journalRepo.entries["om_stale"].ReceivedAt = time.Now().Add(-48 * time.Hour)
+
+	resume, err := uc.Unfinished(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]bool)
+	for _, entry := range resume {
+		got[entry.MsgID] = true
+	}
+	if len(got) != 2 || !got["om_received"] || !got["om_processing"] {
+		t.Errorf("Expected the received and processing messages to be resumed, got %v", got)
+	}
+	if state := journalRepo.entries["om_stale"].State; state != domain.JournalFailed {
+		t.Errorf("Expected the stale message to fail, got %s", state)
+	}
+	if state := journalRepo.entries["om_queued"].State; state != domain.JournalQueued {
+		t.Errorf("Expected the queued message to be left to the queue, got %s", state)
+	}
+
+	// Interrupted again: given up instead of resumed a second time
+	resume, _ = uc.Unfinished(ctx)
+	if len(resume) != 0 {
+		t.Errorf("Expected nothing to be resumed twice, got %d", len(resume))
+	}
+	if entry := journalRepo.entries["om_processing"]; entry.State != domain.JournalFailed || entry.Error == "" {
+		t.Errorf("Expected the message to fail with a reason, got %+v", entry)
+	}
+}
+
+func TestJournalUsecase_MissedSinceLastProcessed(t *testing.T) {
+	now := time.Now()
+	msg := func(id string, ago time.Duration, isBot bool) domain.Message {
+		return domain.Message{ID: id, ChatID: "oc_1", CreateTime: now.Add(-ago), IsBot: isBot}
+	}
+	messageRepo := &mockMessageRepo{
+		history: []domain.Message{
+			msg("om_1", 10*time.Minute, false),
+			msg("om_anchor", 9*time.Minute, false),
+			msg("om_reply", 8*time.Minute, true),
+			msg("om_seen", 7*time.Minute, false),
+			msg("om_missed", 6*time.Minute, false),
+		},
+		threads: map[string][]domain.Message{
+			"omt_1": {
+				msg("om_t1", 30*time.Minute, false),
+				msg("om_t2", 2*time.Minute, false),
+			},
+		},
+	}
+	sessionRepo := &mockSessionRepo{sessions: map[string]*domain.Session{
+		"oc_1": {ChatID: "oc_1", UpdatedAt: now, LastProcessedMsgID: "om_anchor"},
+		// Anchor scrolled out of the listed history, its time is used
+		"oc_1/omt_1": {ChatID: "oc_1", TopicID: "omt_1", UpdatedAt: now, LastProcessedMsgID: "om_gone", LastMsgTime: now.Add(-20 * time.Minute)},
+		// Idle for too long
+		"oc_2": {ChatID: "oc_2", UpdatedAt: now.Add(-72 * time.Hour), LastProcessedMsgID: "om_x"},
+		// Never processed a message
+		"oc_3": {ChatID: "oc_3", UpdatedAt: now, LastMsgTime: time.Unix(0, 0)},
+	}}
+	journalRepo := newMockJournalRepo()
+	uc := NewJournalUsecase(journalRepo, sessionRepo, messageRepo, DefaultJournalConfig())
+	ctx := context.Background()
+	_, _ = uc.Receive(ctx, domain.SessionKey{ChatID: "oc_1"}, "om_seen", now)
+
+	missed, err := uc.Missed(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, m := range missed {
+		ids = append(ids, m.ID)
+	}
+	if len(ids) != 2 || ids[0] != "om_missed" || ids[1] != "om_t2" {
+		t.Errorf("Expected om_missed then om_t2, got %v", ids)
+	}
+}
@@ -0,0 +1,51 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/anthropics/feishu-codex-bridge/internal/biz/repo"
+)
+
+// LifecycleUsecase handles the stored state of chats the bot joins and leaves
+type LifecycleUsecase struct {
+	sessionRepo repo.SessionRepo
+	bufferRepo  repo.BufferRepo
+	memoryRepo  repo.MemoryRepo
+}
+
+// NewLifecycleUsecase creates a new lifecycle usecase
+// bufferRepo and memoryRepo may be nil when those features are off
+func NewLifecycleUsecase(sessionRepo repo.SessionRepo, bufferRepo repo.BufferRepo, memoryRepo repo.MemoryRepo) *LifecycleUsecase {
+	return &LifecycleUsecase{
+		sessionRepo: sessionRepo,
+		bufferRepo:  bufferRepo,
+		memoryRepo:  memoryRepo,
+	}
+}
+
+// CleanupChat deletes everything stored for a chat the bot left:
+// its sessions, buffered messages, whitelist entry, scheduled tasks and heartbeat.
+// Every store is cleaned even if one fails, the errors are joined
+func (uc *LifecycleUsecase) CleanupChat(ctx context.Context, chatID string) error {
+	var errs []error
+	if err := uc.sessionRepo.DeleteChat(ctx, chatID); err != nil {
+		errs = append(errs, fmt.Errorf("sessions: %w", err))
+	}
+	if uc.bufferRepo != nil {
+		if err := uc.bufferRepo.DeleteChat(ctx, chatID); err != nil {
+			errs = append(errs, fmt.Errorf("buffer: %w", err))
+		}
+	}
+	if uc.memoryRepo != nil {
+		if err := uc.memoryRepo.DeleteChat(ctx, chatID); err != nil {
+			errs = append(errs, fmt.Errorf("tasks: %w", err))
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	fmt.Printf("[LifecycleUC] Cleaned up state of %s\n", chatID)
+	return nil
+}
@@ -0,0 +1,111 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/anthropics/feishu-codex-bridge/internal/biz/domain"
+	"github.com/anthropics/feishu-codex-bridge/internal/biz/repo"
+)
+
+// QueueConfig contains per-chat queue configuration
+type QueueConfig struct {
+	Coalesce    bool // Combine queued messages into one turn
+	MaxCoalesce int  // Max messages combined into one turn
+}
+
+// DefaultQueueConfig returns default queue configuration
+func DefaultQueueConfig() QueueConfig {
+	return QueueConfig{
+		Coalesce:    false,
+		MaxCoalesce: 5,
+	}
+}
+
+// QueueUsecase holds messages that arrive while their session has a turn in flight
+type QueueUsecase struct {
+	queueRepo repo.QueueRepo
+	config    QueueConfig
+}
+
+// NewQueueUsecase creates a new queue usecase
+func NewQueueUsecase(queueRepo repo.QueueRepo, config QueueConfig) *QueueUsecase {
+	if config.MaxCoalesce <= 0 {
+		config.MaxCoalesce = 1
+	}
+	return &QueueUsecase{
+		queueRepo: queueRepo,
+		config:    config,
+	}
+}
+
+// Enqueue adds a message to its session's queue and returns its 1-based position
+func (uc *QueueUsecase) Enqueue(ctx context.Context, msg *domain.QueuedMessage) (int, error) {
+	if err := uc.queueRepo.Enqueue(ctx, msg); err != nil {
+		return 0, err
+	}
+	return uc.queueRepo.Count(ctx, msg.Key())
+}
+
+// HasPending checks whether a session has queued messages
+func (uc *QueueUsecase) HasPending(ctx context.Context, key domain.SessionKey) bool {
+	count, err := uc.queueRepo.Count(ctx, key)
+	return err == nil && count > 0
+}
+
+// Count returns the number of queued messages of a session
+func (uc *QueueUsecase) Count(ctx context.Context, key domain.SessionKey) (int, error) {
+	return uc.queueRepo.Count(ctx, key)
+}
+
+// Next takes the next message off a session's queue, coalescing several if enabled
+// Returns nil when the queue is empty
+func (uc *QueueUsecase) Next(ctx context.Context, key domain.SessionKey) (*domain.QueuedMessage, error) {
+	limit := 1
+	if uc.config.Coalesce {
+		limit = uc.config.MaxCoalesce
+	}
+
+	msgs, err := uc.queueRepo.ListPending(ctx, key, limit)
+	if err != nil {
+		return nil, err
+	}
+	if len(msgs) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]int64, len(msgs))
+	for i, msg := range msgs {
+		ids[i] = msg.ID
+	}
+	if err := uc.queueRepo.Remove(ctx, ids); err != nil {
+		return nil, fmt.Errorf("dequeue: %w", err)
+	}
+
+	if len(msgs) > 1 {
+		fmt.Printf("[QueueUC] Coalesced %d queued messages in %s\n", len(msgs), key)
+	}
+	return domain.CoalesceMessages(msgs), nil
+}
+
+// Drop removes a recalled message from the queue
+// Returns false if the message is not queued
+func (uc *QueueUsecase) Drop(ctx context.Context, msgID string) (bool, error) {
+	return uc.queueRepo.RemoveMessage(ctx, msgID)
+}
+
+// Edit replaces the content of a queued message that was edited
+// Returns false if the message is not queued
+func (uc *QueueUsecase) Edit(ctx context.Context, msgID, content string, imagePaths []string) (bool, error) {
+	return uc.queueRepo.UpdateMessage(ctx, msgID, content, imagePaths)
+}
+
+// DropChat removes the queued messages of a chat and all its topics
+func (uc *QueueUsecase) DropChat(ctx context.Context, chatID string) (int64, error) {
+	return uc.queueRepo.RemoveChat(ctx, chatID)
+}
+
+// PendingSessions lists sessions with queued messages (for resuming after a restart)
+func (uc *QueueUsecase) PendingSessions(ctx context.Context) ([]domain.SessionKey, error) {
+	return uc.queueRepo.ListSessions(ctx)
+}
@@ -0,0 +1,144 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+
+	"github.com/anthropics/feishu-codex-bridge/internal/biz/domain"
+)
+
+type mockQueueRepo struct {
+	msgs   []*domain.QueuedMessage
+	nextID int64
+}
+
+func (m *mockQueueRepo) Enqueue(ctx context.Context, msg *domain.QueuedMessage) error {
+	m.nextID++
+	msg.ID = m.nextID
+	m.msgs = append(m.msgs, msg)
+	return nil
+}
+
+func (m *mockQueueRepo) ListPending(ctx context.Context, key domain.SessionKey, limit int) ([]*domain.QueuedMessage, error) {
+	var result []*domain.QueuedMessage
+	for _, msg := range m.msgs {
+		if msg.Key() == key && len(result) < limit {
+			result = append(result, msg)
+		}
+	}
+	return result, nil
+}
+
+func (m *mockQueueRepo) Count(ctx context.Context, key domain.SessionKey) (int, error) {
+	count := 0
+	for _, msg := range m.msgs {
+		if msg.Key() == key {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (m *mockQueueRepo) Remove(ctx context.Context, ids []int64) error {
+	remove := make(map[int64]bool)
+	for _, id := range ids {
+		remove[id] = true
+	}
+	var kept []*domain.QueuedMessage
+	for _, msg := range m.msgs {
+		if !remove[msg.ID] {
+			kept = append(kept, msg)
+		}
+	}
+	m.msgs = kept
+	return nil
+}
+
+func (m *mockQueueRepo) RemoveChat(ctx context.Context, chatID string) (int64, error) {
+	var kept []*domain.QueuedMessage
+	for _, msg := range m.msgs {
+		if msg.ChatID != chatID {
+			kept = append(kept, msg)
+		}
+	}
+	removed := int64(len(m.msgs) - len(kept))
+	m.msgs = kept
+	return removed, nil
+}
+
+func (m *mockQueueRepo) RemoveMessage(ctx context.Context, msgID string) (bool, error) {
+	for i, msg := range m.msgs {
+		if msg.MsgID == msgID {
+			m.msgs = append(m.msgs[:i], m.msgs[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *mockQueueRepo) UpdateMessage(ctx context.Context, msgID, content string, imagePaths []string) (bool, error) {
+	for _, msg := range m.msgs {
+		if msg.MsgID == msgID {
+			msg.Content = content
+			msg.ImagePaths = imagePaths
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *mockQueueRepo) ListSessions(ctx context.Context) ([]domain.SessionKey, error) {
+	return nil, nil
+}
+
+func (m *mockQueueRepo) Close() error {
+	return nil
+}
+
+func TestQueueUsecase_FIFO(t *testing.T) {
+	uc := NewQueueUsecase(&mockQueueRepo{}, DefaultQueueConfig())
+	ctx := context.Background()
+
+	pos1, _ := uc.Enqueue(ctx, &domain.QueuedMessage{ChatID: "chat-1", MsgID: "m1"})
+	_, _ = uc.Enqueue(ctx, &domain.QueuedMessage{ChatID: "chat-2", MsgID: "other"})
+	pos2, _ := uc.Enqueue(ctx, &domain.QueuedMessage{ChatID: "chat-1", MsgID: "m2"})
+	if pos1 != 1 || pos2 != 2 {
+		t.Errorf("Expected positions 1 and 2, got %d and %d", pos1, pos2)
+	}
+
+	for _, want := range []string{"m1", "m2"} {
+		next, err := uc.Next(ctx, domain.SessionKey{ChatID: "chat-1"})
+		if err != nil || next == nil {
+			t.Fatalf("Expected queued message, got %v (err=%v)", next, err)
+		}
+		if next.MsgID != want {
+			t.Errorf("Expected %s, got %s", want, next.MsgID)
+		}
+	}
+
+	if next, _ := uc.Next(ctx, domain.SessionKey{ChatID: "chat-1"}); next != nil {
+		t.Errorf("Expected empty queue, got %s", next.MsgID)
+	}
+	if !uc.HasPending(ctx, domain.SessionKey{ChatID: "chat-2"}) {
+		t.Error("Expected other chat's queue to be untouched")
+	}
+}
+
+func TestQueueUsecase_Coalesce(t *testing.T) {
+	uc := NewQueueUsecase(&mockQueueRepo{}, QueueConfig{Coalesce: true, MaxCoalesce: 2})
+	ctx := context.Background()
+
+	for _, id := range []string{"m1", "m2", "m3"} {
+		_, _ = uc.Enqueue(ctx, &domain.QueuedMessage{ChatID: "chat-1", MsgID: id, Content: id, SenderName: "Alice"})
+	}
+
+	next, _ := uc.Next(ctx, domain.SessionKey{ChatID: "chat-1"})
+	if next.MsgID != "m2" || next.Content != "[Alice]: m1\n[Alice]: m2" {
+		t.Errorf("Unexpected coalesced message: %s %q", next.MsgID, next.Content)
+	}
+
+	next, _ = uc.Next(ctx, domain.SessionKey{ChatID: "chat-1"})
+	if next.MsgID != "m3" || next.Content != "m3" {
+		t.Errorf("Unexpected remaining message: %s %q", next.MsgID, next.Content)
+	}
+}
@@ -13,6 +13,7 @@ import (
 type SessionUsecase struct {
 	sessionRepo repo.SessionRepo
 	codexRepo   repo.CodexRepo
+	settingsUC  *SettingsUsecase
 	config      domain.SessionConfig
 }
 
@@ -29,6 +30,11 @@ func NewSessionUsecase(
 	}
 }
 
+// SetSettingsUsecase makes new threads use the model chosen in the chat's settings
+func (uc *SessionUsecase) SetSettingsUsecase(settingsUC *SettingsUsecase) {
+	uc.settingsUC = settingsUC
+}
+
 // ThreadDecision represents the thread decision result
 type ThreadDecision struct {
 	ThreadID           string
@@ -39,22 +45,22 @@ type ThreadDecision struct {
 }
 
 // ResolveThread resolves Thread (create or reuse)
-func (uc *SessionUsecase) ResolveThread(ctx context.Context, chatID string) (*ThreadDecision, error) {
-	session, err := uc.sessionRepo.GetByChat(ctx, chatID)
+func (uc *SessionUsecase) ResolveThread(ctx context.Context, key domain.SessionKey) (*ThreadDecision, error) {
+	session, err := uc.sessionRepo.Get(ctx, key)
 	if err != nil {
 		return nil, fmt.Errorf("get session: %w", err)
 	}
 
 	// Need to create new Thread
 	if session == nil || !session.IsFresh(uc.config) {
-		return uc.createNewThread(ctx, chatID)
+		return uc.createNewThread(ctx, key)
 	}
 
 	// Verify Thread exists
 	if err := uc.codexRepo.ResumeThread(ctx, session.ThreadID); err != nil {
 		// Thread lost, recreate
-		_ = uc.sessionRepo.Delete(ctx, chatID)
-		return uc.createNewThread(ctx, chatID)
+		_ = uc.sessionRepo.Delete(ctx, key)
+		return uc.createNewThread(ctx, key)
 	}
 
 	return &ThreadDecision{
@@ -66,15 +72,17 @@ func (uc *SessionUsecase) ResolveThread(ctx context.Context, chatID string) (*Th
 	}, nil
 }
 
-func (uc *SessionUsecase) createNewThread(ctx context.Context, chatID string) (*ThreadDecision, error) {
-	threadID, err := uc.codexRepo.CreateThread(ctx)
+func (uc *SessionUsecase) createNewThread(ctx context.Context, key domain.SessionKey) (*ThreadDecision, error) {
+	// Topic sessions use the model of their chat
+	threadID, err := uc.codexRepo.CreateThread(ctx, uc.model(ctx, key.ChatID))
 	if err != nil {
 		return nil, fmt.Errorf("create thread: %w", err)
 	}
 
 	now := time.Now()
 	session := &domain.Session{
-		ChatID:    chatID,
+		ChatID:    key.ChatID,
+		TopicID:   key.TopicID,
 		ThreadID:  threadID,
 		CreatedAt: now,
 		UpdatedAt: now,
@@ -90,27 +98,82 @@ func (uc *SessionUsecase) createNewThread(ctx context.Context, chatID string) (*
 	}, nil
 }
 
+// model returns the Codex model chosen for a chat, empty for the default
+func (uc *SessionUsecase) model(ctx context.Context, chatID string) string {
+	if uc.settingsUC == nil {
+		return ""
+	}
+	settings, err := uc.settingsUC.Get(ctx, chatID)
+	if err != nil {
+		fmt.Printf("[Session] Failed to load settings for %s: %v\n", chatID, err)
+		return ""
+	}
+	return settings.Model
+}
+
+// Reset drops a session so its next message starts a new thread
+func (uc *SessionUsecase) Reset(ctx context.Context, key domain.SessionKey) error {
+	return uc.sessionRepo.Delete(ctx, key)
+}
+
 // MarkReplied marks session as replied
-func (uc *SessionUsecase) MarkReplied(ctx context.Context, chatID string) error {
-	return uc.sessionRepo.MarkReplied(ctx, chatID)
+func (uc *SessionUsecase) MarkReplied(ctx context.Context, key domain.SessionKey) error {
+	return uc.sessionRepo.MarkReplied(ctx, key)
 }
 
 // Touch updates session active time
-func (uc *SessionUsecase) Touch(ctx context.Context, chatID string) error {
-	return uc.sessionRepo.Touch(ctx, chatID)
+func (uc *SessionUsecase) Touch(ctx context.Context, key domain.SessionKey) error {
+	return uc.sessionRepo.Touch(ctx, key)
 }
 
 // GetSession gets a session
-func (uc *SessionUsecase) GetSession(ctx context.Context, chatID string) (*domain.Session, error) {
-	return uc.sessionRepo.GetByChat(ctx, chatID)
+func (uc *SessionUsecase) GetSession(ctx context.Context, key domain.SessionKey) (*domain.Session, error) {
+	return uc.sessionRepo.Get(ctx, key)
+}
+
+// FindByThread returns the session bound to a Codex thread, nil if there is none
+func (uc *SessionUsecase) FindByThread(ctx context.Context, threadID string) (*domain.Session, error) {
+	sessions, err := uc.sessionRepo.ListAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list sessions: %w", err)
+	}
+	for _, session := range sessions {
+		if session.ThreadID == threadID {
+			return session, nil
+		}
+	}
+	return nil, nil
 }
 
 // UpdateLastMsgTime updates the last processed message time
-func (uc *SessionUsecase) UpdateLastMsgTime(ctx context.Context, chatID string, msgTime time.Time) error {
-	return uc.sessionRepo.UpdateLastMsgTime(ctx, chatID, msgTime)
+func (uc *SessionUsecase) UpdateLastMsgTime(ctx context.Context, key domain.SessionKey, msgTime time.Time) error {
+	return uc.sessionRepo.UpdateLastMsgTime(ctx, key, msgTime)
 }
 
 // UpdateLastProcessedMsg updates the last processed message ID and time
-func (uc *SessionUsecase) UpdateLastProcessedMsg(ctx context.Context, chatID string, msgID string, msgTime time.Time) error {
-	return uc.sessionRepo.UpdateLastProcessedMsg(ctx, chatID, msgID, msgTime)
+func (uc *SessionUsecase) UpdateLastProcessedMsg(ctx context.Context, key domain.SessionKey, msgID string, msgTime time.Time) error {
+	return uc.sessionRepo.UpdateLastProcessedMsg(ctx, key, msgID, msgTime)
+}
+
+// ResumeActiveThreads re-attaches fresh sessions to Codex after an app-server restart
+// Sessions whose thread cannot be resumed are deleted so the next message creates a new one
+func (uc *SessionUsecase) ResumeActiveThreads(ctx context.Context) (int, error) {
+	sessions, err := uc.sessionRepo.ListAll(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("list sessions: %w", err)
+	}
+
+	resumed := 0
+	for _, session := range sessions {
+		if !session.IsFresh(uc.config) {
+			continue
+		}
+		if err := uc.codexRepo.ResumeThread(ctx, session.ThreadID); err != nil {
+			fmt.Printf("[Session] Failed to resume thread %s for %s: %v\n", session.ThreadID, session.Key(), err)
+			_ = uc.sessionRepo.Delete(ctx, session.Key())
+			continue
+		}
+		resumed++
+	}
+	return resumed, nil
 }
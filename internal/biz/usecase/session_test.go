@@ -2,6 +2,7 @@ package usecase
 
 import (
 	"context"
+	"fmt"
 	"testing"
 	"time"
 
@@ -15,29 +16,38 @@ type mockSessionRepo struct {
 	sessions map[string]*domain.Session
 }
 
-func (m *mockSessionRepo) GetByChat(ctx context.Context, chatID string) (*domain.Session, error) {
-	return m.sessions[chatID], nil
+func (m *mockSessionRepo) Get(ctx context.Context, key domain.SessionKey) (*domain.Session, error) {
+	return m.sessions[key.String()], nil
 }
 
 func (m *mockSessionRepo) Save(ctx context.Context, session *domain.Session) error {
-	m.sessions[session.ChatID] = session
+	m.sessions[session.Key().String()] = session
 	return nil
 }
 
-func (m *mockSessionRepo) Delete(ctx context.Context, chatID string) error {
-	delete(m.sessions, chatID)
+func (m *mockSessionRepo) Delete(ctx context.Context, key domain.SessionKey) error {
+	delete(m.sessions, key.String())
 	return nil
 }
 
-func (m *mockSessionRepo) Touch(ctx context.Context, chatID string) error {
-	if s, ok := m.sessions[chatID]; ok {
+func (m *mockSessionRepo) DeleteChat(ctx context.Context, chatID string) error {
+	for k, s := range m.sessions {
+		if s.ChatID == chatID {
+			delete(m.sessions, k)
+		}
+	}
+	return nil
+}
+
+func (m *mockSessionRepo) Touch(ctx context.Context, key domain.SessionKey) error {
+	if s, ok := m.sessions[key.String()]; ok {
 		s.UpdatedAt = time.Now()
 	}
 	return nil
 }
 
-func (m *mockSessionRepo) MarkReplied(ctx context.Context, chatID string) error {
-	if s, ok := m.sessions[chatID]; ok {
+func (m *mockSessionRepo) MarkReplied(ctx context.Context, key domain.SessionKey) error {
+	if s, ok := m.sessions[key.String()]; ok {
 		now := time.Now()
 		s.UpdatedAt = now
 		s.LastReplyAt = now
@@ -49,16 +59,16 @@ func (m *mockSessionRepo) CleanupStale(ctx context.Context, before time.Time) (i
 	return 0, nil
 }
 
-func (m *mockSessionRepo) UpdateLastMsgTime(ctx context.Context, chatID string, msgTime time.Time) error {
-	if s, ok := m.sessions[chatID]; ok {
+func (m *mockSessionRepo) UpdateLastMsgTime(ctx context.Context, key domain.SessionKey, msgTime time.Time) error {
+	if s, ok := m.sessions[key.String()]; ok {
 		s.LastMsgTime = msgTime
 		s.UpdatedAt = time.Now()
 	}
 	return nil
 }
 
-func (m *mockSessionRepo) UpdateLastProcessedMsg(ctx context.Context, chatID string, msgID string, msgTime time.Time) error {
-	if s, ok := m.sessions[chatID]; ok {
+func (m *mockSessionRepo) UpdateLastProcessedMsg(ctx context.Context, key domain.SessionKey, msgID string, msgTime time.Time) error {
+	if s, ok := m.sessions[key.String()]; ok {
 		s.LastProcessedMsgID = msgID
 		s.LastMsgTime = msgTime
 		s.UpdatedAt = time.Now()
@@ -80,11 +90,13 @@ func (m *mockSessionRepo) Close() error {
 
 type mockCodexRepo struct {
 	threadCounter int
-	events        chan repo.Event
+	lostThreads   map[string]bool // Threads that fail to resume
+	models        []string        // Model of each created thread
 }
 
-func (m *mockCodexRepo) CreateThread(ctx context.Context) (string, error) {
+func (m *mockCodexRepo) CreateThread(ctx context.Context, model string) (string, error) {
 	m.threadCounter++
+	m.models = append(m.models, model)
 	return "thread-" + string(rune('0'+m.threadCounter)), nil
 }
 
@@ -93,22 +105,50 @@ func (m *mockCodexRepo) StartTurn(ctx context.Context, threadID, prompt string,
 }
 
 func (m *mockCodexRepo) ResumeThread(ctx context.Context, threadID string) error {
+	if m.lostThreads[threadID] {
+		return fmt.Errorf("thread not found: %s", threadID)
+	}
+	return nil
+}
+
+func (m *mockCodexRepo) InterruptTurn(ctx context.Context, threadID string) error {
 	return nil
 }
 
 func (m *mockCodexRepo) Stop() {}
 
-func (m *mockCodexRepo) Events() <-chan repo.Event {
-	if m.events == nil {
-		m.events = make(chan repo.Event, 10)
-	}
-	return m.events
+func (m *mockCodexRepo) Subscribe(filter repo.EventFilter) repo.Subscription {
+	return &mockSubscription{ch: make(chan repo.Event)}
+}
+
+func (m *mockCodexRepo) SubscribeTurn(threadID, turnID string) repo.Subscription {
+	return &mockSubscription{ch: make(chan repo.Event)}
+}
+
+func (m *mockCodexRepo) Status() repo.CodexStatus {
+	return repo.CodexStatus{Running: true}
 }
 
 func (m *mockCodexRepo) DebugConversation(ctx context.Context, prompt string, timeout time.Duration) (string, string, error) {
 	return "mock response", "mock-thread", nil
 }
 
+func (m *mockCodexRepo) SetApprovalHandler(handler repo.ApprovalHandler) {}
+
+func (m *mockCodexRepo) RespondToApproval(requestID int64, decision domain.ApprovalDecision) error {
+	return nil
+}
+
+type mockSubscription struct {
+	ch chan repo.Event
+}
+
+func (s *mockSubscription) Events() <-chan repo.Event {
+	return s.ch
+}
+
+func (s *mockSubscription) Close() {}
+
 // Tests
 
 func TestResolveThread_NewSession(t *testing.T) {
@@ -118,7 +158,7 @@ func TestResolveThread_NewSession(t *testing.T) {
 
 	uc := NewSessionUsecase(sessionRepo, codexRepo, cfg)
 
-	decision, err := uc.ResolveThread(context.Background(), "chat-123")
+	decision, err := uc.ResolveThread(context.Background(), domain.SessionKey{ChatID: "chat-123"})
 	if err != nil {
 		t.Fatalf("Unexpected error: %v", err)
 	}
@@ -136,6 +176,32 @@ func TestResolveThread_NewSession(t *testing.T) {
 	}
 }
 
+func TestResolveThread_TopicSessionsAreSeparate(t *testing.T) {
+	sessionRepo := &mockSessionRepo{sessions: make(map[string]*domain.Session)}
+	codexRepo := &mockCodexRepo{}
+	uc := NewSessionUsecase(sessionRepo, codexRepo, domain.SessionConfig{IdleTimeout: time.Hour, ResetHour: -1})
+	ctx := context.Background()
+
+	chat, _ := uc.ResolveThread(ctx, domain.SessionKey{ChatID: "chat-123"})
+	topicA, _ := uc.ResolveThread(ctx, domain.SessionKey{ChatID: "chat-123", TopicID: "omt_a"})
+	topicB, _ := uc.ResolveThread(ctx, domain.SessionKey{ChatID: "chat-123", TopicID: "omt_b"})
+	if chat.ThreadID == topicA.ThreadID || topicA.ThreadID == topicB.ThreadID || chat.ThreadID == topicB.ThreadID {
+		t.Fatalf("Expected one thread per topic, got %s, %s, %s", chat.ThreadID, topicA.ThreadID, topicB.ThreadID)
+	}
+
+	// Resetting a topic leaves the chat and other topics alone
+	if err := uc.Reset(ctx, domain.SessionKey{ChatID: "chat-123", TopicID: "omt_a"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	again, _ := uc.ResolveThread(ctx, domain.SessionKey{ChatID: "chat-123", TopicID: "omt_b"})
+	if again.IsNew || again.ThreadID != topicB.ThreadID {
+		t.Errorf("Expected topic B to keep %s, got %+v", topicB.ThreadID, again)
+	}
+	if sessionRepo.sessions["chat-123"] == nil || sessionRepo.sessions["chat-123/omt_a"] != nil {
+		t.Errorf("Expected only topic A to be reset, got %v", sessionRepo.sessions)
+	}
+}
+
 func TestResolveThread_ExistingFreshSession(t *testing.T) {
 	sessionRepo := &mockSessionRepo{sessions: make(map[string]*domain.Session)}
 	codexRepo := &mockCodexRepo{}
@@ -152,7 +218,7 @@ func TestResolveThread_ExistingFreshSession(t *testing.T) {
 
 	uc := NewSessionUsecase(sessionRepo, codexRepo, cfg)
 
-	decision, err := uc.ResolveThread(context.Background(), "chat-123")
+	decision, err := uc.ResolveThread(context.Background(), domain.SessionKey{ChatID: "chat-123"})
 	if err != nil {
 		t.Fatalf("Unexpected error: %v", err)
 	}
@@ -181,7 +247,7 @@ func TestResolveThread_StaleSession_IdleTimeout(t *testing.T) {
 
 	uc := NewSessionUsecase(sessionRepo, codexRepo, cfg)
 
-	decision, err := uc.ResolveThread(context.Background(), "chat-123")
+	decision, err := uc.ResolveThread(context.Background(), domain.SessionKey{ChatID: "chat-123"})
 	if err != nil {
 		t.Fatalf("Unexpected error: %v", err)
 	}
@@ -194,6 +260,43 @@ func TestResolveThread_StaleSession_IdleTimeout(t *testing.T) {
 	}
 }
 
+func TestResumeActiveThreads(t *testing.T) {
+	sessionRepo := &mockSessionRepo{sessions: make(map[string]*domain.Session)}
+	codexRepo := &mockCodexRepo{lostThreads: map[string]bool{"lost-thread": true}}
+	cfg := domain.SessionConfig{IdleTimeout: time.Hour, ResetHour: -1}
+
+	recent := time.Now().Add(-5 * time.Minute)
+	sessionRepo.sessions["chat-ok"] = &domain.Session{
+		ChatID: "chat-ok", ThreadID: "ok-thread", CreatedAt: recent, UpdatedAt: recent,
+	}
+	sessionRepo.sessions["chat-lost"] = &domain.Session{
+		ChatID: "chat-lost", ThreadID: "lost-thread", CreatedAt: recent, UpdatedAt: recent,
+	}
+	stale := time.Now().Add(-2 * time.Hour)
+	sessionRepo.sessions["chat-stale"] = &domain.Session{
+		ChatID: "chat-stale", ThreadID: "lost-thread", CreatedAt: stale, UpdatedAt: stale,
+	}
+
+	uc := NewSessionUsecase(sessionRepo, codexRepo, cfg)
+
+	resumed, err := uc.ResumeActiveThreads(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if resumed != 1 {
+		t.Errorf("Expected 1 resumed thread, got %d", resumed)
+	}
+	if sessionRepo.sessions["chat-lost"] != nil {
+		t.Error("Expected session with lost thread to be deleted")
+	}
+	if sessionRepo.sessions["chat-ok"] == nil {
+		t.Error("Expected resumed session to be kept")
+	}
+	if sessionRepo.sessions["chat-stale"] == nil {
+		t.Error("Expected stale session to be skipped, not deleted")
+	}
+}
+
 func TestMarkReplied(t *testing.T) {
 	sessionRepo := &mockSessionRepo{sessions: make(map[string]*domain.Session)}
 	codexRepo := &mockCodexRepo{}
@@ -211,7 +314,7 @@ func TestMarkReplied(t *testing.T) {
 
 	uc := NewSessionUsecase(sessionRepo, codexRepo, cfg)
 
-	err := uc.MarkReplied(context.Background(), "chat-123")
+	err := uc.MarkReplied(context.Background(), domain.SessionKey{ChatID: "chat-123"})
 	if err != nil {
 		t.Fatalf("Unexpected error: %v", err)
 	}
@@ -224,3 +327,53 @@ func TestMarkReplied(t *testing.T) {
 		t.Error("Expected LastReplyAt to be updated")
 	}
 }
+
+type mockSettingsRepo struct {
+	settings map[string]*domain.ChatSettings
+}
+
+func (m *mockSettingsRepo) Get(ctx context.Context, chatID string) (*domain.ChatSettings, error) {
+	return m.settings[chatID], nil
+}
+
+func (m *mockSettingsRepo) Save(ctx context.Context, settings *domain.ChatSettings) error {
+	m.settings[settings.ChatID] = settings
+	return nil
+}
+
+func (m *mockSettingsRepo) Close() error {
+	return nil
+}
+
+func TestResolveThread_ChatModelAfterReset(t *testing.T) {
+	sessionRepo := &mockSessionRepo{sessions: make(map[string]*domain.Session)}
+	codexRepo := &mockCodexRepo{}
+	settingsRepo := &mockSettingsRepo{settings: make(map[string]*domain.ChatSettings)}
+	cfg := domain.SessionConfig{IdleTimeout: time.Hour, ResetHour: -1}
+
+	uc := NewSessionUsecase(sessionRepo, codexRepo, cfg)
+	uc.SetSettingsUsecase(NewSettingsUsecase(settingsRepo, domain.ChatSettings{}))
+	ctx := context.Background()
+
+	first, err := uc.ResolveThread(ctx, domain.SessionKey{ChatID: "chat-123"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Choosing a model only affects new threads, so reset the session
+	settingsRepo.settings["chat-123"] = &domain.ChatSettings{ChatID: "chat-123", Model: "gpt-5-codex"}
+	if err := uc.Reset(ctx, domain.SessionKey{ChatID: "chat-123"}); err != nil {
+		t.Fatalf("Reset failed: %v", err)
+	}
+
+	second, err := uc.ResolveThread(ctx, domain.SessionKey{ChatID: "chat-123"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !second.IsNew || second.ThreadID == first.ThreadID {
+		t.Error("Expected a new thread after reset")
+	}
+	if len(codexRepo.models) != 2 || codexRepo.models[0] != "" || codexRepo.models[1] != "gpt-5-codex" {
+		t.Errorf("Expected threads created with the default then the chat model, got %q", codexRepo.models)
+	}
+}
@@ -0,0 +1,48 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/anthropics/feishu-codex-bridge/internal/biz/domain"
+	"github.com/anthropics/feishu-codex-bridge/internal/biz/repo"
+)
+
+// SettingsUsecase resolves per-chat settings, falling back to global defaults
+type SettingsUsecase struct {
+	settingsRepo repo.SettingsRepo
+	defaults     domain.ChatSettings
+}
+
+// NewSettingsUsecase creates a new settings usecase
+func NewSettingsUsecase(settingsRepo repo.SettingsRepo, defaults domain.ChatSettings) *SettingsUsecase {
+	return &SettingsUsecase{
+		settingsRepo: settingsRepo,
+		defaults:     defaults,
+	}
+}
+
+// Get returns a chat's settings, or the defaults if the chat has none
+func (uc *SettingsUsecase) Get(ctx context.Context, chatID string) (*domain.ChatSettings, error) {
+	settings, err := uc.settingsRepo.Get(ctx, chatID)
+	if err != nil {
+		return nil, err
+	}
+	if settings == nil {
+		defaults := uc.defaults
+		defaults.ChatID = chatID
+		return &defaults, nil
+	}
+	// Rows saved before a setting existed leave it empty
+	if settings.ReplyMode == "" {
+		settings.ReplyMode = uc.defaults.ReplyMode
+	}
+	if settings.Model == "" {
+		settings.Model = uc.defaults.Model
+	}
+	return settings, nil
+}
+
+// Save stores a chat's settings
+func (uc *SettingsUsecase) Save(ctx context.Context, settings *domain.ChatSettings) error {
+	return uc.settingsRepo.Save(ctx, settings)
+}
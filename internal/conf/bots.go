@@ -0,0 +1,145 @@
+package conf
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/anthropics/feishu-codex-bridge/internal/infra/feishu"
+	"gopkg.in/yaml.v3"
+)
+
+// BotConfig describes one Feishu app served by the bridge
+type BotConfig struct {
+	ID            string       `yaml:"id"`
+	Feishu        FeishuConfig `yaml:",inline"`
+	PromptsConfig string       `yaml:"prompts_config"` // Prompts YAML path, empty for the shared prompts
+	WorkingDir    string       `yaml:"working_dir"`    // Codex working directory, empty for WORKING_DIR
+	APIPort       int          `yaml:"api_port"`       // Port of the bot's HTTP API, 0 for the next free default
+
+	Prompts *PromptsConfig `yaml:"-"`
+}
+
+// botsFile is the layout of the BOTS_CONFIG_PATH file
+type botsFile struct {
+	Bots []BotConfig `yaml:"bots"`
+}
+
+// LoadBotsConfig loads the bots of a multi-bot setup from a YAML file
+// ${VAR} references are expanded from the environment, so secrets can stay there.
+// Domain, transport, working directory and prompts a bot leaves empty are taken from base
+func LoadBotsConfig(path string, base BotConfig) ([]BotConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bots config: %w", err)
+	}
+
+	var file botsFile
+	if err := yaml.Unmarshal([]byte(os.ExpandEnv(string(data))), &file); err != nil {
+		return nil, fmt.Errorf("failed to parse bots config: %w", err)
+	}
+	if len(file.Bots) == 0 {
+		return nil, fmt.Errorf("no bots in %s", path)
+	}
+
+	for i := range file.Bots {
+		bot := &file.Bots[i]
+		if bot.Feishu.Domain == "" {
+			bot.Feishu.Domain = base.Feishu.Domain
+		}
+		if bot.Feishu.EventMode == "" {
+			bot.Feishu.EventMode = base.Feishu.EventMode
+		}
+		if bot.Feishu.WebhookAddr == "" {
+			bot.Feishu.WebhookAddr = base.Feishu.WebhookAddr
+		}
+		if bot.Feishu.WebhookPath == "" {
+			bot.Feishu.WebhookPath = base.Feishu.WebhookPath
+		}
+		if bot.WorkingDir == "" {
+			bot.WorkingDir = base.WorkingDir
+		}
+		bot.Prompts = base.Prompts
+		if bot.PromptsConfig != "" {
+			prompts, err := LoadPromptsConfig(bot.PromptsConfig)
+			if err != nil {
+				return nil, fmt.Errorf("bot %s: %w", bot.ID, err)
+			}
+			bot.Prompts = prompts
+		}
+	}
+
+	fmt.Printf("[Config] Loaded %d bots from: %s\n", len(file.Bots), path)
+	return file.Bots, nil
+}
+
+// validateBots checks that bots are identifiable and don't compete for ports
+func validateBots(bots []BotConfig) error {
+	if len(bots) == 0 {
+		return &ConfigError{Field: "BOTS_CONFIG_PATH", Message: "no bots configured"}
+	}
+
+	ids := make(map[string]bool)
+	ports := make(map[int]bool)
+	addrs := make(map[string]bool)
+	for _, bot := range bots {
+		if bot.ID == "" {
+			return &ConfigError{Field: "bots.id", Message: "required"}
+		}
+		if ids[bot.ID] {
+			return &ConfigError{Field: "bots.id", Message: "duplicate bot " + bot.ID}
+		}
+		ids[bot.ID] = true
+
+		if bot.Feishu.AppID == "" || bot.Feishu.AppSecret == "" {
+			return &ConfigError{Field: "bots." + bot.ID + ".app_id/app_secret", Message: "required"}
+		}
+		if _, err := feishu.ResolveBaseURL(bot.Feishu.Domain); err != nil {
+			return &ConfigError{Field: "bots." + bot.ID + ".domain", Message: err.Error()}
+		}
+		if bot.APIPort != 0 {
+			if ports[bot.APIPort] {
+				return &ConfigError{Field: "bots." + bot.ID + ".api_port", Message: "used by another bot"}
+			}
+			ports[bot.APIPort] = true
+		}
+
+		switch bot.Feishu.EventMode {
+		case EventModeWebSocket:
+		case EventModeWebhook:
+			// The token answers Feishu's URL verification challenge
+			if bot.Feishu.VerificationToken == "" {
+				return &ConfigError{Field: "bots." + bot.ID + ".verification_token", Message: "required in webhook mode"}
+			}
+			if addrs[bot.Feishu.WebhookAddr] {
+				return &ConfigError{Field: "bots." + bot.ID + ".webhook_addr", Message: "used by another bot"}
+			}
+			addrs[bot.Feishu.WebhookAddr] = true
+		default:
+			return &ConfigError{Field: "bots." + bot.ID + ".event_mode", Message: "must be websocket or webhook"}
+		}
+	}
+	return nil
+}
+
+// ForBot returns the configuration seen by one bot
+// Credentials, bot name, prompts and working directory are the bot's own,
+// everything else is shared
+func (c *Config) ForBot(bot BotConfig) *Config {
+	cfg := *c
+	cfg.Feishu = bot.Feishu
+	cfg.Codex.WorkingDir = bot.WorkingDir
+	if bot.Prompts != nil {
+		cfg.Prompts = bot.Prompts
+		cfg.Prompt = PromptConfigValues{
+			MaxHistoryCount:   bot.Prompts.History.MaxCount,
+			MaxHistoryMinutes: bot.Prompts.History.MaxMinutes,
+		}
+	}
+
+	// Attachments default to the working directory, follow the bot's
+	if c.Attachment.Dir == filepath.Join(c.Codex.WorkingDir, defaultAttachmentDir) {
+		cfg.Attachment.Dir = filepath.Join(bot.WorkingDir, defaultAttachmentDir)
+	}
+	return &cfg
+}
@@ -1,15 +1,26 @@
 package conf
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/anthropics/feishu-codex-bridge/internal/biz/domain"
 	"github.com/anthropics/feishu-codex-bridge/internal/biz/usecase"
+	"github.com/anthropics/feishu-codex-bridge/internal/infra/feishu"
 )
 
+// defaultAttachmentDir is where attachments are saved, relative to the working directory
+const defaultAttachmentDir = ".feishu-attachments"
+
+// defaultOnboardingMessage is posted when the bot is added to a group
+const defaultOnboardingMessage = "Hi everyone! Mention me with a question or a task and I'll work on it with Codex. " +
+	"Send /help to see what else I can do."
+
 // Config represents application configuration
 type Config struct {
 	// Feishu configuration
@@ -33,6 +44,44 @@ type Config struct {
 	// MCP configuration
 	MCP MCPConfig
 
+	// Approval configuration
+	Approval ApprovalConfig
+
+	// Per-chat message queue configuration
+	Queue QueueConfig
+
+	// Streaming reply configuration
+	Stream StreamConfig
+
+	// Reply configuration
+	Reply ReplyConfig
+
+	// Workspace file upload configuration
+	Artifact ArtifactConfig
+
+	// Inbound attachment configuration
+	Attachment AttachmentConfig
+
+	// Slash command configuration
+	Command CommandConfig
+
+	// Group join and leave configuration
+	Lifecycle LifecycleConfig
+
+	// Feishu API rate limit and retry configuration
+	Outbound OutboundConfig
+
+	// Member and chat info cache configuration
+	Directory DirectoryConfig
+
+	// Inbound message journal configuration
+	Journal JournalConfig
+
+	// Bots served by this process, the Feishu app above unless BOTS_CONFIG_PATH is set
+	Bots     []BotConfig
+	botsPath string
+	botsErr  error
+
 	// Debug mode
 	Debug bool
 }
@@ -45,13 +94,28 @@ type PromptConfigValues struct {
 
 // FeishuConfig contains Feishu configuration
 type FeishuConfig struct {
-	AppID     string
-	AppSecret string
-	BotName   string // Bot name, used for Moonshot filter
+	AppID     string `yaml:"app_id"`
+	AppSecret string `yaml:"app_secret"`
+	BotName   string `yaml:"bot_name"` // Bot name, used for Moonshot filter
+	Domain    string `yaml:"domain"`   // feishu, lark or a custom open platform base URL
+
+	// Event transport: websocket (long connection) or webhook
+	EventMode         string `yaml:"event_mode"`
+	WebhookAddr       string `yaml:"webhook_addr"`       // Listen address in webhook mode
+	WebhookPath       string `yaml:"webhook_path"`       // Path of the event subscription URL
+	VerificationToken string `yaml:"verification_token"` // Verification Token of the event subscription
+	EncryptKey        string `yaml:"encrypt_key"`        // Encrypt Key, empty if events are not encrypted
 }
 
+// Event transports
+const (
+	EventModeWebSocket = "websocket"
+	EventModeWebhook   = "webhook"
+)
+
 // CodexConfig contains Codex configuration
 type CodexConfig struct {
+	Path       string // Codex executable, codex from PATH when empty
 	WorkingDir string
 	Model      string
 }
@@ -74,6 +138,75 @@ type MCPConfig struct {
 	ServerPath string
 }
 
+// ApprovalConfig contains configuration for approving Codex tool calls
+type ApprovalConfig struct {
+	Enabled               bool     // Route Codex approval requests through the policy and chat cards
+	CodexPolicy           string   // Codex approval_policy passed to app-server
+	TimeoutSeconds        int      // How long to wait for a button click
+	DefaultDecision       string   // accept or decline, applied on timeout
+	AutoAcceptCommands    []string // Command prefixes accepted without asking
+	DenyCommands          []string // Command prefixes always declined
+	AutoAcceptFileChanges bool
+}
+
+// QueueConfig contains per-chat message queue configuration
+type QueueConfig struct {
+	Coalesce    bool // Combine messages queued during a turn into one turn
+	MaxCoalesce int  // Max messages combined into one turn
+}
+
+// StreamConfig contains streaming reply configuration
+type StreamConfig struct {
+	Enabled    bool // Default for chats without their own setting
+	IntervalMs int  // Min milliseconds between card updates
+	Feedback   bool // Add rating buttons under finished streamed replies
+}
+
+// ArtifactConfig contains workspace file upload configuration
+type ArtifactConfig struct {
+	MaxFileMB  int // Max size of attached files
+	MaxImageMB int // Max size of inline images
+}
+
+// AttachmentConfig contains configuration for files sent to the bot
+type AttachmentConfig struct {
+	Dir string // Download directory, one subdirectory per message
+}
+
+// CommandConfig contains slash command configuration
+type CommandConfig struct {
+	Admins []string // User open IDs allowed to run admin commands, empty for everyone
+}
+
+// LifecycleConfig contains configuration for groups the bot joins and leaves
+type LifecycleConfig struct {
+	OnboardingMessage string // Posted when the bot is added to a group, empty for none
+}
+
+// OutboundConfig contains Feishu API rate limit and retry configuration
+type OutboundConfig struct {
+	MaxRetries int                // Retries of throttled or failed API calls
+	RateLimits map[string]float64 // Requests per second by API family, overriding the defaults
+}
+
+// DirectoryConfig contains member and chat info cache configuration
+type DirectoryConfig struct {
+	TTLMinutes int // How long members and chat info are cached, 0 disables the cache
+}
+
+// JournalConfig contains inbound message journal configuration
+type JournalConfig struct {
+	Enabled       bool // Journal received messages and catch up after restarts and reconnects
+	CatchUpHours  int  // Sessions active within this many hours are caught up on
+	RetentionDays int  // How long journal entries are kept
+}
+
+// ReplyConfig contains reply posting configuration
+type ReplyConfig struct {
+	Mode          string // Default reply mode: quote or plain
+	RecallReplies bool   // Recall the bot's replies when their triggering message is recalled
+}
+
 // LoadFromEnv loads configuration from environment variables
 func LoadFromEnv() *Config {
 	// Session DB path
@@ -133,6 +266,130 @@ func LoadFromEnv() *Config {
 		workingDir = "."
 	}
 
+	// Approval flow
+	approvalTimeout := 300
+	if val := os.Getenv("APPROVAL_TIMEOUT_SECONDS"); val != "" {
+		if parsed, err := strconv.Atoi(val); err == nil {
+			approvalTimeout = parsed
+		}
+	}
+	approvalDefault := os.Getenv("APPROVAL_DEFAULT_DECISION")
+	if approvalDefault == "" {
+		approvalDefault = "decline"
+	}
+	codexApprovalPolicy := os.Getenv("APPROVAL_CODEX_POLICY")
+	if codexApprovalPolicy == "" {
+		codexApprovalPolicy = "untrusted"
+	}
+	autoAcceptCommands := "ls,cat,pwd,git status,git diff,git log"
+	if val, ok := os.LookupEnv("APPROVAL_AUTO_ACCEPT_COMMANDS"); ok {
+		autoAcceptCommands = val
+	}
+
+	// Message queue
+	queueMaxCoalesce := 5
+	if val := os.Getenv("QUEUE_MAX_COALESCE"); val != "" {
+		if parsed, err := strconv.Atoi(val); err == nil {
+			queueMaxCoalesce = parsed
+		}
+	}
+
+	// Streaming replies
+	streamIntervalMs := 1000
+	if val := os.Getenv("STREAM_INTERVAL_MS"); val != "" {
+		if parsed, err := strconv.Atoi(val); err == nil {
+			streamIntervalMs = parsed
+		}
+	}
+
+	// Open platform: Feishu, Lark or a custom base URL
+	feishuDomain := os.Getenv("FEISHU_DOMAIN")
+	if feishuDomain == "" {
+		feishuDomain = feishu.DomainFeishu
+	}
+
+	// Event transport
+	eventMode := os.Getenv("FEISHU_EVENT_MODE")
+	if eventMode == "" {
+		eventMode = EventModeWebSocket
+	}
+	webhookAddr := os.Getenv("FEISHU_WEBHOOK_ADDR")
+	if webhookAddr == "" {
+		webhookAddr = ":8080"
+	}
+	webhookPath := os.Getenv("FEISHU_WEBHOOK_PATH")
+	if webhookPath == "" {
+		webhookPath = "/webhook/event"
+	}
+
+	// Feishu API retries and per-family rate limits, e.g. "send=10,read=30"
+	apiMaxRetries := feishu.DefaultOutboundConfig().MaxRetries
+	if val := os.Getenv("FEISHU_API_MAX_RETRIES"); val != "" {
+		if parsed, err := strconv.Atoi(val); err == nil && parsed >= 0 {
+			apiMaxRetries = parsed
+		}
+	}
+	apiRateLimits := make(map[string]float64)
+	for _, item := range splitList(os.Getenv("FEISHU_API_RATE_LIMITS")) {
+		family, rate, _ := strings.Cut(item, "=")
+		parsed, _ := strconv.ParseFloat(strings.TrimSpace(rate), 64)
+		apiRateLimits[strings.TrimSpace(family)] = parsed
+	}
+
+	// Member and chat info cache, DIRECTORY_CACHE_TTL_MINUTES=0 disables it
+	directoryTTLMinutes := 10
+	if val := os.Getenv("DIRECTORY_CACHE_TTL_MINUTES"); val != "" {
+		if parsed, err := strconv.Atoi(val); err == nil && parsed >= 0 {
+			directoryTTLMinutes = parsed
+		}
+	}
+
+	// Message journal, JOURNAL_ENABLED=false falls back to in-memory deduplication
+	journalCatchUpHours := 24
+	if val := os.Getenv("JOURNAL_CATCHUP_HOURS"); val != "" {
+		if parsed, err := strconv.Atoi(val); err == nil && parsed > 0 {
+			journalCatchUpHours = parsed
+		}
+	}
+	journalRetentionDays := 7
+	if val := os.Getenv("JOURNAL_RETENTION_DAYS"); val != "" {
+		if parsed, err := strconv.Atoi(val); err == nil && parsed > 0 {
+			journalRetentionDays = parsed
+		}
+	}
+
+	// Onboarding message, set ONBOARDING_MESSAGE= to disable
+	onboardingMessage := defaultOnboardingMessage
+	if val, ok := os.LookupEnv("ONBOARDING_MESSAGE"); ok {
+		onboardingMessage = strings.ReplaceAll(val, `\n`, "\n")
+	}
+
+	// Reply mode
+	replyMode := os.Getenv("REPLY_MODE")
+	if !domain.ReplyMode(replyMode).Valid() {
+		replyMode = string(domain.ReplyModeQuote)
+	}
+
+	// Attachments sent to the bot are saved where Codex can read them
+	attachmentDir := os.Getenv("ATTACHMENT_DIR")
+	if attachmentDir == "" {
+		attachmentDir = filepath.Join(workingDir, defaultAttachmentDir)
+	}
+
+	// Artifact upload limits (Feishu accepts at most 30MB files and 10MB images)
+	artifactMaxFileMB := 30
+	if val := os.Getenv("ARTIFACT_MAX_FILE_MB"); val != "" {
+		if parsed, err := strconv.Atoi(val); err == nil && parsed > 0 && parsed < artifactMaxFileMB {
+			artifactMaxFileMB = parsed
+		}
+	}
+	artifactMaxImageMB := 10
+	if val := os.Getenv("ARTIFACT_MAX_IMAGE_MB"); val != "" {
+		if parsed, err := strconv.Atoi(val); err == nil && parsed > 0 && parsed < artifactMaxImageMB {
+			artifactMaxImageMB = parsed
+		}
+	}
+
 	// Load prompts from YAML
 	promptsConfigPath := os.Getenv("PROMPTS_CONFIG_PATH")
 	promptsConfig, _ := LoadPromptsConfig(promptsConfigPath)
@@ -145,13 +402,48 @@ func LoadFromEnv() *Config {
 		promptsConfig.History.MaxMinutes = maxHistoryMinutes
 	}
 
+	feishuConfig := FeishuConfig{
+		AppID:     os.Getenv("FEISHU_APP_ID"),
+		AppSecret: os.Getenv("FEISHU_APP_SECRET"),
+		BotName:   os.Getenv("BOT_NAME"),
+		Domain:    feishuDomain,
+
+		EventMode:         eventMode,
+		WebhookAddr:       webhookAddr,
+		WebhookPath:       webhookPath,
+		VerificationToken: os.Getenv("FEISHU_VERIFICATION_TOKEN"),
+		EncryptKey:        os.Getenv("FEISHU_ENCRYPT_KEY"),
+	}
+
+	// Bots: the app above, or every app listed in BOTS_CONFIG_PATH
+	defaultBot := BotConfig{
+		ID:         domain.DefaultBotID,
+		Feishu:     feishuConfig,
+		WorkingDir: workingDir,
+		Prompts:    promptsConfig,
+	}
+	bots := []BotConfig{defaultBot}
+	var botsErr error
+	botsPath := os.Getenv("BOTS_CONFIG_PATH")
+	if botsPath != "" {
+		bots, botsErr = LoadBotsConfig(botsPath, defaultBot)
+		for _, bot := range bots {
+			if bot.Prompts == promptsConfig {
+				continue
+			}
+			if maxHistoryCount != 15 {
+				bot.Prompts.History.MaxCount = maxHistoryCount
+			}
+			if maxHistoryMinutes != 120 {
+				bot.Prompts.History.MaxMinutes = maxHistoryMinutes
+			}
+		}
+	}
+
 	return &Config{
-		Feishu: FeishuConfig{
-			AppID:     os.Getenv("FEISHU_APP_ID"),
-			AppSecret: os.Getenv("FEISHU_APP_SECRET"),
-			BotName:   os.Getenv("BOT_NAME"),
-		},
+		Feishu: feishuConfig,
 		Codex: CodexConfig{
+			Path:       os.Getenv("CODEX_PATH"),
 			WorkingDir: workingDir,
 			Model:      os.Getenv("CODEX_MODEL"),
 		},
@@ -172,10 +464,71 @@ func LoadFromEnv() *Config {
 		MCP: MCPConfig{
 			ServerPath: mcpServerPath,
 		},
-		Debug: os.Getenv("DEBUG") == "true",
+		Approval: ApprovalConfig{
+			Enabled:               os.Getenv("APPROVAL_ENABLED") == "true",
+			CodexPolicy:           codexApprovalPolicy,
+			TimeoutSeconds:        approvalTimeout,
+			DefaultDecision:       approvalDefault,
+			AutoAcceptCommands:    splitList(autoAcceptCommands),
+			DenyCommands:          splitList(os.Getenv("APPROVAL_DENY_COMMANDS")),
+			AutoAcceptFileChanges: os.Getenv("APPROVAL_AUTO_ACCEPT_FILE_CHANGES") == "true",
+		},
+		Queue: QueueConfig{
+			Coalesce:    os.Getenv("QUEUE_COALESCE") == "true",
+			MaxCoalesce: queueMaxCoalesce,
+		},
+		Stream: StreamConfig{
+			Enabled:    os.Getenv("STREAM_REPLIES") == "true",
+			IntervalMs: streamIntervalMs,
+			Feedback:   os.Getenv("STREAM_FEEDBACK") == "true",
+		},
+		Reply: ReplyConfig{
+			Mode:          replyMode,
+			RecallReplies: os.Getenv("RECALL_REPLIES") == "true",
+		},
+		Artifact: ArtifactConfig{
+			MaxFileMB:  artifactMaxFileMB,
+			MaxImageMB: artifactMaxImageMB,
+		},
+		Attachment: AttachmentConfig{
+			Dir: attachmentDir,
+		},
+		Command: CommandConfig{
+			Admins: splitList(os.Getenv("COMMAND_ADMINS")),
+		},
+		Directory: DirectoryConfig{
+			TTLMinutes: directoryTTLMinutes,
+		},
+		Journal: JournalConfig{
+			Enabled:       os.Getenv("JOURNAL_ENABLED") != "false",
+			CatchUpHours:  journalCatchUpHours,
+			RetentionDays: journalRetentionDays,
+		},
+		Outbound: OutboundConfig{
+			MaxRetries: apiMaxRetries,
+			RateLimits: apiRateLimits,
+		},
+		Lifecycle: LifecycleConfig{
+			OnboardingMessage: onboardingMessage,
+		},
+		Bots:     bots,
+		botsPath: botsPath,
+		botsErr:  botsErr,
+		Debug:    os.Getenv("DEBUG") == "true",
 	}
 }
 
+// splitList splits a comma-separated list, dropping empty items
+func splitList(val string) []string {
+	var items []string
+	for _, item := range strings.Split(val, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 // ToSessionConfig converts to domain session configuration
 func (c *SessionConfig) ToSessionConfig() domain.SessionConfig {
 	return domain.SessionConfig{
@@ -184,6 +537,50 @@ func (c *SessionConfig) ToSessionConfig() domain.SessionConfig {
 	}
 }
 
+// ToApprovalPolicy converts to domain approval policy
+func (c *ApprovalConfig) ToApprovalPolicy() *domain.ApprovalPolicy {
+	return &domain.ApprovalPolicy{
+		AutoAcceptCommands:    c.AutoAcceptCommands,
+		DenyCommands:          c.DenyCommands,
+		AutoAcceptFileChanges: c.AutoAcceptFileChanges,
+		Timeout:               time.Duration(c.TimeoutSeconds) * time.Second,
+		DefaultDecision:       domain.ApprovalDecision(c.DefaultDecision),
+	}
+}
+
+// ToQueueConfig converts to queue usecase configuration
+func (c *QueueConfig) ToQueueConfig() usecase.QueueConfig {
+	return usecase.QueueConfig{
+		Coalesce:    c.Coalesce,
+		MaxCoalesce: c.MaxCoalesce,
+	}
+}
+
+// ToJournalConfig converts to journal usecase configuration
+func (c *JournalConfig) ToJournalConfig() usecase.JournalConfig {
+	cfg := usecase.DefaultJournalConfig()
+	cfg.CatchUpWindow = time.Duration(c.CatchUpHours) * time.Hour
+	cfg.Retention = time.Duration(c.RetentionDays) * 24 * time.Hour
+	return cfg
+}
+
+// ToArtifactConfig converts to artifact usecase configuration for the Codex workspace
+func (c *Config) ToArtifactConfig() usecase.ArtifactConfig {
+	cfg := usecase.DefaultArtifactConfig(c.Codex.WorkingDir)
+	cfg.MaxFileBytes = int64(c.Artifact.MaxFileMB) << 20
+	cfg.MaxImageBytes = int64(c.Artifact.MaxImageMB) << 20
+	return cfg
+}
+
+// ToChatSettings converts to the default per-chat settings
+func (c *Config) ToChatSettings() domain.ChatSettings {
+	return domain.ChatSettings{
+		StreamReplies: c.Stream.Enabled,
+		ReplyMode:     domain.ReplyMode(c.Reply.Mode),
+		Model:         c.Codex.Model,
+	}
+}
+
 // ToPromptConfig converts to prompt configuration
 func (c *Config) ToPromptConfig() usecase.PromptConfig {
 	if c.Prompts == nil {
@@ -197,6 +594,7 @@ func (c *Config) ToPromptConfig() usecase.PromptConfig {
 		SystemPrompt:        c.Prompts.Codex.SystemPrompt,
 		HistoryMarker:       c.Prompts.Codex.HistoryMarker,
 		CurrentMarker:       c.Prompts.Codex.CurrentMarker,
+		ReplyMarker:         c.Prompts.Codex.ReplyMarker,
 		MemberListHeader:    c.Prompts.Codex.MemberListHeader,
 		ChatContextTemplate: c.Prompts.Codex.ChatContextTemplate,
 		MaxHistoryCount:     c.Prompts.History.MaxCount,
@@ -206,12 +604,69 @@ func (c *Config) ToPromptConfig() usecase.PromptConfig {
 
 // Validate validates the configuration
 func (c *Config) Validate() error {
+	for family, rate := range c.Outbound.RateLimits {
+		if !slices.Contains(feishu.APIFamilies, family) {
+			return &ConfigError{Field: "FEISHU_API_RATE_LIMITS", Message: fmt.Sprintf("unknown API family %q, expected one of %s", family, strings.Join(feishu.APIFamilies, ", "))}
+		}
+		if rate <= 0 {
+			return &ConfigError{Field: "FEISHU_API_RATE_LIMITS", Message: fmt.Sprintf("rate of %s must be a positive number", family)}
+		}
+	}
+	if c.botsErr != nil {
+		return &ConfigError{Field: "BOTS_CONFIG_PATH", Message: c.botsErr.Error()}
+	}
+	if c.botsPath != "" {
+		return validateBots(c.Bots)
+	}
+
 	if c.Feishu.AppID == "" || c.Feishu.AppSecret == "" {
 		return &ConfigError{Field: "FEISHU_APP_ID/FEISHU_APP_SECRET", Message: "required"}
 	}
+	if _, err := feishu.ResolveBaseURL(c.Feishu.Domain); err != nil {
+		return &ConfigError{Field: "FEISHU_DOMAIN", Message: err.Error()}
+	}
+	switch c.Feishu.EventMode {
+	case EventModeWebSocket:
+	case EventModeWebhook:
+		// The token answers Feishu's URL verification challenge
+		if c.Feishu.VerificationToken == "" {
+			return &ConfigError{Field: "FEISHU_VERIFICATION_TOKEN", Message: "required in webhook mode"}
+		}
+	default:
+		return &ConfigError{Field: "FEISHU_EVENT_MODE", Message: "must be websocket or webhook"}
+	}
 	return nil
 }
 
+// ToBaseURL returns the open platform base URL of the configured domain
+func (c *FeishuConfig) ToBaseURL() string {
+	baseURL, err := feishu.ResolveBaseURL(c.Domain)
+	if err != nil {
+		return feishu.FeishuBaseURL
+	}
+	return baseURL
+}
+
+// ToOutboundConfig converts to the Feishu client's outbound configuration
+func (c *OutboundConfig) ToOutboundConfig() feishu.OutboundConfig {
+	cfg := feishu.DefaultOutboundConfig()
+	cfg.MaxRetries = c.MaxRetries
+	for family, rate := range c.RateLimits {
+		cfg.RateLimits[family] = rate
+	}
+	return cfg
+}
+
+// ToWebhookConfig converts to the Feishu client's webhook configuration
+func (c *FeishuConfig) ToWebhookConfig() feishu.WebhookConfig {
+	return feishu.WebhookConfig{
+		Addr:              c.WebhookAddr,
+		Path:              c.WebhookPath,
+		VerificationToken: c.VerificationToken,
+		EncryptKey:        c.EncryptKey,
+	}
+}
+
 // ConfigError represents a configuration error
 type ConfigError struct {
 	Field   string
@@ -21,6 +21,7 @@ type CodexPrompts struct {
 	SystemPrompt        string `yaml:"system_prompt"`
 	HistoryMarker       string `yaml:"history_marker"`
 	CurrentMarker       string `yaml:"current_marker"`
+	ReplyMarker         string `yaml:"reply_marker"`
 	MemberListHeader    string `yaml:"member_list_header"`
 	ChatContextTemplate string `yaml:"chat_context_template"`
 }
@@ -103,6 +104,9 @@ func (c *PromptsConfig) fillDefaults() {
 	if c.Codex.CurrentMarker == "" {
 		c.Codex.CurrentMarker = defaults.Codex.CurrentMarker
 	}
+	if c.Codex.ReplyMarker == "" {
+		c.Codex.ReplyMarker = defaults.Codex.ReplyMarker
+	}
 	if c.Codex.MemberListHeader == "" {
 		c.Codex.MemberListHeader = defaults.Codex.MemberListHeader
 	}
@@ -257,6 +261,9 @@ These topics are used by the Filter Model to decide if a message needs response:
 - feishu_get_chat_members: Get member list for @mentioning
 - feishu_get_chat_history: Get more history messages
 
+### Files
+- feishu_send_file: Post a file or image from your workspace to the chat (e.g. a generated chart or report)
+
 ## Common Scenarios and Actions
 
 ### Scenario 1: "Watch this chat" / "This chat is important"
@@ -301,6 +308,7 @@ Before responding, read the "Recent chat messages" section. Users often refer to
 - Prefer concise responses`,
 			HistoryMarker:    "[Recent chat messages - for reference]",
 			CurrentMarker:    "[Current message]",
+			ReplyMarker:      "[Replying to - the current message quotes this message]",
 			MemberListHeader: "## Chat Members\nHere are the members of this chat. You can use [MENTION:user_id:name] to @ them:",
 			ChatContextTemplate: `## Current Chat Context
 - chat_id: {{chat_id}}
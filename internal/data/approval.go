@@ -0,0 +1,133 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/anthropics/feishu-codex-bridge/internal/biz/domain"
+	"github.com/anthropics/feishu-codex-bridge/internal/biz/repo"
+
+	_ "modernc.org/sqlite"
+)
+
+const createApprovalsTable = `
+	CREATE TABLE IF NOT EXISTS approvals (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		bot_id TEXT NOT NULL DEFAULT 'default',
+		request_id INTEGER NOT NULL,
+		chat_id TEXT,
+		kind TEXT NOT NULL,
+		summary TEXT,
+		decision TEXT NOT NULL,
+		source TEXT NOT NULL,
+		decided_by TEXT,
+		created_at INTEGER NOT NULL,
+		decided_at INTEGER NOT NULL
+	)
+`
+
+// approvalRepo implements the approval audit log repository
+type approvalRepo struct {
+	db    *sql.DB
+	botID string
+}
+
+// NewApprovalRepo creates a new approval repository
+// Only approvals of botID are visible to it
+func NewApprovalRepo(dbPath, botID string) (repo.ApprovalRepo, error) {
+	// Ensure directory exists
+	dir := filepath.Dir(dbPath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create db directory: %w", err)
+	}
+
+	db, err := sql.Open("sqlite", sqliteDSN(dbPath))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	if _, err := db.Exec(createApprovalsTable); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create approvals table: %w", err)
+	}
+
+	// Migration: keep each bot's approvals apart
+	if err := rebuildTable(db, "approvals", "bot_id", createApprovalsTable); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to migrate approvals table: %w", err)
+	}
+
+	_, _ = db.Exec(`CREATE INDEX IF NOT EXISTS idx_approvals_bot_chat ON approvals(bot_id, chat_id, decided_at)`)
+	_, _ = db.Exec(`CREATE INDEX IF NOT EXISTS idx_approvals_bot_user ON approvals(bot_id, decided_by, decided_at)`)
+
+	fmt.Println("[Approval] Database initialized")
+	return &approvalRepo{db: db, botID: botID}, nil
+}
+
+func (r *approvalRepo) SaveRecord(ctx context.Context, record *domain.ApprovalRecord) error {
+	result, err := r.db.ExecContext(ctx, `
+		INSERT INTO approvals (bot_id, request_id, chat_id, kind, summary, decision, source, decided_by, created_at, decided_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, r.botID, record.RequestID, record.ChatID, string(record.Kind), record.Summary, string(record.Decision),
+		record.Source, record.DecidedBy, record.CreatedAt.Unix(), record.DecidedAt.Unix())
+	if err != nil {
+		return fmt.Errorf("failed to save approval record: %w", err)
+	}
+	record.ID, _ = result.LastInsertId()
+	return nil
+}
+
+func (r *approvalRepo) ListByChat(ctx context.Context, chatID string, limit int) ([]*domain.ApprovalRecord, error) {
+	return r.list(ctx, "chat_id", chatID, limit)
+}
+
+func (r *approvalRepo) ListByUser(ctx context.Context, userID string, limit int) ([]*domain.ApprovalRecord, error) {
+	return r.list(ctx, "decided_by", userID, limit)
+}
+
+// list queries records by a fixed column name (never user input)
+func (r *approvalRepo) list(ctx context.Context, column, value string, limit int) ([]*domain.ApprovalRecord, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, request_id, chat_id, kind, summary, decision, source, decided_by, created_at, decided_at
+		FROM approvals WHERE bot_id = ? AND `+column+` = ?
+		ORDER BY decided_at DESC, id DESC
+		LIMIT ?
+	`, r.botID, value, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list approvals: %w", err)
+	}
+	defer rows.Close()
+
+	var records []*domain.ApprovalRecord
+	for rows.Next() {
+		var rec domain.ApprovalRecord
+		var kind, decision string
+		var chatID, summary, decidedBy sql.NullString
+		var createdAt, decidedAt int64
+		if err := rows.Scan(&rec.ID, &rec.RequestID, &chatID, &kind, &summary, &decision,
+			&rec.Source, &decidedBy, &createdAt, &decidedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan approval: %w", err)
+		}
+		rec.ChatID = chatID.String
+		rec.Kind = domain.ApprovalKind(kind)
+		rec.Summary = summary.String
+		rec.Decision = domain.ApprovalDecision(decision)
+		rec.DecidedBy = decidedBy.String
+		rec.CreatedAt = time.Unix(createdAt, 0)
+		rec.DecidedAt = time.Unix(decidedAt, 0)
+		records = append(records, &rec)
+	}
+	return records, rows.Err()
+}
+
+func (r *approvalRepo) Close() error {
+	return r.db.Close()
+}
@@ -0,0 +1,109 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/anthropics/feishu-codex-bridge/internal/biz/domain"
+)
+
+func testApprovalRecord(chatID, user string) *domain.ApprovalRecord {
+	now := time.Now()
+	return &domain.ApprovalRecord{
+		ChatID:    chatID,
+		Kind:      domain.ApprovalKindCommand,
+		Decision:  domain.ApprovalAccept,
+		Source:    domain.ApprovalSourceUser,
+		DecidedBy: user,
+		CreatedAt: now,
+		DecidedAt: now,
+	}
+}
+
+func TestApprovalRepo_MigratesAndKeepsBotsApart(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "approvals.db")
+
+	// A log written before approvals were kept per bot
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec(`
+		CREATE TABLE approvals (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			request_id INTEGER NOT NULL,
+			chat_id TEXT,
+			kind TEXT NOT NULL,
+			summary TEXT,
+			decision TEXT NOT NULL,
+			source TEXT NOT NULL,
+			decided_by TEXT,
+			created_at INTEGER NOT NULL,
+			decided_at INTEGER NOT NULL
+		);
+		INSERT INTO approvals (request_id, chat_id, kind, decision, source, decided_by, created_at, decided_at)
+		VALUES (1, 'oc_1', 'command', 'accept', 'user', 'ou_alice', 1700000000, 1700000000);
+	`)
+	db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := NewApprovalRepo(dbPath, "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	b, err := NewApprovalRepo(dbPath, "b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	ctx := context.Background()
+
+	if err := b.SaveRecord(ctx, testApprovalRecord("oc_1", "ou_alice")); err != nil {
+		t.Fatal(err)
+	}
+	if records, _ := a.ListByChat(ctx, "oc_1", 10); len(records) != 1 || records[0].RequestID != 1 {
+		t.Errorf("Expected the old record to belong to the default bot, got %+v", records)
+	}
+	if records, _ := b.ListByUser(ctx, "ou_alice", 10); len(records) != 1 || records[0].RequestID != 0 {
+		t.Errorf("Expected bot b to only see its own record, got %+v", records)
+	}
+}
+
+func TestApprovalRepo_ConcurrentBots(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "approvals.db")
+	ctx := context.Background()
+
+	// Every bot opens its own handle on the shared file
+	const bots, writes = 4, 25
+	var wg sync.WaitGroup
+	errs := make(chan error, bots*writes)
+	for i := 0; i < bots; i++ {
+		r, err := NewApprovalRepo(dbPath, fmt.Sprintf("bot%d", i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer r.Close()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < writes; j++ {
+				if err := r.SaveRecord(ctx, testApprovalRecord("oc_1", "ou_alice")); err != nil {
+					errs <- err
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatalf("Expected writers to wait for each other, got %v", err)
+	}
+}
@@ -15,92 +15,103 @@ import (
 	_ "modernc.org/sqlite"
 )
 
-// bufferRepo implements the message buffer repository
-type bufferRepo struct {
-	db *sql.DB
-}
-
-// NewBufferRepo creates a new message buffer repository
-func NewBufferRepo(dbPath string) (repo.BufferRepo, error) {
-	// Ensure directory exists
-	dir := filepath.Dir(dbPath)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create db directory: %w", err)
-	}
-
-	db, err := sql.Open("sqlite", dbPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open database: %w", err)
-	}
-
-	// Create buffered messages table
-	_, err = db.Exec(`
+const (
+	createBufferedMessagesTable = `
 		CREATE TABLE IF NOT EXISTS buffered_messages (
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			bot_id TEXT NOT NULL DEFAULT 'default',
 			chat_id TEXT NOT NULL,
-			msg_id TEXT UNIQUE NOT NULL,
+			msg_id TEXT NOT NULL,
 			content TEXT NOT NULL,
 			sender_id TEXT,
 			sender_name TEXT,
 			created_at INTEGER NOT NULL,
 			processed INTEGER DEFAULT 0,
-			processed_at INTEGER
+			processed_at INTEGER,
+			UNIQUE (bot_id, msg_id)
 		)
-	`)
-	if err != nil {
-		db.Close()
-		return nil, fmt.Errorf("failed to create buffered_messages table: %w", err)
-	}
-
-	// Create indexes
-	_, _ = db.Exec(`CREATE INDEX IF NOT EXISTS idx_buffered_chat_processed ON buffered_messages(chat_id, processed)`)
-	_, _ = db.Exec(`CREATE INDEX IF NOT EXISTS idx_buffered_created ON buffered_messages(created_at)`)
+	`
 
-	// Create whitelist table
-	_, err = db.Exec(`
+	createWhitelistTable = `
 		CREATE TABLE IF NOT EXISTS instant_whitelist (
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			chat_id TEXT UNIQUE NOT NULL,
+			bot_id TEXT NOT NULL DEFAULT 'default',
+			chat_id TEXT NOT NULL,
 			reason TEXT,
 			added_by TEXT,
-			created_at INTEGER NOT NULL
+			created_at INTEGER NOT NULL,
+			UNIQUE (bot_id, chat_id)
 		)
-	`)
-	if err != nil {
-		db.Close()
-		return nil, fmt.Errorf("failed to create instant_whitelist table: %w", err)
-	}
+	`
 
-	// Create keywords table
-	_, err = db.Exec(`
+	createKeywordsTable = `
 		CREATE TABLE IF NOT EXISTS trigger_keywords (
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			keyword TEXT UNIQUE NOT NULL,
+			bot_id TEXT NOT NULL DEFAULT 'default',
+			keyword TEXT NOT NULL,
 			priority INTEGER DEFAULT 1,
-			created_at INTEGER NOT NULL
+			created_at INTEGER NOT NULL,
+			UNIQUE (bot_id, keyword)
 		)
-	`)
-	if err != nil {
-		db.Close()
-		return nil, fmt.Errorf("failed to create trigger_keywords table: %w", err)
-	}
+	`
 
-	// Create interest topics table (for Moonshot filtering)
-	_, err = db.Exec(`
+	// Interest topics are used for Moonshot filtering
+	createInterestTopicsTable = `
 		CREATE TABLE IF NOT EXISTS interest_topics (
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			topic TEXT UNIQUE NOT NULL,
+			bot_id TEXT NOT NULL DEFAULT 'default',
+			topic TEXT NOT NULL,
 			description TEXT,
-			created_at INTEGER NOT NULL
+			created_at INTEGER NOT NULL,
+			UNIQUE (bot_id, topic)
 		)
-	`)
+	`
+)
+
+// bufferRepo implements the message buffer repository
+type bufferRepo struct {
+	db    *sql.DB
+	botID string
+}
+
+// NewBufferRepo creates a new message buffer repository
+// Only records of botID are visible to it
+func NewBufferRepo(dbPath, botID string) (repo.BufferRepo, error) {
+	// Ensure directory exists
+	dir := filepath.Dir(dbPath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create db directory: %w", err)
+	}
+
+	db, err := sql.Open("sqlite", sqliteDSN(dbPath))
 	if err != nil {
-		db.Close()
-		return nil, fmt.Errorf("failed to create interest_topics table: %w", err)
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	// Create tables, tagging records created before bots were tagged
+	for _, table := range []struct{ name, create string }{
+		{"buffered_messages", createBufferedMessagesTable},
+		{"instant_whitelist", createWhitelistTable},
+		{"trigger_keywords", createKeywordsTable},
+		{"interest_topics", createInterestTopicsTable},
+	} {
+		if _, err := db.Exec(table.create); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to create %s table: %w", table.name, err)
+		}
+		if err := rebuildTable(db, table.name, "bot_id", table.create); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to migrate %s table: %w", table.name, err)
+		}
 	}
 
+	// Create indexes
+	_, _ = db.Exec(`DROP INDEX IF EXISTS idx_buffered_chat_processed`)
+	_, _ = db.Exec(`CREATE INDEX IF NOT EXISTS idx_buffered_bot_chat_processed ON buffered_messages(bot_id, chat_id, processed)`)
+	_, _ = db.Exec(`CREATE INDEX IF NOT EXISTS idx_buffered_created ON buffered_messages(created_at)`)
+
 	fmt.Println("[Buffer] Database initialized")
-	return &bufferRepo{db: db}, nil
+	return &bufferRepo{db: db, botID: botID}, nil
 }
 
 // ========== Buffered Message Operations ==========
@@ -108,9 +119,9 @@ func NewBufferRepo(dbPath string) (repo.BufferRepo, error) {
 // AddMessage adds a message to the buffer
 func (r *bufferRepo) AddMessage(ctx context.Context, msg *domain.BufferedMessage) error {
 	_, err := r.db.ExecContext(ctx, `
-		INSERT OR IGNORE INTO buffered_messages (chat_id, msg_id, content, sender_id, sender_name, created_at)
-		VALUES (?, ?, ?, ?, ?, ?)
-	`, msg.ChatID, msg.MsgID, msg.Content, msg.SenderID, msg.SenderName, msg.CreatedAt.Unix())
+		INSERT OR IGNORE INTO buffered_messages (bot_id, chat_id, msg_id, content, sender_id, sender_name, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, r.botID, msg.ChatID, msg.MsgID, msg.Content, msg.SenderID, msg.SenderName, msg.CreatedAt.Unix())
 	if err != nil {
 		return fmt.Errorf("failed to add buffered message: %w", err)
 	}
@@ -122,9 +133,9 @@ func (r *bufferRepo) GetUnprocessedMessages(ctx context.Context, chatID string)
 	rows, err := r.db.QueryContext(ctx, `
 		SELECT id, chat_id, msg_id, content, sender_id, sender_name, created_at
 		FROM buffered_messages
-		WHERE chat_id = ? AND processed = 0
+		WHERE bot_id = ? AND chat_id = ? AND processed = 0
 		ORDER BY created_at ASC
-	`, chatID)
+	`, r.botID, chatID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query buffered messages: %w", err)
 	}
@@ -138,9 +149,9 @@ func (r *bufferRepo) GetAllUnprocessedMessages(ctx context.Context) ([]*domain.B
 	rows, err := r.db.QueryContext(ctx, `
 		SELECT id, chat_id, msg_id, content, sender_id, sender_name, created_at
 		FROM buffered_messages
-		WHERE processed = 0
+		WHERE bot_id = ? AND processed = 0
 		ORDER BY chat_id, created_at ASC
-	`)
+	`, r.botID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query all buffered messages: %w", err)
 	}
@@ -171,17 +182,18 @@ func (r *bufferRepo) MarkProcessed(ctx context.Context, msgIDs []int64) error {
 
 	// Build IN clause
 	placeholders := make([]string, len(msgIDs))
-	args := make([]interface{}, len(msgIDs)+1)
+	args := make([]interface{}, len(msgIDs)+2)
 	args[0] = time.Now().Unix()
+	args[1] = r.botID
 	for i, id := range msgIDs {
 		placeholders[i] = "?"
-		args[i+1] = id
+		args[i+2] = id
 	}
 
 	query := fmt.Sprintf(`
 		UPDATE buffered_messages
 		SET processed = 1, processed_at = ?
-		WHERE id IN (%s)
+		WHERE bot_id = ? AND id IN (%s)
 	`, strings.Join(placeholders, ","))
 
 	_, err := r.db.ExecContext(ctx, query, args...)
@@ -196,10 +208,10 @@ func (r *bufferRepo) GetBufferSummary(ctx context.Context) ([]*domain.BufferSumm
 	rows, err := r.db.QueryContext(ctx, `
 		SELECT chat_id, COUNT(*) as msg_count, MAX(created_at) as last_msg
 		FROM buffered_messages
-		WHERE processed = 0
+		WHERE bot_id = ? AND processed = 0
 		GROUP BY chat_id
 		ORDER BY last_msg DESC
-	`)
+	`, r.botID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query buffer summary: %w", err)
 	}
@@ -221,22 +233,39 @@ func (r *bufferRepo) GetBufferSummary(ctx context.Context) ([]*domain.BufferSumm
 // CleanupOld cleans up old messages
 func (r *bufferRepo) CleanupOld(ctx context.Context, before time.Time) (int64, error) {
 	result, err := r.db.ExecContext(ctx, `
-		DELETE FROM buffered_messages WHERE created_at < ? AND processed = 1
-	`, before.Unix())
+		DELETE FROM buffered_messages WHERE bot_id = ? AND created_at < ? AND processed = 1
+	`, r.botID, before.Unix())
 	if err != nil {
 		return 0, fmt.Errorf("failed to cleanup old messages: %w", err)
 	}
 	return result.RowsAffected()
 }
 
+// DeleteChat deletes a chat's buffered messages and whitelist entry
+func (r *bufferRepo) DeleteChat(ctx context.Context, chatID string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM buffered_messages WHERE bot_id = ? AND chat_id = ?`, r.botID, chatID); err != nil {
+		return fmt.Errorf("failed to delete buffered messages: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM instant_whitelist WHERE bot_id = ? AND chat_id = ?`, r.botID, chatID); err != nil {
+		return fmt.Errorf("failed to remove from whitelist: %w", err)
+	}
+	return tx.Commit()
+}
+
 // ========== Whitelist Operations ==========
 
 // AddToWhitelist adds to whitelist
 func (r *bufferRepo) AddToWhitelist(ctx context.Context, entry *domain.WhitelistEntry) error {
 	_, err := r.db.ExecContext(ctx, `
-		INSERT OR REPLACE INTO instant_whitelist (chat_id, reason, added_by, created_at)
-		VALUES (?, ?, ?, ?)
-	`, entry.ChatID, entry.Reason, entry.AddedBy, time.Now().Unix())
+		INSERT OR REPLACE INTO instant_whitelist (bot_id, chat_id, reason, added_by, created_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, r.botID, entry.ChatID, entry.Reason, entry.AddedBy, time.Now().Unix())
 	if err != nil {
 		return fmt.Errorf("failed to add to whitelist: %w", err)
 	}
@@ -246,7 +275,7 @@ func (r *bufferRepo) AddToWhitelist(ctx context.Context, entry *domain.Whitelist
 
 // RemoveFromWhitelist removes from whitelist
 func (r *bufferRepo) RemoveFromWhitelist(ctx context.Context, chatID string) error {
-	_, err := r.db.ExecContext(ctx, `DELETE FROM instant_whitelist WHERE chat_id = ?`, chatID)
+	_, err := r.db.ExecContext(ctx, `DELETE FROM instant_whitelist WHERE bot_id = ? AND chat_id = ?`, r.botID, chatID)
 	if err != nil {
 		return fmt.Errorf("failed to remove from whitelist: %w", err)
 	}
@@ -259,8 +288,9 @@ func (r *bufferRepo) GetWhitelist(ctx context.Context) ([]*domain.WhitelistEntry
 	rows, err := r.db.QueryContext(ctx, `
 		SELECT id, chat_id, reason, added_by, created_at
 		FROM instant_whitelist
+		WHERE bot_id = ?
 		ORDER BY created_at DESC
-	`)
+	`, r.botID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query whitelist: %w", err)
 	}
@@ -283,8 +313,8 @@ func (r *bufferRepo) GetWhitelist(ctx context.Context) ([]*domain.WhitelistEntry
 func (r *bufferRepo) IsInWhitelist(ctx context.Context, chatID string) (bool, error) {
 	var count int
 	err := r.db.QueryRowContext(ctx, `
-		SELECT COUNT(*) FROM instant_whitelist WHERE chat_id = ?
-	`, chatID).Scan(&count)
+		SELECT COUNT(*) FROM instant_whitelist WHERE bot_id = ? AND chat_id = ?
+	`, r.botID, chatID).Scan(&count)
 	if err != nil {
 		return false, fmt.Errorf("failed to check whitelist: %w", err)
 	}
@@ -296,9 +326,9 @@ func (r *bufferRepo) IsInWhitelist(ctx context.Context, chatID string) (bool, er
 // AddKeyword adds a keyword
 func (r *bufferRepo) AddKeyword(ctx context.Context, kw *domain.TriggerKeyword) error {
 	_, err := r.db.ExecContext(ctx, `
-		INSERT OR REPLACE INTO trigger_keywords (keyword, priority, created_at)
-		VALUES (?, ?, ?)
-	`, kw.Keyword, kw.Priority, time.Now().Unix())
+		INSERT OR REPLACE INTO trigger_keywords (bot_id, keyword, priority, created_at)
+		VALUES (?, ?, ?, ?)
+	`, r.botID, kw.Keyword, kw.Priority, time.Now().Unix())
 	if err != nil {
 		return fmt.Errorf("failed to add keyword: %w", err)
 	}
@@ -308,7 +338,7 @@ func (r *bufferRepo) AddKeyword(ctx context.Context, kw *domain.TriggerKeyword)
 
 // RemoveKeyword removes a keyword
 func (r *bufferRepo) RemoveKeyword(ctx context.Context, keyword string) error {
-	_, err := r.db.ExecContext(ctx, `DELETE FROM trigger_keywords WHERE keyword = ?`, keyword)
+	_, err := r.db.ExecContext(ctx, `DELETE FROM trigger_keywords WHERE bot_id = ? AND keyword = ?`, r.botID, keyword)
 	if err != nil {
 		return fmt.Errorf("failed to remove keyword: %w", err)
 	}
@@ -321,8 +351,9 @@ func (r *bufferRepo) GetKeywords(ctx context.Context) ([]*domain.TriggerKeyword,
 	rows, err := r.db.QueryContext(ctx, `
 		SELECT id, keyword, priority, created_at
 		FROM trigger_keywords
+		WHERE bot_id = ?
 		ORDER BY priority DESC, keyword ASC
-	`)
+	`, r.botID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query keywords: %w", err)
 	}
@@ -362,9 +393,9 @@ func (r *bufferRepo) MatchKeyword(ctx context.Context, content string) (*domain.
 // AddInterestTopic adds an interest topic
 func (r *bufferRepo) AddInterestTopic(ctx context.Context, topic, description string) error {
 	_, err := r.db.ExecContext(ctx, `
-		INSERT OR REPLACE INTO interest_topics (topic, description, created_at)
-		VALUES (?, ?, ?)
-	`, topic, description, time.Now().Unix())
+		INSERT OR REPLACE INTO interest_topics (bot_id, topic, description, created_at)
+		VALUES (?, ?, ?, ?)
+	`, r.botID, topic, description, time.Now().Unix())
 	if err != nil {
 		return fmt.Errorf("failed to add interest topic: %w", err)
 	}
@@ -374,7 +405,7 @@ func (r *bufferRepo) AddInterestTopic(ctx context.Context, topic, description st
 
 // RemoveInterestTopic removes an interest topic
 func (r *bufferRepo) RemoveInterestTopic(ctx context.Context, topic string) error {
-	_, err := r.db.ExecContext(ctx, `DELETE FROM interest_topics WHERE topic = ?`, topic)
+	_, err := r.db.ExecContext(ctx, `DELETE FROM interest_topics WHERE bot_id = ? AND topic = ?`, r.botID, topic)
 	if err != nil {
 		return fmt.Errorf("failed to remove interest topic: %w", err)
 	}
@@ -385,8 +416,8 @@ func (r *bufferRepo) RemoveInterestTopic(ctx context.Context, topic string) erro
 // GetInterestTopics gets all interest topics
 func (r *bufferRepo) GetInterestTopics(ctx context.Context) ([]string, error) {
 	rows, err := r.db.QueryContext(ctx, `
-		SELECT topic FROM interest_topics ORDER BY created_at DESC
-	`)
+		SELECT topic FROM interest_topics WHERE bot_id = ? ORDER BY created_at DESC
+	`, r.botID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query interest topics: %w", err)
 	}
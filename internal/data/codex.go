@@ -4,35 +4,83 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/anthropics/feishu-codex-bridge/internal/biz/domain"
 	"github.com/anthropics/feishu-codex-bridge/internal/biz/repo"
 	"github.com/anthropics/feishu-codex-bridge/internal/infra/acp"
 )
 
 // codexRepo implements the Codex repository
+// Bots sharing one app-server each get a codexRepo over the same CodexShare
 type codexRepo struct {
-	client   *acp.Client
-	eventsCh chan repo.Event
+	client *acp.Client
+	share  *CodexShare
+	botID  string
+	thread CodexThreadOptions
 }
 
-// NewCodexRepo creates a new Codex repository
-func NewCodexRepo(client *acp.Client) repo.CodexRepo {
-	r := &codexRepo{
+// CodexThreadOptions are applied to every thread a bot starts or resumes
+type CodexThreadOptions struct {
+	Cwd    string                 // Working directory, empty for the app-server's
+	Config map[string]interface{} // Codex config overrides, e.g. the MCP server env
+}
+
+// CodexShare is one Codex app-server shared by the repositories of several bots
+// Events go to every bot, approval requests to the bot owning the thread
+type CodexShare struct {
+	client *acp.Client
+	bus    *eventBus
+
+	respond func(requestID int64, decision string) error // Answers an approval request
+
+	mu       sync.Mutex
+	owners   map[string]string               // Thread ID -> bot ID
+	handlers map[string]repo.ApprovalHandler // Bot ID -> approval handler
+	order    []string                        // Bot IDs in the order handlers were set
+}
+
+// NewCodexShare starts forwarding the events of a Codex client
+func NewCodexShare(client *acp.Client) *CodexShare {
+	s := &CodexShare{
 		client:   client,
-		eventsCh: make(chan repo.Event, 100),
+		bus:      newEventBus(),
+		respond:  client.RespondToApproval,
+		owners:   make(map[string]string),
+		handlers: make(map[string]repo.ApprovalHandler),
 	}
 
 	// Forward Codex events
-	go r.forwardEvents()
+	go s.forwardEvents()
+
+	return s
+}
+
+// ForBot returns the Codex repository of one bot
+func (s *CodexShare) ForBot(botID string, opts CodexThreadOptions) repo.CodexRepo {
+	return &codexRepo{client: s.client, share: s, botID: botID, thread: opts}
+}
 
-	return r
+// NewCodexRepo creates a Codex repository for a single bot
+func NewCodexRepo(client *acp.Client) repo.CodexRepo {
+	return NewCodexShare(client).ForBot(domain.DefaultBotID, CodexThreadOptions{})
 }
 
 // CreateThread creates a new Thread
-func (r *codexRepo) CreateThread(ctx context.Context) (string, error) {
-	return r.client.ThreadStart(ctx, nil)
+func (r *codexRepo) CreateThread(ctx context.Context, model string) (string, error) {
+	threadID, err := r.client.ThreadStart(ctx, &acp.ThreadStartParams{
+		Model:  model,
+		Cwd:    r.thread.Cwd,
+		Config: r.thread.Config,
+	})
+	if err != nil {
+		return "", err
+	}
+	r.share.own(threadID, r.botID)
+	return threadID, nil
 }
 
 // StartTurn starts a conversation turn
@@ -42,19 +90,59 @@ func (r *codexRepo) StartTurn(ctx context.Context, threadID, prompt string, imag
 
 // ResumeThread resumes a Thread
 func (r *codexRepo) ResumeThread(ctx context.Context, threadID string) error {
-	_, err := r.client.ThreadResume(ctx, threadID)
-	return err
+	_, err := r.client.ThreadResumeWith(ctx, &acp.ThreadResumeParams{
+		ThreadID: threadID,
+		Cwd:      r.thread.Cwd,
+		Config:   r.thread.Config,
+	})
+	if err != nil {
+		return err
+	}
+	r.share.own(threadID, r.botID)
+	return nil
+}
+
+// InterruptTurn interrupts the running turn of a Thread
+func (r *codexRepo) InterruptTurn(ctx context.Context, threadID string) error {
+	return r.client.TurnInterrupt(ctx, threadID)
 }
 
 // Stop stops the client
+// Subscriptions are ended by forwardEvents once the client channel drains
 func (r *codexRepo) Stop() {
 	r.client.Stop()
-	close(r.eventsCh)
 }
 
-// Events returns the event channel
-func (r *codexRepo) Events() <-chan repo.Event {
-	return r.eventsCh
+// Subscribe delivers every event matching the filter until closed
+func (r *codexRepo) Subscribe(filter repo.EventFilter) repo.Subscription {
+	return r.share.bus.subscribe(filter, false)
+}
+
+// SubscribeTurn delivers every event of one turn, ending when it completes
+func (r *codexRepo) SubscribeTurn(threadID, turnID string) repo.Subscription {
+	return r.share.bus.subscribe(repo.EventFilter{ThreadID: threadID, TurnID: turnID}, true)
+}
+
+// Status returns the app-server supervisor status
+func (r *codexRepo) Status() repo.CodexStatus {
+	st := r.client.Status()
+	return repo.CodexStatus{
+		Running:     st.Running,
+		Restarting:  st.Restarting,
+		Restarts:    st.Restarts,
+		LastRestart: st.LastRestart,
+		LastExitErr: st.LastExitErr,
+	}
+}
+
+// SetApprovalHandler sets the handler for approval requests of this bot's threads
+func (r *codexRepo) SetApprovalHandler(handler repo.ApprovalHandler) {
+	r.share.setApprovalHandler(r.botID, handler)
+}
+
+// RespondToApproval sends an approval decision back to Codex
+func (r *codexRepo) RespondToApproval(requestID int64, decision domain.ApprovalDecision) error {
+	return r.client.RespondToApproval(requestID, string(decision))
 }
 
 // DebugConversation runs a complete conversation synchronously for debugging
@@ -62,21 +150,81 @@ func (r *codexRepo) DebugConversation(ctx context.Context, prompt string, timeou
 	return r.client.DebugConversation(ctx, prompt, timeout)
 }
 
-// forwardEvents forwards Codex events
-func (r *codexRepo) forwardEvents() {
-	for event := range r.client.Events() {
-		repoEvent := r.convertEvent(event)
-		if repoEvent != nil {
-			select {
-			case r.eventsCh <- *repoEvent:
-			default:
-				// Channel full, drop event
-			}
+// own records which bot a thread belongs to
+func (s *CodexShare) own(threadID, botID string) {
+	s.mu.Lock()
+	s.owners[threadID] = botID
+	s.mu.Unlock()
+}
+
+// setApprovalHandler sets or clears the approval handler of a bot
+// Without any handler approvals are disabled and the client auto-accepts every request
+func (s *CodexShare) setApprovalHandler(botID string, handler repo.ApprovalHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if handler == nil {
+		delete(s.handlers, botID)
+		s.order = slices.DeleteFunc(s.order, func(id string) bool { return id == botID })
+	} else {
+		if _, ok := s.handlers[botID]; !ok {
+			s.order = append(s.order, botID)
+		}
+		s.handlers[botID] = handler
+	}
+
+	if len(s.handlers) == 0 {
+		s.client.SetApprovalHandler(nil)
+		return
+	}
+	s.client.SetApprovalHandler(s.routeApproval)
+}
+
+// routeApproval hands an approval request to the bot owning its thread
+// Threads of unknown owner go to the first bot that set a handler
+// Requests that cannot be read or handed to anyone are declined, never accepted
+func (s *CodexShare) routeApproval(req acp.ServerRequest) {
+	approval, err := convertApprovalRequest(req)
+	if err != nil {
+		fmt.Printf("[CodexRepo] Declining request %d: %v\n", req.ID, err)
+		s.decline(req)
+		return
+	}
+
+	s.mu.Lock()
+	handler, ok := s.handlers[s.owners[approval.ThreadID]]
+	if !ok && len(s.order) > 0 {
+		handler = s.handlers[s.order[0]]
+	}
+	s.mu.Unlock()
+
+	if handler == nil {
+		fmt.Printf("[CodexRepo] Declining %s of thread %s: no approval handler\n", req.Method, approval.ThreadID)
+		s.decline(req)
+		return
+	}
+	handler(approval)
+}
+
+// decline answers a request nobody can decide on
+func (s *CodexShare) decline(req acp.ServerRequest) {
+	if err := s.respond(req.ID, string(domain.ApprovalDecline)); err != nil {
+		fmt.Printf("[CodexRepo] Failed to respond to %s: %v\n", req.Method, err)
+	}
+}
+
+// forwardEvents publishes Codex events to subscribers
+// Subscriptions are ended once the client channel drains
+func (s *CodexShare) forwardEvents() {
+	defer s.bus.close()
+	for event := range s.client.Events() {
+		if repoEvent := convertEvent(event); repoEvent != nil {
+			s.bus.publish(*repoEvent)
 		}
 	}
 }
 
-func (r *codexRepo) convertEvent(event acp.Event) *repo.Event {
+func convertEvent(event acp.Event) *repo.Event {
 	switch event.Method {
 	case acp.MethodAgentMessageDelta:
 		var params acp.AgentMessageDeltaParams
@@ -118,10 +266,36 @@ func (r *codexRepo) convertEvent(event acp.Event) *repo.Event {
 			TurnID:   params.TurnID,
 		}
 
+	case acp.MethodCodexExited:
+		var params acp.CodexExitedParams
+		_ = json.Unmarshal(event.Params, &params)
+		return &repo.Event{
+			Type: repo.EventTypeCodexExited,
+			Data: &repo.ErrorData{
+				Error: fmt.Errorf("codex app-server exited: %s", params.Error),
+			},
+		}
+
+	case acp.MethodCodexRestarted:
+		var params acp.CodexRestartedParams
+		_ = json.Unmarshal(event.Params, &params)
+		return &repo.Event{
+			Type: repo.EventTypeCodexRestarted,
+			Data: &repo.CodexRestartData{Restarts: params.Restarts},
+		}
+
 	default:
 		if strings.Contains(string(event.Method), "error") {
+			// Scope the error to its turn when Codex says which one failed
+			var params struct {
+				ThreadID string `json:"threadId"`
+				TurnID   string `json:"turnId"`
+			}
+			_ = json.Unmarshal(event.Params, &params)
 			return &repo.Event{
-				Type: repo.EventTypeError,
+				Type:     repo.EventTypeError,
+				ThreadID: params.ThreadID,
+				TurnID:   params.TurnID,
 				Data: &repo.ErrorData{
 					Error: fmt.Errorf("codex error: %s", event.Method),
 				},
@@ -131,3 +305,46 @@ func (r *codexRepo) convertEvent(event acp.Event) *repo.Event {
 
 	return nil
 }
+
+// convertApprovalRequest converts a Codex server request to a domain approval request
+// Fails for request types that are not approvals and for params that do not parse
+func convertApprovalRequest(req acp.ServerRequest) (*domain.ApprovalRequest, error) {
+	switch req.Method {
+	case acp.MethodCommandExecutionRequestApproval:
+		var params acp.CommandExecutionApprovalParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, fmt.Errorf("failed to parse command approval params: %w", err)
+		}
+		return &domain.ApprovalRequest{
+			RequestID: req.ID,
+			Kind:      domain.ApprovalKindCommand,
+			ThreadID:  params.ThreadID,
+			TurnID:    params.TurnID,
+			ItemID:    params.ItemID,
+			Command:   string(params.Command),
+			Cwd:       params.Cwd,
+			CreatedAt: time.Now(),
+		}, nil
+
+	case acp.MethodFileChangeRequestApproval:
+		var params acp.FileChangeApprovalParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, fmt.Errorf("failed to parse file change approval params: %w", err)
+		}
+		var files []string
+		for _, c := range params.Changes {
+			files = append(files, c.Path)
+		}
+		return &domain.ApprovalRequest{
+			RequestID: req.ID,
+			Kind:      domain.ApprovalKindFileChange,
+			ThreadID:  params.ThreadID,
+			TurnID:    params.TurnID,
+			ItemID:    params.ItemID,
+			Files:     files,
+			CreatedAt: time.Now(),
+		}, nil
+	}
+
+	return nil, fmt.Errorf("unknown approval request %s", req.Method)
+}
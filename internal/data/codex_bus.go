@@ -0,0 +1,179 @@
+package data
+
+import (
+	"sync"
+
+	"github.com/anthropics/feishu-codex-bridge/internal/biz/repo"
+)
+
+// eventBus fans Codex events out to subscriptions
+// Each subscription has its own unbounded queue, so a slow consumer
+// never blocks the bus or loses events
+type eventBus struct {
+	mu     sync.Mutex
+	subs   map[*subscription]struct{}
+	closed bool
+}
+
+func newEventBus() *eventBus {
+	return &eventBus{subs: make(map[*subscription]struct{})}
+}
+
+// subscribe registers a subscription. Turn-scoped subscriptions end after
+// the turn completes or the app-server exits.
+func (b *eventBus) subscribe(filter repo.EventFilter, turnScoped bool) *subscription {
+	sub := &subscription{
+		bus:        b,
+		filter:     filter,
+		turnScoped: turnScoped,
+		out:        make(chan repo.Event),
+		notify:     make(chan struct{}, 1),
+		done:       make(chan struct{}),
+	}
+
+	b.mu.Lock()
+	if b.closed {
+		sub.ended = true
+	} else {
+		b.subs[sub] = struct{}{}
+	}
+	b.mu.Unlock()
+
+	go sub.pump()
+	return sub
+}
+
+// publish delivers an event to every matching subscription
+func (b *eventBus) publish(event repo.Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for sub := range b.subs {
+		if sub.turnScoped && event.Type == repo.EventTypeCodexExited {
+			// The turn died with the process
+			sub.push(event)
+			sub.end()
+			delete(b.subs, sub)
+			continue
+		}
+
+		if !sub.matches(event) {
+			continue
+		}
+		sub.push(event)
+
+		if sub.turnScoped && event.Type == repo.EventTypeTurnComplete {
+			sub.end()
+			delete(b.subs, sub)
+		}
+	}
+}
+
+// close ends all subscriptions; they still drain queued events
+func (b *eventBus) close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for sub := range b.subs {
+		sub.end()
+		delete(b.subs, sub)
+	}
+}
+
+func (b *eventBus) remove(sub *subscription) {
+	b.mu.Lock()
+	delete(b.subs, sub)
+	b.mu.Unlock()
+}
+
+// subscription implements repo.Subscription
+type subscription struct {
+	bus        *eventBus
+	filter     repo.EventFilter
+	turnScoped bool
+
+	mu     sync.Mutex
+	queue  []repo.Event
+	ended  bool // No more events will be queued
+	notify chan struct{}
+
+	out       chan repo.Event
+	done      chan struct{} // Closed when the consumer closes the subscription
+	closeOnce sync.Once
+}
+
+// Events returns the event stream
+func (s *subscription) Events() <-chan repo.Event {
+	return s.out
+}
+
+// Close stops the subscription, discarding undelivered events
+func (s *subscription) Close() {
+	s.closeOnce.Do(func() {
+		s.bus.remove(s)
+		close(s.done)
+	})
+}
+
+// matches checks the filter, binding an open turn subscription to the first turn seen
+// Called with the bus lock held
+func (s *subscription) matches(event repo.Event) bool {
+	if s.turnScoped && s.filter.TurnID == "" && event.ThreadID == s.filter.ThreadID && event.TurnID != "" {
+		s.filter.TurnID = event.TurnID
+	}
+	return s.filter.Matches(event)
+}
+
+func (s *subscription) push(event repo.Event) {
+	s.mu.Lock()
+	s.queue = append(s.queue, event)
+	s.mu.Unlock()
+	s.signal()
+}
+
+func (s *subscription) end() {
+	s.mu.Lock()
+	s.ended = true
+	s.mu.Unlock()
+	s.signal()
+}
+
+func (s *subscription) signal() {
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+// pump moves queued events to the consumer and closes the stream once ended
+func (s *subscription) pump() {
+	defer close(s.out)
+
+	for {
+		s.mu.Lock()
+		if len(s.queue) == 0 {
+			ended := s.ended
+			s.mu.Unlock()
+			if ended {
+				return
+			}
+			select {
+			case <-s.notify:
+				continue
+			case <-s.done:
+				return
+			}
+		}
+		event := s.queue[0]
+		s.queue[0] = repo.Event{}
+		s.queue = s.queue[1:]
+		s.mu.Unlock()
+
+		select {
+		case s.out <- event:
+		case <-s.done:
+			return
+		}
+	}
+}
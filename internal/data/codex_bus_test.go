@@ -0,0 +1,151 @@
+package data
+
+import (
+	"testing"
+	"time"
+
+	"github.com/anthropics/feishu-codex-bridge/internal/biz/repo"
+)
+
+func delta(threadID, turnID, text string) repo.Event {
+	return repo.Event{
+		Type:     repo.EventTypeAgentDelta,
+		ThreadID: threadID,
+		TurnID:   turnID,
+		Data:     &repo.AgentDeltaData{Delta: text},
+	}
+}
+
+func complete(threadID, turnID string) repo.Event {
+	return repo.Event{Type: repo.EventTypeTurnComplete, ThreadID: threadID, TurnID: turnID}
+}
+
+// drain reads a subscription until it closes
+func drain(t *testing.T, sub repo.Subscription) []repo.Event {
+	t.Helper()
+	var events []repo.Event
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case event, ok := <-sub.Events():
+			if !ok {
+				return events
+			}
+			events = append(events, event)
+		case <-timeout:
+			t.Fatalf("Subscription did not close, got %d events", len(events))
+		}
+	}
+}
+
+func TestEventBus_TurnSubscriptionsAreIsolated(t *testing.T) {
+	bus := newEventBus()
+	subA := bus.subscribe(repo.EventFilter{ThreadID: "thread-a"}, true)
+	subB := bus.subscribe(repo.EventFilter{ThreadID: "thread-b"}, true)
+
+	bus.publish(delta("thread-a", "turn-1", "hello "))
+	bus.publish(delta("thread-b", "turn-2", "other"))
+	bus.publish(delta("thread-a", "turn-1", "world"))
+	bus.publish(complete("thread-a", "turn-1"))
+	bus.publish(complete("thread-b", "turn-2"))
+
+	eventsA := drain(t, subA)
+	if len(eventsA) != 3 {
+		t.Fatalf("Expected 3 events for thread-a, got %d", len(eventsA))
+	}
+	text := ""
+	for _, e := range eventsA {
+		if e.ThreadID != "thread-a" {
+			t.Errorf("Unexpected event from %s", e.ThreadID)
+		}
+		if d, ok := e.Data.(*repo.AgentDeltaData); ok {
+			text += d.Delta
+		}
+	}
+	if text != "hello world" {
+		t.Errorf("Expected 'hello world', got %q", text)
+	}
+	if last := eventsA[len(eventsA)-1]; last.Type != repo.EventTypeTurnComplete {
+		t.Errorf("Expected last event to be turn complete, got %s", last.Type)
+	}
+
+	if eventsB := drain(t, subB); len(eventsB) != 2 {
+		t.Errorf("Expected 2 events for thread-b, got %d", len(eventsB))
+	}
+}
+
+func TestEventBus_TurnSubscriptionBindsFirstTurn(t *testing.T) {
+	bus := newEventBus()
+	sub := bus.subscribe(repo.EventFilter{ThreadID: "thread-a"}, true)
+
+	bus.publish(delta("thread-a", "turn-1", "a"))
+	bus.publish(delta("thread-a", "turn-other", "b"))
+	bus.publish(complete("thread-a", "turn-1"))
+
+	events := drain(t, sub)
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 events, got %d", len(events))
+	}
+	for _, e := range events {
+		if e.TurnID != "turn-1" {
+			t.Errorf("Unexpected event from %s", e.TurnID)
+		}
+	}
+}
+
+func TestEventBus_SlowConsumerLosesNothing(t *testing.T) {
+	bus := newEventBus()
+	sub := bus.subscribe(repo.EventFilter{ThreadID: "thread-a"}, true)
+
+	// Publish far more than any channel buffer before reading anything
+	const n = 1000
+	for i := 0; i < n; i++ {
+		bus.publish(delta("thread-a", "turn-1", "x"))
+	}
+	bus.publish(complete("thread-a", "turn-1"))
+
+	if events := drain(t, sub); len(events) != n+1 {
+		t.Errorf("Expected %d events, got %d", n+1, len(events))
+	}
+}
+
+func TestEventBus_CodexExitEndsTurnSubscriptions(t *testing.T) {
+	bus := newEventBus()
+	turnSub := bus.subscribe(repo.EventFilter{ThreadID: "thread-a"}, true)
+	lifecycle := bus.subscribe(repo.EventFilter{Types: []repo.EventType{repo.EventTypeCodexExited}}, false)
+	defer lifecycle.Close()
+
+	bus.publish(delta("thread-a", "turn-1", "partial"))
+	bus.publish(repo.Event{Type: repo.EventTypeCodexExited})
+
+	events := drain(t, turnSub)
+	if len(events) != 2 || events[1].Type != repo.EventTypeCodexExited {
+		t.Errorf("Expected delta then exit, got %+v", events)
+	}
+
+	select {
+	case e := <-lifecycle.Events():
+		if e.Type != repo.EventTypeCodexExited {
+			t.Errorf("Expected exit event, got %s", e.Type)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Lifecycle subscription did not receive exit event")
+	}
+}
+
+func TestEventBus_CloseAndShutdown(t *testing.T) {
+	bus := newEventBus()
+	sub := bus.subscribe(repo.EventFilter{}, false)
+	sub.Close()
+	sub.Close() // idempotent
+
+	bus.publish(delta("thread-a", "turn-1", "x"))
+	drain(t, sub)
+
+	long := bus.subscribe(repo.EventFilter{}, false)
+	bus.close()
+	drain(t, long)
+
+	// Subscribing after shutdown yields a closed stream
+	drain(t, bus.subscribe(repo.EventFilter{}, false))
+}
@@ -4,15 +4,12 @@ import (
 	"encoding/json"
 	"testing"
 
+	"github.com/anthropics/feishu-codex-bridge/internal/biz/domain"
 	"github.com/anthropics/feishu-codex-bridge/internal/biz/repo"
 	"github.com/anthropics/feishu-codex-bridge/internal/infra/acp"
 )
 
 func TestConvertEvent_AgentMessageDelta(t *testing.T) {
-	r := &codexRepo{
-		eventsCh: make(chan repo.Event, 10),
-	}
-
 	params := acp.AgentMessageDeltaParams{
 		ThreadID: "thread-123",
 		TurnID:   "turn-456",
@@ -26,7 +23,7 @@ func TestConvertEvent_AgentMessageDelta(t *testing.T) {
 		Params: paramsJSON,
 	}
 
-	result := r.convertEvent(event)
+	result := convertEvent(event)
 
 	if result == nil {
 		t.Fatal("Expected non-nil result")
@@ -51,10 +48,6 @@ func TestConvertEvent_AgentMessageDelta(t *testing.T) {
 }
 
 func TestConvertEvent_TurnCompleted(t *testing.T) {
-	r := &codexRepo{
-		eventsCh: make(chan repo.Event, 10),
-	}
-
 	params := acp.TurnCompletedParams{
 		ThreadID: "thread-abc",
 		TurnID:   "turn-def",
@@ -67,7 +60,7 @@ func TestConvertEvent_TurnCompleted(t *testing.T) {
 		Params: paramsJSON,
 	}
 
-	result := r.convertEvent(event)
+	result := convertEvent(event)
 
 	if result == nil {
 		t.Fatal("Expected non-nil result")
@@ -84,10 +77,6 @@ func TestConvertEvent_TurnCompleted(t *testing.T) {
 }
 
 func TestConvertEvent_ItemCompleted(t *testing.T) {
-	r := &codexRepo{
-		eventsCh: make(chan repo.Event, 10),
-	}
-
 	params := acp.ItemCompletedParams{
 		ThreadID: "thread-item",
 		TurnID:   "turn-item",
@@ -99,7 +88,7 @@ func TestConvertEvent_ItemCompleted(t *testing.T) {
 		Params: paramsJSON,
 	}
 
-	result := r.convertEvent(event)
+	result := convertEvent(event)
 
 	if result == nil {
 		t.Fatal("Expected non-nil result")
@@ -113,16 +102,12 @@ func TestConvertEvent_ItemCompleted(t *testing.T) {
 }
 
 func TestConvertEvent_InvalidJSON(t *testing.T) {
-	r := &codexRepo{
-		eventsCh: make(chan repo.Event, 10),
-	}
-
 	event := acp.Event{
 		Method: acp.MethodAgentMessageDelta,
 		Params: json.RawMessage(`{invalid json}`),
 	}
 
-	result := r.convertEvent(event)
+	result := convertEvent(event)
 
 	if result != nil {
 		t.Error("Expected nil result for invalid JSON")
@@ -130,16 +115,12 @@ func TestConvertEvent_InvalidJSON(t *testing.T) {
 }
 
 func TestConvertEvent_UnknownMethod(t *testing.T) {
-	r := &codexRepo{
-		eventsCh: make(chan repo.Event, 10),
-	}
-
 	event := acp.Event{
 		Method: "unknown/method",
 		Params: json.RawMessage(`{}`),
 	}
 
-	result := r.convertEvent(event)
+	result := convertEvent(event)
 
 	if result != nil {
 		t.Error("Expected nil result for unknown method")
@@ -147,16 +128,12 @@ func TestConvertEvent_UnknownMethod(t *testing.T) {
 }
 
 func TestConvertEvent_ErrorMethod(t *testing.T) {
-	r := &codexRepo{
-		eventsCh: make(chan repo.Event, 10),
-	}
-
 	event := acp.Event{
 		Method: "some/error/event",
 		Params: json.RawMessage(`{}`),
 	}
 
-	result := r.convertEvent(event)
+	result := convertEvent(event)
 
 	if result == nil {
 		t.Fatal("Expected non-nil result for error method")
@@ -165,3 +142,168 @@ func TestConvertEvent_ErrorMethod(t *testing.T) {
 		t.Errorf("Expected type %s, got %s", repo.EventTypeError, result.Type)
 	}
 }
+
+func TestConvertApprovalRequest_Command(t *testing.T) {
+	params, _ := json.Marshal(acp.CommandExecutionApprovalParams{
+		ThreadID: "thread-1",
+		TurnID:   "turn-1",
+		ItemID:   "item-1",
+		Command:  "go test ./...",
+		Cwd:      "/work",
+	})
+
+	result, err := convertApprovalRequest(acp.ServerRequest{
+		ID:     7,
+		Method: acp.MethodCommandExecutionRequestApproval,
+		Params: params,
+	})
+
+	if err != nil {
+		t.Fatalf("convertApprovalRequest failed: %v", err)
+	}
+	if result.RequestID != 7 {
+		t.Errorf("Expected request ID 7, got %d", result.RequestID)
+	}
+	if result.Kind != domain.ApprovalKindCommand {
+		t.Errorf("Expected kind %s, got %s", domain.ApprovalKindCommand, result.Kind)
+	}
+	if result.ThreadID != "thread-1" || result.Command != "go test ./..." || result.Cwd != "/work" {
+		t.Errorf("Unexpected request: %+v", result)
+	}
+}
+
+func TestConvertApprovalRequest_FileChange(t *testing.T) {
+	params, _ := json.Marshal(acp.FileChangeApprovalParams{
+		ThreadID: "thread-2",
+		Changes: []acp.FileChange{
+			{Path: "a.go"},
+			{Path: "b.go"},
+		},
+	})
+
+	result, err := convertApprovalRequest(acp.ServerRequest{
+		ID:     8,
+		Method: acp.MethodFileChangeRequestApproval,
+		Params: params,
+	})
+
+	if err != nil {
+		t.Fatalf("convertApprovalRequest failed: %v", err)
+	}
+	if result.Kind != domain.ApprovalKindFileChange {
+		t.Errorf("Expected kind %s, got %s", domain.ApprovalKindFileChange, result.Kind)
+	}
+	if len(result.Files) != 2 || result.Files[0] != "a.go" {
+		t.Errorf("Unexpected files: %v", result.Files)
+	}
+}
+
+func TestConvertApprovalRequest_CommandArgv(t *testing.T) {
+	params := json.RawMessage(`{"threadId":"thread-1","command":["git","commit","-m","fix build"],"cwd":"/work"}`)
+
+	result, err := convertApprovalRequest(acp.ServerRequest{ID: 7, Method: acp.MethodCommandExecutionRequestApproval, Params: params})
+	if err != nil {
+		t.Fatalf("convertApprovalRequest failed: %v", err)
+	}
+	if result.Command != `git commit -m "fix build"` {
+		t.Errorf("Unexpected command: %q", result.Command)
+	}
+}
+
+func TestConvertApprovalRequest_Unknown(t *testing.T) {
+	result, err := convertApprovalRequest(acp.ServerRequest{ID: 9, Method: "some/other"})
+	if err == nil {
+		t.Errorf("Expected an error for unknown method, got %+v", result)
+	}
+}
+
+func TestCodexShare_DeclinesUnreadableApprovals(t *testing.T) {
+	responses := make(map[int64]string)
+	share := &CodexShare{
+		client:   acp.NewClient("", ""),
+		respond:  func(id int64, decision string) error { responses[id] = decision; return nil },
+		owners:   make(map[string]string),
+		handlers: make(map[string]repo.ApprovalHandler),
+	}
+	handled := 0
+	share.ForBot("sales", CodexThreadOptions{}).SetApprovalHandler(func(req *domain.ApprovalRequest) { handled++ })
+
+	share.routeApproval(acp.ServerRequest{ID: 1, Method: acp.MethodCommandExecutionRequestApproval, Params: json.RawMessage(`{"threadId":"thread-1","command":42}`)})
+	share.routeApproval(acp.ServerRequest{ID: 2, Method: acp.MethodFileChangeRequestApproval, Params: json.RawMessage(`{"changes":"a.go"}`)})
+	share.routeApproval(acp.ServerRequest{ID: 3, Method: "item/unknown/requestApproval", Params: json.RawMessage(`{}`)})
+
+	if handled != 0 {
+		t.Errorf("Expected no request to reach the handler, got %d", handled)
+	}
+	for id := int64(1); id <= 3; id++ {
+		if responses[id] != string(domain.ApprovalDecline) {
+			t.Errorf("Expected request %d to be declined, got %q", id, responses[id])
+		}
+	}
+}
+
+func TestCodexShare_RoutesApprovalsByThread(t *testing.T) {
+	share := &CodexShare{
+		client:   acp.NewClient("", ""),
+		owners:   make(map[string]string),
+		handlers: make(map[string]repo.ApprovalHandler),
+	}
+	got := make(map[string][]string)
+	for _, botID := range []string{"sales", "support"} {
+		share.ForBot(botID, CodexThreadOptions{}).SetApprovalHandler(func(req *domain.ApprovalRequest) {
+			got[botID] = append(got[botID], req.ThreadID)
+		})
+	}
+	share.own("thread-1", "support")
+
+	for _, threadID := range []string{"thread-1", "thread-unknown"} {
+		params, _ := json.Marshal(acp.CommandExecutionApprovalParams{ThreadID: threadID, Command: "ls"})
+		share.routeApproval(acp.ServerRequest{ID: 1, Method: acp.MethodCommandExecutionRequestApproval, Params: params})
+	}
+
+	if len(got["support"]) != 1 || got["support"][0] != "thread-1" {
+		t.Errorf("Expected the owning bot to get its thread's request, got %v", got["support"])
+	}
+	if len(got["sales"]) != 1 || got["sales"][0] != "thread-unknown" {
+		t.Errorf("Expected the first bot to get requests of unknown threads, got %v", got["sales"])
+	}
+}
+
+func TestConvertEvent_CodexRestarted(t *testing.T) {
+	event := acp.Event{
+		Method: acp.MethodCodexRestarted,
+		Params: json.RawMessage(`{"restarts": 3}`),
+	}
+
+	result := convertEvent(event)
+
+	if result == nil {
+		t.Fatal("Expected non-nil result")
+	}
+	if result.Type != repo.EventTypeCodexRestarted {
+		t.Errorf("Expected type %s, got %s", repo.EventTypeCodexRestarted, result.Type)
+	}
+	data, ok := result.Data.(*repo.CodexRestartData)
+	if !ok {
+		t.Fatal("Expected CodexRestartData")
+	}
+	if data.Restarts != 3 {
+		t.Errorf("Expected 3 restarts, got %d", data.Restarts)
+	}
+}
+
+func TestConvertEvent_CodexExited(t *testing.T) {
+	event := acp.Event{
+		Method: acp.MethodCodexExited,
+		Params: json.RawMessage(`{"error": "signal: killed"}`),
+	}
+
+	result := convertEvent(event)
+
+	if result == nil {
+		t.Fatal("Expected non-nil result")
+	}
+	if result.Type != repo.EventTypeCodexExited {
+		t.Errorf("Expected type %s, got %s", repo.EventTypeCodexExited, result.Type)
+	}
+}
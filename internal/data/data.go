@@ -1,58 +1,127 @@
 package data
 
 import (
+	"context"
+	"time"
+
 	"github.com/anthropics/feishu-codex-bridge/internal/biz/repo"
 	"github.com/anthropics/feishu-codex-bridge/internal/conf"
-	"github.com/anthropics/feishu-codex-bridge/internal/infra/acp"
 	"github.com/anthropics/feishu-codex-bridge/internal/infra/feishu"
 	"github.com/anthropics/feishu-codex-bridge/internal/infra/openai"
 )
 
 // Repositories contains all repositories
 type Repositories struct {
-	Message repo.MessageRepo
-	Session repo.SessionRepo
-	Codex   repo.CodexRepo
-	Filter  repo.FilterRepo
-	Buffer  repo.BufferRepo
-	Memory  repo.MemoryRepo
+	Message  repo.MessageRepo
+	Session  repo.SessionRepo
+	Codex    repo.CodexRepo
+	Filter   repo.FilterRepo
+	Buffer   repo.BufferRepo
+	Memory   repo.MemoryRepo
+	Approval repo.ApprovalRepo
+	Queue    repo.QueueRepo
+	Settings repo.SettingsRepo
+	User     repo.UserRepo
+	Journal  repo.JournalRepo
+	Feedback repo.FeedbackRepo
 }
 
-// NewRepositories creates all repositories
+// NewRepositories creates the repositories of one bot
+// Stored records are tagged with botID, the databases are shared by all bots
 func NewRepositories(
+	botID string,
 	feishuClient *feishu.Client,
-	codexClient *acp.Client,
+	codexRepo repo.CodexRepo,
 	moonshotClient *openai.Client,
 	sessionDBPath string,
 	botName string,
 	promptsConfig *conf.PromptsConfig,
+	directoryTTL time.Duration,
 ) (*Repositories, error) {
-	sessionRepo, err := NewSessionRepo(sessionDBPath)
+	sessionRepo, err := NewSessionRepo(sessionDBPath, botID)
 	if err != nil {
 		return nil, err
 	}
 
 	// Buffer repository uses same database directory as Session
 	bufferDBPath := sessionDBPath[:len(sessionDBPath)-len("sessions.db")] + "buffer.db"
-	bufferRepo, err := NewBufferRepo(bufferDBPath)
+	bufferRepo, err := NewBufferRepo(bufferDBPath, botID)
 	if err != nil {
 		return nil, err
 	}
 
 	// Memory repository for persistent memory storage
 	memoryDBPath := sessionDBPath[:len(sessionDBPath)-len("sessions.db")] + "memory.db"
-	memoryRepo, err := NewMemoryRepo(memoryDBPath)
+	memoryRepo, err := NewMemoryRepo(memoryDBPath, botID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Approval repository for the approval audit log
+	approvalDBPath := sessionDBPath[:len(sessionDBPath)-len("sessions.db")] + "approvals.db"
+	approvalRepo, err := NewApprovalRepo(approvalDBPath, botID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Queue repository for messages waiting on a chat's current turn
+	queueDBPath := sessionDBPath[:len(sessionDBPath)-len("sessions.db")] + "queue.db"
+	queueRepo, err := NewQueueRepo(queueDBPath, botID)
 	if err != nil {
 		return nil, err
 	}
 
+	// Settings repository for per-chat reply preferences
+	settingsDBPath := sessionDBPath[:len(sessionDBPath)-len("sessions.db")] + "settings.db"
+	settingsRepo, err := NewSettingsRepo(settingsDBPath, botID)
+	if err != nil {
+		return nil, err
+	}
+
+	// User directory for senders who are not chat members
+	usersDBPath := sessionDBPath[:len(sessionDBPath)-len("sessions.db")] + "users.db"
+	userRepo, err := NewUserRepo(usersDBPath, botID, feishuClient)
+	if err != nil {
+		return nil, err
+	}
+
+	// Journal of received messages, for catching up after restarts and reconnects
+	journalDBPath := sessionDBPath[:len(sessionDBPath)-len("sessions.db")] + "journal.db"
+	journalRepo, err := NewJournalRepo(journalDBPath, botID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Ratings of streamed replies
+	feedbackDBPath := sessionDBPath[:len(sessionDBPath)-len("sessions.db")] + "feedback.db"
+	feedbackRepo, err := NewFeedbackRepo(feedbackDBPath, botID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Members and chat info are cached, listed messages resolve senders through the cache too
+	feishuRepo := &feishuRepo{client: feishuClient, users: userRepo}
+	messageRepo := NewDirectoryRepo(feishuRepo, directoryTTL)
+	feishuRepo.members = messageRepo.GetChatMembers
+
+	// Forwarded chat records name their senders like listed messages do
+	feishuClient.SetSenderNames(func(chatID, openID string) string {
+		return feishuRepo.senderName(context.Background(), chatID, openID)
+	})
+
 	// bufferRepo implements TopicsProvider interface, passed to Moonshot for dynamic topic fetching
 	return &Repositories{
-		Message: NewFeishuRepo(feishuClient),
-		Session: sessionRepo,
-		Codex:   NewCodexRepo(codexClient),
-		Filter:  NewMoonshotRepoWithConfig(moonshotClient, botName, bufferRepo, promptsConfig),
-		Buffer:  bufferRepo,
-		Memory:  memoryRepo,
+		Message:  messageRepo,
+		Session:  sessionRepo,
+		Codex:    codexRepo,
+		Filter:   NewMoonshotRepoWithConfig(moonshotClient, botName, bufferRepo, promptsConfig),
+		Buffer:   bufferRepo,
+		Memory:   memoryRepo,
+		Approval: approvalRepo,
+		Queue:    queueRepo,
+		Settings: settingsRepo,
+		User:     userRepo,
+		Journal:  journalRepo,
+		Feedback: feedbackRepo,
 	}, nil
 }
@@ -0,0 +1,159 @@
+package data
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/anthropics/feishu-codex-bridge/internal/biz/domain"
+	"github.com/anthropics/feishu-codex-bridge/internal/biz/repo"
+)
+
+// directoryRepo caches chat members and chat info in front of a MessageRepo
+// Concurrent lookups of the same chat share one API call. A chat's entries are
+// dropped when its members change, and everything expires after the TTL
+type directoryRepo struct {
+	repo.MessageRepo
+	ttl time.Duration
+	now func() time.Time
+
+	mu      sync.Mutex
+	members map[string]directoryEntry
+	chats   map[string]directoryEntry
+	flights map[string]*directoryFlight
+
+	hits          atomic.Int64
+	misses        atomic.Int64
+	shared        atomic.Int64
+	invalidations atomic.Int64
+}
+
+type directoryEntry struct {
+	value   interface{}
+	expires time.Time
+}
+
+// directoryFlight is an API call in progress that identical lookups wait for
+type directoryFlight struct {
+	done  chan struct{}
+	value interface{}
+	err   error
+	stale bool // Invalidated while in flight, the result is not cached
+}
+
+// NewDirectoryRepo wraps a message repository with a member and chat info cache
+// A ttl of 0 or less disables caching
+func NewDirectoryRepo(inner repo.MessageRepo, ttl time.Duration) repo.MessageRepo {
+	if ttl <= 0 {
+		return inner
+	}
+	return &directoryRepo{
+		MessageRepo: inner,
+		ttl:         ttl,
+		now:         time.Now,
+		members:     make(map[string]directoryEntry),
+		chats:       make(map[string]directoryEntry),
+		flights:     make(map[string]*directoryFlight),
+	}
+}
+
+// GetChatMembers returns the chat's members, from the cache when fresh
+func (r *directoryRepo) GetChatMembers(ctx context.Context, chatID string) ([]domain.Member, error) {
+	value, err := r.lookup(ctx, r.members, "members", chatID, func() (interface{}, error) {
+		return r.MessageRepo.GetChatMembers(ctx, chatID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	// Callers get their own copy of the shared list
+	return append([]domain.Member(nil), value.([]domain.Member)...), nil
+}
+
+// GetChatInfo returns the chat's info, from the cache when fresh
+func (r *directoryRepo) GetChatInfo(ctx context.Context, chatID string) (*repo.ChatInfo, error) {
+	value, err := r.lookup(ctx, r.chats, "chat", chatID, func() (interface{}, error) {
+		return r.MessageRepo.GetChatInfo(ctx, chatID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	info, _ := value.(*repo.ChatInfo)
+	if info == nil {
+		return nil, nil
+	}
+	copied := *info
+	return &copied, nil
+}
+
+// InvalidateMembers drops the cached members and info of a chat
+func (r *directoryRepo) InvalidateMembers(chatID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.members, chatID)
+	delete(r.chats, chatID)
+	for _, kind := range []string{"members", "chat"} {
+		if flight, ok := r.flights[kind+"/"+chatID]; ok {
+			flight.stale = true
+		}
+	}
+	r.invalidations.Add(1)
+	if inner, ok := r.MessageRepo.(repo.MemberCache); ok {
+		inner.InvalidateMembers(chatID)
+	}
+}
+
+// DirectoryStats returns the cache counters
+func (r *directoryRepo) DirectoryStats() repo.DirectoryStats {
+	r.mu.Lock()
+	members, chats := len(r.members), len(r.chats)
+	r.mu.Unlock()
+
+	return repo.DirectoryStats{
+		Hits:          r.hits.Load(),
+		Misses:        r.misses.Load(),
+		Shared:        r.shared.Load(),
+		Invalidations: r.invalidations.Load(),
+		Members:       members,
+		Chats:         chats,
+	}
+}
+
+// lookup returns the cached value of chatID, or fetches it once for all
+// concurrent callers and caches it on success
+func (r *directoryRepo) lookup(ctx context.Context, cache map[string]directoryEntry, kind, chatID string, fetch func() (interface{}, error)) (interface{}, error) {
+	key := kind + "/" + chatID
+
+	r.mu.Lock()
+	if entry, ok := cache[chatID]; ok && r.now().Before(entry.expires) {
+		r.mu.Unlock()
+		r.hits.Add(1)
+		return entry.value, nil
+	}
+	if flight, ok := r.flights[key]; ok {
+		r.mu.Unlock()
+		r.shared.Add(1)
+		select {
+		case <-flight.done:
+			return flight.value, flight.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	flight := &directoryFlight{done: make(chan struct{})}
+	r.flights[key] = flight
+	r.mu.Unlock()
+
+	r.misses.Add(1)
+	flight.value, flight.err = fetch()
+
+	r.mu.Lock()
+	delete(r.flights, key)
+	if flight.err == nil && !flight.stale {
+		cache[chatID] = directoryEntry{value: flight.value, expires: r.now().Add(r.ttl)}
+	}
+	r.mu.Unlock()
+	close(flight.done)
+	return flight.value, flight.err
+}
@@ -0,0 +1,122 @@
+package data
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/anthropics/feishu-codex-bridge/internal/biz/domain"
+	"github.com/anthropics/feishu-codex-bridge/internal/biz/repo"
+)
+
+// countingMessageRepo counts member and chat info calls, blocking them until release is closed
+type countingMessageRepo struct {
+	repo.MessageRepo
+	memberCalls atomic.Int32
+	infoCalls   atomic.Int32
+	release     chan struct{}
+}
+
+func (m *countingMessageRepo) GetChatMembers(ctx context.Context, chatID string) ([]domain.Member, error) {
+	m.memberCalls.Add(1)
+	if m.release != nil {
+		<-m.release
+	}
+	return []domain.Member{{UserID: "ou_1", Name: "Alice"}}, nil
+}
+
+func (m *countingMessageRepo) GetChatInfo(ctx context.Context, chatID string) (*repo.ChatInfo, error) {
+	m.infoCalls.Add(1)
+	return &repo.ChatInfo{ChatID: chatID, Name: "Team", ChatType: domain.ChatTypeGroup}, nil
+}
+
+func newTestDirectory(inner repo.MessageRepo) (*directoryRepo, *time.Time) {
+	r := NewDirectoryRepo(inner, time.Minute).(*directoryRepo)
+	now := time.Unix(1700000000, 0)
+	r.now = func() time.Time { return now }
+	return r, &now
+}
+
+func TestDirectoryRepo_CachesUntilTTL(t *testing.T) {
+	inner := &countingMessageRepo{}
+	r, now := newTestDirectory(inner)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		members, err := r.GetChatMembers(ctx, "oc_1")
+		if err != nil || len(members) != 1 || members[0].Name != "Alice" {
+			t.Fatalf("Unexpected members: %v, %v", members, err)
+		}
+		if _, err := r.GetChatInfo(ctx, "oc_1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if inner.memberCalls.Load() != 1 || inner.infoCalls.Load() != 1 {
+		t.Errorf("Expected one API call each, got %d members and %d info", inner.memberCalls.Load(), inner.infoCalls.Load())
+	}
+
+	*now = now.Add(2 * time.Minute)
+	_, _ = r.GetChatMembers(ctx, "oc_1")
+	if inner.memberCalls.Load() != 2 {
+		t.Errorf("Expected an expired entry to be fetched again, got %d calls", inner.memberCalls.Load())
+	}
+
+	stats := r.DirectoryStats()
+	if stats.Hits != 4 || stats.Misses != 3 || stats.Members != 1 || stats.Chats != 1 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
+
+func TestDirectoryRepo_SharesConcurrentLookups(t *testing.T) {
+	inner := &countingMessageRepo{release: make(chan struct{})}
+	r, _ := newTestDirectory(inner)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if members, err := r.GetChatMembers(context.Background(), "oc_1"); err != nil || len(members) != 1 {
+				t.Errorf("Unexpected members: %v, %v", members, err)
+			}
+		}()
+	}
+	// Let the other lookups queue up behind the first call
+	for r.shared.Load() < 4 {
+		time.Sleep(time.Millisecond)
+	}
+	close(inner.release)
+	wg.Wait()
+
+	if calls := inner.memberCalls.Load(); calls != 1 {
+		t.Errorf("Expected concurrent lookups to share one call, got %d", calls)
+	}
+}
+
+func TestDirectoryRepo_Invalidate(t *testing.T) {
+	inner := &countingMessageRepo{}
+	r, _ := newTestDirectory(inner)
+	ctx := context.Background()
+
+	_, _ = r.GetChatMembers(ctx, "oc_1")
+	_, _ = r.GetChatMembers(ctx, "oc_2")
+	r.InvalidateMembers("oc_1")
+	_, _ = r.GetChatMembers(ctx, "oc_1")
+	_, _ = r.GetChatMembers(ctx, "oc_2")
+
+	if calls := inner.memberCalls.Load(); calls != 3 {
+		t.Errorf("Expected only the invalidated chat to be fetched again, got %d calls", calls)
+	}
+	if stats := r.DirectoryStats(); stats.Invalidations != 1 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
+
+func TestNewDirectoryRepo_Disabled(t *testing.T) {
+	inner := &countingMessageRepo{}
+	if r := NewDirectoryRepo(inner, 0); r != repo.MessageRepo(inner) {
+		t.Error("Expected a zero TTL to return the repository unwrapped")
+	}
+}
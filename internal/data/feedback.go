@@ -0,0 +1,101 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/anthropics/feishu-codex-bridge/internal/biz/domain"
+	"github.com/anthropics/feishu-codex-bridge/internal/biz/repo"
+
+	_ "modernc.org/sqlite"
+)
+
+const createReplyFeedbackTable = `
+	CREATE TABLE IF NOT EXISTS reply_feedback (
+		bot_id TEXT NOT NULL DEFAULT 'default',
+		msg_id TEXT NOT NULL,
+		operator_id TEXT NOT NULL,
+		chat_id TEXT NOT NULL DEFAULT '',
+		rating TEXT NOT NULL,
+		created_at INTEGER NOT NULL,
+		PRIMARY KEY (bot_id, msg_id, operator_id)
+	)
+`
+
+// feedbackRepo implements the reply rating repository
+type feedbackRepo struct {
+	db    *sql.DB
+	botID string
+}
+
+// NewFeedbackRepo creates a new reply rating repository
+// Only ratings of botID's replies are visible to it
+func NewFeedbackRepo(dbPath, botID string) (repo.FeedbackRepo, error) {
+	// Ensure directory exists
+	dir := filepath.Dir(dbPath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create db directory: %w", err)
+	}
+
+	db, err := sql.Open("sqlite", sqliteDSN(dbPath))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	if _, err := db.Exec(createReplyFeedbackTable); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create reply_feedback table: %w", err)
+	}
+	_, _ = db.Exec(`CREATE INDEX IF NOT EXISTS idx_feedback_bot_chat ON reply_feedback(bot_id, chat_id, created_at)`)
+
+	fmt.Println("[Feedback] Database initialized")
+	return &feedbackRepo{db: db, botID: botID}, nil
+}
+
+func (r *feedbackRepo) Save(ctx context.Context, feedback *domain.Feedback) error {
+	if feedback.CreatedAt.IsZero() {
+		feedback.CreatedAt = time.Now()
+	}
+	_, err := r.db.ExecContext(ctx, `
+		INSERT OR REPLACE INTO reply_feedback (bot_id, msg_id, operator_id, chat_id, rating, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, r.botID, feedback.MsgID, feedback.OperatorID, feedback.ChatID, string(feedback.Rating), feedback.CreatedAt.Unix())
+	if err != nil {
+		return fmt.Errorf("failed to save feedback: %w", err)
+	}
+	return nil
+}
+
+func (r *feedbackRepo) ListByChat(ctx context.Context, chatID string, limit int) ([]*domain.Feedback, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT msg_id, operator_id, chat_id, rating, created_at
+		FROM reply_feedback WHERE bot_id = ? AND chat_id = ?
+		ORDER BY created_at DESC LIMIT ?
+	`, r.botID, chatID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list feedback: %w", err)
+	}
+	defer rows.Close()
+
+	var result []*domain.Feedback
+	for rows.Next() {
+		var feedback domain.Feedback
+		var rating string
+		var createdAt int64
+		if err := rows.Scan(&feedback.MsgID, &feedback.OperatorID, &feedback.ChatID, &rating, &createdAt); err != nil {
+			return nil, fmt.Errorf("failed to scan feedback: %w", err)
+		}
+		feedback.Rating = domain.FeedbackRating(rating)
+		feedback.CreatedAt = time.Unix(createdAt, 0)
+		result = append(result, &feedback)
+	}
+	return result, rows.Err()
+}
+
+func (r *feedbackRepo) Close() error {
+	return r.db.Close()
+}
@@ -0,0 +1,47 @@
+package data
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/anthropics/feishu-codex-bridge/internal/biz/domain"
+)
+
+func TestFeedbackRepo_RatingAgainReplaces(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "feedback.db")
+	r, err := NewFeedbackRepo(dbPath, "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	other, err := NewFeedbackRepo(dbPath, "other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+	ctx := context.Background()
+
+	for _, f := range []*domain.Feedback{
+		{MsgID: "om_1", ChatID: "oc_1", OperatorID: "ou_alice", Rating: domain.FeedbackUp},
+		{MsgID: "om_1", ChatID: "oc_1", OperatorID: "ou_bob", Rating: domain.FeedbackUp},
+		{MsgID: "om_1", ChatID: "oc_1", OperatorID: "ou_alice", Rating: domain.FeedbackDown},
+	} {
+		if err := r.Save(ctx, f); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	list, err := r.ListByChat(ctx, "oc_1", 10)
+	if err != nil || len(list) != 2 {
+		t.Fatalf("Expected 2 ratings, got %d, %v", len(list), err)
+	}
+	for _, f := range list {
+		if f.OperatorID == "ou_alice" && f.Rating != domain.FeedbackDown {
+			t.Errorf("Expected Alice's second rating to replace the first, got %s", f.Rating)
+		}
+	}
+	if list, _ := other.ListByChat(ctx, "oc_1", 10); len(list) != 0 {
+		t.Errorf("Expected other bots not to see the ratings, got %d", len(list))
+	}
+}
@@ -14,11 +14,19 @@ import (
 // feishuRepo implements the Feishu message repository
 type feishuRepo struct {
 	client *feishu.Client
+
+	// members resolves sender names of listed messages, the cached lookup when wrapped
+	members func(ctx context.Context, chatID string) ([]domain.Member, error)
+
+	// users resolves senders who are not chat members, nil to skip them
+	users repo.UserRepo
 }
 
 // NewFeishuRepo creates a new Feishu repository
 func NewFeishuRepo(client *feishu.Client) repo.MessageRepo {
-	return &feishuRepo{client: client}
+	r := &feishuRepo{client: client}
+	r.members = r.GetChatMembers
+	return r
 }
 
 // GetChatHistory gets chat history
@@ -27,12 +35,25 @@ func (r *feishuRepo) GetChatHistory(ctx context.Context, chatID string, limit in
 	if err != nil {
 		return nil, err
 	}
+	return r.toMessages(ctx, chatID, msgs), nil
+}
+
+// GetThreadHistory gets the history of a topic thread
+func (r *feishuRepo) GetThreadHistory(ctx context.Context, chatID, threadID string, limit int) ([]domain.Message, error) {
+	msgs, err := r.client.GetThreadHistory(threadID, limit)
+	if err != nil {
+		return nil, err
+	}
+	return r.toMessages(ctx, chatID, msgs), nil
+}
 
+// toMessages converts listed messages, resolving sender names from the chat's members
+func (r *feishuRepo) toMessages(ctx context.Context, chatID string, msgs []*feishu.HistoryMessage) []domain.Message {
 	// Get member list for resolving sender names
-	members, _ := r.client.GetChatMembers(chatID)
+	members, _ := r.members(ctx, chatID)
 	memberMap := make(map[string]string)
 	for _, m := range members {
-		memberMap[m.MemberID] = m.Name
+		memberMap[m.UserID] = m.Name
 	}
 
 	var result []domain.Message
@@ -51,8 +72,15 @@ func (r *feishuRepo) GetChatHistory(ctx context.Context, chatID string, limit in
 		isBot := false
 		if m.Sender != nil {
 			senderID = m.Sender.SenderID
-			senderName = memberMap[senderID]
 			isBot = m.Sender.SenderType == "bot"
+			senderName = memberMap[senderID]
+			if senderName == "" && !isBot && r.users != nil {
+				// P2P chats and users who left the group
+				if profile, _ := r.users.GetUser(ctx, senderID); profile != nil {
+					senderName = profile.Name
+					memberMap[senderID] = profile.Name
+				}
+			}
 		}
 
 		// Message content already parsed in feishu.Client.GetChatHistory (including @ mention replacement)
@@ -67,9 +95,28 @@ func (r *feishuRepo) GetChatHistory(ctx context.Context, chatID string, limit in
 			MsgType:    m.MsgType,
 			CreateTime: createTime,
 			IsBot:      isBot,
+			ParentID:   m.ParentID,
+			RootID:     m.RootID,
 		})
 	}
-	return result, nil
+	return result
+}
+
+// senderName names a user seen in a chat, from the chat's members or else the
+// user directory. Empty when neither knows the user
+func (r *feishuRepo) senderName(ctx context.Context, chatID, openID string) string {
+	members, _ := r.members(ctx, chatID)
+	for _, m := range members {
+		if m.UserID == openID {
+			return m.Name
+		}
+	}
+	if r.users != nil {
+		if profile, _ := r.users.GetUser(ctx, openID); profile != nil {
+			return profile.Name
+		}
+	}
+	return ""
 }
 
 // GetChatMembers gets chat member list
@@ -91,14 +138,19 @@ func (r *feishuRepo) GetChatMembers(ctx context.Context, chatID string) ([]domai
 
 // GetChatInfo gets chat info
 func (r *feishuRepo) GetChatInfo(ctx context.Context, chatID string) (*repo.ChatInfo, error) {
-	// Simple implementation: determine type by chatID prefix
-	chatType := domain.ChatTypeP2P
-	if len(chatID) > 3 && chatID[:3] == "oc_" {
-		chatType = domain.ChatTypeGroup
+	info, err := r.client.GetChatInfo(chatID)
+	if err != nil {
+		return nil, err
 	}
 
+	// Chat mode is p2p, group or topic
+	chatType := domain.ChatTypeGroup
+	if info.ChatType == "p2p" {
+		chatType = domain.ChatTypeP2P
+	}
 	return &repo.ChatInfo{
 		ChatID:   chatID,
+		Name:     info.Name,
 		ChatType: chatType,
 	}, nil
 }
@@ -110,6 +162,65 @@ func (r *feishuRepo) SendText(ctx context.Context, chatID, text string) error {
 
 // SendTextWithMentions sends a text message with @ mentions
 func (r *feishuRepo) SendTextWithMentions(ctx context.Context, chatID, text string, mentions []domain.Member) error {
+	return r.client.SendTextWithMentions(chatID, text, toFeishuMentions(mentions))
+}
+
+// SendTextMentionAll sends a text message with @all
+func (r *feishuRepo) SendTextMentionAll(ctx context.Context, chatID, text string) error {
+	return r.client.SendTextMentionAll(chatID, text)
+}
+
+// SendMarkdown sends Markdown as one or more rich text messages
+func (r *feishuRepo) SendMarkdown(ctx context.Context, chatID, markdown string, mentions []domain.Member) error {
+	return r.client.SendMarkdown(chatID, markdown, toFeishuMentions(mentions))
+}
+
+// ReplyText replies to a message with text
+func (r *feishuRepo) ReplyText(ctx context.Context, to repo.ReplyTo, text string) error {
+	return r.client.ReplyText(to.MsgID, text, to.InTopic)
+}
+
+// ReplyMarkdown replies to a message with rich text
+func (r *feishuRepo) ReplyMarkdown(ctx context.Context, to repo.ReplyTo, markdown string, mentions []domain.Member) error {
+	return r.client.ReplyMarkdown(to.MsgID, markdown, toFeishuMentions(mentions), to.InTopic)
+}
+
+// ReplyCard replies to a message with an interactive card
+func (r *feishuRepo) ReplyCard(ctx context.Context, to repo.ReplyTo, card string) (string, error) {
+	return r.client.ReplyCard(to.MsgID, card, to.InTopic)
+}
+
+// SendCard sends an interactive card
+func (r *feishuRepo) SendCard(ctx context.Context, chatID, card string) (string, error) {
+	return r.client.SendCard(chatID, card)
+}
+
+// UpdateCard updates a sent card
+func (r *feishuRepo) UpdateCard(ctx context.Context, msgID, card string) error {
+	return r.client.UpdateCard(msgID, card)
+}
+
+// RecallReplies recalls the replies sent to a message
+func (r *feishuRepo) RecallReplies(ctx context.Context, msgID string) (int, error) {
+	return r.client.RecallReplies(msgID)
+}
+
+// SendImage uploads and sends an image
+func (r *feishuRepo) SendImage(ctx context.Context, chatID, path string) error {
+	return r.client.SendImage(chatID, path)
+}
+
+// SendFile uploads and sends a file
+func (r *feishuRepo) SendFile(ctx context.Context, chatID, path string) error {
+	return r.client.SendFile(chatID, path)
+}
+
+// AddReaction adds an emoji reaction
+func (r *feishuRepo) AddReaction(ctx context.Context, msgID, reactionType string) error {
+	return r.client.AddReaction(msgID, reactionType)
+}
+
+func toFeishuMentions(mentions []domain.Member) []feishu.Mention {
 	var feishuMentions []feishu.Mention
 	for _, m := range mentions {
 		feishuMentions = append(feishuMentions, feishu.Mention{
@@ -117,12 +228,7 @@ func (r *feishuRepo) SendTextWithMentions(ctx context.Context, chatID, text stri
 			UserName: m.Name,
 		})
 	}
-	return r.client.SendTextWithMentions(chatID, text, feishuMentions)
-}
-
-// AddReaction adds an emoji reaction
-func (r *feishuRepo) AddReaction(ctx context.Context, msgID, reactionType string) error {
-	return r.client.AddReaction(msgID, reactionType)
+	return feishuMentions
 }
 
 // parseMessageContent parses message content, extracting text from JSON
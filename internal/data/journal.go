@@ -0,0 +1,186 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/anthropics/feishu-codex-bridge/internal/biz/domain"
+	"github.com/anthropics/feishu-codex-bridge/internal/biz/repo"
+
+	_ "modernc.org/sqlite"
+)
+
+const createMessageJournalTable = `
+	CREATE TABLE IF NOT EXISTS message_journal (
+		bot_id TEXT NOT NULL DEFAULT 'default',
+		msg_id TEXT NOT NULL,
+		chat_id TEXT NOT NULL,
+		topic_id TEXT NOT NULL DEFAULT '',
+		state TEXT NOT NULL,
+		error TEXT NOT NULL DEFAULT '',
+		recovered INTEGER NOT NULL DEFAULT 0,
+		create_time INTEGER NOT NULL DEFAULT 0,
+		received_at INTEGER NOT NULL,
+		updated_at INTEGER NOT NULL,
+		PRIMARY KEY (bot_id, msg_id)
+	)
+`
+
+// journalRepo implements the inbound message journal repository
+type journalRepo struct {
+	db    *sql.DB
+	botID string
+}
+
+// NewJournalRepo creates a new message journal repository
+// Only messages received by botID are visible to it
+func NewJournalRepo(dbPath, botID string) (repo.JournalRepo, error) {
+	// Ensure directory exists
+	dir := filepath.Dir(dbPath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create db directory: %w", err)
+	}
+
+	db, err := sql.Open("sqlite", sqliteDSN(dbPath))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	if _, err := db.Exec(createMessageJournalTable); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create message_journal table: %w", err)
+	}
+	_, _ = db.Exec(`CREATE INDEX IF NOT EXISTS idx_journal_bot_state ON message_journal(bot_id, state, received_at)`)
+
+	fmt.Println("[Journal] Database initialized")
+	return &journalRepo{db: db, botID: botID}, nil
+}
+
+func (r *journalRepo) Receive(ctx context.Context, entry *domain.JournalEntry) (bool, error) {
+	now := time.Now()
+	if entry.ReceivedAt.IsZero() {
+		entry.ReceivedAt = now
+	}
+	if entry.State == "" {
+		entry.State = domain.JournalReceived
+	}
+	entry.UpdatedAt = now
+
+	result, err := r.db.ExecContext(ctx, `
+		INSERT OR IGNORE INTO message_journal (bot_id, msg_id, chat_id, topic_id, state, create_time, received_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, r.botID, entry.MsgID, entry.ChatID, entry.TopicID, string(entry.State),
+		entry.CreateTime.UnixMilli(), entry.ReceivedAt.Unix(), entry.UpdatedAt.Unix())
+	if err != nil {
+		return false, fmt.Errorf("failed to journal message: %w", err)
+	}
+	affected, _ := result.RowsAffected()
+	return affected > 0, nil
+}
+
+func (r *journalRepo) Get(ctx context.Context, msgID string) (*domain.JournalEntry, error) {
+	row := r.db.QueryRowContext(ctx, `
+		SELECT msg_id, chat_id, topic_id, state, error, recovered, create_time, received_at, updated_at
+		FROM message_journal WHERE bot_id = ? AND msg_id = ?
+	`, r.botID, msgID)
+	entry, err := scanJournalEntry(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get journal entry: %w", err)
+	}
+	return entry, nil
+}
+
+func (r *journalRepo) SetState(ctx context.Context, msgIDs []string, state domain.JournalState, errMsg string) error {
+	if len(msgIDs) == 0 {
+		return nil
+	}
+
+	// Build IN clause
+	placeholders := make([]string, len(msgIDs))
+	args := []interface{}{string(state), errMsg, time.Now().Unix(), r.botID}
+	for i, id := range msgIDs {
+		placeholders[i] = "?"
+		args = append(args, id)
+	}
+
+	query := fmt.Sprintf(`UPDATE message_journal SET state = ?, error = ?, updated_at = ? WHERE bot_id = ? AND msg_id IN (%s)`,
+		strings.Join(placeholders, ","))
+	if _, err := r.db.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to update journal state: %w", err)
+	}
+	return nil
+}
+
+func (r *journalRepo) ListUnfinished(ctx context.Context) ([]*domain.JournalEntry, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT msg_id, chat_id, topic_id, state, error, recovered, create_time, received_at, updated_at
+		FROM message_journal
+		WHERE bot_id = ? AND state NOT IN (?, ?)
+		ORDER BY create_time ASC, received_at ASC
+	`, r.botID, string(domain.JournalReplied), string(domain.JournalFailed))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list unfinished messages: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []*domain.JournalEntry
+	for rows.Next() {
+		entry, err := scanJournalEntry(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan journal entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+func (r *journalRepo) ClaimRecovery(ctx context.Context, msgID string) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE message_journal SET recovered = 1, updated_at = ?
+		WHERE bot_id = ? AND msg_id = ? AND recovered = 0
+	`, time.Now().Unix(), r.botID, msgID)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim message: %w", err)
+	}
+	affected, _ := result.RowsAffected()
+	return affected > 0, nil
+}
+
+func (r *journalRepo) Prune(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM message_journal WHERE bot_id = ? AND received_at < ?`,
+		r.botID, before.Unix())
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune journal: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+func (r *journalRepo) Close() error {
+	return r.db.Close()
+}
+
+// scanJournalEntry scans a row of message_journal
+func scanJournalEntry(row interface{ Scan(...interface{}) error }) (*domain.JournalEntry, error) {
+	var entry domain.JournalEntry
+	var state string
+	var recovered int
+	var createTime, receivedAt, updatedAt int64
+	if err := row.Scan(&entry.MsgID, &entry.ChatID, &entry.TopicID, &state, &entry.Error, &recovered,
+		&createTime, &receivedAt, &updatedAt); err != nil {
+		return nil, err
+	}
+	entry.State = domain.JournalState(state)
+	entry.Recovered = recovered == 1
+	entry.CreateTime = time.UnixMilli(createTime)
+	entry.ReceivedAt = time.Unix(receivedAt, 0)
+	entry.UpdatedAt = time.Unix(updatedAt, 0)
+	return &entry, nil
+}
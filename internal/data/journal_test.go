@@ -0,0 +1,93 @@
+package data
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/anthropics/feishu-codex-bridge/internal/biz/domain"
+)
+
+func TestJournalRepo_ReceiveOnceAndStates(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "journal.db")
+	r, err := NewJournalRepo(dbPath, "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	ctx := context.Background()
+
+	entry := &domain.JournalEntry{ChatID: "oc_1", MsgID: "om_1", CreateTime: time.UnixMilli(1700000000123)}
+	if fresh, err := r.Receive(ctx, entry); err != nil || !fresh {
+		t.Fatalf("Expected a fresh message, got %v, %v", fresh, err)
+	}
+	if fresh, _ := r.Receive(ctx, &domain.JournalEntry{ChatID: "oc_1", MsgID: "om_1"}); fresh {
+		t.Error("Expected a redelivered message to be a duplicate")
+	}
+	_, _ = r.Receive(ctx, &domain.JournalEntry{ChatID: "oc_1", MsgID: "om_2", CreateTime: time.UnixMilli(1700000001000)})
+	_, _ = r.Receive(ctx, &domain.JournalEntry{ChatID: "oc_1", MsgID: "om_3", CreateTime: time.UnixMilli(1700000002000)})
+
+	if err := r.SetState(ctx, []string{"om_1", "om_3"}, domain.JournalFailed, "stopped"); err != nil {
+		t.Fatal(err)
+	}
+	got, err := r.Get(ctx, "om_1")
+	if err != nil || got == nil || got.State != domain.JournalFailed || got.Error != "stopped" || got.CreateTime.UnixMilli() != 1700000000123 {
+		t.Fatalf("Unexpected entry: %+v, %v", got, err)
+	}
+	if got, _ := r.Get(ctx, "om_missing"); got != nil {
+		t.Errorf("Expected no entry, got %+v", got)
+	}
+
+	unfinished, err := r.ListUnfinished(ctx)
+	if err != nil || len(unfinished) != 1 || unfinished[0].MsgID != "om_2" || unfinished[0].State != domain.JournalReceived {
+		t.Fatalf("Unexpected unfinished messages: %+v, %v", unfinished, err)
+	}
+
+	// A message is resumed at most once
+	if claimed, _ := r.ClaimRecovery(ctx, "om_2"); !claimed {
+		t.Error("Expected the first claim to succeed")
+	}
+	if claimed, _ := r.ClaimRecovery(ctx, "om_2"); claimed {
+		t.Error("Expected the second claim to fail")
+	}
+	if got, _ := r.Get(ctx, "om_2"); !got.Recovered {
+		t.Error("Expected the entry to be marked recovered")
+	}
+}
+
+func TestJournalRepo_BotsAndPrune(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "journal.db")
+	a, err := NewJournalRepo(dbPath, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	b, err := NewJournalRepo(dbPath, "b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	ctx := context.Background()
+
+	// Both bots in a chat receive the same message
+	old := &domain.JournalEntry{ChatID: "oc_1", MsgID: "om_1", ReceivedAt: time.Now().Add(-48 * time.Hour)}
+	if fresh, _ := a.Receive(ctx, old); !fresh {
+		t.Fatal("Expected bot a to receive the message")
+	}
+	if fresh, _ := b.Receive(ctx, &domain.JournalEntry{ChatID: "oc_1", MsgID: "om_1"}); !fresh {
+		t.Fatal("Expected bot b to receive the message too")
+	}
+	_, _ = a.Receive(ctx, &domain.JournalEntry{ChatID: "oc_1", MsgID: "om_2"})
+
+	n, err := a.Prune(ctx, time.Now().Add(-24*time.Hour))
+	if err != nil || n != 1 {
+		t.Fatalf("Expected one pruned entry, got %d, %v", n, err)
+	}
+	if got, _ := a.Get(ctx, "om_1"); got != nil {
+		t.Error("Expected the old entry to be pruned")
+	}
+	if got, _ := b.Get(ctx, "om_1"); got == nil {
+		t.Error("Expected bot b's entry to be kept")
+	}
+}
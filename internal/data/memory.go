@@ -14,39 +14,93 @@ import (
 	_ "modernc.org/sqlite"
 )
 
+const (
+	createMemoriesTable = `
+		CREATE TABLE IF NOT EXISTS memories (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			bot_id TEXT NOT NULL DEFAULT 'default',
+			key TEXT NOT NULL,
+			content TEXT NOT NULL,
+			category TEXT DEFAULT 'note',
+			chat_id TEXT,
+			created_at INTEGER NOT NULL,
+			updated_at INTEGER NOT NULL,
+			UNIQUE (bot_id, key)
+		)
+	`
+
+	createScheduledTasksTable = `
+		CREATE TABLE IF NOT EXISTS scheduled_tasks (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			bot_id TEXT NOT NULL DEFAULT 'default',
+			name TEXT NOT NULL,
+			prompt TEXT NOT NULL,
+			schedule_type TEXT NOT NULL,
+			schedule_value TEXT NOT NULL,
+			chat_id TEXT NOT NULL,
+			enabled INTEGER DEFAULT 1,
+			next_run INTEGER,
+			last_run INTEGER,
+			last_status TEXT DEFAULT 'pending',
+			last_error TEXT,
+			created_at INTEGER NOT NULL,
+			updated_at INTEGER NOT NULL,
+			UNIQUE (bot_id, name)
+		)
+	`
+
+	createHeartbeatConfigsTable = `
+		CREATE TABLE IF NOT EXISTS heartbeat_configs (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			bot_id TEXT NOT NULL DEFAULT 'default',
+			chat_id TEXT NOT NULL,
+			interval_mins INTEGER NOT NULL DEFAULT 30,
+			template TEXT,
+			active_hours TEXT DEFAULT '00:00-23:59',
+			timezone TEXT DEFAULT 'Asia/Shanghai',
+			enabled INTEGER DEFAULT 1,
+			last_heartbeat INTEGER,
+			created_at INTEGER NOT NULL,
+			UNIQUE (bot_id, chat_id)
+		)
+	`
+)
+
 // memoryRepo implements the memory repository
 type memoryRepo struct {
-	db *sql.DB
+	db    *sql.DB
+	botID string
 }
 
 // NewMemoryRepo creates a new memory repository
-func NewMemoryRepo(dbPath string) (repo.MemoryRepo, error) {
+// Only records of botID are visible to it
+func NewMemoryRepo(dbPath, botID string) (repo.MemoryRepo, error) {
 	// Ensure directory exists
 	dir := filepath.Dir(dbPath)
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create db directory: %w", err)
 	}
 
-	db, err := sql.Open("sqlite", dbPath)
+	db, err := sql.Open("sqlite", sqliteDSN(dbPath))
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
-	// Create memories table
-	_, err = db.Exec(`
-		CREATE TABLE IF NOT EXISTS memories (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			key TEXT UNIQUE NOT NULL,
-			content TEXT NOT NULL,
-			category TEXT DEFAULT 'note',
-			chat_id TEXT,
-			created_at INTEGER NOT NULL,
-			updated_at INTEGER NOT NULL
-		)
-	`)
-	if err != nil {
-		db.Close()
-		return nil, fmt.Errorf("failed to create memories table: %w", err)
+	// Create tables, tagging records created before bots were tagged
+	// Row IDs are kept so the full-text index stays valid
+	for _, table := range []struct{ name, create string }{
+		{"memories", createMemoriesTable},
+		{"scheduled_tasks", createScheduledTasksTable},
+		{"heartbeat_configs", createHeartbeatConfigsTable},
+	} {
+		if _, err := db.Exec(table.create); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to create %s table: %w", table.name, err)
+		}
+		if err := rebuildTable(db, table.name, "bot_id", table.create); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to migrate %s table: %w", table.name, err)
+		}
 	}
 
 	// Create FTS virtual table for full-text search
@@ -82,54 +136,12 @@ func NewMemoryRepo(dbPath string) (repo.MemoryRepo, error) {
 		END
 	`)
 
-	// Create scheduled_tasks table
-	_, err = db.Exec(`
-		CREATE TABLE IF NOT EXISTS scheduled_tasks (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			name TEXT UNIQUE NOT NULL,
-			prompt TEXT NOT NULL,
-			schedule_type TEXT NOT NULL,
-			schedule_value TEXT NOT NULL,
-			chat_id TEXT NOT NULL,
-			enabled INTEGER DEFAULT 1,
-			next_run INTEGER,
-			last_run INTEGER,
-			last_status TEXT DEFAULT 'pending',
-			last_error TEXT,
-			created_at INTEGER NOT NULL,
-			updated_at INTEGER NOT NULL
-		)
-	`)
-	if err != nil {
-		db.Close()
-		return nil, fmt.Errorf("failed to create scheduled_tasks table: %w", err)
-	}
-
 	// Create indexes for scheduled_tasks
 	_, _ = db.Exec(`CREATE INDEX IF NOT EXISTS idx_tasks_next_run ON scheduled_tasks(next_run) WHERE enabled = 1`)
 	_, _ = db.Exec(`CREATE INDEX IF NOT EXISTS idx_tasks_enabled ON scheduled_tasks(enabled)`)
 
-	// Create heartbeat_configs table
-	_, err = db.Exec(`
-		CREATE TABLE IF NOT EXISTS heartbeat_configs (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			chat_id TEXT UNIQUE NOT NULL,
-			interval_mins INTEGER NOT NULL DEFAULT 30,
-			template TEXT,
-			active_hours TEXT DEFAULT '00:00-23:59',
-			timezone TEXT DEFAULT 'Asia/Shanghai',
-			enabled INTEGER DEFAULT 1,
-			last_heartbeat INTEGER,
-			created_at INTEGER NOT NULL
-		)
-	`)
-	if err != nil {
-		db.Close()
-		return nil, fmt.Errorf("failed to create heartbeat_configs table: %w", err)
-	}
-
 	fmt.Println("[Memory] Database initialized")
-	return &memoryRepo{db: db}, nil
+	return &memoryRepo{db: db, botID: botID}, nil
 }
 
 // ========== Memory Operations ==========
@@ -137,14 +149,14 @@ func NewMemoryRepo(dbPath string) (repo.MemoryRepo, error) {
 func (r *memoryRepo) SaveMemory(ctx context.Context, entry *domain.MemoryEntry) error {
 	now := time.Now().Unix()
 	_, err := r.db.ExecContext(ctx, `
-		INSERT INTO memories (key, content, category, chat_id, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?)
-		ON CONFLICT(key) DO UPDATE SET
+		INSERT INTO memories (bot_id, key, content, category, chat_id, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(bot_id, key) DO UPDATE SET
 			content = excluded.content,
 			category = excluded.category,
 			chat_id = excluded.chat_id,
 			updated_at = excluded.updated_at
-	`, entry.Key, entry.Content, entry.Category, entry.ChatID, now, now)
+	`, r.botID, entry.Key, entry.Content, entry.Category, entry.ChatID, now, now)
 	if err != nil {
 		return fmt.Errorf("failed to save memory: %w", err)
 	}
@@ -157,8 +169,8 @@ func (r *memoryRepo) GetMemory(ctx context.Context, key string) (*domain.MemoryE
 	var createdAt, updatedAt int64
 	err := r.db.QueryRowContext(ctx, `
 		SELECT id, key, content, category, chat_id, created_at, updated_at
-		FROM memories WHERE key = ?
-	`, key).Scan(&entry.ID, &entry.Key, &entry.Content, &entry.Category, &entry.ChatID, &createdAt, &updatedAt)
+		FROM memories WHERE bot_id = ? AND key = ?
+	`, r.botID, key).Scan(&entry.ID, &entry.Key, &entry.Content, &entry.Category, &entry.ChatID, &createdAt, &updatedAt)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -180,20 +192,20 @@ func (r *memoryRepo) SearchMemory(ctx context.Context, query string, limit int)
 		SELECT m.id, m.key, m.content, m.category, m.chat_id, m.created_at, m.updated_at
 		FROM memories m
 		JOIN memories_fts f ON m.id = f.rowid
-		WHERE memories_fts MATCH ?
+		WHERE memories_fts MATCH ? AND m.bot_id = ?
 		ORDER BY rank
 		LIMIT ?
-	`, query, limit)
+	`, query, r.botID, limit)
 
 	if err != nil {
 		// Fall back to LIKE search
 		rows, err = r.db.QueryContext(ctx, `
 			SELECT id, key, content, category, chat_id, created_at, updated_at
 			FROM memories
-			WHERE key LIKE ? OR content LIKE ?
+			WHERE bot_id = ? AND (key LIKE ? OR content LIKE ?)
 			ORDER BY updated_at DESC
 			LIMIT ?
-		`, "%"+query+"%", "%"+query+"%", limit)
+		`, r.botID, "%"+query+"%", "%"+query+"%", limit)
 		if err != nil {
 			return nil, fmt.Errorf("failed to search memories: %w", err)
 		}
@@ -213,17 +225,17 @@ func (r *memoryRepo) ListMemories(ctx context.Context, category string, limit in
 	if category != "" {
 		rows, err = r.db.QueryContext(ctx, `
 			SELECT id, key, content, category, chat_id, created_at, updated_at
-			FROM memories WHERE category = ?
+			FROM memories WHERE bot_id = ? AND category = ?
 			ORDER BY updated_at DESC
 			LIMIT ?
-		`, category, limit)
+		`, r.botID, category, limit)
 	} else {
 		rows, err = r.db.QueryContext(ctx, `
 			SELECT id, key, content, category, chat_id, created_at, updated_at
-			FROM memories
+			FROM memories WHERE bot_id = ?
 			ORDER BY updated_at DESC
 			LIMIT ?
-		`, limit)
+		`, r.botID, limit)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to list memories: %w", err)
@@ -234,7 +246,7 @@ func (r *memoryRepo) ListMemories(ctx context.Context, category string, limit in
 }
 
 func (r *memoryRepo) DeleteMemory(ctx context.Context, key string) error {
-	_, err := r.db.ExecContext(ctx, `DELETE FROM memories WHERE key = ?`, key)
+	_, err := r.db.ExecContext(ctx, `DELETE FROM memories WHERE bot_id = ? AND key = ?`, r.botID, key)
 	if err != nil {
 		return fmt.Errorf("failed to delete memory: %w", err)
 	}
@@ -266,9 +278,9 @@ func (r *memoryRepo) CreateTask(ctx context.Context, task *domain.ScheduledTask)
 		nextRun = task.NextRun.Unix()
 	}
 	_, err := r.db.ExecContext(ctx, `
-		INSERT INTO scheduled_tasks (name, prompt, schedule_type, schedule_value, chat_id, enabled, next_run, last_status, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, 'pending', ?, ?)
-		ON CONFLICT(name) DO UPDATE SET
+		INSERT INTO scheduled_tasks (bot_id, name, prompt, schedule_type, schedule_value, chat_id, enabled, next_run, last_status, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, 'pending', ?, ?)
+		ON CONFLICT(bot_id, name) DO UPDATE SET
 			prompt = excluded.prompt,
 			schedule_type = excluded.schedule_type,
 			schedule_value = excluded.schedule_value,
@@ -276,7 +288,7 @@ func (r *memoryRepo) CreateTask(ctx context.Context, task *domain.ScheduledTask)
 			enabled = excluded.enabled,
 			next_run = excluded.next_run,
 			updated_at = excluded.updated_at
-	`, task.Name, task.Prompt, task.ScheduleType, task.ScheduleValue, task.ChatID, task.Enabled, nextRun, now, now)
+	`, r.botID, task.Name, task.Prompt, task.ScheduleType, task.ScheduleValue, task.ChatID, task.Enabled, nextRun, now, now)
 	if err != nil {
 		return fmt.Errorf("failed to create task: %w", err)
 	}
@@ -291,8 +303,8 @@ func (r *memoryRepo) GetTask(ctx context.Context, id int64) (*domain.ScheduledTa
 	var lastError sql.NullString
 	err := r.db.QueryRowContext(ctx, `
 		SELECT id, name, prompt, schedule_type, schedule_value, chat_id, enabled, next_run, last_run, last_status, last_error, created_at, updated_at
-		FROM scheduled_tasks WHERE id = ?
-	`, id).Scan(&task.ID, &task.Name, &task.Prompt, &task.ScheduleType, &task.ScheduleValue, &task.ChatID, &task.Enabled, &nextRun, &lastRun, &task.LastStatus, &lastError, &createdAt, &updatedAt)
+		FROM scheduled_tasks WHERE bot_id = ? AND id = ?
+	`, r.botID, id).Scan(&task.ID, &task.Name, &task.Prompt, &task.ScheduleType, &task.ScheduleValue, &task.ChatID, &task.Enabled, &nextRun, &lastRun, &task.LastStatus, &lastError, &createdAt, &updatedAt)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -320,8 +332,8 @@ func (r *memoryRepo) GetTaskByName(ctx context.Context, name string) (*domain.Sc
 	var lastError sql.NullString
 	err := r.db.QueryRowContext(ctx, `
 		SELECT id, name, prompt, schedule_type, schedule_value, chat_id, enabled, next_run, last_run, last_status, last_error, created_at, updated_at
-		FROM scheduled_tasks WHERE name = ?
-	`, name).Scan(&task.ID, &task.Name, &task.Prompt, &task.ScheduleType, &task.ScheduleValue, &task.ChatID, &task.Enabled, &nextRun, &lastRun, &task.LastStatus, &lastError, &createdAt, &updatedAt)
+		FROM scheduled_tasks WHERE bot_id = ? AND name = ?
+	`, r.botID, name).Scan(&task.ID, &task.Name, &task.Prompt, &task.ScheduleType, &task.ScheduleValue, &task.ChatID, &task.Enabled, &nextRun, &lastRun, &task.LastStatus, &lastError, &createdAt, &updatedAt)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -348,15 +360,15 @@ func (r *memoryRepo) ListTasks(ctx context.Context, enabledOnly bool) ([]*domain
 	if enabledOnly {
 		rows, err = r.db.QueryContext(ctx, `
 			SELECT id, name, prompt, schedule_type, schedule_value, chat_id, enabled, next_run, last_run, last_status, last_error, created_at, updated_at
-			FROM scheduled_tasks WHERE enabled = 1
+			FROM scheduled_tasks WHERE bot_id = ? AND enabled = 1
 			ORDER BY next_run ASC
-		`)
+		`, r.botID)
 	} else {
 		rows, err = r.db.QueryContext(ctx, `
 			SELECT id, name, prompt, schedule_type, schedule_value, chat_id, enabled, next_run, last_run, last_status, last_error, created_at, updated_at
-			FROM scheduled_tasks
+			FROM scheduled_tasks WHERE bot_id = ?
 			ORDER BY created_at DESC
-		`)
+		`, r.botID)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to list tasks: %w", err)
@@ -370,9 +382,9 @@ func (r *memoryRepo) GetDueTasks(ctx context.Context, now time.Time) ([]*domain.
 	rows, err := r.db.QueryContext(ctx, `
 		SELECT id, name, prompt, schedule_type, schedule_value, chat_id, enabled, next_run, last_run, last_status, last_error, created_at, updated_at
 		FROM scheduled_tasks
-		WHERE enabled = 1 AND next_run <= ?
+		WHERE bot_id = ? AND enabled = 1 AND next_run <= ?
 		ORDER BY next_run ASC
-	`, now.Unix())
+	`, r.botID, now.Unix())
 	if err != nil {
 		return nil, fmt.Errorf("failed to get due tasks: %w", err)
 	}
@@ -390,8 +402,8 @@ func (r *memoryRepo) UpdateTaskAfterRun(ctx context.Context, id int64, nextRun t
 	_, err := r.db.ExecContext(ctx, `
 		UPDATE scheduled_tasks
 		SET last_run = ?, next_run = ?, last_status = ?, last_error = ?, updated_at = ?
-		WHERE id = ?
-	`, now, nextRunVal, status, errorMsg, now, id)
+		WHERE bot_id = ? AND id = ?
+	`, now, nextRunVal, status, errorMsg, now, r.botID, id)
 	if err != nil {
 		return fmt.Errorf("failed to update task after run: %w", err)
 	}
@@ -401,8 +413,8 @@ func (r *memoryRepo) UpdateTaskAfterRun(ctx context.Context, id int64, nextRun t
 func (r *memoryRepo) EnableTask(ctx context.Context, id int64, enabled bool) error {
 	now := time.Now().Unix()
 	_, err := r.db.ExecContext(ctx, `
-		UPDATE scheduled_tasks SET enabled = ?, updated_at = ? WHERE id = ?
-	`, enabled, now, id)
+		UPDATE scheduled_tasks SET enabled = ?, updated_at = ? WHERE bot_id = ? AND id = ?
+	`, enabled, now, r.botID, id)
 	if err != nil {
 		return fmt.Errorf("failed to enable/disable task: %w", err)
 	}
@@ -410,7 +422,7 @@ func (r *memoryRepo) EnableTask(ctx context.Context, id int64, enabled bool) err
 }
 
 func (r *memoryRepo) DeleteTask(ctx context.Context, id int64) error {
-	_, err := r.db.ExecContext(ctx, `DELETE FROM scheduled_tasks WHERE id = ?`, id)
+	_, err := r.db.ExecContext(ctx, `DELETE FROM scheduled_tasks WHERE bot_id = ? AND id = ?`, r.botID, id)
 	if err != nil {
 		return fmt.Errorf("failed to delete task: %w", err)
 	}
@@ -448,15 +460,15 @@ func scanScheduledTasks(rows *sql.Rows) ([]*domain.ScheduledTask, error) {
 func (r *memoryRepo) SetHeartbeat(ctx context.Context, config *domain.HeartbeatConfig) error {
 	now := time.Now().Unix()
 	_, err := r.db.ExecContext(ctx, `
-		INSERT INTO heartbeat_configs (chat_id, interval_mins, template, active_hours, timezone, enabled, created_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?)
-		ON CONFLICT(chat_id) DO UPDATE SET
+		INSERT INTO heartbeat_configs (bot_id, chat_id, interval_mins, template, active_hours, timezone, enabled, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(bot_id, chat_id) DO UPDATE SET
 			interval_mins = excluded.interval_mins,
 			template = excluded.template,
 			active_hours = excluded.active_hours,
 			timezone = excluded.timezone,
 			enabled = excluded.enabled
-	`, config.ChatID, config.IntervalMins, config.Template, config.ActiveHours, config.Timezone, config.Enabled, now)
+	`, r.botID, config.ChatID, config.IntervalMins, config.Template, config.ActiveHours, config.Timezone, config.Enabled, now)
 	if err != nil {
 		return fmt.Errorf("failed to set heartbeat: %w", err)
 	}
@@ -471,8 +483,8 @@ func (r *memoryRepo) GetHeartbeat(ctx context.Context, chatID string) (*domain.H
 	var template sql.NullString
 	err := r.db.QueryRowContext(ctx, `
 		SELECT id, chat_id, interval_mins, template, active_hours, timezone, enabled, last_heartbeat, created_at
-		FROM heartbeat_configs WHERE chat_id = ?
-	`, chatID).Scan(&config.ID, &config.ChatID, &config.IntervalMins, &template, &config.ActiveHours, &config.Timezone, &config.Enabled, &lastHeartbeat, &createdAt)
+		FROM heartbeat_configs WHERE bot_id = ? AND chat_id = ?
+	`, r.botID, chatID).Scan(&config.ID, &config.ChatID, &config.IntervalMins, &template, &config.ActiveHours, &config.Timezone, &config.Enabled, &lastHeartbeat, &createdAt)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -495,13 +507,13 @@ func (r *memoryRepo) ListHeartbeats(ctx context.Context, enabledOnly bool) ([]*d
 	if enabledOnly {
 		rows, err = r.db.QueryContext(ctx, `
 			SELECT id, chat_id, interval_mins, template, active_hours, timezone, enabled, last_heartbeat, created_at
-			FROM heartbeat_configs WHERE enabled = 1
-		`)
+			FROM heartbeat_configs WHERE bot_id = ? AND enabled = 1
+		`, r.botID)
 	} else {
 		rows, err = r.db.QueryContext(ctx, `
 			SELECT id, chat_id, interval_mins, template, active_hours, timezone, enabled, last_heartbeat, created_at
-			FROM heartbeat_configs
-		`)
+			FROM heartbeat_configs WHERE bot_id = ?
+		`, r.botID)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to list heartbeats: %w", err)
@@ -531,8 +543,8 @@ func (r *memoryRepo) ListHeartbeats(ctx context.Context, enabledOnly bool) ([]*d
 
 func (r *memoryRepo) UpdateHeartbeatTime(ctx context.Context, chatID string, lastHeartbeat time.Time) error {
 	_, err := r.db.ExecContext(ctx, `
-		UPDATE heartbeat_configs SET last_heartbeat = ? WHERE chat_id = ?
-	`, lastHeartbeat.Unix(), chatID)
+		UPDATE heartbeat_configs SET last_heartbeat = ? WHERE bot_id = ? AND chat_id = ?
+	`, lastHeartbeat.Unix(), r.botID, chatID)
 	if err != nil {
 		return fmt.Errorf("failed to update heartbeat time: %w", err)
 	}
@@ -540,13 +552,30 @@ func (r *memoryRepo) UpdateHeartbeatTime(ctx context.Context, chatID string, las
 }
 
 func (r *memoryRepo) DeleteHeartbeat(ctx context.Context, chatID string) error {
-	_, err := r.db.ExecContext(ctx, `DELETE FROM heartbeat_configs WHERE chat_id = ?`, chatID)
+	_, err := r.db.ExecContext(ctx, `DELETE FROM heartbeat_configs WHERE bot_id = ? AND chat_id = ?`, r.botID, chatID)
 	if err != nil {
 		return fmt.Errorf("failed to delete heartbeat: %w", err)
 	}
 	return nil
 }
 
+// DeleteChat deletes the scheduled tasks and heartbeat of a chat
+func (r *memoryRepo) DeleteChat(ctx context.Context, chatID string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM scheduled_tasks WHERE bot_id = ? AND chat_id = ?`, r.botID, chatID); err != nil {
+		return fmt.Errorf("failed to delete tasks: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM heartbeat_configs WHERE bot_id = ? AND chat_id = ?`, r.botID, chatID); err != nil {
+		return fmt.Errorf("failed to delete heartbeat: %w", err)
+	}
+	return tx.Commit()
+}
+
 // Close closes the database connection
 func (r *memoryRepo) Close() error {
 	return r.db.Close()
@@ -0,0 +1,61 @@
+package data
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// rebuildTable recreates a table that lacks a column, copying its rows
+// SQLite cannot change keys or constraints in place. createSQL must create the
+// table under its own name; indexes and triggers of the old table are dropped
+// with it, so create them after calling this
+func rebuildTable(db *sql.DB, table, column, createSQL string) error {
+	var hasColumn int
+	err := db.QueryRow(`SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?`, table, column).Scan(&hasColumn)
+	if err != nil || hasColumn > 0 {
+		return err
+	}
+
+	// The new table has every old column, new ones take their defaults
+	rows, err := db.Query(`SELECT name FROM pragma_table_info(?)`, table)
+	if err != nil {
+		return err
+	}
+	var columns []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return err
+		}
+		columns = append(columns, name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	cols := strings.Join(columns, ", ")
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, stmt := range []string{
+		fmt.Sprintf(`ALTER TABLE %s RENAME TO %s_old`, table, table),
+		createSQL,
+		fmt.Sprintf(`INSERT INTO %s (%s) SELECT %s FROM %s_old`, table, cols, cols, table),
+		fmt.Sprintf(`DROP TABLE %s_old`, table),
+	} {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	fmt.Printf("[Data] Rebuilt table %s to add %s\n", table, column)
+	return nil
+}
@@ -0,0 +1,234 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/anthropics/feishu-codex-bridge/internal/biz/domain"
+	"github.com/anthropics/feishu-codex-bridge/internal/biz/repo"
+
+	_ "modernc.org/sqlite"
+)
+
+const createMessageQueueTable = `
+	CREATE TABLE IF NOT EXISTS message_queue (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		bot_id TEXT NOT NULL DEFAULT 'default',
+		chat_id TEXT NOT NULL,
+		msg_id TEXT NOT NULL,
+		content TEXT NOT NULL,
+		sender_id TEXT,
+		sender_name TEXT,
+		chat_type TEXT NOT NULL,
+		mentions_bot INTEGER DEFAULT 0,
+		image_paths TEXT,
+		msg_create_time INTEGER,
+		topic_id TEXT NOT NULL DEFAULT '',
+		parent TEXT NOT NULL DEFAULT '',
+		enqueued_at INTEGER NOT NULL,
+		UNIQUE (bot_id, msg_id)
+	)
+`
+
+// queueRepo implements the per-session message queue repository
+type queueRepo struct {
+	db    *sql.DB
+	botID string
+}
+
+// NewQueueRepo creates a new message queue repository
+// Only messages queued for botID are visible to it
+func NewQueueRepo(dbPath, botID string) (repo.QueueRepo, error) {
+	// Ensure directory exists
+	dir := filepath.Dir(dbPath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create db directory: %w", err)
+	}
+
+	db, err := sql.Open("sqlite", sqliteDSN(dbPath))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	if _, err := db.Exec(createMessageQueueTable); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create message_queue table: %w", err)
+	}
+
+	// Migration: add topic_id column if not exists
+	_, _ = db.Exec(`ALTER TABLE message_queue ADD COLUMN topic_id TEXT NOT NULL DEFAULT ''`)
+
+	// Migration: add parent column if not exists
+	_, _ = db.Exec(`ALTER TABLE message_queue ADD COLUMN parent TEXT NOT NULL DEFAULT ''`)
+
+	// Migration: tag messages with their bot, unique per bot
+	if err := rebuildTable(db, "message_queue", "bot_id", createMessageQueueTable); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to migrate message_queue table: %w", err)
+	}
+
+	_, _ = db.Exec(`DROP INDEX IF EXISTS idx_queue_chat`)
+	_, _ = db.Exec(`CREATE INDEX IF NOT EXISTS idx_queue_bot_chat ON message_queue(bot_id, chat_id, id)`)
+
+	fmt.Println("[Queue] Database initialized")
+	return &queueRepo{db: db, botID: botID}, nil
+}
+
+func (r *queueRepo) Enqueue(ctx context.Context, msg *domain.QueuedMessage) error {
+	imagePaths, _ := json.Marshal(msg.ImagePaths)
+	var parent []byte
+	if msg.Parent != nil {
+		parent, _ = json.Marshal(msg.Parent)
+	}
+	mentionsBot := 0
+	if msg.MentionsBot {
+		mentionsBot = 1
+	}
+	if msg.EnqueuedAt.IsZero() {
+		msg.EnqueuedAt = time.Now()
+	}
+
+	result, err := r.db.ExecContext(ctx, `
+		INSERT INTO message_queue (bot_id, chat_id, msg_id, content, sender_id, sender_name, chat_type, mentions_bot, image_paths, msg_create_time, topic_id, parent, enqueued_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, r.botID, msg.ChatID, msg.MsgID, msg.Content, msg.SenderID, msg.SenderName, string(msg.ChatType),
+		mentionsBot, string(imagePaths), msg.MsgCreateTime, msg.TopicID, string(parent), msg.EnqueuedAt.Unix())
+	if err != nil {
+		return fmt.Errorf("failed to enqueue message: %w", err)
+	}
+	msg.ID, _ = result.LastInsertId()
+	return nil
+}
+
+func (r *queueRepo) ListPending(ctx context.Context, key domain.SessionKey, limit int) ([]*domain.QueuedMessage, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, chat_id, msg_id, content, sender_id, sender_name, chat_type, mentions_bot, image_paths, msg_create_time, topic_id, parent, enqueued_at
+		FROM message_queue WHERE bot_id = ? AND chat_id = ? AND topic_id = ?
+		ORDER BY id ASC
+		LIMIT ?
+	`, r.botID, key.ChatID, key.TopicID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list queued messages: %w", err)
+	}
+	defer rows.Close()
+
+	var messages []*domain.QueuedMessage
+	for rows.Next() {
+		var msg domain.QueuedMessage
+		var chatType string
+		var senderID, senderName, imagePaths sql.NullString
+		var msgCreateTime sql.NullInt64
+		var mentionsBot int
+		var parent string
+		var enqueuedAt int64
+		if err := rows.Scan(&msg.ID, &msg.ChatID, &msg.MsgID, &msg.Content, &senderID, &senderName,
+			&chatType, &mentionsBot, &imagePaths, &msgCreateTime, &msg.TopicID, &parent, &enqueuedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan queued message: %w", err)
+		}
+		msg.SenderID = senderID.String
+		msg.SenderName = senderName.String
+		msg.ChatType = domain.ChatType(chatType)
+		msg.MentionsBot = mentionsBot == 1
+		if imagePaths.String != "" {
+			_ = json.Unmarshal([]byte(imagePaths.String), &msg.ImagePaths)
+		}
+		if parent != "" {
+			_ = json.Unmarshal([]byte(parent), &msg.Parent)
+		}
+		msg.MsgCreateTime = msgCreateTime.Int64
+		msg.EnqueuedAt = time.Unix(enqueuedAt, 0)
+		messages = append(messages, &msg)
+	}
+	return messages, rows.Err()
+}
+
+func (r *queueRepo) Count(ctx context.Context, key domain.SessionKey) (int, error) {
+	var count int
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM message_queue WHERE bot_id = ? AND chat_id = ? AND topic_id = ?`,
+		r.botID, key.ChatID, key.TopicID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count queued messages: %w", err)
+	}
+	return count, nil
+}
+
+func (r *queueRepo) Remove(ctx context.Context, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	// Build IN clause
+	placeholders := make([]string, len(ids))
+	args := make([]interface{}, len(ids)+1)
+	args[0] = r.botID
+	for i, id := range ids {
+		placeholders[i] = "?"
+		args[i+1] = id
+	}
+
+	query := fmt.Sprintf(`DELETE FROM message_queue WHERE bot_id = ? AND id IN (%s)`, strings.Join(placeholders, ","))
+	if _, err := r.db.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to remove queued messages: %w", err)
+	}
+	return nil
+}
+
+func (r *queueRepo) RemoveMessage(ctx context.Context, msgID string) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM message_queue WHERE bot_id = ? AND msg_id = ?`, r.botID, msgID)
+	if err != nil {
+		return false, fmt.Errorf("failed to remove queued message: %w", err)
+	}
+	affected, _ := result.RowsAffected()
+	return affected > 0, nil
+}
+
+func (r *queueRepo) UpdateMessage(ctx context.Context, msgID, content string, imagePaths []string) (bool, error) {
+	paths, _ := json.Marshal(imagePaths)
+	result, err := r.db.ExecContext(ctx, `UPDATE message_queue SET content = ?, image_paths = ? WHERE bot_id = ? AND msg_id = ?`,
+		content, string(paths), r.botID, msgID)
+	if err != nil {
+		return false, fmt.Errorf("failed to update queued message: %w", err)
+	}
+	affected, _ := result.RowsAffected()
+	return affected > 0, nil
+}
+
+func (r *queueRepo) RemoveChat(ctx context.Context, chatID string) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM message_queue WHERE bot_id = ? AND chat_id = ?`, r.botID, chatID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to remove queued messages: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+func (r *queueRepo) ListSessions(ctx context.Context) ([]domain.SessionKey, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT DISTINCT chat_id, topic_id FROM message_queue WHERE bot_id = ?`, r.botID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list queued sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []domain.SessionKey
+	for rows.Next() {
+		var key domain.SessionKey
+		if err := rows.Scan(&key.ChatID, &key.TopicID); err != nil {
+			return nil, fmt.Errorf("failed to scan session key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+func (r *queueRepo) Close() error {
+	return r.db.Close()
+}
@@ -14,40 +14,63 @@ import (
 	_ "modernc.org/sqlite"
 )
 
+const createSessionsTable = `
+	CREATE TABLE IF NOT EXISTS sessions (
+		bot_id TEXT NOT NULL DEFAULT 'default',
+		chat_id TEXT NOT NULL,
+		topic_id TEXT NOT NULL DEFAULT '',
+		thread_id TEXT NOT NULL,
+		created_at INTEGER NOT NULL,
+		updated_at INTEGER NOT NULL,
+		last_reply_at INTEGER NOT NULL DEFAULT 0,
+		last_msg_time INTEGER NOT NULL DEFAULT 0,
+		last_processed_msg_id TEXT NOT NULL DEFAULT '',
+		PRIMARY KEY (bot_id, chat_id, topic_id)
+	)
+`
+
 // sessionRepo implements the Session repository
 type sessionRepo struct {
-	db *sql.DB
+	db    *sql.DB
+	botID string
 }
 
 // NewSessionRepo creates a new Session repository
-func NewSessionRepo(dbPath string) (repo.SessionRepo, error) {
+// Only sessions of botID are visible to it
+func NewSessionRepo(dbPath, botID string) (repo.SessionRepo, error) {
 	// Ensure directory exists
 	dir := filepath.Dir(dbPath)
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create db directory: %w", err)
 	}
 
-	db, err := sql.Open("sqlite", dbPath)
+	db, err := sql.Open("sqlite", sqliteDSN(dbPath))
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
 	// Create table
-	_, err = db.Exec(`
-		CREATE TABLE IF NOT EXISTS sessions (
-			chat_id TEXT PRIMARY KEY,
-			thread_id TEXT NOT NULL,
-			created_at INTEGER NOT NULL,
-			updated_at INTEGER NOT NULL,
-			last_reply_at INTEGER NOT NULL DEFAULT 0,
-			last_msg_time INTEGER NOT NULL DEFAULT 0
-		)
-	`)
-	if err != nil {
+	if _, err := db.Exec(createSessionsTable); err != nil {
 		db.Close()
 		return nil, fmt.Errorf("failed to create table: %w", err)
 	}
 
+	// Add last_msg_time column (if not exists) - for database migration
+	_, _ = db.Exec(`ALTER TABLE sessions ADD COLUMN last_msg_time INTEGER NOT NULL DEFAULT 0`)
+
+	// Add last_processed_msg_id column (if not exists) - for reliable message recovery
+	_, _ = db.Exec(`ALTER TABLE sessions ADD COLUMN last_processed_msg_id TEXT NOT NULL DEFAULT ''`)
+
+	// Re-key sessions by bot, chat and topic (if created before topics or bots)
+	if err := rebuildTable(db, "sessions", "topic_id", createSessionsTable); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to migrate sessions: %w", err)
+	}
+	if err := rebuildTable(db, "sessions", "bot_id", createSessionsTable); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to migrate sessions: %w", err)
+	}
+
 	// Create index
 	_, err = db.Exec(`
 		CREATE INDEX IF NOT EXISTS idx_sessions_updated_at ON sessions(updated_at)
@@ -57,26 +80,20 @@ func NewSessionRepo(dbPath string) (repo.SessionRepo, error) {
 		return nil, fmt.Errorf("failed to create index: %w", err)
 	}
 
-	// Add last_msg_time column (if not exists) - for database migration
-	_, _ = db.Exec(`ALTER TABLE sessions ADD COLUMN last_msg_time INTEGER NOT NULL DEFAULT 0`)
-
-	// Add last_processed_msg_id column (if not exists) - for reliable message recovery
-	_, _ = db.Exec(`ALTER TABLE sessions ADD COLUMN last_processed_msg_id TEXT NOT NULL DEFAULT ''`)
-
-	return &sessionRepo{db: db}, nil
+	return &sessionRepo{db: db, botID: botID}, nil
 }
 
-// GetByChat gets session by ChatID
-func (r *sessionRepo) GetByChat(ctx context.Context, chatID string) (*domain.Session, error) {
+// Get gets session by key
+func (r *sessionRepo) Get(ctx context.Context, key domain.SessionKey) (*domain.Session, error) {
 	row := r.db.QueryRowContext(ctx, `
-		SELECT chat_id, thread_id, created_at, updated_at, last_reply_at, last_msg_time, last_processed_msg_id
+		SELECT chat_id, topic_id, thread_id, created_at, updated_at, last_reply_at, last_msg_time, last_processed_msg_id
 		FROM sessions
-		WHERE chat_id = ?
-	`, chatID)
+		WHERE bot_id = ? AND chat_id = ? AND topic_id = ?
+	`, r.botID, key.ChatID, key.TopicID)
 
 	var session domain.Session
 	var createdAt, updatedAt, lastReplyAt, lastMsgTime int64
-	err := row.Scan(&session.ChatID, &session.ThreadID, &createdAt, &updatedAt, &lastReplyAt, &lastMsgTime, &session.LastProcessedMsgID)
+	err := row.Scan(&session.ChatID, &session.TopicID, &session.ThreadID, &createdAt, &updatedAt, &lastReplyAt, &lastMsgTime, &session.LastProcessedMsgID)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -95,10 +112,12 @@ func (r *sessionRepo) GetByChat(ctx context.Context, chatID string) (*domain.Ses
 // Save saves a session
 func (r *sessionRepo) Save(ctx context.Context, session *domain.Session) error {
 	_, err := r.db.ExecContext(ctx, `
-		INSERT OR REPLACE INTO sessions (chat_id, thread_id, created_at, updated_at, last_reply_at, last_msg_time, last_processed_msg_id)
-		VALUES (?, ?, ?, ?, ?, ?, ?)
+		INSERT OR REPLACE INTO sessions (bot_id, chat_id, topic_id, thread_id, created_at, updated_at, last_reply_at, last_msg_time, last_processed_msg_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`,
+		r.botID,
 		session.ChatID,
+		session.TopicID,
 		session.ThreadID,
 		session.CreatedAt.Unix(),
 		session.UpdatedAt.Unix(),
@@ -113,19 +132,28 @@ func (r *sessionRepo) Save(ctx context.Context, session *domain.Session) error {
 }
 
 // Delete deletes a session
-func (r *sessionRepo) Delete(ctx context.Context, chatID string) error {
-	_, err := r.db.ExecContext(ctx, `DELETE FROM sessions WHERE chat_id = ?`, chatID)
+func (r *sessionRepo) Delete(ctx context.Context, key domain.SessionKey) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM sessions WHERE bot_id = ? AND chat_id = ? AND topic_id = ?`, r.botID, key.ChatID, key.TopicID)
 	if err != nil {
 		return fmt.Errorf("failed to delete session: %w", err)
 	}
 	return nil
 }
 
+// DeleteChat deletes the sessions of a chat and all its topics
+func (r *sessionRepo) DeleteChat(ctx context.Context, chatID string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM sessions WHERE bot_id = ? AND chat_id = ?`, r.botID, chatID)
+	if err != nil {
+		return fmt.Errorf("failed to delete chat sessions: %w", err)
+	}
+	return nil
+}
+
 // Touch updates session active time
-func (r *sessionRepo) Touch(ctx context.Context, chatID string) error {
+func (r *sessionRepo) Touch(ctx context.Context, key domain.SessionKey) error {
 	_, err := r.db.ExecContext(ctx, `
-		UPDATE sessions SET updated_at = ? WHERE chat_id = ?
-	`, time.Now().Unix(), chatID)
+		UPDATE sessions SET updated_at = ? WHERE bot_id = ? AND chat_id = ? AND topic_id = ?
+	`, time.Now().Unix(), r.botID, key.ChatID, key.TopicID)
 	if err != nil {
 		return fmt.Errorf("failed to touch session: %w", err)
 	}
@@ -133,11 +161,11 @@ func (r *sessionRepo) Touch(ctx context.Context, chatID string) error {
 }
 
 // MarkReplied marks session as replied
-func (r *sessionRepo) MarkReplied(ctx context.Context, chatID string) error {
+func (r *sessionRepo) MarkReplied(ctx context.Context, key domain.SessionKey) error {
 	now := time.Now().Unix()
 	_, err := r.db.ExecContext(ctx, `
-		UPDATE sessions SET updated_at = ?, last_reply_at = ? WHERE chat_id = ?
-	`, now, now, chatID)
+		UPDATE sessions SET updated_at = ?, last_reply_at = ? WHERE bot_id = ? AND chat_id = ? AND topic_id = ?
+	`, now, now, r.botID, key.ChatID, key.TopicID)
 	if err != nil {
 		return fmt.Errorf("failed to mark replied: %w", err)
 	}
@@ -145,11 +173,11 @@ func (r *sessionRepo) MarkReplied(ctx context.Context, chatID string) error {
 }
 
 // UpdateLastMsgTime updates the last processed message time
-func (r *sessionRepo) UpdateLastMsgTime(ctx context.Context, chatID string, msgTime time.Time) error {
+func (r *sessionRepo) UpdateLastMsgTime(ctx context.Context, key domain.SessionKey, msgTime time.Time) error {
 	now := time.Now().Unix()
 	_, err := r.db.ExecContext(ctx, `
-		UPDATE sessions SET updated_at = ?, last_msg_time = ? WHERE chat_id = ?
-	`, now, msgTime.Unix(), chatID)
+		UPDATE sessions SET updated_at = ?, last_msg_time = ? WHERE bot_id = ? AND chat_id = ? AND topic_id = ?
+	`, now, msgTime.Unix(), r.botID, key.ChatID, key.TopicID)
 	if err != nil {
 		return fmt.Errorf("failed to update last msg time: %w", err)
 	}
@@ -157,11 +185,11 @@ func (r *sessionRepo) UpdateLastMsgTime(ctx context.Context, chatID string, msgT
 }
 
 // UpdateLastProcessedMsg updates the last processed message ID and time
-func (r *sessionRepo) UpdateLastProcessedMsg(ctx context.Context, chatID string, msgID string, msgTime time.Time) error {
+func (r *sessionRepo) UpdateLastProcessedMsg(ctx context.Context, key domain.SessionKey, msgID string, msgTime time.Time) error {
 	now := time.Now().Unix()
 	_, err := r.db.ExecContext(ctx, `
-		UPDATE sessions SET updated_at = ?, last_msg_time = ?, last_processed_msg_id = ? WHERE chat_id = ?
-	`, now, msgTime.Unix(), msgID, chatID)
+		UPDATE sessions SET updated_at = ?, last_msg_time = ?, last_processed_msg_id = ? WHERE bot_id = ? AND chat_id = ? AND topic_id = ?
+	`, now, msgTime.Unix(), msgID, r.botID, key.ChatID, key.TopicID)
 	if err != nil {
 		return fmt.Errorf("failed to update last processed msg: %w", err)
 	}
@@ -171,8 +199,8 @@ func (r *sessionRepo) UpdateLastProcessedMsg(ctx context.Context, chatID string,
 // CleanupStale cleans up stale sessions
 func (r *sessionRepo) CleanupStale(ctx context.Context, before time.Time) (int64, error) {
 	result, err := r.db.ExecContext(ctx, `
-		DELETE FROM sessions WHERE updated_at < ?
-	`, before.Unix())
+		DELETE FROM sessions WHERE bot_id = ? AND updated_at < ?
+	`, r.botID, before.Unix())
 	if err != nil {
 		return 0, fmt.Errorf("failed to cleanup stale sessions: %w", err)
 	}
@@ -182,10 +210,11 @@ func (r *sessionRepo) CleanupStale(ctx context.Context, before time.Time) (int64
 // ListAll lists all sessions
 func (r *sessionRepo) ListAll(ctx context.Context) ([]*domain.Session, error) {
 	rows, err := r.db.QueryContext(ctx, `
-		SELECT chat_id, thread_id, created_at, updated_at, last_reply_at, last_msg_time, last_processed_msg_id
+		SELECT chat_id, topic_id, thread_id, created_at, updated_at, last_reply_at, last_msg_time, last_processed_msg_id
 		FROM sessions
+		WHERE bot_id = ?
 		ORDER BY updated_at DESC
-	`)
+	`, r.botID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list sessions: %w", err)
 	}
@@ -195,7 +224,7 @@ func (r *sessionRepo) ListAll(ctx context.Context) ([]*domain.Session, error) {
 	for rows.Next() {
 		var session domain.Session
 		var createdAt, updatedAt, lastReplyAt, lastMsgTime int64
-		if err := rows.Scan(&session.ChatID, &session.ThreadID, &createdAt, &updatedAt, &lastReplyAt, &lastMsgTime, &session.LastProcessedMsgID); err != nil {
+		if err := rows.Scan(&session.ChatID, &session.TopicID, &session.ThreadID, &createdAt, &updatedAt, &lastReplyAt, &lastMsgTime, &session.LastProcessedMsgID); err != nil {
 			return nil, fmt.Errorf("failed to scan session: %w", err)
 		}
 		session.CreatedAt = time.Unix(createdAt, 0)
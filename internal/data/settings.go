@@ -0,0 +1,108 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/anthropics/feishu-codex-bridge/internal/biz/domain"
+	"github.com/anthropics/feishu-codex-bridge/internal/biz/repo"
+
+	_ "modernc.org/sqlite"
+)
+
+const createChatSettingsTable = `
+	CREATE TABLE IF NOT EXISTS chat_settings (
+		bot_id TEXT NOT NULL DEFAULT 'default',
+		chat_id TEXT NOT NULL,
+		stream_replies INTEGER DEFAULT 0,
+		reply_mode TEXT NOT NULL DEFAULT '',
+		model TEXT NOT NULL DEFAULT '',
+		updated_at INTEGER NOT NULL,
+		PRIMARY KEY (bot_id, chat_id)
+	)
+`
+
+// settingsRepo implements the per-chat settings repository
+type settingsRepo struct {
+	db    *sql.DB
+	botID string
+}
+
+// NewSettingsRepo creates a new settings repository
+// Only settings of botID are visible to it
+func NewSettingsRepo(dbPath, botID string) (repo.SettingsRepo, error) {
+	// Ensure directory exists
+	dir := filepath.Dir(dbPath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create db directory: %w", err)
+	}
+
+	db, err := sql.Open("sqlite", sqliteDSN(dbPath))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	if _, err := db.Exec(createChatSettingsTable); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create chat_settings table: %w", err)
+	}
+
+	// Migration: add reply_mode column if not exists
+	_, _ = db.Exec(`ALTER TABLE chat_settings ADD COLUMN reply_mode TEXT NOT NULL DEFAULT ''`)
+
+	// Migration: add model column if not exists
+	_, _ = db.Exec(`ALTER TABLE chat_settings ADD COLUMN model TEXT NOT NULL DEFAULT ''`)
+
+	// Migration: key settings by bot and chat
+	if err := rebuildTable(db, "chat_settings", "bot_id", createChatSettingsTable); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to migrate chat_settings table: %w", err)
+	}
+
+	fmt.Println("[Settings] Database initialized")
+	return &settingsRepo{db: db, botID: botID}, nil
+}
+
+func (r *settingsRepo) Get(ctx context.Context, chatID string) (*domain.ChatSettings, error) {
+	var settings domain.ChatSettings
+	var replyMode string
+	var updatedAt int64
+	err := r.db.QueryRowContext(ctx, `
+		SELECT chat_id, stream_replies, reply_mode, model, updated_at
+		FROM chat_settings WHERE bot_id = ? AND chat_id = ?
+	`, r.botID, chatID).Scan(&settings.ChatID, &settings.StreamReplies, &replyMode, &settings.Model, &updatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chat settings: %w", err)
+	}
+	settings.ReplyMode = domain.ReplyMode(replyMode)
+	settings.UpdatedAt = time.Unix(updatedAt, 0)
+	return &settings, nil
+}
+
+func (r *settingsRepo) Save(ctx context.Context, settings *domain.ChatSettings) error {
+	settings.UpdatedAt = time.Now()
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO chat_settings (bot_id, chat_id, stream_replies, reply_mode, model, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(bot_id, chat_id) DO UPDATE SET
+			stream_replies = excluded.stream_replies,
+			reply_mode = excluded.reply_mode,
+			model = excluded.model,
+			updated_at = excluded.updated_at
+	`, r.botID, settings.ChatID, settings.StreamReplies, string(settings.ReplyMode), settings.Model, settings.UpdatedAt.Unix())
+	if err != nil {
+		return fmt.Errorf("failed to save chat settings: %w", err)
+	}
+	return nil
+}
+
+func (r *settingsRepo) Close() error {
+	return r.db.Close()
+}
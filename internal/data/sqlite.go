@@ -0,0 +1,13 @@
+package data
+
+import "fmt"
+
+// busyTimeoutMs is how long a write waits for another connection's lock
+const busyTimeoutMs = 5000
+
+// sqliteDSN returns the data source name for a database file
+// Every bot opens its own handles on the shared files, so writers wait for
+// each other's locks instead of failing with SQLITE_BUSY
+func sqliteDSN(dbPath string) string {
+	return fmt.Sprintf("%s?_pragma=busy_timeout(%d)", dbPath, busyTimeoutMs)
+}
@@ -0,0 +1,170 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/anthropics/feishu-codex-bridge/internal/biz/domain"
+	"github.com/anthropics/feishu-codex-bridge/internal/biz/repo"
+	"github.com/anthropics/feishu-codex-bridge/internal/infra/feishu"
+
+	_ "modernc.org/sqlite"
+)
+
+const createUserProfilesTable = `
+	CREATE TABLE IF NOT EXISTS user_profiles (
+		bot_id TEXT NOT NULL DEFAULT 'default',
+		user_id TEXT NOT NULL,
+		union_id TEXT NOT NULL DEFAULT '',
+		name TEXT NOT NULL DEFAULT '',
+		department TEXT NOT NULL DEFAULT '',
+		updated_at INTEGER NOT NULL,
+		PRIMARY KEY (bot_id, user_id)
+	)
+`
+
+const (
+	// userProfileTTL is how long a stored profile is used before it is refreshed
+	userProfileTTL = 24 * time.Hour
+	// userFailureTTL is how long a user the contact API could not resolve is not retried
+	userFailureTTL = time.Hour
+)
+
+// userFetcher looks users up in the contact API
+type userFetcher interface {
+	GetUser(openID string) (*feishu.User, error)
+}
+
+// userRepo implements the user directory, backed by the contact API
+// Profiles are stored in SQLite so names survive restarts. A stale profile is
+// still returned when refreshing it fails
+type userRepo struct {
+	db      *sql.DB
+	botID   string
+	fetcher userFetcher
+	now     func() time.Time
+
+	mu     sync.Mutex
+	failed map[string]time.Time // open_id -> when resolving it last failed
+}
+
+// NewUserRepo creates a new user directory
+// open_ids are per app, so only profiles of botID are visible to it
+func NewUserRepo(dbPath, botID string, client *feishu.Client) (repo.UserRepo, error) {
+	return newUserRepo(dbPath, botID, client)
+}
+
+func newUserRepo(dbPath, botID string, fetcher userFetcher) (*userRepo, error) {
+	// Ensure directory exists
+	dir := filepath.Dir(dbPath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create db directory: %w", err)
+	}
+
+	db, err := sql.Open("sqlite", sqliteDSN(dbPath))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	if _, err := db.Exec(createUserProfilesTable); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create user_profiles table: %w", err)
+	}
+
+	fmt.Println("[Users] Database initialized")
+	return &userRepo{
+		db:      db,
+		botID:   botID,
+		fetcher: fetcher,
+		now:     time.Now,
+		failed:  make(map[string]time.Time),
+	}, nil
+}
+
+// GetUser returns the stored profile, refreshing it from the contact API when old
+func (r *userRepo) GetUser(ctx context.Context, userID string) (*domain.UserProfile, error) {
+	if userID == "" {
+		return nil, nil
+	}
+
+	stored, err := r.load(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if stored != nil && r.now().Sub(stored.UpdatedAt) < userProfileTTL {
+		return stored, nil
+	}
+
+	r.mu.Lock()
+	failedAt, failed := r.failed[userID]
+	r.mu.Unlock()
+	if failed && r.now().Sub(failedAt) < userFailureTTL {
+		return stored, nil
+	}
+
+	user, err := r.fetcher.GetUser(userID)
+	if err != nil {
+		fmt.Printf("[Users] Failed to resolve %s: %v\n", userID, err)
+		r.mu.Lock()
+		r.failed[userID] = r.now()
+		r.mu.Unlock()
+		return stored, nil
+	}
+
+	profile := &domain.UserProfile{
+		UserID:     userID,
+		UnionID:    user.UnionID,
+		Name:       user.Name,
+		Department: user.Department,
+		UpdatedAt:  r.now(),
+	}
+	if err := r.save(ctx, profile); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	delete(r.failed, userID)
+	r.mu.Unlock()
+	return profile, nil
+}
+
+func (r *userRepo) load(ctx context.Context, userID string) (*domain.UserProfile, error) {
+	profile := &domain.UserProfile{UserID: userID}
+	var updatedAt int64
+	err := r.db.QueryRowContext(ctx, `
+		SELECT union_id, name, department, updated_at
+		FROM user_profiles WHERE bot_id = ? AND user_id = ?
+	`, r.botID, userID).Scan(&profile.UnionID, &profile.Name, &profile.Department, &updatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user profile: %w", err)
+	}
+	profile.UpdatedAt = time.Unix(updatedAt, 0)
+	return profile, nil
+}
+
+func (r *userRepo) save(ctx context.Context, profile *domain.UserProfile) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO user_profiles (bot_id, user_id, union_id, name, department, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(bot_id, user_id) DO UPDATE SET
+			union_id = excluded.union_id,
+			name = excluded.name,
+			department = excluded.department,
+			updated_at = excluded.updated_at
+	`, r.botID, profile.UserID, profile.UnionID, profile.Name, profile.Department, profile.UpdatedAt.Unix())
+	if err != nil {
+		return fmt.Errorf("failed to save user profile: %w", err)
+	}
+	return nil
+}
+
+func (r *userRepo) Close() error {
+	return r.db.Close()
+}
@@ -0,0 +1,123 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/anthropics/feishu-codex-bridge/internal/biz/domain"
+	"github.com/anthropics/feishu-codex-bridge/internal/infra/feishu"
+)
+
+// fakeUserFetcher serves users from a map and counts lookups
+type fakeUserFetcher struct {
+	users map[string]*feishu.User
+	calls int
+}
+
+func (f *fakeUserFetcher) GetUser(openID string) (*feishu.User, error) {
+	f.calls++
+	if user, ok := f.users[openID]; ok {
+		return user, nil
+	}
+	return nil, errors.New("no permission")
+}
+
+func TestUserRepo_ResolvesAndPersists(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "users.db")
+	fetcher := &fakeUserFetcher{users: map[string]*feishu.User{
+		"ou_1": {OpenID: "ou_1", UnionID: "on_1", Name: "Alice", Department: "Platform"},
+	}}
+	r, err := newUserRepo(dbPath, "default", fetcher)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	profile, err := r.GetUser(ctx, "ou_1")
+	if err != nil || profile == nil || profile.Name != "Alice" || profile.UnionID != "on_1" || profile.Department != "Platform" {
+		t.Fatalf("Unexpected profile: %+v, %v", profile, err)
+	}
+	_, _ = r.GetUser(ctx, "ou_1")
+	if fetcher.calls != 1 {
+		t.Errorf("Expected a stored profile to be reused, got %d lookups", fetcher.calls)
+	}
+	r.Close()
+
+	// Profiles survive a restart, and are per bot
+	reopened, err := newUserRepo(dbPath, "default", fetcher)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	if profile, _ := reopened.GetUser(ctx, "ou_1"); profile == nil || profile.Name != "Alice" || fetcher.calls != 1 {
+		t.Errorf("Expected the stored profile after reopening, got %+v (%d lookups)", profile, fetcher.calls)
+	}
+	other, _ := newUserRepo(dbPath, "other", &fakeUserFetcher{})
+	defer other.Close()
+	if profile, _ := other.GetUser(ctx, "ou_1"); profile != nil {
+		t.Errorf("Expected another bot not to see the profile, got %+v", profile)
+	}
+}
+
+func TestUserRepo_Failures(t *testing.T) {
+	fetcher := &fakeUserFetcher{users: map[string]*feishu.User{
+		"ou_1": {OpenID: "ou_1", Name: "Alice"},
+	}}
+	r, err := newUserRepo(filepath.Join(t.TempDir(), "users.db"), "default", fetcher)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	now := time.Unix(1700000000, 0)
+	r.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	// Unresolvable users are not looked up again for a while
+	for i := 0; i < 2; i++ {
+		if profile, err := r.GetUser(ctx, "ou_external"); profile != nil || err != nil {
+			t.Fatalf("Expected no profile, got %+v, %v", profile, err)
+		}
+	}
+	if fetcher.calls != 1 {
+		t.Errorf("Expected one lookup of a failing user, got %d", fetcher.calls)
+	}
+
+	// An old profile is still used when refreshing it fails
+	_, _ = r.GetUser(ctx, "ou_1")
+	delete(fetcher.users, "ou_1")
+	now = now.Add(2 * userProfileTTL)
+	if profile, _ := r.GetUser(ctx, "ou_1"); profile == nil || profile.Name != "Alice" {
+		t.Errorf("Expected the stale profile, got %+v", profile)
+	}
+}
+
+func TestFeishuRepo_SenderName(t *testing.T) {
+	fetcher := &fakeUserFetcher{users: map[string]*feishu.User{
+		"ou_2": {OpenID: "ou_2", Name: "Bob"},
+	}}
+	users, err := newUserRepo(filepath.Join(t.TempDir(), "users.db"), "default", fetcher)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer users.Close()
+	r := &feishuRepo{
+		members: func(ctx context.Context, chatID string) ([]domain.Member, error) {
+			return []domain.Member{{UserID: "ou_1", Name: "Alice"}}, nil
+		},
+		users: users,
+	}
+	ctx := context.Background()
+
+	if name := r.senderName(ctx, "oc_1", "ou_1"); name != "Alice" || fetcher.calls != 0 {
+		t.Errorf("Expected the member name without a lookup, got %q (%d lookups)", name, fetcher.calls)
+	}
+	if name := r.senderName(ctx, "oc_1", "ou_2"); name != "Bob" {
+		t.Errorf("Expected the directory name of a non-member, got %q", name)
+	}
+	if name := r.senderName(ctx, "oc_1", "ou_3"); name != "" {
+		t.Errorf("Expected no name for an unknown user, got %q", name)
+	}
+}
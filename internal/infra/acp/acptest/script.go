@@ -0,0 +1,103 @@
+// Package acptest is a fake Codex app-server for offline tests and local development
+//
+// It speaks the app-server JSON-RPC protocol on stdin/stdout and plays scripted
+// turns: agent message deltas, thread items, approval requests and errors.
+// Build cmd/fake-codex, or re-exec a test binary that calls Main, and point
+// acp.Client at it with SetExecutable (CODEX_PATH for the bridge)
+package acptest
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/anthropics/feishu-codex-bridge/internal/infra/acp"
+)
+
+// EnvScript names the environment variable holding the path of the JSON script
+const EnvScript = "FAKE_CODEX_SCRIPT"
+
+// Script describes how the fake answers
+type Script struct {
+	UserAgent  string            `json:"userAgent,omitempty"`
+	Turns      []Turn            `json:"turns,omitempty"`      // First turn whose Match is in the prompt is played
+	Errors     map[string]string `json:"errors,omitempty"`     // Methods answered with an RPC error, method -> message
+	MCPServers []string          `json:"mcpServers,omitempty"` // Names listed by mcpServerStatus/list
+}
+
+// Turn is a scripted answer to a prompt
+type Turn struct {
+	Match string `json:"match,omitempty"` // Substring of the prompt, empty matches every prompt
+	Steps []Step `json:"steps"`
+}
+
+// Step is one thing the fake does during a turn, exactly one field is set
+type Step struct {
+	Delta    string          `json:"delta,omitempty"`    // Agent message delta, consecutive deltas form one message
+	Item     *acp.ThreadItem `json:"item,omitempty"`     // Item started and completed, e.g. a command execution
+	Approval *Approval       `json:"approval,omitempty"` // Approval request, the turn waits for the decision
+	Error    string          `json:"error,omitempty"`    // Error notification, the turn fails
+	SleepMS  int             `json:"sleepMs,omitempty"`  // Pause, interruptible
+	Exit     int             `json:"exit,omitempty"`     // Exit the process with this code, simulating a crash
+}
+
+// Approval is a command execution approval, or a file change approval when Files is set
+type Approval struct {
+	Command string   `json:"command,omitempty"`
+	Cwd     string   `json:"cwd,omitempty"`
+	Files   []string `json:"files,omitempty"`
+}
+
+// LoadScript reads a JSON script, an empty path gives the default script
+func LoadScript(path string) (*Script, error) {
+	if path == "" {
+		return &Script{}, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read script: %w", err)
+	}
+	var script Script
+	if err := json.Unmarshal(data, &script); err != nil {
+		return nil, fmt.Errorf("failed to parse script: %w", err)
+	}
+	return &script, nil
+}
+
+// steps returns the steps answering prompt
+// Without a matching turn the prompt's last line is echoed back
+func (s *Script) steps(prompt string) []Step {
+	for _, turn := range s.Turns {
+		if strings.Contains(prompt, turn.Match) {
+			return turn.Steps
+		}
+	}
+	lines := strings.Split(strings.TrimSpace(prompt), "\n")
+	return []Step{{Delta: "echo: "}, {Delta: lines[len(lines)-1]}}
+}
+
+// Main runs the fake as a codex executable and exits
+// "app-server" serves the protocol on stdin/stdout with the script named by
+// FAKE_CODEX_SCRIPT, "mcp" subcommands succeed without doing anything
+func Main() {
+	args := os.Args[1:]
+	if len(args) > 0 && args[0] == "mcp" {
+		os.Exit(0)
+	}
+	if len(args) == 0 || args[0] != "app-server" {
+		fmt.Fprintln(os.Stderr, "usage: fake-codex app-server [-c key=value]...")
+		os.Exit(2)
+	}
+
+	script, err := LoadScript(os.Getenv(EnvScript))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[FakeCodex] %v\n", err)
+		os.Exit(1)
+	}
+	if err := NewServer(script).Serve(os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "[FakeCodex] %v\n", err)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
@@ -0,0 +1,387 @@
+package acptest
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anthropics/feishu-codex-bridge/internal/infra/acp"
+)
+
+// JSON-RPC error codes
+const (
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+	codeServerError    = -32000
+)
+
+// Server is one fake app-server session
+type Server struct {
+	script *Script
+
+	writeMu sync.Mutex
+	out     *json.Encoder
+
+	mu        sync.Mutex
+	nextID    int
+	threads   map[string]*acp.Thread
+	running   map[string]context.CancelFunc       // Thread ID -> cancel of its running turn
+	approvals map[int64]chan acp.ApprovalResponse // Pending approval request ID -> decision
+	turns     sync.WaitGroup
+}
+
+// NewServer creates a server playing script
+func NewServer(script *Script) *Server {
+	if script == nil {
+		script = &Script{}
+	}
+	return &Server{
+		script:    script,
+		threads:   make(map[string]*acp.Thread),
+		running:   make(map[string]context.CancelFunc),
+		approvals: make(map[int64]chan acp.ApprovalResponse),
+	}
+}
+
+// Serve answers requests from in until it is closed, interrupting running turns at the end
+func (s *Server) Serve(in io.Reader, out io.Writer) error {
+	s.out = json.NewEncoder(out)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer func() {
+		cancel()
+		s.turns.Wait()
+	}()
+
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 1024*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var msg struct {
+			ID     int64           `json:"id"`
+			Method string          `json:"method"`
+			Params json.RawMessage `json:"params"`
+			Result json.RawMessage `json:"result"`
+		}
+		if err := json.Unmarshal(line, &msg); err != nil {
+			return fmt.Errorf("invalid message %q: %w", line, err)
+		}
+		switch {
+		case msg.Method == "":
+			s.resolveApproval(msg.ID, msg.Result)
+		case msg.ID == 0:
+			// Notifications such as initialized need no answer
+		default:
+			s.handle(ctx, msg.ID, msg.Method, msg.Params)
+		}
+	}
+	return scanner.Err()
+}
+
+// handle answers a client request
+func (s *Server) handle(ctx context.Context, id int64, method string, params json.RawMessage) {
+	if message, ok := s.script.Errors[method]; ok {
+		s.respondError(id, codeServerError, message)
+		return
+	}
+
+	switch method {
+	case "initialize":
+		userAgent := s.script.UserAgent
+		if userAgent == "" {
+			userAgent = "fake-codex/0.0.0"
+		}
+		s.respond(id, acp.InitializeResult{UserAgent: userAgent})
+
+	case "thread/start":
+		var p acp.ThreadStartParams
+		_ = json.Unmarshal(params, &p)
+		s.mu.Lock()
+		s.nextID++
+		thread := &acp.Thread{ID: fmt.Sprintf("thread-%d", s.nextID), CreatedAt: time.Now().Unix(), Cwd: p.Cwd}
+		s.threads[thread.ID] = thread
+		copied := *thread
+		s.mu.Unlock()
+		s.respond(id, acp.ThreadStartResult{Thread: copied})
+		s.notify(acp.MethodThreadStarted, acp.ThreadStartedParams{ThreadID: copied.ID, Thread: &copied})
+
+	case "thread/resume":
+		var p acp.ThreadResumeParams
+		_ = json.Unmarshal(params, &p)
+		s.mu.Lock()
+		thread, ok := s.threads[p.ThreadID]
+		var copied acp.Thread
+		if ok {
+			copied = *thread
+			copied.Turns = append([]acp.Turn(nil), thread.Turns...)
+		}
+		s.mu.Unlock()
+		if !ok {
+			s.respondError(id, codeInvalidParams, "thread not found: "+p.ThreadID)
+			return
+		}
+		s.respond(id, acp.ThreadResumeResult{Thread: copied})
+
+	case "turn/start":
+		var p acp.TurnStartParams
+		_ = json.Unmarshal(params, &p)
+		var prompt []string
+		for _, input := range p.Input {
+			if input.Type == "text" {
+				prompt = append(prompt, input.Text)
+			}
+		}
+		turn, turnCtx, err := s.startTurn(ctx, p.ThreadID)
+		if err != nil {
+			s.respondError(id, codeInvalidParams, err.Error())
+			return
+		}
+		s.respond(id, acp.TurnStartResult{Turn: &acp.Turn{ID: turn.ID, Status: "inProgress", Items: []acp.ThreadItem{}}})
+		s.turns.Add(1)
+		go func() {
+			defer s.turns.Done()
+			s.runTurn(turnCtx, p.ThreadID, turn.ID, s.script.steps(strings.Join(prompt, "\n")))
+		}()
+
+	case "turn/interrupt":
+		var p acp.TurnInterruptParams
+		_ = json.Unmarshal(params, &p)
+		s.mu.Lock()
+		if cancel, ok := s.running[p.ThreadID]; ok {
+			cancel()
+		}
+		s.mu.Unlock()
+		s.respond(id, struct{}{})
+
+	case "config/mcpServer/reload":
+		s.respond(id, struct{}{})
+
+	case "mcpServerStatus/list":
+		names := s.script.MCPServers
+		if names == nil {
+			names = []string{"feishu"}
+		}
+		servers := make([]map[string]interface{}, 0, len(names))
+		for _, name := range names {
+			servers = append(servers, map[string]interface{}{"name": name, "tools": map[string]interface{}{}})
+		}
+		s.respond(id, map[string]interface{}{"data": servers, "nextCursor": nil})
+
+	default:
+		s.respondError(id, codeMethodNotFound, "method not found: "+method)
+	}
+}
+
+// startTurn adds an in-progress turn to a thread, one turn runs per thread at a time
+func (s *Server) startTurn(ctx context.Context, threadID string) (*acp.Turn, context.Context, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	thread, ok := s.threads[threadID]
+	if !ok {
+		return nil, nil, fmt.Errorf("thread not found: %s", threadID)
+	}
+	if _, busy := s.running[threadID]; busy {
+		return nil, nil, fmt.Errorf("thread %s already has a turn in progress", threadID)
+	}
+	s.nextID++
+	turn := acp.Turn{ID: fmt.Sprintf("turn-%d", s.nextID), Status: "inProgress"}
+	thread.Turns = append(thread.Turns, turn)
+	thread.UpdatedAt = time.Now().Unix()
+	turnCtx, cancel := context.WithCancel(ctx)
+	s.running[threadID] = cancel
+	return &turn, turnCtx, nil
+}
+
+// runTurn plays the steps of a turn and completes it
+func (s *Server) runTurn(ctx context.Context, threadID, turnID string, steps []Step) {
+	s.notify(acp.MethodTurnStarted, acp.TurnStartedParams{ThreadID: threadID, Turn: &acp.Turn{ID: turnID, Status: "inProgress"}})
+
+	var items []acp.ThreadItem
+	var turnErr *acp.TurnError
+	complete := func(item acp.ThreadItem) {
+		items = append(items, item)
+		s.notify(acp.MethodItemCompleted, acp.ItemCompletedParams{ThreadID: threadID, TurnID: turnID, Item: &item})
+	}
+
+	// Consecutive deltas stream into one agent message
+	var message *acp.ThreadItem
+	flush := func() {
+		if message != nil {
+			complete(*message)
+			message = nil
+		}
+	}
+
+steps:
+	for _, step := range steps {
+		if ctx.Err() != nil {
+			break
+		}
+		switch {
+		case step.Delta != "":
+			if message == nil {
+				message = &acp.ThreadItem{Type: "agentMessage", ID: s.itemID()}
+				s.notify(acp.MethodItemStarted, acp.ItemStartedParams{ThreadID: threadID, TurnID: turnID, Item: message})
+			}
+			message.Text += step.Delta
+			s.notify(acp.MethodAgentMessageDelta, acp.AgentMessageDeltaParams{ThreadID: threadID, TurnID: turnID, ItemID: message.ID, Delta: step.Delta})
+
+		case step.Item != nil:
+			flush()
+			item := *step.Item
+			if item.ID == "" {
+				item.ID = s.itemID()
+			}
+			s.notify(acp.MethodItemStarted, acp.ItemStartedParams{ThreadID: threadID, TurnID: turnID, Item: &item})
+			complete(item)
+
+		case step.Approval != nil:
+			flush()
+			item, ok := s.approve(ctx, threadID, turnID, step.Approval)
+			if !ok {
+				break steps
+			}
+			complete(item)
+
+		case step.Error != "":
+			flush()
+			s.notify("error", map[string]interface{}{
+				"threadId":  threadID,
+				"turnId":    turnID,
+				"error":     map[string]string{"message": step.Error},
+				"willRetry": false,
+			})
+			turnErr = &acp.TurnError{Type: "error", Message: step.Error}
+			break steps
+
+		case step.SleepMS > 0:
+			select {
+			case <-time.After(time.Duration(step.SleepMS) * time.Millisecond):
+			case <-ctx.Done():
+			}
+
+		case step.Exit != 0:
+			os.Exit(step.Exit)
+		}
+	}
+	flush()
+
+	status := "completed"
+	if turnErr != nil {
+		status = "failed"
+	} else if ctx.Err() != nil {
+		status = "interrupted"
+	}
+
+	s.mu.Lock()
+	if cancel, ok := s.running[threadID]; ok {
+		cancel()
+		delete(s.running, threadID)
+	}
+	if thread, ok := s.threads[threadID]; ok {
+		for i := range thread.Turns {
+			if thread.Turns[i].ID == turnID {
+				thread.Turns[i] = acp.Turn{ID: turnID, Status: status, Items: items, Error: turnErr}
+			}
+		}
+		thread.UpdatedAt = time.Now().Unix()
+	}
+	s.mu.Unlock()
+
+	s.notify(acp.MethodTurnCompleted, acp.TurnCompletedParams{ThreadID: threadID, TurnID: turnID, Status: status})
+}
+
+// approve asks the client to approve a command or file change and returns the resulting item
+// The item fails when the request is declined; ok is false when the turn was interrupted
+func (s *Server) approve(ctx context.Context, threadID, turnID string, approval *Approval) (acp.ThreadItem, bool) {
+	itemID := s.itemID()
+	item := acp.ThreadItem{ID: itemID, Type: "commandExecution", Command: approval.Command}
+	method := acp.MethodCommandExecutionRequestApproval
+	var params interface{} = acp.CommandExecutionApprovalParams{
+		ThreadID: threadID, TurnID: turnID, ItemID: itemID, Command: acp.CommandLine(approval.Command), Cwd: approval.Cwd,
+	}
+	if len(approval.Files) > 0 {
+		var changes []acp.FileChange
+		for _, path := range approval.Files {
+			changes = append(changes, acp.FileChange{Path: path})
+		}
+		item = acp.ThreadItem{ID: itemID, Type: "fileChange", Changes: changes}
+		method = acp.MethodFileChangeRequestApproval
+		params = acp.FileChangeApprovalParams{ThreadID: threadID, TurnID: turnID, ItemID: itemID, Changes: changes}
+	}
+	s.notify(acp.MethodItemStarted, acp.ItemStartedParams{ThreadID: threadID, TurnID: turnID, Item: &item})
+
+	s.mu.Lock()
+	s.nextID++
+	requestID := int64(s.nextID)
+	decision := make(chan acp.ApprovalResponse, 1)
+	s.approvals[requestID] = decision
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.approvals, requestID)
+		s.mu.Unlock()
+	}()
+
+	s.write(acp.Notification{ID: requestID, Method: method, Params: mustMarshal(params)})
+	select {
+	case resp := <-decision:
+		item.Status = acp.StatusCompleted
+		if resp.Decision != "accept" && resp.Decision != "acceptForSession" {
+			item.Status = acp.StatusFailed
+		}
+		return item, true
+	case <-ctx.Done():
+		return item, false
+	}
+}
+
+// resolveApproval delivers the client's answer to an approval request
+func (s *Server) resolveApproval(id int64, result json.RawMessage) {
+	var resp acp.ApprovalResponse
+	_ = json.Unmarshal(result, &resp)
+	s.mu.Lock()
+	decision, ok := s.approvals[id]
+	s.mu.Unlock()
+	if ok {
+		decision <- resp
+	}
+}
+
+func (s *Server) itemID() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextID++
+	return fmt.Sprintf("item-%d", s.nextID)
+}
+
+func (s *Server) respond(id int64, result interface{}) {
+	s.write(acp.Response{ID: id, Result: mustMarshal(result)})
+}
+
+func (s *Server) respondError(id int64, code int, message string) {
+	s.write(acp.Response{ID: id, Error: &acp.RPCError{Code: code, Message: message}})
+}
+
+func (s *Server) notify(method string, params interface{}) {
+	s.write(acp.Notification{Method: method, Params: mustMarshal(params)})
+}
+
+func (s *Server) write(v interface{}) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	_ = s.out.Encode(v)
+}
+
+func mustMarshal(v interface{}) json.RawMessage {
+	data, _ := json.Marshal(v)
+	return data
+}
@@ -0,0 +1,212 @@
+package acptest
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anthropics/feishu-codex-bridge/internal/infra/acp"
+)
+
+// TestMain lets the test binary stand in for codex: acp.Client runs it as "app-server"
+func TestMain(m *testing.M) {
+	if len(os.Args) > 1 && (os.Args[1] == "app-server" || os.Args[1] == "mcp") {
+		Main()
+	}
+	os.Exit(m.Run())
+}
+
+// startFake starts a client talking to the fake playing script
+func startFake(t *testing.T, script *Script, setup func(*acp.Client)) *acp.Client {
+	t.Helper()
+	data, _ := json.Marshal(script)
+	path := filepath.Join(t.TempDir(), "script.json")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(EnvScript, path)
+
+	executable, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := acp.NewClient(t.TempDir(), "")
+	client.SetExecutable(executable)
+	if setup != nil {
+		setup(client)
+	}
+	if err := client.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	t.Cleanup(func() { client.Stop() })
+	return client
+}
+
+// collectTurn returns the events of a turn up to and including turn/completed
+func collectTurn(t *testing.T, client *acp.Client, turnID string) []acp.Event {
+	t.Helper()
+	var events []acp.Event
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case event := <-client.Events():
+			var params struct {
+				TurnID string `json:"turnId"`
+			}
+			_ = json.Unmarshal(event.Params, &params)
+			if params.TurnID != turnID {
+				continue
+			}
+			events = append(events, event)
+			if event.Method == acp.MethodTurnCompleted {
+				return events
+			}
+		case <-timeout:
+			t.Fatalf("Timed out waiting for turn %s, got %d events", turnID, len(events))
+		}
+	}
+}
+
+func completedStatus(t *testing.T, events []acp.Event) string {
+	t.Helper()
+	var params acp.TurnCompletedParams
+	_ = json.Unmarshal(events[len(events)-1].Params, &params)
+	return params.Status
+}
+
+func TestFake_ScriptedTurn(t *testing.T) {
+	client := startFake(t, &Script{Turns: []Turn{{
+		Match: "list files",
+		Steps: []Step{
+			{Delta: "Let me "},
+			{Delta: "look."},
+			{Item: &acp.ThreadItem{Type: "commandExecution", Command: "ls", Status: acp.StatusCompleted, Output: "a.go"}},
+			{Delta: "Found a.go"},
+		},
+	}}}, func(c *acp.Client) {
+		// The fake accepts mcp add/remove and answers the reload and status list
+		c.SetMCPServer("/usr/bin/feishu-mcp", nil)
+	})
+	ctx := context.Background()
+
+	threadID, err := client.ThreadStart(ctx, nil)
+	if err != nil {
+		t.Fatalf("ThreadStart failed: %v", err)
+	}
+	turnID, err := client.TurnStart(ctx, threadID, "please list files", nil)
+	if err != nil {
+		t.Fatalf("TurnStart failed: %v", err)
+	}
+
+	events := collectTurn(t, client, turnID)
+	var deltas strings.Builder
+	var items []string
+	for _, event := range events {
+		switch event.Method {
+		case acp.MethodAgentMessageDelta:
+			var params acp.AgentMessageDeltaParams
+			_ = json.Unmarshal(event.Params, &params)
+			deltas.WriteString(params.Delta)
+		case acp.MethodItemCompleted:
+			var params acp.ItemCompletedParams
+			_ = json.Unmarshal(event.Params, &params)
+			items = append(items, params.Item.Type)
+		}
+	}
+	if deltas.String() != "Let me look.Found a.go" {
+		t.Errorf("Unexpected deltas: %q", deltas.String())
+	}
+	if strings.Join(items, ",") != "agentMessage,commandExecution,agentMessage" {
+		t.Errorf("Unexpected items: %v", items)
+	}
+	if status := completedStatus(t, events); status != "completed" {
+		t.Errorf("Expected the turn to complete, got %s", status)
+	}
+
+	thread, err := client.ThreadResume(ctx, threadID)
+	if err != nil {
+		t.Fatalf("ThreadResume failed: %v", err)
+	}
+	if len(thread.Turns) != 1 || len(thread.Turns[0].Items) != 3 || thread.Turns[0].Items[2].Text != "Found a.go" {
+		t.Errorf("Unexpected resumed thread: %+v", thread)
+	}
+
+	// Prompts without a scripted turn are echoed
+	turnID, _ = client.TurnStart(ctx, threadID, "hello", nil)
+	events = collectTurn(t, client, turnID)
+	thread, _ = client.ThreadResume(ctx, threadID)
+	if len(thread.Turns) != 2 || thread.Turns[1].Items[0].Text != "echo: hello" {
+		t.Errorf("Expected the prompt to be echoed, got %+v", thread.Turns)
+	}
+}
+
+func TestFake_Approval(t *testing.T) {
+	requests := make(chan acp.ServerRequest, 1)
+	client := startFake(t, &Script{Turns: []Turn{{
+		Steps: []Step{{Approval: &Approval{Command: "rm -rf build"}}},
+	}}}, func(c *acp.Client) {
+		c.SetApprovalHandler(func(req acp.ServerRequest) { requests <- req })
+	})
+	ctx := context.Background()
+
+	threadID, _ := client.ThreadStart(ctx, nil)
+	turnID, err := client.TurnStart(ctx, threadID, "clean up", nil)
+	if err != nil {
+		t.Fatalf("TurnStart failed: %v", err)
+	}
+
+	req := <-requests
+	var params acp.CommandExecutionApprovalParams
+	_ = json.Unmarshal(req.Params, &params)
+	if req.Method != acp.MethodCommandExecutionRequestApproval || params.Command != "rm -rf build" || params.TurnID != turnID {
+		t.Fatalf("Unexpected approval request: %+v %+v", req, params)
+	}
+	if err := client.RespondToApproval(req.ID, "decline"); err != nil {
+		t.Fatal(err)
+	}
+
+	events := collectTurn(t, client, turnID)
+	var item acp.ItemCompletedParams
+	for _, event := range events {
+		if event.Method == acp.MethodItemCompleted {
+			_ = json.Unmarshal(event.Params, &item)
+		}
+	}
+	if item.Item == nil || item.Item.Status != acp.StatusFailed {
+		t.Errorf("Expected the declined command to fail, got %+v", item.Item)
+	}
+}
+
+func TestFake_InterruptAndErrors(t *testing.T) {
+	client := startFake(t, &Script{
+		Turns: []Turn{
+			{Match: "slow", Steps: []Step{{Delta: "working"}, {SleepMS: 10000}, {Delta: "never sent"}}},
+			{Match: "broken", Steps: []Step{{Error: "model overloaded"}}},
+		},
+		Errors: map[string]string{"thread/resume": "resume disabled"},
+	}, nil)
+	ctx := context.Background()
+	threadID, _ := client.ThreadStart(ctx, nil)
+
+	turnID, _ := client.TurnStart(ctx, threadID, "slow task", nil)
+	if err := client.TurnInterrupt(ctx, threadID); err != nil {
+		t.Fatalf("TurnInterrupt failed: %v", err)
+	}
+	if status := completedStatus(t, collectTurn(t, client, turnID)); status != "interrupted" {
+		t.Errorf("Expected the turn to be interrupted, got %s", status)
+	}
+
+	turnID, _ = client.TurnStart(ctx, threadID, "broken", nil)
+	events := collectTurn(t, client, turnID)
+	if events[len(events)-2].Method != "error" || completedStatus(t, events) != "failed" {
+		t.Errorf("Expected an error notification and a failed turn, got %+v", events)
+	}
+
+	if _, err := client.ThreadResume(ctx, threadID); err == nil || !strings.Contains(err.Error(), "resume disabled") {
+		t.Errorf("Expected the scripted RPC error, got %v", err)
+	}
+}
@@ -19,40 +19,94 @@ type Event struct {
 	Params json.RawMessage
 }
 
+// ServerRequest represents a request from the Codex server that expects a response
+// (e.g. command execution or file change approvals)
+type ServerRequest struct {
+	ID     int64
+	Method string
+	Params json.RawMessage
+}
+
+// ApprovalHandler is called for each server request that needs a decision.
+// The handler must eventually call RespondToApproval with the request ID.
+type ApprovalHandler func(req ServerRequest)
+
 // Client is the ACP client for communicating with Codex app-server
+// The app-server process is supervised: if it exits unexpectedly it is restarted
+// with exponential backoff and re-initialized.
 type Client struct {
-	cmd    *exec.Cmd
-	stdin  io.WriteCloser
-	stdout *bufio.Scanner
-	stderr io.ReadCloser
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	exited  chan struct{} // Closed when the current process has exited
+	writeMu sync.Mutex    // Serializes writes to stdin (and swaps on restart)
 
 	requestID int64
 	pending   map[int64]chan *Response
 	pendingMu sync.Mutex
 
-	events      chan Event
-	initialized bool
-	running     bool
-
-	workingDir    string
-	model         string
-	systemPrompt  string
-	mcpServerPath string            // Path to the MCP server binary
-	mcpEnvVars    map[string]string // Environment variables for MCP server
+	events          chan Event
+	approvalHandler ApprovalHandler
+
+	// Events wait in an unbounded queue so a slow consumer never stalls the read loop
+	eventMu     sync.Mutex
+	eventQueue  []Event
+	eventsEnded bool // Stopped, the channel closes once the queue drains
+	eventNotify chan struct{}
+
+	initialized atomic.Bool // Handshake done with the current process
+	running     atomic.Bool // Between Start and Stop, across restarts
+
+	// Supervisor state
+	stateMu        sync.RWMutex
+	restarting     bool
+	restarts       int
+	lastRestart    time.Time
+	lastExitErr    string
+	startedAt      time.Time
+	restartBackoff time.Duration // Initial restart delay, doubled on each failure
+	maxBackoff     time.Duration
+	supervisorDone chan struct{}
+
+	executable     string // Codex executable, "codex" from PATH by default
+	workingDir     string
+	model          string
+	approvalPolicy string
+	systemPrompt   string
+	mcpServerPath  string            // Path to the MCP server binary
+	mcpEnvVars     map[string]string // Environment variables for MCP server
 
 	ctx    context.Context
 	cancel context.CancelFunc
 	wg     sync.WaitGroup
 }
 
+// Status represents the supervisor view of the app-server process
+type Status struct {
+	Running     bool      `json:"running"`
+	Restarting  bool      `json:"restarting"`
+	Restarts    int       `json:"restarts"`
+	LastRestart time.Time `json:"last_restart,omitempty"`
+	LastExitErr string    `json:"last_exit_error,omitempty"`
+}
+
+// ErrRestarting is returned for requests made while the app-server is being restarted
+var ErrRestarting = fmt.Errorf("codex app-server is restarting")
+
 // NewClient creates a new ACP client
 func NewClient(workingDir, model string) *Client {
-	return &Client{
-		workingDir: workingDir,
-		model:      model,
-		pending:    make(map[int64]chan *Response),
-		events:     make(chan Event, 100),
-	}
+	c := &Client{
+		executable:     "codex",
+		workingDir:     workingDir,
+		model:          model,
+		ctx:            context.Background(), // Replaced by Start
+		pending:        make(map[int64]chan *Response),
+		events:         make(chan Event, 100),
+		eventNotify:    make(chan struct{}, 1),
+		restartBackoff: time.Second,
+		maxBackoff:     time.Minute,
+	}
+	go c.pumpEvents()
+	return c
 }
 
 // SetSystemPrompt sets a system prompt to prepend to the first message of each thread
@@ -60,13 +114,32 @@ func (c *Client) SetSystemPrompt(prompt string) {
 	c.systemPrompt = prompt
 }
 
+// SetExecutable sets the Codex executable, e.g. a fake app-server for tests
+// Must be called before Start
+func (c *Client) SetExecutable(path string) {
+	c.executable = path
+}
+
+// SetApprovalHandler sets the handler for server approval requests.
+// When no handler is set, all requests are auto-accepted.
+func (c *Client) SetApprovalHandler(handler ApprovalHandler) {
+	c.approvalHandler = handler
+}
+
+// SetApprovalPolicy sets the Codex approval_policy (e.g. "untrusted", "on-request", "never")
+// Empty keeps the Codex default. Must be called before Start.
+func (c *Client) SetApprovalPolicy(policy string) {
+	c.approvalPolicy = policy
+}
+
 // SetMCPServer configures the MCP server for Codex
 func (c *Client) SetMCPServer(path string, envVars map[string]string) {
 	c.mcpServerPath = path
 	c.mcpEnvVars = envVars
 }
 
-// Start spawns the Codex app-server process and initializes the connection
+// Start spawns the Codex app-server process, initializes the connection
+// and starts supervising the process
 func (c *Client) Start(ctx context.Context) error {
 	c.ctx, c.cancel = context.WithCancel(ctx)
 
@@ -78,51 +151,90 @@ func (c *Client) Start(ctx context.Context) error {
 		}
 	}
 
-	// Build command arguments
-	args := []string{"app-server"}
-	if c.model != "" {
-		args = append(args, "-c", fmt.Sprintf("model=\"%s\"", c.model))
+	c.running.Store(true)
+	if err := c.spawn(); err != nil {
+		c.Stop()
+		return err
 	}
-	// Enable full-auto mode for sandbox permissions
-	args = append(args, "-c", `sandbox_permissions=["disk-full-read-access","disk-full-write-access","network-full-access"]`)
 
-	fmt.Printf("[Codex] Starting: codex %v\n", args)
+	c.supervisorDone = make(chan struct{})
+	go c.supervise()
+
+	fmt.Println("[Codex] Initialized successfully")
+	return nil
+}
+
+// spawn starts a new app-server process and performs the initialize handshake
+func (c *Client) spawn() error {
+	args := c.buildArgs()
+	fmt.Printf("[Codex] Starting: %s %v\n", c.executable, args)
 
-	c.cmd = exec.CommandContext(c.ctx, "codex", args...)
-	c.cmd.Dir = c.workingDir
+	cmd := exec.CommandContext(c.ctx, c.executable, args...)
+	cmd.Dir = c.workingDir
 
-	var err error
-	c.stdin, err = c.cmd.StdinPipe()
+	stdin, err := cmd.StdinPipe()
 	if err != nil {
 		return fmt.Errorf("failed to create stdin pipe: %w", err)
 	}
 
-	stdout, err := c.cmd.StdoutPipe()
+	stdoutPipe, err := cmd.StdoutPipe()
 	if err != nil {
 		return fmt.Errorf("failed to create stdout pipe: %w", err)
 	}
-	c.stdout = bufio.NewScanner(stdout)
-	c.stdout.Buffer(make([]byte, 1024*1024), 1024*1024) // 1MB buffer for large responses
+	stdout := bufio.NewScanner(stdoutPipe)
+	stdout.Buffer(make([]byte, 1024*1024), 1024*1024) // 1MB buffer for large responses
 
-	c.stderr, err = c.cmd.StderrPipe()
+	stderr, err := cmd.StderrPipe()
 	if err != nil {
 		return fmt.Errorf("failed to create stderr pipe: %w", err)
 	}
 
-	if err := c.cmd.Start(); err != nil {
+	if err := cmd.Start(); err != nil {
 		return fmt.Errorf("failed to start codex: %w", err)
 	}
 
-	c.running = true
+	exited := make(chan struct{})
+	c.writeMu.Lock()
+	c.cmd = cmd
+	c.stdin = stdin
+	c.exited = exited
+	c.writeMu.Unlock()
 
-	// Start read loops
-	c.wg.Add(2)
-	go c.readLoop()
-	go c.readStderr()
+	c.stateMu.Lock()
+	c.startedAt = time.Now()
+	c.stateMu.Unlock()
+
+	// Start read loops; Wait must only be called after all reads have completed
+	var readers sync.WaitGroup
+	readers.Add(2)
+	c.wg.Add(1)
+	go func() {
+		defer readers.Done()
+		c.readLoop(stdout)
+	}()
+	go func() {
+		defer readers.Done()
+		c.readStderr(stderr)
+	}()
+	go func() {
+		defer c.wg.Done()
+		readers.Wait()
+		err := cmd.Wait()
+		c.stateMu.Lock()
+		if err != nil {
+			c.lastExitErr = err.Error()
+		} else {
+			c.lastExitErr = "exited"
+		}
+		c.stateMu.Unlock()
+		close(exited)
+		// Nothing will answer requests sent to this process anymore
+		c.failPending("codex app-server exited")
+	}()
 
 	// Initialize handshake
 	if err := c.initialize(); err != nil {
-		c.Stop()
+		cmd.Process.Kill()
 		return fmt.Errorf("failed to initialize: %w", err)
 	}
 
@@ -133,43 +245,194 @@ func (c *Client) Start(ctx context.Context) error {
 		}
 	}
 
-	fmt.Println("[Codex] Initialized successfully")
 	return nil
 }
 
+// buildArgs builds the app-server command arguments
+func (c *Client) buildArgs() []string {
+	args := []string{"app-server"}
+	if c.model != "" {
+		args = append(args, "-c", fmt.Sprintf("model=\"%s\"", c.model))
+	}
+	if c.approvalPolicy != "" {
+		args = append(args, "-c", fmt.Sprintf("approval_policy=\"%s\"", c.approvalPolicy))
+	}
+	// Enable full-auto mode for sandbox permissions
+	args = append(args, "-c", `sandbox_permissions=["disk-full-read-access","disk-full-write-access","network-full-access"]`)
+	return args
+}
+
+// supervise restarts the app-server whenever it exits unexpectedly
+func (c *Client) supervise() {
+	defer close(c.supervisorDone)
+
+	backoff := c.restartBackoff
+	for {
+		c.writeMu.Lock()
+		exited := c.exited
+		c.writeMu.Unlock()
+
+		select {
+		case <-exited:
+		case <-c.ctx.Done():
+			return
+		}
+		if !c.running.Load() || c.ctx.Err() != nil {
+			return
+		}
+
+		c.stateMu.Lock()
+		c.restarting = true
+		exitErr := c.lastExitErr
+		// A process that ran for a while resets the backoff, a crash loop keeps growing it
+		if time.Since(c.startedAt) > c.maxBackoff {
+			backoff = c.restartBackoff
+		}
+		c.stateMu.Unlock()
+		c.initialized.Store(false)
+
+		fmt.Printf("[Codex] app-server exited (%s), restarting in %s\n", exitErr, backoff)
+		c.emit(Event{Method: MethodCodexExited, Params: mustMarshal(CodexExitedParams{Error: exitErr})})
+
+		for attempt := 1; ; attempt++ {
+			select {
+			case <-time.After(backoff):
+			case <-c.ctx.Done():
+				return
+			}
+
+			err := c.spawn()
+			backoff *= 2
+			if backoff > c.maxBackoff {
+				backoff = c.maxBackoff
+			}
+			if err == nil {
+				break
+			}
+			fmt.Printf("[Codex] Restart attempt %d failed: %v (next in %s)\n", attempt, err, backoff)
+		}
+
+		c.stateMu.Lock()
+		c.restarting = false
+		c.restarts++
+		c.lastRestart = time.Now()
+		restarts := c.restarts
+		c.stateMu.Unlock()
+
+		fmt.Printf("[Codex] app-server restarted (restarts=%d)\n", restarts)
+		c.emit(Event{Method: MethodCodexRestarted, Params: mustMarshal(CodexRestartedParams{Restarts: restarts})})
+	}
+}
+
+// failPending fails all in-flight requests (their process is gone)
+func (c *Client) failPending(message string) {
+	c.pendingMu.Lock()
+	defer c.pendingMu.Unlock()
+	for id, ch := range c.pending {
+		ch <- &Response{ID: id, Error: &RPCError{Code: -32000, Message: message}}
+		delete(c.pending, id)
+	}
+}
+
+// emit queues an event for the events channel without blocking
+func (c *Client) emit(event Event) {
+	c.eventMu.Lock()
+	if c.eventsEnded {
+		c.eventMu.Unlock()
+		return
+	}
+	c.eventQueue = append(c.eventQueue, event)
+	c.eventMu.Unlock()
+	c.signalEvents()
+}
+
+// endEvents closes the events channel once the queued events are delivered
+func (c *Client) endEvents() {
+	c.eventMu.Lock()
+	c.eventsEnded = true
+	c.eventMu.Unlock()
+	c.signalEvents()
+}
+
+func (c *Client) signalEvents() {
+	select {
+	case c.eventNotify <- struct{}{}:
+	default:
+	}
+}
+
+// pumpEvents moves queued events to the events channel
+func (c *Client) pumpEvents() {
+	defer close(c.events)
+
+	for {
+		c.eventMu.Lock()
+		if len(c.eventQueue) == 0 {
+			ended := c.eventsEnded
+			c.eventMu.Unlock()
+			if ended {
+				return
+			}
+			<-c.eventNotify
+			continue
+		}
+		event := c.eventQueue[0]
+		c.eventQueue[0] = Event{}
+		c.eventQueue = c.eventQueue[1:]
+		c.eventMu.Unlock()
+
+		c.events <- event
+	}
+}
+
 // Stop gracefully shuts down the client
 func (c *Client) Stop() error {
-	if !c.running {
+	if !c.running.CompareAndSwap(true, false) {
 		return nil
 	}
 
-	c.running = false
-	c.cancel()
+	c.writeMu.Lock()
+	stdin, cmd, exited := c.stdin, c.cmd, c.exited
+	c.writeMu.Unlock()
 
 	// Close stdin to signal EOF
-	if c.stdin != nil {
-		c.stdin.Close()
+	if stdin != nil {
+		stdin.Close()
 	}
 
 	// Wait for process with timeout
-	done := make(chan error, 1)
-	go func() {
-		done <- c.cmd.Wait()
-	}()
-
-	select {
-	case <-done:
-	case <-time.After(5 * time.Second):
-		c.cmd.Process.Kill()
+	if exited != nil {
+		select {
+		case <-exited:
+		case <-time.After(5 * time.Second):
+			cmd.Process.Kill()
+		}
 	}
 
-	close(c.events)
+	c.cancel()
+	if c.supervisorDone != nil {
+		<-c.supervisorDone
+	}
 	c.wg.Wait()
+	c.endEvents()
 
 	fmt.Println("[Codex] Stopped")
 	return nil
 }
 
+// Status returns the supervisor status of the app-server
+func (c *Client) Status() Status {
+	c.stateMu.RLock()
+	defer c.stateMu.RUnlock()
+	return Status{
+		Running:     c.running.Load() && c.initialized.Load(),
+		Restarting:  c.restarting,
+		Restarts:    c.restarts,
+		LastRestart: c.lastRestart,
+		LastExitErr: c.lastExitErr,
+	}
+}
+
 // Events returns the channel for receiving server notifications
 func (c *Client) Events() <-chan Event {
 	return c.events
@@ -177,7 +440,7 @@ func (c *Client) Events() <-chan Event {
 
 // IsRunning returns true if the client is running
 func (c *Client) IsRunning() bool {
-	return c.running && c.initialized
+	return c.running.Load() && c.initialized.Load()
 }
 
 // ============ High-level API ============
@@ -207,8 +470,11 @@ func (c *Client) ThreadStart(ctx context.Context, params *ThreadStartParams) (st
 
 // ThreadResume resumes an existing thread
 func (c *Client) ThreadResume(ctx context.Context, threadID string) (*Thread, error) {
-	params := ThreadResumeParams{ThreadID: threadID}
+	return c.ThreadResumeWith(ctx, &ThreadResumeParams{ThreadID: threadID})
+}
 
+// ThreadResumeWith resumes an existing thread with overrides, e.g. its working directory
+func (c *Client) ThreadResumeWith(ctx context.Context, params *ThreadResumeParams) (*Thread, error) {
 	resp, err := c.sendRequest("thread/resume", params)
 	if err != nil {
 		return nil, err
@@ -357,7 +623,7 @@ func (c *Client) initialize() error {
 		},
 	}
 
-	resp, err := c.sendRequest("initialize", params)
+	resp, err := c.call("initialize", params)
 	if err != nil {
 		return err
 	}
@@ -372,14 +638,25 @@ func (c *Client) initialize() error {
 	// Send initialized notification
 	c.sendNotification("initialized", nil)
 
-	c.initialized = true
+	c.initialized.Store(true)
 	return nil
 }
 
 func (c *Client) sendRequest(method string, params interface{}) (*Response, error) {
-	if !c.running {
+	if !c.running.Load() {
 		return nil, fmt.Errorf("client not running")
 	}
+	c.stateMu.RLock()
+	restarting := c.restarting
+	c.stateMu.RUnlock()
+	if restarting {
+		return nil, ErrRestarting
+	}
+	return c.call(method, params)
+}
+
+// call sends a request without checking the client state (used during the handshake)
+func (c *Client) call(method string, params interface{}) (*Response, error) {
 
 	id := atomic.AddInt64(&c.requestID, 1)
 	req := Request{
@@ -436,16 +713,20 @@ func (c *Client) sendRaw(v interface{}) error {
 		return fmt.Errorf("failed to marshal: %w", err)
 	}
 
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.stdin == nil {
+		return fmt.Errorf("codex client not started")
+	}
+
 	line := append(data, '\n')
 	_, err = c.stdin.Write(line)
 	return err
 }
 
-func (c *Client) readLoop() {
-	defer c.wg.Done()
-
-	for c.stdout.Scan() {
-		line := c.stdout.Text()
+func (c *Client) readLoop(stdout *bufio.Scanner) {
+	for stdout.Scan() {
+		line := stdout.Text()
 		if line == "" {
 			continue
 		}
@@ -453,13 +734,21 @@ func (c *Client) readLoop() {
 		c.handleLine(line)
 	}
 
-	if err := c.stdout.Err(); err != nil && c.running {
+	if err := stdout.Err(); err != nil && c.running.Load() {
 		fmt.Printf("[Codex] Read error: %v\n", err)
 	}
 }
 
 func (c *Client) handleLine(line string) {
-	// Try to parse as Response (has "id" and "result" or "error")
+	// Messages with a method are notifications or server requests; check them first
+	// because server requests also carry an "id" and would otherwise look like responses
+	var notif Notification
+	if err := json.Unmarshal([]byte(line), &notif); err == nil && notif.Method != "" {
+		c.handleNotification(notif)
+		return
+	}
+
+	// Otherwise it's a Response (has "id" and "result" or "error")
 	var resp Response
 	if err := json.Unmarshal([]byte(line), &resp); err == nil && resp.ID != 0 {
 		c.pendingMu.Lock()
@@ -470,30 +759,29 @@ func (c *Client) handleLine(line string) {
 		c.pendingMu.Unlock()
 		return
 	}
+}
 
-	// Otherwise it's a Notification (may or may not have "id" for approval requests)
-	var notif Notification
-	if err := json.Unmarshal([]byte(line), &notif); err == nil && notif.Method != "" {
-		// Check if it's an approval request (has ID)
-		if notif.ID != 0 {
-			// Auto-approve all requests
-			c.RespondToApproval(notif.ID, "accept")
+// handleNotification handles a notification or a server request (has ID)
+func (c *Client) handleNotification(notif Notification) {
+	// Check if it's an approval request (has ID)
+	if notif.ID != 0 {
+		if c.approvalHandler != nil {
+			// Handler may block waiting for a human decision, don't stall the read loop
+			go c.approvalHandler(ServerRequest{ID: notif.ID, Method: notif.Method, Params: notif.Params})
 			return
 		}
-
-		// Regular notification - send to events channel
-		select {
-		case c.events <- Event{Method: notif.Method, Params: notif.Params}:
-		default:
-			fmt.Printf("[Codex] Event channel full, dropping: %s\n", notif.Method)
-		}
+		// No handler configured, auto-approve
+		c.RespondToApproval(notif.ID, "accept")
+		return
 	}
-}
 
-func (c *Client) readStderr() {
-	defer c.wg.Done()
+	// Regular notification - queue for the events channel
+	// Never drop: a lost delta or turn/completed corrupts the reply
+	c.emit(Event{Method: notif.Method, Params: notif.Params})
+}
 
-	scanner := bufio.NewScanner(c.stderr)
+func (c *Client) readStderr(stderr io.Reader) {
+	scanner := bufio.NewScanner(stderr)
 	for scanner.Scan() {
 		line := scanner.Text()
 		if line != "" {
@@ -520,14 +808,14 @@ func (c *Client) configureMCPServer() error {
 	// Add the command separator and the MCP server path
 	args = append(args, "--", c.mcpServerPath)
 
-	fmt.Printf("[Codex] Configuring MCP server: codex %v\n", args)
+	fmt.Printf("[Codex] Configuring MCP server: %s %v\n", c.executable, args)
 
 	// First remove any existing configuration
-	removeCmd := exec.Command("codex", "mcp", "remove", "feishu")
+	removeCmd := exec.Command(c.executable, "mcp", "remove", "feishu")
 	removeCmd.Run() // Ignore errors if it doesn't exist
 
 	// Add the MCP server
-	cmd := exec.Command("codex", args...)
+	cmd := exec.Command(c.executable, args...)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		return fmt.Errorf("failed to add MCP server: %w, output: %s", err, string(output))
@@ -542,7 +830,7 @@ func (c *Client) refreshMCPServers() error {
 	fmt.Println("[Codex] Refreshing MCP servers...")
 
 	// Send config/mcpServer/reload request
-	resp, err := c.sendRequest("config/mcpServer/reload", nil)
+	resp, err := c.call("config/mcpServer/reload", nil)
 	if err != nil {
 		return err
 	}
@@ -550,7 +838,7 @@ func (c *Client) refreshMCPServers() error {
 	fmt.Printf("[Codex] MCP servers refreshed: %s\n", string(resp.Result))
 
 	// Also list the MCP server status to verify
-	statusResp, err := c.sendRequest("mcpServerStatus/list", map[string]interface{}{})
+	statusResp, err := c.call("mcpServerStatus/list", map[string]interface{}{})
 	if err != nil {
 		fmt.Printf("[Codex] Warning: failed to list MCP server status: %v\n", err)
 	} else {
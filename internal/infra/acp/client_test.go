@@ -3,7 +3,12 @@ package acp
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
 	"testing"
+	"time"
 )
 
 func TestNewClient(t *testing.T) {
@@ -30,12 +35,12 @@ func TestIsRunning(t *testing.T) {
 		t.Error("new client should not be running")
 	}
 
-	client.running = true
+	client.running.Store(true)
 	if client.IsRunning() {
 		t.Error("should not be running without initialization")
 	}
 
-	client.initialized = true
+	client.initialized.Store(true)
 	if !client.IsRunning() {
 		t.Error("should be running after both flags set")
 	}
@@ -88,7 +93,7 @@ func TestSendRequestNotRunning(t *testing.T) {
 
 func TestHandleLineResponse(t *testing.T) {
 	client := NewClient("/home/test", "")
-	client.running = true
+	client.running.Store(true)
 
 	// Create a pending response channel
 	respChan := make(chan *Response, 1)
@@ -114,7 +119,7 @@ func TestHandleLineResponse(t *testing.T) {
 
 func TestHandleLineNotification(t *testing.T) {
 	client := NewClient("/home/test", "")
-	client.running = true
+	client.running.Store(true)
 
 	// Simulate receiving a notification
 	line := `{"method": "turn/completed", "params": {"threadId": "test"}}`
@@ -126,14 +131,14 @@ func TestHandleLineNotification(t *testing.T) {
 		if event.Method != "turn/completed" {
 			t.Errorf("Method mismatch: got %q", event.Method)
 		}
-	default:
+	case <-time.After(time.Second):
 		t.Error("Event not delivered")
 	}
 }
 
 func TestHandleLineApprovalRequest(t *testing.T) {
 	client := NewClient("/home/test", "")
-	client.running = true
+	client.running.Store(true)
 
 	// We can't fully test auto-approval without a running stdin,
 	// but we can test that approval requests with ID are recognized
@@ -152,9 +157,41 @@ func TestHandleLineApprovalRequest(t *testing.T) {
 	}
 }
 
+func TestHandleLineApprovalRequestWithHandler(t *testing.T) {
+	client := NewClient("/home/test", "")
+	client.running.Store(true)
+
+	received := make(chan ServerRequest, 1)
+	client.SetApprovalHandler(func(req ServerRequest) {
+		received <- req
+	})
+
+	line := `{"id": 42, "method": "item/commandExecution/requestApproval", "params": {"command": "rm -rf build"}}`
+	client.handleLine(line)
+
+	select {
+	case req := <-received:
+		if req.ID != 42 {
+			t.Errorf("ID mismatch: got %d", req.ID)
+		}
+		if req.Method != MethodCommandExecutionRequestApproval {
+			t.Errorf("Method mismatch: got %q", req.Method)
+		}
+		var params CommandExecutionApprovalParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			t.Fatalf("Failed to unmarshal params: %v", err)
+		}
+		if params.Command != "rm -rf build" {
+			t.Errorf("Command mismatch: got %q", params.Command)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Approval handler not called")
+	}
+}
+
 func TestHandleLineInvalidJSON(t *testing.T) {
 	client := NewClient("/home/test", "")
-	client.running = true
+	client.running.Store(true)
 
 	// Invalid JSON should not panic
 	client.handleLine("invalid json")
@@ -241,7 +278,7 @@ func TestEventStruct(t *testing.T) {
 
 func TestHandleLineEmptyLine(t *testing.T) {
 	client := NewClient("/home/test", "")
-	client.running = true
+	client.running.Store(true)
 
 	// Empty line should be handled gracefully
 	client.handleLine("")
@@ -249,26 +286,169 @@ func TestHandleLineEmptyLine(t *testing.T) {
 
 func TestHandleLineResponseNotPending(t *testing.T) {
 	client := NewClient("/home/test", "")
-	client.running = true
+	client.running.Store(true)
 
 	// Response for non-pending request (should be ignored)
 	line := `{"id": 999, "result": {"test": true}}`
 	client.handleLine(line)
 }
 
-func TestHandleLineNotificationDropped(t *testing.T) {
+func TestHandleLineNotificationDoesNotBlock(t *testing.T) {
 	client := NewClient("/home/test", "")
-	client.running = true
+	client.running.Store(true)
+	respChan := make(chan *Response, 1)
+	client.pending[1] = respChan
+
+	// Nobody reads the events, the read loop must still get to the response
+	count := cap(client.events) * 3
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < count; i++ {
+			client.handleLine(fmt.Sprintf(`{"method": "test/notification", "params": {"n": %d}}`, i))
+		}
+		client.handleLine(`{"id": 1, "result": {}}`)
+		close(done)
+	}()
+
+	select {
+	case <-respChan:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the response to be delivered behind a slow event consumer")
+	}
+	<-done
+
+	// Nothing was dropped or reordered
+	for i := 0; i < count; i++ {
+		var params struct {
+			N int `json:"n"`
+		}
+		json.Unmarshal((<-client.events).Params, &params)
+		if params.N != i {
+			t.Fatalf("Expected notification %d, got %d", i, params.N)
+		}
+	}
+}
 
-	// Fill the events channel
-	for i := 0; i < 100; i++ {
+// writeFakeCodex installs a fake "codex" executable on PATH that answers the
+// initialize handshake, crashes on its first run and stays up afterwards
+func writeFakeCodex(t *testing.T) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake codex script requires a POSIX shell")
+	}
+
+	dir := t.TempDir()
+	script := `#!/bin/sh
+n=$(cat "` + dir + `/count" 2>/dev/null || echo 0)
+n=$((n+1))
+echo $n > "` + dir + `/count"
+read line
+id=$(echo "$line" | sed -n 's/.*"id":\([0-9]*\).*/\1/p')
+echo "{\"id\":$id,\"result\":{\"userAgent\":\"fake\"}}"
+read line
+if [ "$n" -eq 1 ]; then exit 1; fi
+while read line; do :; done
+`
+	if err := os.WriteFile(filepath.Join(dir, "codex"), []byte(script), 0755); err != nil {
+		t.Fatalf("Failed to write fake codex: %v", err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func waitForEvent(t *testing.T, events <-chan Event, method string) Event {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
 		select {
-		case client.events <- Event{Method: "fill"}:
-		default:
+		case event := <-events:
+			if event.Method == method {
+				return event
+			}
+		case <-timeout:
+			t.Fatalf("Timed out waiting for %s", method)
 		}
 	}
+}
 
-	// This notification should be dropped (channel full)
-	line := `{"method": "test/notification", "params": {}}`
-	client.handleLine(line)
+func TestSupervisorRestartsExitedProcess(t *testing.T) {
+	writeFakeCodex(t)
+
+	client := NewClient(t.TempDir(), "")
+	client.restartBackoff = 10 * time.Millisecond
+
+	if err := client.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer client.Stop()
+
+	waitForEvent(t, client.Events(), MethodCodexExited)
+	event := waitForEvent(t, client.Events(), MethodCodexRestarted)
+
+	var params CodexRestartedParams
+	if err := json.Unmarshal(event.Params, &params); err != nil {
+		t.Fatalf("Failed to parse restart params: %v", err)
+	}
+	if params.Restarts != 1 {
+		t.Errorf("Expected 1 restart, got %d", params.Restarts)
+	}
+
+	status := client.Status()
+	if !status.Running || status.Restarting || status.Restarts != 1 {
+		t.Errorf("Unexpected status: %+v", status)
+	}
+	if !client.IsRunning() {
+		t.Error("Client should be running after restart")
+	}
+}
+
+func TestStatusWhileRestarting(t *testing.T) {
+	writeFakeCodex(t)
+
+	client := NewClient(t.TempDir(), "")
+	client.restartBackoff = 10 * time.Millisecond
+
+	if err := client.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer client.Stop()
+
+	// Poll the state the supervisor and the handshake write, run with -race
+	stop := make(chan struct{})
+	polled := make(chan struct{})
+	go func() {
+		defer close(polled)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			client.Status()
+			client.IsRunning()
+		}
+	}()
+
+	// The first run crashes on its own, the second one is killed
+	waitForEvent(t, client.Events(), MethodCodexRestarted)
+	client.writeMu.Lock()
+	client.cmd.Process.Kill()
+	client.writeMu.Unlock()
+	waitForEvent(t, client.Events(), MethodCodexRestarted)
+
+	close(stop)
+	<-polled
+	if status := client.Status(); !status.Running || status.Restarts != 2 {
+		t.Errorf("Unexpected status: %+v", status)
+	}
+}
+
+func TestSendRequestWhileRestarting(t *testing.T) {
+	client := NewClient("/home/test", "")
+	client.running.Store(true)
+	client.restarting = true
+
+	_, err := client.sendRequest("test", nil)
+	if err != ErrRestarting {
+		t.Errorf("Expected ErrRestarting, got %v", err)
+	}
 }
@@ -8,11 +8,13 @@ type CodexClient interface {
 	Stop() error
 	Events() <-chan Event
 	IsRunning() bool
+	Status() Status
 	ThreadStart(ctx context.Context, params *ThreadStartParams) (string, error)
 	ThreadResume(ctx context.Context, threadID string) (*Thread, error)
 	TurnStart(ctx context.Context, threadID, prompt string, images []string) (string, error)
 	TurnInterrupt(ctx context.Context, threadID string) error
 	RespondToApproval(requestID int64, decision string) error
+	SetApprovalHandler(handler ApprovalHandler)
 }
 
 // Ensure Client implements CodexClient
@@ -2,6 +2,8 @@ package acp
 
 import (
 	"encoding/json"
+	"fmt"
+	"strconv"
 	"strings"
 )
 
@@ -150,6 +152,30 @@ func (f *FlexString) UnmarshalJSON(data []byte) error {
 	return nil // Ignore unparseable content
 }
 
+// CommandLine is a command given either as a shell string or as an argv array
+// An argv array is joined with spaces, quoting the arguments that need it
+type CommandLine string
+
+func (c *CommandLine) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*c = CommandLine(s)
+		return nil
+	}
+
+	var argv []string
+	if err := json.Unmarshal(data, &argv); err != nil {
+		return fmt.Errorf("command is neither a string nor an array of strings: %s", data)
+	}
+	for i, arg := range argv {
+		if arg == "" || strings.ContainsAny(arg, " \t\n\"'\\$`") {
+			argv[i] = strconv.Quote(arg)
+		}
+	}
+	*c = CommandLine(strings.Join(argv, " "))
+	return nil
+}
+
 type ExecutionStatus string
 
 const (
@@ -176,10 +202,14 @@ type ThreadStartParams struct {
 	ReasoningEffort    string `json:"reasoning_effort,omitempty"`
 	Personality        string `json:"personality,omitempty"`
 	SandboxPermissions string `json:"sandbox_permissions,omitempty"`
+	// Config overrides Codex config keys for this thread, e.g. "mcp_servers.feishu.env.BRIDGE_API_URL"
+	Config map[string]interface{} `json:"config,omitempty"`
 }
 
 type ThreadResumeParams struct {
-	ThreadID string `json:"threadId"`
+	ThreadID string                 `json:"threadId"`
+	Cwd      string                 `json:"cwd,omitempty"`
+	Config   map[string]interface{} `json:"config,omitempty"`
 }
 
 // UserInput represents input content for a turn
@@ -277,11 +307,11 @@ type CommandExecutionOutputDeltaParams struct {
 // ============ Approval Requests (Server → Client) ============
 
 type CommandExecutionApprovalParams struct {
-	ThreadID string `json:"threadId"`
-	TurnID   string `json:"turnId"`
-	ItemID   string `json:"itemId"`
-	Command  string `json:"command"`
-	Cwd      string `json:"cwd"`
+	ThreadID string      `json:"threadId"`
+	TurnID   string      `json:"turnId"`
+	ItemID   string      `json:"itemId"`
+	Command  CommandLine `json:"command"`
+	Cwd      string      `json:"cwd"`
 }
 
 type FileChangeApprovalParams struct {
@@ -304,6 +334,18 @@ type TokenUsageUpdatedParams struct {
 	OutputTokens int64  `json:"outputTokens"`
 }
 
+// ============ Supervisor Events (Client → Bridge) ============
+
+// CodexExitedParams is emitted when the app-server exits unexpectedly
+type CodexExitedParams struct {
+	Error string `json:"error"`
+}
+
+// CodexRestartedParams is emitted when the app-server has been restarted and re-initialized
+type CodexRestartedParams struct {
+	Restarts int `json:"restarts"`
+}
+
 // ============ Event Methods ============
 
 const (
@@ -328,4 +370,8 @@ const (
 	// Approval requests
 	MethodCommandExecutionRequestApproval = "item/commandExecution/requestApproval"
 	MethodFileChangeRequestApproval       = "item/fileChange/requestApproval"
+
+	// Supervisor events (generated by the client, not the server)
+	MethodCodexExited    = "bridge/codexExited"
+	MethodCodexRestarted = "bridge/codexRestarted"
 )
@@ -14,6 +14,7 @@ import (
 	lark "github.com/larksuite/oapi-sdk-go/v3"
 	larkcore "github.com/larksuite/oapi-sdk-go/v3/core"
 	"github.com/larksuite/oapi-sdk-go/v3/event/dispatcher"
+	"github.com/larksuite/oapi-sdk-go/v3/event/dispatcher/callback"
 	larkim "github.com/larksuite/oapi-sdk-go/v3/service/im/v1"
 	larkws "github.com/larksuite/oapi-sdk-go/v3/ws"
 )
@@ -22,15 +23,21 @@ import (
 type Message struct {
 	ChatID      string
 	MsgID       string
-	MsgType     string            // text, image, post
+	MsgType     string            // text, image, post, file, audio, media, sticker, interactive, share_chat, merge_forward
 	ChatType    string            // p2p (private), group
 	Content     string            // Text content (extracted from all message types)
 	ImageKeys   []string          // Image keys for downloading
+	Attachments []Attachment      // Files, audio and video for downloading
 	Sender      *Sender           // Message sender info
 	Mentions    []string          // Mentioned user IDs (including bot)
 	MentionMap  map[string]string // Map from mention key (@_user_1) to real name
 	MentionsBot bool              // True if the bot was mentioned
 	CreateTime  int64             // Message creation time (milliseconds Unix timestamp from Feishu)
+	UpdateTime  int64             // Last edit time in milliseconds, set for edited messages
+	ThreadID    string            // Topic thread the message belongs to (empty outside topics)
+	ParentID    string            // Message this one replies to, if any
+	RootID      string            // First message of the reply chain, if any
+	Recalled    bool              // Set on fetched messages that were recalled
 }
 
 // Sender represents the message sender
@@ -63,23 +70,55 @@ type HistoryMessage struct {
 	MsgType    string `json:"msg_type"`
 	Content    string `json:"content"`
 	CreateTime string `json:"create_time"`
+	ParentID   string `json:"parent_id"`
+	RootID     string `json:"root_id"`
 	Sender     *Sender
 }
 
 // MessageHandler is the callback for received messages
 type MessageHandler func(msg *Message)
 
+// CardAction represents a button click (or other interaction) on an interactive card
+type CardAction struct {
+	MessageID  string                 // Card message ID
+	ChatID     string                 // Chat the card was posted in
+	OperatorID string                 // open_id of the user who clicked
+	Value      map[string]interface{} // Value attached to the clicked element
+}
+
+// CardResponse answers a card action
+type CardResponse struct {
+	Toast string // Shown to the operator as a toast, empty for none
+	Card  string // Card JSON replacing the clicked card, empty to keep it
+}
+
+// CardActionHandler is the callback for card actions, a nil response shows nothing
+type CardActionHandler func(action *CardAction) (*CardResponse, error)
+
 // Client is the Feishu API client
 type Client struct {
-	appID       string
-	appSecret   string
-	larkCli     *lark.Client
-	wsCli       *larkws.Client
-	onMessage   MessageHandler
-	downloadDir string
-	ctx         context.Context
-	cancel      context.CancelFunc
-	botOpenID   string // Bot's own open_id, learned from first mention
+	appID        string
+	appSecret    string
+	baseURL      string // Open platform base URL, Feishu or Lark
+	larkCli      *lark.Client
+	outbound     *outbound // Rate limits and retries every API call
+	wsCli        *larkws.Client
+	onMessage    MessageHandler
+	onCard       CardActionHandler
+	onRecall     RecallHandler
+	onEdit       MessageHandler
+	onBotAdded   ChatEventHandler
+	onBotRemoved ChatEventHandler
+	onMembers    ChatEventHandler
+	onConnected  ConnectedHandler
+	senderNames  SenderNameFunc // Names the senders of forwarded chat records, nil to show open_ids
+	webhook      *WebhookConfig // Receive events over HTTP instead of WebSocket when set
+	httpSrv      *http.Server   // Webhook server, nil in WebSocket mode
+	replies      *replyLog      // Replies per triggering message, for recalling them
+	downloadDir  string
+	ctx          context.Context
+	cancel       context.CancelFunc
+	botOpenID    string // Bot's own open_id, learned from first mention
 }
 
 // NewClient creates a new Feishu client
@@ -87,7 +126,10 @@ func NewClient(appID, appSecret string) *Client {
 	return &Client{
 		appID:       appID,
 		appSecret:   appSecret,
+		baseURL:     FeishuBaseURL,
+		outbound:    newOutbound(http.DefaultClient, DefaultOutboundConfig()),
 		downloadDir: "/tmp/feishu-images",
+		replies:     newReplyLog(),
 	}
 }
 
@@ -101,31 +143,38 @@ func (c *Client) OnMessage(handler MessageHandler) {
 	c.onMessage = handler
 }
 
-// Start connects to Feishu via WebSocket and starts listening for messages
+// OnCardAction sets the card action handler
+func (c *Client) OnCardAction(handler CardActionHandler) {
+	c.onCard = handler
+}
+
+// Start connects to Feishu and starts listening for events
+// Events arrive over the WebSocket long connection, or over HTTP after SetWebhook
 func (c *Client) Start() error {
 	c.ctx, c.cancel = context.WithCancel(context.Background())
 
 	// Create Lark API client
-	c.larkCli = lark.NewClient(c.appID, c.appSecret)
+	c.larkCli = c.newLarkClient()
 
 	// Fetch bot's own open_id at startup
 	if err := c.fetchBotOpenID(); err != nil {
 		fmt.Printf("[Feishu] Warning: failed to fetch bot open_id: %v\n", err)
 	}
 
-	// Register event handler
-	// Note: Must return quickly so SDK can send ACK, otherwise Feishu will retry due to timeout
-	eventHandler := dispatcher.NewEventDispatcher("", "").
-		OnP2MessageReceiveV1(func(ctx context.Context, event *larkim.P2MessageReceiveV1) error {
-			// Process message asynchronously, return immediately to let SDK send ACK
-			go c.handleMessage(event)
-			return nil
-		})
+	eventHandler := c.newEventDispatcher()
+	if c.webhook != nil {
+		c.notifyConnected()
+		return c.serveWebhook(eventHandler)
+	}
 
 	// Create WebSocket client
 	c.wsCli = larkws.NewClient(c.appID, c.appSecret,
 		larkws.WithEventHandler(eventHandler),
-		larkws.WithLogLevel(larkcore.LogLevelInfo),
+		larkws.WithDomain(c.baseURL),
+		larkws.WithLogger(&connLogger{
+			Logger:    larkcore.NewDefaultLogger(larkcore.LogLevelInfo),
+			onConnect: c.notifyConnected,
+		}),
 	)
 
 	fmt.Println("[Feishu] Starting WebSocket connection...")
@@ -134,15 +183,46 @@ func (c *Client) Start() error {
 	return c.wsCli.Start(c.ctx)
 }
 
+// newLarkClient creates the API client, sending through the outbound layer
+func (c *Client) newLarkClient() *lark.Client {
+	return lark.NewClient(c.appID, c.appSecret,
+		lark.WithOpenBaseUrl(c.baseURL),
+		lark.WithHttpClient(c.outbound),
+	)
+}
+
+// newEventDispatcher registers the event handlers
+// Note: Handlers must return quickly so SDK can send ACK, otherwise Feishu will retry due to timeout
+func (c *Client) newEventDispatcher() *dispatcher.EventDispatcher {
+	// Webhook requests are verified with the token and decrypted with the key
+	var verificationToken, encryptKey string
+	if c.webhook != nil {
+		verificationToken, encryptKey = c.webhook.VerificationToken, c.webhook.EncryptKey
+	}
+	return dispatcher.NewEventDispatcher(verificationToken, encryptKey).
+		OnP2MessageReceiveV1(func(ctx context.Context, event *larkim.P2MessageReceiveV1) error {
+			// Process message asynchronously, return immediately to let SDK send ACK
+			go c.handleMessage(event)
+			return nil
+		}).
+		OnP2MessageRecalledV1(c.handleRecall).
+		OnCustomizedEvent(messageUpdatedEvent, c.handleEdit).
+		OnP2ChatMemberBotAddedV1(c.handleBotAdded).
+		OnP2ChatMemberBotDeletedV1(c.handleBotDeleted).
+		OnP2ChatDisbandedV1(c.handleChatDisbanded).
+		OnP2ChatMemberUserAddedV1(c.handleUsersAdded).
+		OnP2ChatMemberUserDeletedV1(c.handleUsersDeleted).
+		OnP2ChatMemberUserWithdrawnV1(c.handleUsersWithdrawn).
+		OnP2CardActionTrigger(c.handleCardAction)
+}
+
 // fetchBotOpenID fetches the bot's own open_id
 func (c *Client) fetchBotOpenID() error {
 	// 1. First get tenant_access_token
 	tokenReq := fmt.Sprintf(`{"app_id":"%s","app_secret":"%s"}`, c.appID, c.appSecret)
-	tokenResp, err := http.Post(
-		"https://open.feishu.cn/open-apis/auth/v3/tenant_access_token/internal",
-		"application/json",
-		strings.NewReader(tokenReq),
-	)
+	tokenHTTPReq, _ := http.NewRequest("POST", c.baseURL+"/open-apis/auth/v3/tenant_access_token/internal", strings.NewReader(tokenReq))
+	tokenHTTPReq.Header.Set("Content-Type", "application/json")
+	tokenResp, err := c.outbound.Do(tokenHTTPReq)
 	if err != nil {
 		return fmt.Errorf("get token: %w", err)
 	}
@@ -157,10 +237,10 @@ func (c *Client) fetchBotOpenID() error {
 	}
 
 	// 2. Get bot info
-	req, _ := http.NewRequest("GET", "https://open.feishu.cn/open-apis/bot/v3/info", nil)
+	req, _ := http.NewRequest("GET", c.baseURL+"/open-apis/bot/v3/info", nil)
 	req.Header.Set("Authorization", "Bearer "+tokenResult.TenantAccessToken)
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := c.outbound.Do(req)
 	if err != nil {
 		return fmt.Errorf("get bot info: %w", err)
 	}
@@ -194,18 +274,70 @@ func (c *Client) Stop() {
 	}
 }
 
+// handleCardAction processes interactive card callbacks
+func (c *Client) handleCardAction(ctx context.Context, event *callback.CardActionTriggerEvent) (*callback.CardActionTriggerResponse, error) {
+	if c.onCard == nil || event.Event == nil || event.Event.Action == nil {
+		return nil, nil
+	}
+
+	action := &CardAction{Value: event.Event.Action.Value}
+	if event.Event.Operator != nil {
+		action.OperatorID = event.Event.Operator.OpenID
+	}
+	if event.Event.Context != nil {
+		action.MessageID = event.Event.Context.OpenMessageID
+		action.ChatID = event.Event.Context.OpenChatID
+	}
+
+	resp, err := c.onCard(action)
+	if err != nil {
+		fmt.Printf("[Feishu] Card action failed: %v\n", err)
+		return &callback.CardActionTriggerResponse{
+			Toast: &callback.Toast{Type: "error", Content: err.Error()},
+		}, nil
+	}
+	if resp == nil || (resp.Toast == "" && resp.Card == "") {
+		return nil, nil
+	}
+
+	result := &callback.CardActionTriggerResponse{}
+	if resp.Toast != "" {
+		result.Toast = &callback.Toast{Type: "success", Content: resp.Toast}
+	}
+	if resp.Card != "" {
+		// Replaces the clicked card for everyone who sees it
+		result.Card = &callback.Card{Type: "raw", Data: json.RawMessage(resp.Card)}
+	}
+	return result, nil
+}
+
 // handleMessage processes incoming Feishu messages
 func (c *Client) handleMessage(event *larkim.P2MessageReceiveV1) {
-	rawMsg := event.Event.Message
-	if rawMsg == nil {
+	msg := c.parseMessageEvent(event.Event)
+	if msg == nil {
 		return
 	}
 
+	fmt.Printf("[Feishu] Received %s from %s chat %s: %s\n", msg.MsgType, msg.ChatType, msg.ChatID, truncate(msg.Content, 50))
+
+	if c.onMessage != nil {
+		c.onMessage(msg)
+	}
+}
+
+// parseMessageEvent converts a message event into a Message
+// Returns nil for the bot's own messages and unsupported types
+func (c *Client) parseMessageEvent(event *larkim.P2MessageReceiveV1Data) *Message {
+	if event == nil || event.Message == nil {
+		return nil
+	}
+	rawMsg := event.Message
+
 	// Filter out messages sent by the bot itself to prevent infinite loops
-	if event.Event.Sender != nil && event.Event.Sender.SenderType != nil {
-		if *event.Event.Sender.SenderType == "app" {
+	if event.Sender != nil && event.Sender.SenderType != nil {
+		if *event.Sender.SenderType == "app" {
 			// Message sent by bot, ignore
-			return
+			return nil
 		}
 	}
 
@@ -221,6 +353,15 @@ func (c *Client) handleMessage(event *larkim.P2MessageReceiveV1) {
 			msg.CreateTime = ts
 		}
 	}
+	if rawMsg.UpdateTime != nil {
+		if ts, err := strconv.ParseInt(*rawMsg.UpdateTime, 10, 64); err == nil {
+			msg.UpdateTime = ts
+		}
+	}
+
+	msg.ThreadID = stringValue(rawMsg.ThreadId)
+	msg.ParentID = stringValue(rawMsg.ParentId)
+	msg.RootID = stringValue(rawMsg.RootId)
 
 	// Parse chat type
 	if rawMsg.ChatType != nil {
@@ -228,18 +369,18 @@ func (c *Client) handleMessage(event *larkim.P2MessageReceiveV1) {
 	}
 
 	// Parse sender info
-	if event.Event.Sender != nil {
+	if event.Sender != nil {
 		msg.Sender = &Sender{}
-		if event.Event.Sender.SenderId != nil {
-			if event.Event.Sender.SenderId.OpenId != nil {
-				msg.Sender.SenderID = *event.Event.Sender.SenderId.OpenId
+		if event.Sender.SenderId != nil {
+			if event.Sender.SenderId.OpenId != nil {
+				msg.Sender.SenderID = *event.Sender.SenderId.OpenId
 			}
 		}
-		if event.Event.Sender.SenderType != nil {
-			msg.Sender.SenderType = *event.Event.Sender.SenderType
+		if event.Sender.SenderType != nil {
+			msg.Sender.SenderType = *event.Sender.SenderType
 		}
-		if event.Event.Sender.TenantKey != nil {
-			msg.Sender.TenantKey = *event.Event.Sender.TenantKey
+		if event.Sender.TenantKey != nil {
+			msg.Sender.TenantKey = *event.Sender.TenantKey
 		}
 	}
 
@@ -281,27 +422,26 @@ func (c *Client) handleMessage(event *larkim.P2MessageReceiveV1) {
 		fmt.Printf("[Feishu] DEBUG: Mentions is nil\n")
 	}
 
-	switch msg.MsgType {
-	case "text":
-		msg.Content = c.parseTextContent(*rawMsg.Content, msg.MentionMap)
-	case "image":
-		msg.ImageKeys = c.parseImageContent(*rawMsg.Content)
-		msg.Content = "[Image]"
-	case "post":
-		content, imageKeys := c.parsePostContent(*rawMsg.Content, msg.MentionMap)
+	if msg.MsgType == "merge_forward" {
+		// The record only holds a placeholder, fetch the forwarded messages
+		transcript, err := c.expandMergeForward(msg.ChatID, msg.MsgID)
+		if err != nil {
+			fmt.Printf("[Feishu] Failed to expand forwarded messages %s: %v\n", msg.MsgID, err)
+			transcript = "[Forwarded chat record]"
+		}
+		msg.Content = transcript
+	} else {
+		content, imageKeys, attachments, ok := c.parseContent(msg.MsgType, *rawMsg.Content, msg.MentionMap)
+		if !ok {
+			// Unsupported message type
+			fmt.Printf("[Feishu] Unsupported message type: %s\n", msg.MsgType)
+			return nil
+		}
 		msg.Content = content
 		msg.ImageKeys = imageKeys
-	default:
-		// Unsupported message type
-		fmt.Printf("[Feishu] Unsupported message type: %s\n", msg.MsgType)
-		return
-	}
-
-	fmt.Printf("[Feishu] Received %s from %s chat %s: %s\n", msg.MsgType, msg.ChatType, msg.ChatID, truncate(msg.Content, 50))
-
-	if c.onMessage != nil {
-		c.onMessage(msg)
+		msg.Attachments = attachments
 	}
+	return msg
 }
 
 // parseTextContent extracts text from a text message
@@ -405,40 +545,56 @@ func replaceMentions(text string, mentionMap map[string]string) string {
 
 // DownloadImage downloads an image from Feishu and saves it locally
 func (c *Client) DownloadImage(messageID, imageKey string) (string, error) {
+	filePath := filepath.Join(c.downloadDir, imageKey+".png")
+	if err := c.downloadResource(messageID, imageKey, "image", filePath); err != nil {
+		return "", err
+	}
+	fmt.Printf("[Feishu] Downloaded image to %s\n", filePath)
+	return filePath, nil
+}
+
+// DownloadFile downloads a message attachment into dir and returns its path
+func (c *Client) DownloadFile(messageID string, attachment Attachment, dir string) (string, error) {
+	filePath := filepath.Join(dir, attachmentName(attachment.Name, attachment.Key, ""))
+	if err := c.downloadResource(messageID, attachment.Key, "file", filePath); err != nil {
+		return "", err
+	}
+	fmt.Printf("[Feishu] Downloaded %s to %s\n", attachment.Kind, filePath)
+	return filePath, nil
+}
+
+// downloadResource saves a message resource (image or file) to filePath
+func (c *Client) downloadResource(messageID, fileKey, resourceType, filePath string) error {
 	// Ensure download directory exists
-	if err := os.MkdirAll(c.downloadDir, 0755); err != nil {
-		return "", fmt.Errorf("failed to create download dir: %w", err)
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return fmt.Errorf("failed to create download dir: %w", err)
 	}
 
 	req := larkim.NewGetMessageResourceReqBuilder().
 		MessageId(messageID).
-		FileKey(imageKey).
-		Type("image").
+		FileKey(fileKey).
+		Type(resourceType).
 		Build()
 
 	resp, err := c.larkCli.Im.MessageResource.Get(context.Background(), req)
 	if err != nil {
-		return "", fmt.Errorf("failed to get image: %w", err)
+		return fmt.Errorf("failed to get %s: %w", resourceType, err)
 	}
 	if !resp.Success() {
-		return "", fmt.Errorf("get image error: %s", resp.Msg)
+		return fmt.Errorf("get %s error: %s", resourceType, resp.Msg)
 	}
 
 	// Save to file
-	filePath := filepath.Join(c.downloadDir, imageKey+".png")
 	file, err := os.Create(filePath)
 	if err != nil {
-		return "", fmt.Errorf("failed to create file: %w", err)
+		return fmt.Errorf("failed to create file: %w", err)
 	}
 	defer file.Close()
 
-	_, err = io.Copy(file, resp.File)
-	if err != nil {
-		return "", fmt.Errorf("failed to write file: %w", err)
+	if _, err := io.Copy(file, resp.File); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
 	}
-
-	fmt.Printf("[Feishu] Downloaded image to %s\n", filePath)
-	return filePath, nil
+	return nil
 }
 
 // Mention represents a user to be mentioned in a message
@@ -456,6 +612,7 @@ func (c *Client) SendText(chatID, text string) error {
 		ReceiveIdType(larkim.ReceiveIdTypeChatId).
 		Body(larkim.NewCreateMessageReqBodyBuilder().
 			ReceiveId(chatID).
+			Uuid(newUUID()).
 			MsgType(larkim.MsgTypeText).
 			Content(string(contentJSON)).
 			Build()).
@@ -491,6 +648,7 @@ func (c *Client) SendTextWithMentions(chatID, text string, mentions []Mention) e
 		ReceiveIdType(larkim.ReceiveIdTypeChatId).
 		Body(larkim.NewCreateMessageReqBodyBuilder().
 			ReceiveId(chatID).
+			Uuid(newUUID()).
 			MsgType(larkim.MsgTypeText).
 			Content(string(contentJSON)).
 			Build()).
@@ -520,6 +678,7 @@ func (c *Client) SendTextMentionAll(chatID, text string) error {
 		ReceiveIdType(larkim.ReceiveIdTypeChatId).
 		Body(larkim.NewCreateMessageReqBodyBuilder().
 			ReceiveId(chatID).
+			Uuid(newUUID()).
 			MsgType(larkim.MsgTypeText).
 			Content(string(contentJSON)).
 			Build()).
@@ -539,6 +698,122 @@ func (c *Client) SendTextMentionAll(chatID, text string) error {
 
 // SendRichText sends a rich text (post) message to a chat
 func (c *Client) SendRichText(chatID, title string, content [][]map[string]interface{}) error {
+	req := larkim.NewCreateMessageReqBuilder().
+		ReceiveIdType(larkim.ReceiveIdTypeChatId).
+		Body(larkim.NewCreateMessageReqBodyBuilder().
+			ReceiveId(chatID).
+			Uuid(newUUID()).
+			MsgType(larkim.MsgTypePost).
+			Content(postJSON(title, content)).
+			Build()).
+		Build()
+
+	resp, err := c.larkCli.Im.Message.Create(context.Background(), req)
+	if err != nil {
+		return fmt.Errorf("send rich text failed: %w", err)
+	}
+	if !resp.Success() {
+		return fmt.Errorf("send rich text error: %s", resp.Msg)
+	}
+
+	fmt.Printf("[Feishu] Rich text sent to %s\n", chatID)
+	return nil
+}
+
+// SendMarkdown renders Markdown as rich text and sends it, split into
+// ordered messages when it is too long for one. Mentions lead the first message
+func (c *Client) SendMarkdown(chatID, markdown string, mentions []Mention) error {
+	posts := markdownPosts(markdown, mentions)
+	for i, content := range posts {
+		if err := c.SendRichText(chatID, "", content); err != nil {
+			return fmt.Errorf("send part %d/%d: %w", i+1, len(posts), err)
+		}
+	}
+	return nil
+}
+
+// ReplyText replies to a message with text
+// inThread keeps the reply inside the message's topic thread
+func (c *Client) ReplyText(messageID, text string, inThread bool) error {
+	contentJSON, _ := json.Marshal(map[string]string{"text": text})
+	if _, err := c.reply(messageID, larkim.MsgTypeText, string(contentJSON), inThread); err != nil {
+		return fmt.Errorf("reply text: %w", err)
+	}
+	return nil
+}
+
+// ReplyMarkdown replies to a message with rendered Markdown, like SendMarkdown
+// Every part replies to the same message so they stay together in order
+func (c *Client) ReplyMarkdown(messageID, markdown string, mentions []Mention, inThread bool) error {
+	posts := markdownPosts(markdown, mentions)
+	for i, content := range posts {
+		if _, err := c.reply(messageID, larkim.MsgTypePost, postJSON("", content), inThread); err != nil {
+			return fmt.Errorf("reply part %d/%d: %w", i+1, len(posts), err)
+		}
+	}
+	return nil
+}
+
+// ReplyCard replies to a message with an interactive card and returns its message ID
+func (c *Client) ReplyCard(messageID, card string, inThread bool) (string, error) {
+	msgID, err := c.reply(messageID, larkim.MsgTypeInteractive, card, inThread)
+	if err != nil {
+		return "", fmt.Errorf("reply card: %w", err)
+	}
+	return msgID, nil
+}
+
+// reply sends a message as a reply and returns the new message ID
+func (c *Client) reply(messageID, msgType, content string, inThread bool) (string, error) {
+	req := larkim.NewReplyMessageReqBuilder().
+		MessageId(messageID).
+		Body(larkim.NewReplyMessageReqBodyBuilder().
+			MsgType(msgType).
+			Content(content).
+			ReplyInThread(inThread).
+			Uuid(newUUID()).
+			Build()).
+		Build()
+
+	resp, err := c.larkCli.Im.Message.Reply(context.Background(), req)
+	if err != nil {
+		return "", fmt.Errorf("reply failed: %w", err)
+	}
+	if !resp.Success() {
+		return "", fmt.Errorf("reply error: %s", resp.Msg)
+	}
+
+	msgID := ""
+	if resp.Data != nil && resp.Data.MessageId != nil {
+		msgID = *resp.Data.MessageId
+		c.replies.add(messageID, msgID)
+	}
+	fmt.Printf("[Feishu] Replied to %s with %s (msg=%s)\n", messageID, msgType, msgID)
+	return msgID, nil
+}
+
+// markdownPosts renders Markdown into posts, with mentions leading the first one
+func markdownPosts(markdown string, mentions []Mention) [][][]map[string]interface{} {
+	posts := RenderPosts(markdown)
+	if len(posts) == 0 || len(mentions) == 0 {
+		return posts
+	}
+
+	var line []map[string]interface{}
+	for _, m := range mentions {
+		line = append(line, map[string]interface{}{"tag": "at", "user_id": m.UserID}, textElement(" ", nil))
+	}
+	first := posts[0]
+	if len(first) > 0 && isInlineParagraph(first[0]) {
+		first[0] = append(line, first[0]...)
+	} else {
+		posts[0] = append([][]map[string]interface{}{line}, first...)
+	}
+	return posts
+}
+
+// postJSON wraps post content into message content JSON
+func postJSON(title string, content [][]map[string]interface{}) string {
 	post := map[string]interface{}{
 		"zh_cn": map[string]interface{}{
 			"title":   title,
@@ -546,25 +821,66 @@ func (c *Client) SendRichText(chatID, title string, content [][]map[string]inter
 		},
 	}
 	contentJSON, _ := json.Marshal(post)
+	return string(contentJSON)
+}
+
+// isInlineParagraph reports whether a paragraph can take leading mentions
+func isInlineParagraph(paragraph []map[string]interface{}) bool {
+	for _, el := range paragraph {
+		if tag := el["tag"]; tag == "code_block" || tag == "hr" {
+			return false
+		}
+	}
+	return true
+}
 
+// SendCard sends an interactive card message and returns its message ID
+// card is the card JSON
+func (c *Client) SendCard(chatID, card string) (string, error) {
 	req := larkim.NewCreateMessageReqBuilder().
 		ReceiveIdType(larkim.ReceiveIdTypeChatId).
 		Body(larkim.NewCreateMessageReqBodyBuilder().
 			ReceiveId(chatID).
-			MsgType(larkim.MsgTypePost).
-			Content(string(contentJSON)).
+			Uuid(newUUID()).
+			MsgType(larkim.MsgTypeInteractive).
+			Content(card).
 			Build()).
 		Build()
 
 	resp, err := c.larkCli.Im.Message.Create(context.Background(), req)
 	if err != nil {
-		return fmt.Errorf("send rich text failed: %w", err)
+		return "", fmt.Errorf("send card failed: %w", err)
 	}
 	if !resp.Success() {
-		return fmt.Errorf("send rich text error: %s", resp.Msg)
+		return "", fmt.Errorf("send card error: %s", resp.Msg)
 	}
 
-	fmt.Printf("[Feishu] Rich text sent to %s\n", chatID)
+	msgID := ""
+	if resp.Data != nil && resp.Data.MessageId != nil {
+		msgID = *resp.Data.MessageId
+	}
+	fmt.Printf("[Feishu] Card sent to %s (msg=%s)\n", chatID, msgID)
+	return msgID, nil
+}
+
+// UpdateCard replaces the content of a previously sent card message
+func (c *Client) UpdateCard(messageID, card string) error {
+	req := larkim.NewPatchMessageReqBuilder().
+		MessageId(messageID).
+		Body(larkim.NewPatchMessageReqBodyBuilder().
+			Content(card).
+			Build()).
+		Build()
+
+	resp, err := c.larkCli.Im.Message.Patch(context.Background(), req)
+	if err != nil {
+		return fmt.Errorf("update card failed: %w", err)
+	}
+	if !resp.Success() {
+		return fmt.Errorf("update card error: %s", resp.Msg)
+	}
+
+	fmt.Printf("[Feishu] Card %s updated\n", messageID)
 	return nil
 }
 
@@ -612,6 +928,16 @@ func (c *Client) RemoveReaction(messageID, reactionID string) error {
 // pageSize: number of messages to retrieve (max 50)
 // Returns messages in chronological order (oldest first, newest last)
 func (c *Client) GetChatHistory(chatID string, pageSize int) ([]*HistoryMessage, error) {
+	return c.listMessages("chat", chatID, pageSize)
+}
+
+// GetThreadHistory retrieves recent messages from a topic thread, like GetChatHistory
+func (c *Client) GetThreadHistory(threadID string, pageSize int) ([]*HistoryMessage, error) {
+	return c.listMessages("thread", threadID, pageSize)
+}
+
+// listMessages lists the latest messages of a chat or thread container
+func (c *Client) listMessages(containerType, containerID string, pageSize int) ([]*HistoryMessage, error) {
 	if pageSize > 50 {
 		pageSize = 50
 	}
@@ -622,8 +948,8 @@ func (c *Client) GetChatHistory(chatID string, pageSize int) ([]*HistoryMessage,
 	// Use ByCreateTimeDesc to get latest messages (descending: newest first)
 	// Feishu API defaults to ascending (oldest first), which would return old messages from when group was created
 	req := larkim.NewListMessageReqBuilder().
-		ContainerIdType("chat").
-		ContainerId(chatID).
+		ContainerIdType(containerType).
+		ContainerId(containerID).
 		SortType("ByCreateTimeDesc"). // Key: descending order for latest messages
 		PageSize(pageSize).
 		Build()
@@ -642,6 +968,8 @@ func (c *Client) GetChatHistory(chatID string, pageSize int) ([]*HistoryMessage,
 			MsgID:      *item.MessageId,
 			MsgType:    *item.MsgType,
 			CreateTime: *item.CreateTime,
+			ParentID:   stringValue(item.ParentId),
+			RootID:     stringValue(item.RootId),
 		}
 
 		// Build mention map from the message's mentions (if any)
@@ -658,13 +986,9 @@ func (c *Client) GetChatHistory(chatID string, pageSize int) ([]*HistoryMessage,
 		// Parse content based on message type, resolving mention placeholders
 		if item.Body != nil && item.Body.Content != nil {
 			rawContent := *item.Body.Content
-			switch *item.MsgType {
-			case "text":
-				msg.Content = c.parseTextContent(rawContent, mentionMap)
-			case "post":
-				content, _ := c.parsePostContent(rawContent, mentionMap)
+			if content, _, _, ok := c.parseContent(*item.MsgType, rawContent, mentionMap); ok {
 				msg.Content = content
-			default:
+			} else {
 				msg.Content = rawContent
 			}
 		}
@@ -691,7 +1015,7 @@ func (c *Client) GetChatHistory(chatID string, pageSize int) ([]*HistoryMessage,
 		messages[i], messages[j] = messages[j], messages[i]
 	}
 
-	fmt.Printf("[Feishu] Retrieved %d messages from chat %s\n", len(messages), chatID)
+	fmt.Printf("[Feishu] Retrieved %d messages from %s %s\n", len(messages), containerType, containerID)
 	return messages, nil
 }
 
@@ -0,0 +1,41 @@
+package feishu
+
+import (
+	"context"
+	"strings"
+
+	larkcore "github.com/larksuite/oapi-sdk-go/v3/core"
+)
+
+// ConnectedHandler is the callback for the client becoming ready to receive events
+type ConnectedHandler func()
+
+// OnConnected sets the handler called whenever events start flowing: when the
+// WebSocket connects or reconnects, or once when the webhook server starts.
+// Events sent while disconnected are lost, the handler can catch up on them
+func (c *Client) OnConnected(handler ConnectedHandler) {
+	c.onConnected = handler
+}
+
+// notifyConnected calls the connected handler without blocking the caller
+func (c *Client) notifyConnected() {
+	if c.onConnected != nil {
+		go c.onConnected()
+	}
+}
+
+// connLogger passes SDK logs through and spots WebSocket (re)connections
+// The SDK has no connection callback, it only logs "connected to <url>"
+type connLogger struct {
+	larkcore.Logger
+	onConnect func()
+}
+
+func (l *connLogger) Info(ctx context.Context, args ...interface{}) {
+	l.Logger.Info(ctx, args...)
+	if len(args) > 0 {
+		if msg, ok := args[0].(string); ok && strings.HasPrefix(msg, "connected to ") {
+			l.onConnect()
+		}
+	}
+}
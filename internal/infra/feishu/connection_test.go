@@ -0,0 +1,32 @@
+package feishu
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	larkcore "github.com/larksuite/oapi-sdk-go/v3/core"
+)
+
+func TestConnLogger_SpotsConnections(t *testing.T) {
+	connected := make(chan struct{}, 2)
+	c := &Client{}
+	c.OnConnected(func() { connected <- struct{}{} })
+	logger := &connLogger{Logger: larkcore.NewDefaultLogger(larkcore.LogLevelError), onConnect: c.notifyConnected}
+	ctx := context.Background()
+
+	logger.Info(ctx, "trying to reconnect: 1")
+	logger.Info(ctx, "connected to wss://example.com/ws", "[conn_id=1]")
+	logger.Warn(ctx, "connected to nowhere")
+
+	select {
+	case <-connected:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the connection to be reported")
+	}
+	select {
+	case <-connected:
+		t.Error("Expected only the connected log to be reported")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,74 @@
+package feishu
+
+import (
+	"context"
+	"fmt"
+
+	larkcontact "github.com/larksuite/oapi-sdk-go/v3/service/contact/v3"
+)
+
+// User is a user profile from the contact API
+type User struct {
+	OpenID     string
+	UnionID    string // Same for the user across all apps of the tenant
+	Name       string
+	Department string // Name of the user's first department, empty if not visible
+}
+
+// GetUser retrieves a user's profile by open_id
+// Needs the contact:user.base:readonly scope, and the department name
+// contact:department.base:readonly. Users outside the app's visibility fail
+func (c *Client) GetUser(openID string) (*User, error) {
+	req := larkcontact.NewGetUserReqBuilder().
+		UserId(openID).
+		UserIdType(larkcontact.UserIdTypeOpenId).
+		DepartmentIdType(larkcontact.DepartmentIdTypeOpenDepartmentId).
+		Build()
+
+	resp, err := c.larkCli.Contact.User.Get(context.Background(), req)
+	if err != nil {
+		return nil, fmt.Errorf("get user failed: %w", err)
+	}
+	if !resp.Success() {
+		return nil, fmt.Errorf("get user error: %s", resp.Msg)
+	}
+	if resp.Data == nil || resp.Data.User == nil {
+		return nil, fmt.Errorf("get user error: empty response")
+	}
+
+	raw := resp.Data.User
+	user := &User{
+		OpenID:  openID,
+		UnionID: stringValue(raw.UnionId),
+		Name:    stringValue(raw.Name),
+	}
+	if len(raw.DepartmentIds) > 0 {
+		// The department is a nice-to-have, the profile is still useful without it
+		if name, err := c.departmentName(raw.DepartmentIds[0]); err == nil {
+			user.Department = name
+		}
+	}
+
+	fmt.Printf("[Feishu] Got user %s: %s\n", openID, user.Name)
+	return user, nil
+}
+
+// departmentName retrieves the name of a department by open_department_id
+func (c *Client) departmentName(departmentID string) (string, error) {
+	req := larkcontact.NewGetDepartmentReqBuilder().
+		DepartmentId(departmentID).
+		DepartmentIdType(larkcontact.DepartmentIdTypeOpenDepartmentId).
+		Build()
+
+	resp, err := c.larkCli.Contact.Department.Get(context.Background(), req)
+	if err != nil {
+		return "", fmt.Errorf("get department failed: %w", err)
+	}
+	if !resp.Success() {
+		return "", fmt.Errorf("get department error: %s", resp.Msg)
+	}
+	if resp.Data == nil || resp.Data.Department == nil {
+		return "", nil
+	}
+	return stringValue(resp.Data.Department.Name), nil
+}
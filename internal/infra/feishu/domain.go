@@ -0,0 +1,40 @@
+package feishu
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Open platform base URLs
+const (
+	FeishuBaseURL = "https://open.feishu.cn"     // Feishu (China)
+	LarkBaseURL   = "https://open.larksuite.com" // Lark (international)
+)
+
+// Open platform domains
+const (
+	DomainFeishu = "feishu"
+	DomainLark   = "lark"
+)
+
+// ResolveBaseURL returns the open platform base URL of a domain
+// domain is feishu, lark or a custom http(s) base URL such as a mock server.
+// Empty means feishu
+func ResolveBaseURL(domain string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(domain)) {
+	case "", DomainFeishu:
+		return FeishuBaseURL, nil
+	case DomainLark:
+		return LarkBaseURL, nil
+	}
+	if strings.HasPrefix(domain, "http://") || strings.HasPrefix(domain, "https://") {
+		return strings.TrimRight(domain, "/"), nil
+	}
+	return "", fmt.Errorf("unknown domain %q, want feishu, lark or an http(s) base URL", domain)
+}
+
+// SetBaseURL sets the open platform base URL, see ResolveBaseURL
+// API calls, the WebSocket connection and downloads all use it. Must be called before Start
+func (c *Client) SetBaseURL(baseURL string) {
+	c.baseURL = strings.TrimRight(baseURL, "/")
+}
@@ -0,0 +1,58 @@
+package feishu
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestResolveBaseURL(t *testing.T) {
+	tests := []struct {
+		domain string
+		want   string
+	}{
+		{"", FeishuBaseURL},
+		{"feishu", FeishuBaseURL},
+		{"Lark", LarkBaseURL},
+		{"http://127.0.0.1:9000/", "http://127.0.0.1:9000"},
+		{"https://open.example.com", "https://open.example.com"},
+	}
+	for _, tt := range tests {
+		got, err := ResolveBaseURL(tt.domain)
+		if err != nil || got != tt.want {
+			t.Errorf("ResolveBaseURL(%q) = %q, %v, want %q", tt.domain, got, err, tt.want)
+		}
+	}
+
+	if _, err := ResolveBaseURL("open.feishu.cn"); err == nil {
+		t.Error("Expected an error for a domain without scheme")
+	}
+}
+
+func TestFetchBotOpenID_UsesBaseURL(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/open-apis/auth/v3/tenant_access_token/internal":
+			fmt.Fprint(w, `{"code":0,"tenant_access_token":"t-123","expire":7200}`)
+		case "/open-apis/bot/v3/info":
+			if r.Header.Get("Authorization") != "Bearer t-123" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprint(w, `{"code":0,"bot":{"open_id":"ou_bot","app_name":"Mock Bot"}}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	client := NewClient("app_id", "app_secret")
+	client.SetBaseURL(srv.URL + "/")
+	if err := client.fetchBotOpenID(); err != nil {
+		t.Fatalf("fetchBotOpenID failed: %v", err)
+	}
+	if client.botOpenID != "ou_bot" {
+		t.Errorf("Expected the bot open_id from the custom base URL, got %q", client.botOpenID)
+	}
+}
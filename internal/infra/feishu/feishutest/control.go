@@ -0,0 +1,84 @@
+package feishutest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// serveControl serves the control API used by scripts driving the mock binary
+//
+//	POST /mock/messages         Inject a user message, body is an Inbound
+//	                            ("offline": true stores it without an event)
+//	POST /mock/events/{type}    Deliver a raw event, body is the event
+//	PUT  /mock/chats            Add or replace a chat, body is a Chat
+//	GET  /mock/sent             Messages sent by the bot
+//	GET  /mock/reactions        Reactions added by the bot
+//	POST /mock/reset            Forget all state
+func (s *Server) serveControl(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/mock/")
+	switch {
+	case path == "messages" && r.Method == http.MethodPost:
+		var in struct {
+			ChatID     string `json:"chat_id"`
+			ChatType   string `json:"chat_type"`
+			SenderID   string `json:"sender_id"`
+			Text       string `json:"text"`
+			MentionBot bool   `json:"mention_bot"`
+			ThreadID   string `json:"thread_id"`
+			ParentID   string `json:"parent_id"`
+			Offline    bool   `json:"offline"`
+		}
+		if !readJSON(w, r, &in) {
+			return
+		}
+		inbound := Inbound{
+			ChatID: in.ChatID, ChatType: in.ChatType, SenderID: in.SenderID, Text: in.Text,
+			MentionBot: in.MentionBot, ThreadID: in.ThreadID, ParentID: in.ParentID,
+		}
+		if in.Offline {
+			writeJSON(w, http.StatusOK, s.Post(inbound))
+			return
+		}
+		msg, err := s.Receive(inbound)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		writeJSON(w, http.StatusOK, msg)
+	case strings.HasPrefix(path, "events/") && r.Method == http.MethodPost:
+		var event json.RawMessage
+		if !readJSON(w, r, &event) {
+			return
+		}
+		if err := s.PostEvent(strings.TrimPrefix(path, "events/"), event); err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"success": true})
+	case path == "chats" && r.Method == http.MethodPut:
+		var chat Chat
+		if !readJSON(w, r, &chat) {
+			return
+		}
+		if chat.ChatID == "" {
+			http.Error(w, "chat_id is required", http.StatusBadRequest)
+			return
+		}
+		s.AddChat(chat)
+		writeJSON(w, http.StatusOK, map[string]interface{}{"success": true})
+	case path == "sent" && r.Method == http.MethodGet:
+		sent := s.Sent()
+		if sent == nil {
+			sent = []Message{}
+		}
+		writeJSON(w, http.StatusOK, sent)
+	case path == "reactions" && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, s.Reactions())
+	case path == "reset" && r.Method == http.MethodPost:
+		s.Reset()
+		writeJSON(w, http.StatusOK, map[string]interface{}{"success": true})
+	default:
+		http.NotFound(w, r)
+	}
+}
@@ -0,0 +1,131 @@
+package feishutest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Inbound is a message a user sends to a chat the bot is in
+type Inbound struct {
+	ChatID     string
+	ChatType   string // p2p or group, group if empty
+	SenderID   string // open_id of the user
+	Text       string
+	MentionBot bool   // Prefix the text with an @mention of the bot
+	ThreadID   string // Topic the message is posted in
+	ParentID   string // Message replied to
+}
+
+// Post stores a user's message without delivering an event, like a message
+// sent while the bridge was disconnected, and returns it
+func (s *Server) Post(in Inbound) Message {
+	text := in.Text
+	if in.MentionBot {
+		text = "@_user_1 " + text
+	}
+	content, _ := json.Marshal(map[string]string{"text": text})
+	msg := &Message{
+		ChatID:      in.ChatID,
+		ThreadID:    in.ThreadID,
+		ParentID:    in.ParentID,
+		MsgType:     "text",
+		Content:     string(content),
+		SenderID:    in.SenderID,
+		SenderType:  "user",
+		MentionsBot: in.MentionBot,
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if parent, ok := s.byID[in.ParentID]; ok {
+		msg.RootID = parent.RootID
+		if msg.RootID == "" {
+			msg.RootID = parent.MessageID
+		}
+	}
+	s.store(msg)
+	return *msg
+}
+
+// Receive stores a user's message and delivers it to the bridge as an
+// im.message.receive_v1 event, returning the message
+func (s *Server) Receive(in Inbound) (Message, error) {
+	if in.ChatType == "" {
+		in.ChatType = "group"
+	}
+	stored := s.Post(in)
+	var mentions []interface{}
+	if stored.MentionsBot {
+		s.mu.Lock()
+		mentions = append(mentions, map[string]interface{}{
+			"key":  "@_user_1",
+			"id":   map[string]string{"open_id": s.botOpenID},
+			"name": s.botName,
+		})
+		s.mu.Unlock()
+	}
+
+	event := map[string]interface{}{
+		"sender": map[string]interface{}{
+			"sender_id":   map[string]string{"open_id": in.SenderID},
+			"sender_type": "user",
+		},
+		"message": map[string]interface{}{
+			"message_id":   stored.MessageID,
+			"root_id":      stored.RootID,
+			"parent_id":    stored.ParentID,
+			"thread_id":    stored.ThreadID,
+			"chat_id":      stored.ChatID,
+			"chat_type":    in.ChatType,
+			"message_type": stored.MsgType,
+			"create_time":  strconv.FormatInt(stored.CreateTime.UnixMilli(), 10),
+			"content":      stored.Content,
+			"mentions":     mentions,
+		},
+	}
+	return stored, s.PostEvent("im.message.receive_v1", event)
+}
+
+// PostEvent delivers an event of any type to the bridge's webhook
+func (s *Server) PostEvent(eventType string, event interface{}) error {
+	s.mu.Lock()
+	webhookURL, token := s.webhookURL, s.verificationToken
+	s.nextID++
+	eventID := fmt.Sprintf("ev_mock_%d", s.nextID)
+	s.mu.Unlock()
+	if webhookURL == "" {
+		return fmt.Errorf("no webhook set")
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"schema": "2.0",
+		"header": map[string]interface{}{
+			"event_id":    eventID,
+			"event_type":  eventType,
+			"create_time": strconv.FormatInt(time.Now().UnixMilli(), 10),
+			"token":       token,
+			"app_id":      "cli_mock",
+			"tenant_key":  "mock_tenant",
+		},
+		"event": event,
+	})
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.httpClient.Post(webhookURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to deliver %s: %w", eventType, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		reply, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("webhook answered %s with %d: %s", eventType, resp.StatusCode, reply)
+	}
+	return nil
+}
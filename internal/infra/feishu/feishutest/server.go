@@ -0,0 +1,648 @@
+// Package feishutest is a fake Feishu Open Platform for end-to-end tests
+//
+// It serves the APIs the bridge calls, keeps chats and messages in memory and
+// delivers inbound events to the bridge's webhook endpoint. Point the bridge at
+// it with FEISHU_DOMAIN set to the server's URL and FEISHU_EVENT_MODE=webhook
+package feishutest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// TenantToken is the tenant_access_token handed out to every app
+	TenantToken = "t-mock-tenant-token"
+	// DefaultBotOpenID is the open_id of the bot until SetBot changes it
+	DefaultBotOpenID = "ou_mock_bot"
+
+	// codeInvalidToken answers API calls without the tenant token
+	codeInvalidToken = 99991663
+	// codeNotFound answers unknown message and chat IDs
+	codeNotFound = 230001
+)
+
+// Member is a user in a chat
+type Member struct {
+	OpenID string `json:"open_id"`
+	Name   string `json:"name"`
+}
+
+// Chat is a chat known to the server
+type Chat struct {
+	ChatID  string   `json:"chat_id"`
+	Name    string   `json:"name"`
+	Mode    string   `json:"mode"` // p2p, group or topic
+	OwnerID string   `json:"owner_id"`
+	Members []Member `json:"members"`
+}
+
+// Message is a message received from a user or sent by the bot
+type Message struct {
+	MessageID   string    `json:"message_id"`
+	ChatID      string    `json:"chat_id"`
+	ThreadID    string    `json:"thread_id,omitempty"`
+	ParentID    string    `json:"parent_id,omitempty"`
+	RootID      string    `json:"root_id,omitempty"`
+	MsgType     string    `json:"msg_type"`
+	Content     string    `json:"content"` // Content JSON as sent to the API
+	SenderID    string    `json:"sender_id"`
+	SenderType  string    `json:"sender_type"` // user, or app for the bot's messages
+	CreateTime  time.Time `json:"create_time"`
+	UUID        string    `json:"uuid,omitempty"`         // Request uuid of the bot's sends
+	Updates     int       `json:"updates,omitempty"`      // Times the card was patched
+	Deleted     bool      `json:"deleted,omitempty"`      // Recalled
+	MentionsBot bool      `json:"mentions_bot,omitempty"` // Text starts with an @mention of the bot
+}
+
+// Text returns the text of a text or rich text message, the raw content otherwise
+func (m *Message) Text() string {
+	switch m.MsgType {
+	case "text":
+		var content struct {
+			Text string `json:"text"`
+		}
+		if json.Unmarshal([]byte(m.Content), &content) == nil {
+			return content.Text
+		}
+	case "post":
+		var content map[string]struct {
+			Title   string                     `json:"title"`
+			Content [][]map[string]interface{} `json:"content"`
+		}
+		if json.Unmarshal([]byte(m.Content), &content) == nil {
+			var lines []string
+			for _, post := range content {
+				if post.Title != "" {
+					lines = append(lines, post.Title)
+				}
+				for _, paragraph := range post.Content {
+					var line strings.Builder
+					for _, el := range paragraph {
+						if text, ok := el["text"].(string); ok {
+							line.WriteString(text)
+						}
+					}
+					lines = append(lines, line.String())
+				}
+			}
+			return strings.Join(lines, "\n")
+		}
+	}
+	return m.Content
+}
+
+// Reaction is an emoji reaction the bot added
+type Reaction struct {
+	ReactionID string `json:"reaction_id"`
+	MessageID  string `json:"message_id"`
+	EmojiType  string `json:"emoji_type"`
+	Deleted    bool   `json:"deleted,omitempty"`
+}
+
+// failure is an injected API error
+type failure struct {
+	method string
+	path   string
+	code   int
+	times  int
+}
+
+// Server is the fake open platform, an http.Handler
+type Server struct {
+	mu        sync.Mutex
+	botOpenID string
+	botName   string
+
+	// Bridge webhook events are delivered to
+	webhookURL        string
+	verificationToken string
+
+	chats     map[string]*Chat
+	messages  []*Message // Oldest first
+	byID      map[string]*Message
+	byUUID    map[string]*Message
+	reactions []*Reaction
+	resources map[string][]byte // Image and file keys to content
+	failures  []*failure
+	nextID    int
+
+	httpClient *http.Client
+}
+
+// New creates an empty server
+func New() *Server {
+	s := &Server{
+		botOpenID:  DefaultBotOpenID,
+		botName:    "Mock Bot",
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+	s.Reset()
+	return s
+}
+
+// Reset forgets all chats, messages, reactions, resources and injected errors
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.chats = make(map[string]*Chat)
+	s.messages = nil
+	s.byID = make(map[string]*Message)
+	s.byUUID = make(map[string]*Message)
+	s.reactions = nil
+	s.resources = make(map[string][]byte)
+	s.failures = nil
+}
+
+// SetBot sets the bot's open_id and name
+func (s *Server) SetBot(openID, name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.botOpenID, s.botName = openID, name
+}
+
+// SetWebhook sets where inbound events are delivered, the bridge's event subscription URL
+func (s *Server) SetWebhook(url, verificationToken string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.webhookURL, s.verificationToken = url, verificationToken
+}
+
+// AddChat adds or replaces a chat
+func (s *Server) AddChat(chat Chat) {
+	if chat.Mode == "" {
+		chat.Mode = "group"
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.chats[chat.ChatID] = &chat
+}
+
+// AddResource stores an image or file that messages can reference by key
+func (s *Server) AddResource(key string, data []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.resources[key] = data
+}
+
+// FailNext makes the next times calls of method on path fail with code
+// path may be a prefix, e.g. "/open-apis/im/v1/messages". Code 99991400 is
+// answered with HTTP 429 like Feishu's frequency control
+func (s *Server) FailNext(method, path string, code, times int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = append(s.failures, &failure{method: method, path: path, code: code, times: times})
+}
+
+// Sent returns the messages sent by the bot, oldest first
+func (s *Server) Sent() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var sent []Message
+	for _, msg := range s.messages {
+		if msg.SenderType == "app" {
+			sent = append(sent, *msg)
+		}
+	}
+	return sent
+}
+
+// Reactions returns the reactions the bot added, oldest first
+func (s *Server) Reactions() []Reaction {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	reactions := make([]Reaction, 0, len(s.reactions))
+	for _, r := range s.reactions {
+		reactions = append(reactions, *r)
+	}
+	return reactions
+}
+
+// WaitSent waits until the bot has sent a message matching match, nil matches any
+func (s *Server) WaitSent(timeout time.Duration, match func(Message) bool) (Message, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		for _, msg := range s.Sent() {
+			if match == nil || match(msg) {
+				return msg, nil
+			}
+		}
+		if time.Now().After(deadline) {
+			return Message{}, fmt.Errorf("no matching message sent within %v", timeout)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// ServeHTTP serves the open platform APIs and the control API under /mock/
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Path
+	if strings.HasPrefix(path, "/mock/") {
+		s.serveControl(w, r)
+		return
+	}
+
+	switch path {
+	case "/open-apis/auth/v3/tenant_access_token/internal", "/open-apis/auth/v3/app_access_token/internal":
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"code": 0, "msg": "ok", "expire": 7200,
+			"tenant_access_token": TenantToken, "app_access_token": TenantToken,
+		})
+		return
+	}
+
+	if r.Header.Get("Authorization") != "Bearer "+TenantToken {
+		writeError(w, http.StatusUnauthorized, codeInvalidToken, "invalid access token")
+		return
+	}
+	if code, ok := s.takeFailure(r); ok {
+		status := http.StatusBadRequest
+		if code == 99991400 {
+			status = http.StatusTooManyRequests
+		}
+		writeError(w, status, code, "injected error")
+		return
+	}
+
+	parts := strings.Split(strings.TrimPrefix(path, "/open-apis/"), "/")
+	switch {
+	case path == "/open-apis/bot/v3/info" && r.Method == http.MethodGet:
+		s.mu.Lock()
+		bot := map[string]interface{}{"open_id": s.botOpenID, "app_name": s.botName}
+		s.mu.Unlock()
+		writeJSON(w, http.StatusOK, map[string]interface{}{"code": 0, "msg": "ok", "bot": bot})
+	case len(parts) >= 3 && parts[0] == "im" && parts[2] == "messages":
+		s.serveMessages(w, r, parts[3:])
+	case len(parts) >= 4 && parts[0] == "im" && parts[2] == "chats":
+		s.serveChat(w, r, parts[3], parts[4:])
+	case len(parts) == 3 && parts[0] == "im" && (parts[2] == "images" || parts[2] == "files") && r.Method == http.MethodPost:
+		s.serveUpload(w, r, strings.TrimSuffix(parts[2], "s"))
+	case len(parts) == 4 && parts[0] == "contact" && parts[2] == "users" && r.Method == http.MethodGet:
+		s.serveUser(w, parts[3])
+	default:
+		writeError(w, http.StatusNotFound, codeNotFound, "unknown API "+r.Method+" "+path)
+	}
+}
+
+// takeFailure returns the code of an injected error matching r
+func (s *Server) takeFailure(r *http.Request) (int, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, f := range s.failures {
+		if f.method == r.Method && strings.HasPrefix(r.URL.Path, f.path) {
+			if f.times--; f.times <= 0 {
+				s.failures = append(s.failures[:i], s.failures[i+1:]...)
+			}
+			return f.code, true
+		}
+	}
+	return 0, false
+}
+
+// serveMessages serves /im/v1/messages and the APIs of a single message
+func (s *Server) serveMessages(w http.ResponseWriter, r *http.Request, rest []string) {
+	switch {
+	case len(rest) == 0 && r.Method == http.MethodPost:
+		var body sendBody
+		if !readJSON(w, r, &body) {
+			return
+		}
+		s.mu.Lock()
+		_, known := s.chats[body.ReceiveID]
+		s.mu.Unlock()
+		if r.URL.Query().Get("receive_id_type") != "chat_id" || !known && !strings.HasPrefix(body.ReceiveID, "oc_") {
+			writeError(w, http.StatusBadRequest, codeNotFound, "unknown receiver "+body.ReceiveID)
+			return
+		}
+		msg := s.send(&Message{ChatID: body.ReceiveID, MsgType: body.MsgType, Content: body.Content}, body.UUID)
+		s.writeMessage(w, msg)
+	case len(rest) == 0 && r.Method == http.MethodGet:
+		s.listMessages(w, r)
+	case len(rest) == 2 && rest[1] == "reply" && r.Method == http.MethodPost:
+		var body sendBody
+		if !readJSON(w, r, &body) {
+			return
+		}
+		parent := s.message(rest[0])
+		if parent == nil {
+			writeError(w, http.StatusBadRequest, codeNotFound, "unknown message "+rest[0])
+			return
+		}
+		reply := &Message{ChatID: parent.ChatID, MsgType: body.MsgType, Content: body.Content, ParentID: parent.MessageID, RootID: parent.RootID}
+		if reply.RootID == "" {
+			reply.RootID = parent.MessageID
+		}
+		if parent.ThreadID != "" || body.ReplyInThread {
+			reply.ThreadID = parent.ThreadID
+			if reply.ThreadID == "" {
+				reply.ThreadID = "omt_" + parent.MessageID
+			}
+		}
+		s.writeMessage(w, s.send(reply, body.UUID))
+	case len(rest) == 1 && r.Method == http.MethodGet:
+		msg := s.message(rest[0])
+		if msg == nil {
+			writeError(w, http.StatusBadRequest, codeNotFound, "unknown message "+rest[0])
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"code": 0, "msg": "ok", "data": map[string]interface{}{
+			"items": []interface{}{s.messageJSON(msg)},
+		}})
+	case len(rest) == 1 && (r.Method == http.MethodPatch || r.Method == http.MethodPut):
+		var body struct {
+			Content string `json:"content"`
+		}
+		if !readJSON(w, r, &body) {
+			return
+		}
+		s.mu.Lock()
+		msg := s.byID[rest[0]]
+		if msg != nil {
+			msg.Content = body.Content
+			msg.Updates++
+		}
+		s.mu.Unlock()
+		if msg == nil {
+			writeError(w, http.StatusBadRequest, codeNotFound, "unknown message "+rest[0])
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"code": 0, "msg": "ok", "data": map[string]interface{}{}})
+	case len(rest) == 1 && r.Method == http.MethodDelete:
+		s.mu.Lock()
+		msg := s.byID[rest[0]]
+		if msg != nil {
+			msg.Deleted = true
+		}
+		s.mu.Unlock()
+		if msg == nil {
+			writeError(w, http.StatusBadRequest, codeNotFound, "unknown message "+rest[0])
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"code": 0, "msg": "ok", "data": map[string]interface{}{}})
+	case len(rest) >= 2 && rest[1] == "reactions":
+		s.serveReactions(w, r, rest[0], rest[2:])
+	case len(rest) == 3 && rest[1] == "resources" && r.Method == http.MethodGet:
+		s.mu.Lock()
+		data, ok := s.resources[rest[2]]
+		s.mu.Unlock()
+		if !ok {
+			writeError(w, http.StatusBadRequest, codeNotFound, "unknown resource "+rest[2])
+			return
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", rest[2]))
+		_, _ = w.Write(data)
+	default:
+		writeError(w, http.StatusNotFound, codeNotFound, "unknown API "+r.Method+" "+r.URL.Path)
+	}
+}
+
+// sendBody is the body of message create and reply requests
+type sendBody struct {
+	ReceiveID     string `json:"receive_id"`
+	MsgType       string `json:"msg_type"`
+	Content       string `json:"content"`
+	UUID          string `json:"uuid"`
+	ReplyInThread bool   `json:"reply_in_thread"`
+}
+
+// send stores a message from the bot, a repeated uuid returns the first message
+func (s *Server) send(msg *Message, uuid string) *Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if existing, ok := s.byUUID[uuid]; ok && uuid != "" {
+		return existing
+	}
+	msg.SenderID = s.botOpenID
+	msg.SenderType = "app"
+	msg.UUID = uuid
+	s.store(msg)
+	if uuid != "" {
+		s.byUUID[uuid] = msg
+	}
+	return msg
+}
+
+// store assigns the message an ID and creation time, the caller holds mu
+func (s *Server) store(msg *Message) {
+	s.nextID++
+	msg.MessageID = fmt.Sprintf("om_mock_%d", s.nextID)
+	if msg.CreateTime.IsZero() {
+		msg.CreateTime = time.Now()
+	}
+	s.messages = append(s.messages, msg)
+	s.byID[msg.MessageID] = msg
+}
+
+func (s *Server) message(id string) *Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.byID[id]
+}
+
+// listMessages lists a chat's or thread's messages, one page of the newest or oldest
+func (s *Server) listMessages(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	containerType, containerID := query.Get("container_id_type"), query.Get("container_id")
+	pageSize, _ := strconv.Atoi(query.Get("page_size"))
+	if pageSize <= 0 {
+		pageSize = 20
+	}
+
+	s.mu.Lock()
+	var matched []*Message
+	for _, msg := range s.messages {
+		if msg.Deleted {
+			continue
+		}
+		if containerType == "thread" && msg.ThreadID == containerID || containerType != "thread" && msg.ChatID == containerID {
+			matched = append(matched, msg)
+		}
+	}
+	s.mu.Unlock()
+
+	if query.Get("sort_type") == "ByCreateTimeDesc" {
+		for i, j := 0, len(matched)-1; i < j; i, j = i+1, j-1 {
+			matched[i], matched[j] = matched[j], matched[i]
+		}
+	}
+	hasMore := len(matched) > pageSize
+	if hasMore {
+		matched = matched[:pageSize]
+	}
+	items := make([]interface{}, 0, len(matched))
+	for _, msg := range matched {
+		items = append(items, s.messageJSON(msg))
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"code": 0, "msg": "ok", "data": map[string]interface{}{
+		"has_more": hasMore, "page_token": "", "items": items,
+	}})
+}
+
+// serveReactions adds and removes reactions of a message
+func (s *Server) serveReactions(w http.ResponseWriter, r *http.Request, msgID string, rest []string) {
+	if s.message(msgID) == nil {
+		writeError(w, http.StatusBadRequest, codeNotFound, "unknown message "+msgID)
+		return
+	}
+	switch {
+	case len(rest) == 0 && r.Method == http.MethodPost:
+		var body struct {
+			ReactionType struct {
+				EmojiType string `json:"emoji_type"`
+			} `json:"reaction_type"`
+		}
+		if !readJSON(w, r, &body) {
+			return
+		}
+		s.mu.Lock()
+		s.nextID++
+		reaction := &Reaction{ReactionID: fmt.Sprintf("r_mock_%d", s.nextID), MessageID: msgID, EmojiType: body.ReactionType.EmojiType}
+		s.reactions = append(s.reactions, reaction)
+		s.mu.Unlock()
+		writeJSON(w, http.StatusOK, map[string]interface{}{"code": 0, "msg": "ok", "data": map[string]interface{}{
+			"reaction_id":   reaction.ReactionID,
+			"reaction_type": map[string]string{"emoji_type": reaction.EmojiType},
+		}})
+	case len(rest) == 1 && r.Method == http.MethodDelete:
+		s.mu.Lock()
+		for _, reaction := range s.reactions {
+			if reaction.ReactionID == rest[0] {
+				reaction.Deleted = true
+			}
+		}
+		s.mu.Unlock()
+		writeJSON(w, http.StatusOK, map[string]interface{}{"code": 0, "msg": "ok", "data": map[string]interface{}{"reaction_id": rest[0]}})
+	default:
+		writeError(w, http.StatusNotFound, codeNotFound, "unknown API "+r.Method+" "+r.URL.Path)
+	}
+}
+
+// serveChat serves chat info and members
+func (s *Server) serveChat(w http.ResponseWriter, r *http.Request, chatID string, rest []string) {
+	s.mu.Lock()
+	chat, ok := s.chats[chatID]
+	if ok {
+		copied := *chat
+		chat = &copied
+	}
+	s.mu.Unlock()
+	if !ok || r.Method != http.MethodGet {
+		writeError(w, http.StatusBadRequest, codeNotFound, "unknown chat "+chatID)
+		return
+	}
+
+	switch {
+	case len(rest) == 0:
+		writeJSON(w, http.StatusOK, map[string]interface{}{"code": 0, "msg": "ok", "data": map[string]interface{}{
+			"name":       chat.Name,
+			"chat_mode":  chat.Mode,
+			"owner_id":   chat.OwnerID,
+			"user_count": strconv.Itoa(len(chat.Members)),
+		}})
+	case len(rest) == 1 && rest[0] == "members":
+		items := make([]interface{}, 0, len(chat.Members))
+		for _, m := range chat.Members {
+			items = append(items, map[string]string{"member_id_type": "open_id", "member_id": m.OpenID, "name": m.Name})
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"code": 0, "msg": "ok", "data": map[string]interface{}{
+			"items": items, "page_token": "", "has_more": false, "member_total": len(items),
+		}})
+	default:
+		writeError(w, http.StatusNotFound, codeNotFound, "unknown API "+r.Method+" "+r.URL.Path)
+	}
+}
+
+// serveUpload stores an uploaded image or file and returns its key
+func (s *Server) serveUpload(w http.ResponseWriter, r *http.Request, kind string) {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeNotFound, err.Error())
+		return
+	}
+	s.mu.Lock()
+	s.nextID++
+	key := fmt.Sprintf("%s_mock_%d", kind, s.nextID)
+	s.resources[key] = data
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]interface{}{"code": 0, "msg": "ok", "data": map[string]string{kind + "_key": key}})
+}
+
+// serveUser answers contact lookups from the members of known chats
+func (s *Server) serveUser(w http.ResponseWriter, openID string) {
+	s.mu.Lock()
+	var member *Member
+	for _, chat := range s.chats {
+		for i := range chat.Members {
+			if chat.Members[i].OpenID == openID {
+				member = &chat.Members[i]
+			}
+		}
+	}
+	s.mu.Unlock()
+	if member == nil {
+		writeError(w, http.StatusBadRequest, codeNotFound, "unknown user "+openID)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"code": 0, "msg": "ok", "data": map[string]interface{}{
+		"user": map[string]interface{}{"open_id": member.OpenID, "union_id": "on_" + strings.TrimPrefix(member.OpenID, "ou_"), "name": member.Name},
+	}})
+}
+
+func (s *Server) writeMessage(w http.ResponseWriter, msg *Message) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{"code": 0, "msg": "ok", "data": s.messageJSON(msg)})
+}
+
+// messageJSON renders a message the way the IM API returns it
+func (s *Server) messageJSON(msg *Message) map[string]interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	idType := "open_id"
+	if msg.SenderType == "app" {
+		idType = "app_id"
+	}
+	mentions := []interface{}{}
+	if msg.MentionsBot {
+		mentions = append(mentions, map[string]string{
+			"key": "@_user_1", "id": s.botOpenID, "id_type": "open_id", "name": s.botName,
+		})
+	}
+	return map[string]interface{}{
+		"message_id":  msg.MessageID,
+		"root_id":     msg.RootID,
+		"parent_id":   msg.ParentID,
+		"thread_id":   msg.ThreadID,
+		"msg_type":    msg.MsgType,
+		"create_time": strconv.FormatInt(msg.CreateTime.UnixMilli(), 10),
+		"update_time": strconv.FormatInt(msg.CreateTime.UnixMilli(), 10),
+		"deleted":     msg.Deleted,
+		"chat_id":     msg.ChatID,
+		"sender":      map[string]string{"id": msg.SenderID, "id_type": idType, "sender_type": msg.SenderType},
+		"body":        map[string]string{"content": msg.Content},
+		"mentions":    mentions,
+	}
+}
+
+func readJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, codeNotFound, "invalid body: "+err.Error())
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status, code int, msg string) {
+	writeJSON(w, status, map[string]interface{}{"code": code, "msg": msg})
+}
@@ -0,0 +1,349 @@
+package feishu
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	larkim "github.com/larksuite/oapi-sdk-go/v3/service/im/v1"
+)
+
+// Attachment is a file sent with a message, downloaded with DownloadFile
+type Attachment struct {
+	Key  string // file_key for the message resource API
+	Name string // File name to save as
+	Kind string // file, audio or media
+}
+
+// parseContent extracts text, images and attachments from a message
+// Types without a text form are described in brackets, e.g. "[File: report.pdf]".
+// Returns false for unsupported types
+func (c *Client) parseContent(msgType, content string, mentionMap map[string]string) (string, []string, []Attachment, bool) {
+	switch msgType {
+	case "text":
+		return c.parseTextContent(content, mentionMap), nil, nil, true
+	case "image":
+		return "[Image]", c.parseImageContent(content), nil, true
+	case "post":
+		text, imageKeys := c.parsePostContent(content, mentionMap)
+		return text, imageKeys, nil, true
+	}
+
+	var parsed struct {
+		FileKey  string `json:"file_key"`
+		FileName string `json:"file_name"`
+		Duration int64  `json:"duration"` // Milliseconds
+		ChatID   string `json:"chat_id"`
+	}
+	if msgType != "interactive" {
+		if err := json.Unmarshal([]byte(content), &parsed); err != nil {
+			return "", nil, nil, false
+		}
+	}
+
+	switch msgType {
+	case "file":
+		name := attachmentName(parsed.FileName, parsed.FileKey, "")
+		return "[File: " + name + "]", nil, []Attachment{{Key: parsed.FileKey, Name: name, Kind: "file"}}, true
+	case "audio":
+		name := attachmentName("", parsed.FileKey, ".opus")
+		text := "[Audio" + formatDuration(parsed.Duration) + "]"
+		return text, nil, []Attachment{{Key: parsed.FileKey, Name: name, Kind: "audio"}}, true
+	case "media":
+		name := attachmentName(parsed.FileName, parsed.FileKey, ".mp4")
+		text := "[Video: " + name + formatDuration(parsed.Duration) + "]"
+		return text, nil, []Attachment{{Key: parsed.FileKey, Name: name, Kind: "media"}}, true
+	case "sticker":
+		// Stickers cannot be downloaded through the resource API
+		return "[Sticker]", nil, nil, true
+	case "share_chat":
+		return "[Shared chat: " + parsed.ChatID + "]", nil, nil, true
+	case "interactive":
+		text := cardText(content)
+		if text == "" {
+			return "[Card]", nil, nil, true
+		}
+		return "[Card]\n" + replaceMentions(text, mentionMap), nil, nil, true
+	}
+	return "", nil, nil, false
+}
+
+// attachmentName picks a safe file name, falling back to the file key
+func attachmentName(name, key, ext string) string {
+	name = filepath.Base(strings.TrimSpace(name))
+	if name == "" || name == "." || name == ".." || name == "/" {
+		name = key + ext
+	}
+	return name
+}
+
+// formatDuration formats a millisecond duration as ", 12s"
+func formatDuration(ms int64) string {
+	if ms <= 0 {
+		return ""
+	}
+	return ", " + (time.Duration(ms) * time.Millisecond).Round(time.Second).String()
+}
+
+// cardText collects the readable text of a card, in document order
+// Received cards are simplified by Feishu, the text is in title/text/content fields
+func cardText(content string) string {
+	var card interface{}
+	if err := json.Unmarshal([]byte(content), &card); err != nil {
+		return ""
+	}
+	var lines []string
+	var line []string
+	flush := func() {
+		if len(line) > 0 {
+			lines = append(lines, strings.Join(line, " "))
+			line = nil
+		}
+	}
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			// Map order is random, visit the text fields first and the rest sorted
+			for _, key := range []string{"title", "text", "content"} {
+				if s, ok := v[key].(string); ok && strings.TrimSpace(s) != "" {
+					line = append(line, strings.TrimSpace(s))
+				}
+			}
+			keys := make([]string, 0, len(v))
+			for key := range v {
+				switch v[key].(type) {
+				case map[string]interface{}, []interface{}:
+					keys = append(keys, key)
+				}
+			}
+			if len(keys) == 0 {
+				return
+			}
+			// A container's own text (e.g. a title) is a line of its own
+			flush()
+			sort.Strings(keys)
+			for _, key := range keys {
+				walk(v[key])
+			}
+		case []interface{}:
+			for _, item := range v {
+				walk(item)
+				// Card rows are arrays of elements, end a line after each row
+				if _, ok := item.([]interface{}); ok {
+					flush()
+				}
+			}
+		}
+	}
+	walk(card)
+	flush()
+	return strings.Join(lines, "\n")
+}
+
+// GetMessage fetches a message by ID, e.g. the parent of a quote reply
+// Unlike received messages, the bot's own messages are returned too
+func (c *Client) GetMessage(messageID string) (*Message, error) {
+	req := larkim.NewGetMessageReqBuilder().
+		MessageId(messageID).
+		Build()
+
+	resp, err := c.larkCli.Im.Message.Get(context.Background(), req)
+	if err != nil {
+		return nil, fmt.Errorf("get message failed: %w", err)
+	}
+	if !resp.Success() {
+		return nil, fmt.Errorf("get message error: %s", resp.Msg)
+	}
+	if resp.Data == nil {
+		return nil, fmt.Errorf("get message returned no data")
+	}
+	for _, item := range resp.Data.Items {
+		if stringValue(item.MessageId) == messageID {
+			return c.parseFetchedMessage(item), nil
+		}
+	}
+	return nil, fmt.Errorf("message %s not found", messageID)
+}
+
+// parseFetchedMessage converts a message returned by the message API
+func (c *Client) parseFetchedMessage(item *larkim.Message) *Message {
+	msg := &Message{
+		ChatID:   stringValue(item.ChatId),
+		MsgID:    stringValue(item.MessageId),
+		MsgType:  stringValue(item.MsgType),
+		ThreadID: stringValue(item.ThreadId),
+		ParentID: stringValue(item.ParentId),
+		RootID:   stringValue(item.RootId),
+	}
+	if ms, err := strconv.ParseInt(stringValue(item.CreateTime), 10, 64); err == nil {
+		msg.CreateTime = ms
+	}
+	if item.Sender != nil {
+		msg.Sender = &Sender{
+			SenderID:   stringValue(item.Sender.Id),
+			SenderType: stringValue(item.Sender.SenderType),
+			TenantKey:  stringValue(item.Sender.TenantKey),
+		}
+	}
+
+	msg.MentionMap = make(map[string]string)
+	for _, mention := range item.Mentions {
+		if mention.Key != nil && mention.Name != nil {
+			msg.MentionMap[*mention.Key] = *mention.Name
+		}
+		// Fetched messages name mentions by open_id
+		if openID := stringValue(mention.Id); openID != "" {
+			msg.Mentions = append(msg.Mentions, openID)
+			msg.MentionsBot = msg.MentionsBot || (c.botOpenID != "" && openID == c.botOpenID)
+		}
+	}
+
+	content := ""
+	if item.Body != nil {
+		content = stringValue(item.Body.Content)
+	}
+	switch {
+	case item.Deleted != nil && *item.Deleted:
+		msg.Content = "[Recalled message]"
+		msg.Recalled = true
+	case msg.MsgType == "merge_forward":
+		transcript, err := c.expandMergeForward(msg.ChatID, msg.MsgID)
+		if err != nil {
+			fmt.Printf("[Feishu] Failed to expand forwarded messages %s: %v\n", msg.MsgID, err)
+			transcript = "[Forwarded chat record]"
+		}
+		msg.Content = transcript
+	default:
+		text, imageKeys, attachments, ok := c.parseContent(msg.MsgType, content, msg.MentionMap)
+		if !ok {
+			text = "[" + msg.MsgType + "]"
+		}
+		msg.Content = text
+		msg.ImageKeys = imageKeys
+		msg.Attachments = attachments
+	}
+	return msg
+}
+
+// SenderNameFunc returns the display name of a user seen in a chat, empty when unknown
+type SenderNameFunc func(chatID, openID string) string
+
+// SetSenderNames sets how senders of forwarded chat records are named
+// Forwarded messages only carry open_ids, which are shown when the lookup fails
+func (c *Client) SetSenderNames(names SenderNameFunc) {
+	c.senderNames = names
+}
+
+// expandMergeForward fetches a merge-forward record posted in chatID and formats it as a transcript
+func (c *Client) expandMergeForward(chatID, messageID string) (string, error) {
+	req := larkim.NewGetMessageReqBuilder().
+		MessageId(messageID).
+		Build()
+
+	resp, err := c.larkCli.Im.Message.Get(context.Background(), req)
+	if err != nil {
+		return "", fmt.Errorf("get merged messages failed: %w", err)
+	}
+	if !resp.Success() {
+		return "", fmt.Errorf("get merged messages error: %s", resp.Msg)
+	}
+	if resp.Data == nil {
+		return "", fmt.Errorf("get merged messages returned no data")
+	}
+	return c.formatForwarded(chatID, messageID, resp.Data.Items), nil
+}
+
+// formatForwarded renders the messages under rootID as an indented transcript
+// Nested merge-forward records are expanded in place
+func (c *Client) formatForwarded(chatID, rootID string, items []*larkim.Message) string {
+	children := make(map[string][]*larkim.Message)
+	for _, item := range items {
+		if item.UpperMessageId != nil && item.MessageId != nil && *item.MessageId != rootID {
+			children[*item.UpperMessageId] = append(children[*item.UpperMessageId], item)
+		}
+	}
+
+	names := make(map[string]string) // Senders looked up once per transcript
+	var sb strings.Builder
+	sb.WriteString("[Forwarded chat record]")
+	var render func(parentID, indent string)
+	render = func(parentID, indent string) {
+		for _, item := range children[parentID] {
+			msgType := stringValue(item.MsgType)
+			sender := c.forwardedSender(chatID, item.Sender, names)
+			when := ""
+			if ms, err := strconv.ParseInt(stringValue(item.CreateTime), 10, 64); err == nil {
+				when = time.UnixMilli(ms).Format("01-02 15:04") + " "
+			}
+
+			var text string
+			if msgType == "merge_forward" {
+				text = "[Forwarded chat record]"
+			} else {
+				mentionMap := make(map[string]string)
+				for _, mention := range item.Mentions {
+					if mention.Key != nil && mention.Name != nil {
+						mentionMap[*mention.Key] = *mention.Name
+					}
+				}
+				content := ""
+				if item.Body != nil {
+					content = stringValue(item.Body.Content)
+				}
+				var ok bool
+				text, _, _, ok = c.parseContent(msgType, content, mentionMap)
+				if !ok {
+					text = "[" + msgType + "]"
+				}
+			}
+
+			lines := strings.Split(text, "\n")
+			fmt.Fprintf(&sb, "\n%s%s%s: %s", indent, when, sender, lines[0])
+			for _, line := range lines[1:] {
+				sb.WriteString("\n" + indent + "  " + line)
+			}
+			if msgType == "merge_forward" && item.MessageId != nil {
+				render(*item.MessageId, indent+"  ")
+			}
+		}
+	}
+	render(rootID, "")
+	return sb.String()
+}
+
+// forwardedSender names the sender of a forwarded message, falling back to its id
+func (c *Client) forwardedSender(chatID string, sender *larkim.Sender, names map[string]string) string {
+	id := ""
+	if sender != nil {
+		id = stringValue(sender.Id)
+	}
+	if id == "" {
+		return "unknown"
+	}
+	// Apps are named by app_id, only users can be looked up
+	if c.senderNames == nil || stringValue(sender.SenderType) == "app" {
+		return id
+	}
+	name, ok := names[id]
+	if !ok {
+		name = c.senderNames(chatID, id)
+		names[id] = name
+	}
+	if name == "" {
+		return id
+	}
+	return name
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,134 @@
+package feishu
+
+import (
+	"testing"
+
+	larkim "github.com/larksuite/oapi-sdk-go/v3/service/im/v1"
+)
+
+func TestParseContentTypes(t *testing.T) {
+	c := &Client{}
+	tests := []struct {
+		msgType     string
+		content     string
+		text        string
+		attachments int
+	}{
+		{"file", `{"file_key":"file_v3_1","file_name":"app.log"}`, "[File: app.log]", 1},
+		{"file", `{"file_key":"file_v3_1","file_name":"../../etc/passwd"}`, "[File: passwd]", 1},
+		{"audio", `{"file_key":"file_v3_2","duration":4600}`, "[Audio, 5s]", 1},
+		{"media", `{"file_key":"file_v3_3","image_key":"img_1","file_name":"demo.mp4","duration":12000}`, "[Video: demo.mp4, 12s]", 1},
+		{"sticker", `{"file_key":"file_v3_4"}`, "[Sticker]", 0},
+		{"share_chat", `{"chat_id":"oc_123"}`, "[Shared chat: oc_123]", 0},
+		{"interactive", `{"title":"Deploy failed","elements":[[{"tag":"text","text":"Service"},{"tag":"text","text":"api"}],[{"tag":"a","text":"logs","href":"https://x.io"}]]}`, "[Card]\nDeploy failed\nService api\nlogs", 0},
+	}
+	for _, tt := range tests {
+		text, _, attachments, ok := c.parseContent(tt.msgType, tt.content, nil)
+		if !ok {
+			t.Errorf("%s: not supported", tt.msgType)
+			continue
+		}
+		if text != tt.text {
+			t.Errorf("%s: text = %q, want %q", tt.msgType, text, tt.text)
+		}
+		if len(attachments) != tt.attachments {
+			t.Errorf("%s: %d attachments, want %d", tt.msgType, len(attachments), tt.attachments)
+		}
+	}
+
+	if _, _, _, ok := c.parseContent("hongbao", `{}`, nil); ok {
+		t.Error("Expected unknown types to be unsupported")
+	}
+}
+
+func TestFormatForwarded(t *testing.T) {
+	str := func(s string) *string { return &s }
+	item := func(id, upper, sender, msgType, content string) *larkim.Message {
+		return &larkim.Message{
+			MessageId:      str(id),
+			UpperMessageId: str(upper),
+			MsgType:        str(msgType),
+			Sender:         &larkim.Sender{Id: str(sender)},
+			Body:           &larkim.MessageBody{Content: str(content)},
+		}
+	}
+
+	items := []*larkim.Message{
+		{MessageId: str("om_root"), MsgType: str("merge_forward")},
+		item("om_1", "om_root", "ou_alice", "text", `{"text":"the build is red"}`),
+		item("om_2", "om_root", "ou_bob", "merge_forward", `{}`),
+		item("om_3", "om_2", "ou_carol", "file", `{"file_key":"f","file_name":"ci.log"}`),
+		item("om_4", "om_root", "ou_bob", "post", `{"title":"Fix","content":[[{"tag":"text","text":"reverted"}]]}`),
+	}
+
+	got := (&Client{}).formatForwarded("oc_1", "om_root", items)
+	want := "[Forwarded chat record]\n" +
+		"ou_alice: the build is red\n" +
+		"ou_bob: [Forwarded chat record]\n" +
+		"  ou_carol: [File: ci.log]\n" +
+		"ou_bob: Fix\n" +
+		"  reverted"
+	if got != want {
+		t.Errorf("transcript =\n%s\nwant\n%s", got, want)
+	}
+
+	// Senders are looked up once each, unknown ones keep their open_id
+	lookups := make(map[string]int)
+	c := &Client{}
+	c.SetSenderNames(func(chatID, openID string) string {
+		lookups[openID]++
+		if chatID != "oc_1" {
+			return ""
+		}
+		return map[string]string{"ou_alice": "Alice", "ou_bob": "Bob"}[openID]
+	})
+	got = c.formatForwarded("oc_1", "om_root", items)
+	want = "[Forwarded chat record]\n" +
+		"Alice: the build is red\n" +
+		"Bob: [Forwarded chat record]\n" +
+		"  ou_carol: [File: ci.log]\n" +
+		"Bob: Fix\n" +
+		"  reverted"
+	if got != want {
+		t.Errorf("transcript =\n%s\nwant\n%s", got, want)
+	}
+	if lookups["ou_bob"] != 1 || lookups["ou_carol"] != 1 {
+		t.Errorf("Expected one lookup per sender, got %v", lookups)
+	}
+}
+
+func TestParseFetchedMessage(t *testing.T) {
+	c := &Client{}
+	str := func(s string) *string { return &s }
+	deleted := true
+
+	msg := c.parseFetchedMessage(&larkim.Message{
+		MessageId:  str("om_2"),
+		ChatId:     str("oc_1"),
+		ParentId:   str("om_1"),
+		RootId:     str("om_1"),
+		MsgType:    str("file"),
+		CreateTime: str("1700000000000"),
+		Sender:     &larkim.Sender{Id: str("ou_alice"), SenderType: str("user")},
+		Body:       &larkim.MessageBody{Content: str(`{"file_key":"f","file_name":"ci.log"}`)},
+	})
+	if msg.MsgID != "om_2" || msg.ChatID != "oc_1" || msg.ParentID != "om_1" || msg.RootID != "om_1" {
+		t.Errorf("Unexpected IDs: %+v", msg)
+	}
+	if msg.Content != "[File: ci.log]" || len(msg.Attachments) != 1 {
+		t.Errorf("Unexpected content %q with %d attachments", msg.Content, len(msg.Attachments))
+	}
+	if msg.Sender == nil || msg.Sender.SenderID != "ou_alice" || msg.CreateTime != 1700000000000 {
+		t.Errorf("Unexpected sender or time: %+v", msg)
+	}
+
+	recalled := c.parseFetchedMessage(&larkim.Message{
+		MessageId: str("om_3"),
+		MsgType:   str("text"),
+		Deleted:   &deleted,
+		Body:      &larkim.MessageBody{Content: str(`{"text":"oops"}`)},
+	})
+	if recalled.Content != "[Recalled message]" || !recalled.Recalled {
+		t.Errorf("Expected a recalled placeholder, got %q", recalled.Content)
+	}
+}
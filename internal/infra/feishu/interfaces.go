@@ -3,17 +3,37 @@ package feishu
 // FeishuClient defines the interface for Feishu operations
 type FeishuClient interface {
 	OnMessage(handler MessageHandler)
+	OnCardAction(handler CardActionHandler)
+	OnMessageRecalled(handler RecallHandler)
+	OnMessageEdited(handler MessageHandler)
+	OnBotAdded(handler ChatEventHandler)
+	OnBotRemoved(handler ChatEventHandler)
+	OnMembersChanged(handler ChatEventHandler)
+	OnConnected(handler ConnectedHandler)
 	Start() error
 	Stop()
 	SendText(chatID, text string) error
 	SendTextWithMentions(chatID, text string, mentions []Mention) error
 	SendTextMentionAll(chatID, text string) error
 	SendRichText(chatID, title string, content [][]map[string]interface{}) error
+	SendMarkdown(chatID, markdown string, mentions []Mention) error
+	SendCard(chatID, card string) (string, error)
+	ReplyText(messageID, text string, inThread bool) error
+	ReplyMarkdown(messageID, markdown string, mentions []Mention, inThread bool) error
+	ReplyCard(messageID, card string, inThread bool) (string, error)
+	UpdateCard(messageID, card string) error
+	DeleteMessage(messageID string) error
+	RecallReplies(messageID string) (int, error)
+	SendImage(chatID, path string) error
+	SendFile(chatID, path string) error
 	AddReaction(messageID, emojiType string) error
 	RemoveReaction(messageID, reactionID string) error
 	DownloadImage(messageID, imageKey string) (string, error)
+	DownloadFile(messageID string, attachment Attachment, dir string) (string, error)
 	SetDownloadDir(dir string)
 	GetChatHistory(chatID string, pageSize int) ([]*HistoryMessage, error)
+	GetThreadHistory(threadID string, pageSize int) ([]*HistoryMessage, error)
+	GetMessage(messageID string) (*Message, error)
 	GetChatMembers(chatID string) ([]*ChatMember, error)
 	GetChatInfo(chatID string) (*ChatInfo, error)
 }
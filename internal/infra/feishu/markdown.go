@@ -0,0 +1,417 @@
+package feishu
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// MaxMarkdownChunk is the largest piece of Markdown sent as one post
+	MaxMarkdownChunk = 10 * 1024
+	// maxPostBytes keeps the rendered post under Feishu's 30KB request limit
+	maxPostBytes = 28 * 1024
+	// minMarkdownChunk stops re-splitting chunks whose rendering stays too large
+	minMarkdownChunk = 512
+)
+
+var (
+	headingRegex  = regexp.MustCompile(`^#{1,6}\s+(.*)$`)
+	hrRegex       = regexp.MustCompile(`^\s*([-*_])(\s*([-*_])){2,}\s*$`)
+	bulletRegex   = regexp.MustCompile(`^(\s*)[-*+]\s+(.*)$`)
+	taskRegex     = regexp.MustCompile(`^\[([ xX])\]\s+(.*)$`)
+	quoteRegex    = regexp.MustCompile(`^\s*>\s?(.*)$`)
+	tableSepRegex = regexp.MustCompile(`^\s*\|?\s*:?-+:?\s*(\|\s*:?-+:?\s*)*\|?\s*$`)
+
+	// inlineRegex matches inline Markdown in priority order
+	inlineRegex = regexp.MustCompile("(?P<code>`[^`]+`)" +
+		`|!?\[(?P<ltext>[^\]]+)\]\((?P<lhref>[^)\s]+)\)` +
+		`|<at (?:user_)?id="?(?P<at>[^">\s]+)"?>[^<]*</at>` +
+		`|\*\*(?P<bold>.+?)\*\*` +
+		`|__(?P<bold2>.+?)__` +
+		`|~~(?P<strike>.+?)~~` +
+		`|\*(?P<italic>[^*\s](?:[^*]*[^*\s])?)\*` +
+		`|<(?P<autolink>https?://[^\s>]+)>` +
+		`|(?P<url>https?://[^\s<>()]+[^\s<>().,;:!?'"])`)
+)
+
+// RenderPosts renders Markdown into one or more post contents,
+// split at safe boundaries so each one fits in a single message
+func RenderPosts(markdown string) [][][]map[string]interface{} {
+	return renderPosts(markdown, MaxMarkdownChunk)
+}
+
+func renderPosts(markdown string, maxBytes int) [][][]map[string]interface{} {
+	var posts [][][]map[string]interface{}
+	for _, chunk := range SplitMarkdown(markdown, maxBytes) {
+		content := RenderPost(chunk)
+		// Many short lines render much larger than their source
+		if data, _ := json.Marshal(content); len(data) > maxPostBytes && maxBytes > minMarkdownChunk {
+			posts = append(posts, renderPosts(chunk, maxBytes/2)...)
+			continue
+		}
+		posts = append(posts, content)
+	}
+	return posts
+}
+
+// RenderPost converts Markdown into post content (paragraphs of elements)
+// Headings become bold lines, tables become code blocks, and images become links
+func RenderPost(markdown string) [][]map[string]interface{} {
+	var content [][]map[string]interface{}
+	lines := strings.Split(strings.ReplaceAll(markdown, "\r\n", "\n"), "\n")
+	blank := true // Collapse runs of blank lines
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+
+		if fence, lang, ok := openFence(line); ok {
+			var code []string
+			for i+1 < len(lines) {
+				i++
+				if isCloseFence(lines[i], fence) {
+					break
+				}
+				code = append(code, lines[i])
+			}
+			content = append(content, []map[string]interface{}{codeBlock(lang, strings.Join(code, "\n"))})
+			blank = false
+			continue
+		}
+
+		if isTableRow(line) && i+1 < len(lines) && tableSepRegex.MatchString(lines[i+1]) {
+			rows := [][]string{splitTableRow(line)}
+			i++ // Skip separator
+			for i+1 < len(lines) && isTableRow(lines[i+1]) {
+				i++
+				rows = append(rows, splitTableRow(lines[i]))
+			}
+			content = append(content, []map[string]interface{}{codeBlock("", formatTable(rows))})
+			blank = false
+			continue
+		}
+
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+			if !blank {
+				content = append(content, []map[string]interface{}{})
+			}
+			blank = true
+			continue
+		case hrRegex.MatchString(line):
+			content = append(content, []map[string]interface{}{{"tag": "hr"}})
+		case headingRegex.MatchString(trimmed):
+			title := headingRegex.FindStringSubmatch(trimmed)[1]
+			content = append(content, renderInline(title, []string{"bold"}))
+		case quoteRegex.MatchString(line):
+			quote := quoteRegex.FindStringSubmatch(line)[1]
+			content = append(content, prefixed("┃ ", renderInline(quote, []string{"italic"})))
+		case bulletRegex.MatchString(line):
+			m := bulletRegex.FindStringSubmatch(line)
+			indent, item := m[1], m[2]
+			marker := "• "
+			if task := taskRegex.FindStringSubmatch(item); task != nil {
+				marker = "☐ "
+				if task[1] != " " {
+					marker = "☑ "
+				}
+				item = task[2]
+			}
+			content = append(content, prefixed(indentOf(indent)+marker, renderInline(item, nil)))
+		default:
+			// Ordered lists and plain paragraphs keep their text as is
+			content = append(content, renderInline(strings.TrimRight(line, " \t"), nil))
+		}
+		blank = false
+	}
+
+	// Drop trailing blank paragraphs
+	for len(content) > 0 && len(content[len(content)-1]) == 0 {
+		content = content[:len(content)-1]
+	}
+	return content
+}
+
+// renderInline converts inline Markdown (emphasis, links, mentions) into elements
+func renderInline(text string, style []string) []map[string]interface{} {
+	var elements []map[string]interface{}
+	names := inlineRegex.SubexpNames()
+
+	for text != "" {
+		loc := inlineRegex.FindStringSubmatchIndex(text)
+		if loc == nil {
+			elements = append(elements, textElement(text, style))
+			break
+		}
+		if loc[0] > 0 {
+			elements = append(elements, textElement(text[:loc[0]], style))
+		}
+
+		group := func(name string) (string, bool) {
+			for i, n := range names {
+				if n == name && loc[2*i] >= 0 {
+					return text[loc[2*i]:loc[2*i+1]], true
+				}
+			}
+			return "", false
+		}
+
+		if code, ok := group("code"); ok {
+			// Posts have no inline code style, keep the backticks
+			elements = append(elements, textElement(code, style))
+		} else if label, ok := group("ltext"); ok {
+			href, _ := group("lhref")
+			elements = append(elements, map[string]interface{}{"tag": "a", "text": label, "href": href})
+		} else if userID, ok := group("at"); ok {
+			elements = append(elements, map[string]interface{}{"tag": "at", "user_id": userID})
+		} else if inner, ok := group("bold"); ok {
+			elements = append(elements, renderInline(inner, withStyle(style, "bold"))...)
+		} else if inner, ok := group("bold2"); ok {
+			elements = append(elements, renderInline(inner, withStyle(style, "bold"))...)
+		} else if inner, ok := group("strike"); ok {
+			elements = append(elements, renderInline(inner, withStyle(style, "lineThrough"))...)
+		} else if inner, ok := group("italic"); ok {
+			elements = append(elements, renderInline(inner, withStyle(style, "italic"))...)
+		} else if href, ok := group("autolink"); ok {
+			elements = append(elements, map[string]interface{}{"tag": "a", "text": href, "href": href})
+		} else if href, ok := group("url"); ok {
+			elements = append(elements, map[string]interface{}{"tag": "a", "text": href, "href": href})
+		}
+
+		text = text[loc[1]:]
+	}
+	return elements
+}
+
+// SplitMarkdown splits Markdown into chunks of at most maxBytes
+// Cuts prefer blank lines, then line breaks; code fences split across
+// chunks are closed and reopened so each chunk renders on its own
+func SplitMarkdown(text string, maxBytes int) []string {
+	text = strings.TrimSpace(strings.ReplaceAll(text, "\r\n", "\n"))
+	if text == "" {
+		return nil
+	}
+	if len(text) <= maxBytes {
+		return []string{text}
+	}
+
+	var chunks []string
+	var units []string // Lines, with whole code blocks kept together
+	size := 0
+
+	emit := func(upTo int) {
+		chunk := strings.TrimSpace(strings.Join(units[:upTo], "\n"))
+		if chunk != "" {
+			chunks = append(chunks, chunk)
+		}
+		if upTo < len(units) {
+			upTo++ // Drop the blank line we cut at
+		}
+		units = append([]string(nil), units[upTo:]...)
+		size = 0
+		for _, u := range units {
+			size += len(u) + 1
+		}
+	}
+
+	for _, unit := range markdownUnits(text) {
+		if len(unit) > maxBytes {
+			emit(len(units))
+			chunks = append(chunks, splitOversized(unit, maxBytes)...)
+			continue
+		}
+		if size+len(unit) > maxBytes {
+			// Prefer cutting at the last paragraph break
+			cut := len(units)
+			for i := len(units) - 1; i > 0; i-- {
+				if strings.TrimSpace(units[i]) == "" {
+					cut = i
+					break
+				}
+			}
+			emit(cut)
+			if size+len(unit) > maxBytes {
+				emit(len(units))
+			}
+		}
+		units = append(units, unit)
+		size += len(unit) + 1
+	}
+	emit(len(units))
+	return chunks
+}
+
+// markdownUnits splits text into lines, keeping fenced code blocks as one unit
+func markdownUnits(text string) []string {
+	var units []string
+	lines := strings.Split(text, "\n")
+	for i := 0; i < len(lines); i++ {
+		fence, _, ok := openFence(lines[i])
+		if !ok {
+			units = append(units, lines[i])
+			continue
+		}
+		start := i
+		for i+1 < len(lines) {
+			i++
+			if isCloseFence(lines[i], fence) {
+				break
+			}
+		}
+		units = append(units, strings.Join(lines[start:i+1], "\n"))
+	}
+	return units
+}
+
+// splitOversized splits a single unit that exceeds maxBytes
+func splitOversized(unit string, maxBytes int) []string {
+	lines := strings.Split(unit, "\n")
+	fence, _, ok := openFence(lines[0])
+	if !ok || len(lines) < 2 {
+		return splitRunes(unit, maxBytes)
+	}
+
+	// Re-wrap each piece of the code block in its own fence
+	open := strings.TrimSpace(lines[0])
+	body := lines[1:]
+	if isCloseFence(body[len(body)-1], fence) {
+		body = body[:len(body)-1]
+	}
+	budget := maxBytes - len(open) - len(fence) - 2
+	if budget < 1 {
+		return splitRunes(unit, maxBytes)
+	}
+
+	var chunks []string
+	var piece []string
+	size := 0
+	flush := func() {
+		if len(piece) > 0 {
+			chunks = append(chunks, open+"\n"+strings.Join(piece, "\n")+"\n"+fence)
+		}
+		piece = nil
+		size = 0
+	}
+	for _, line := range body {
+		for _, part := range splitRunes(line, budget) {
+			if size+len(part)+1 > budget {
+				flush()
+			}
+			piece = append(piece, part)
+			size += len(part) + 1
+		}
+	}
+	flush()
+	return chunks
+}
+
+// splitRunes hard-splits text without cutting inside a UTF-8 character
+func splitRunes(text string, maxBytes int) []string {
+	if text == "" {
+		return []string{""}
+	}
+	var parts []string
+	for len(text) > maxBytes {
+		cut := maxBytes
+		for cut > 0 && !utf8.RuneStart(text[cut]) {
+			cut--
+		}
+		parts = append(parts, text[:cut])
+		text = text[cut:]
+	}
+	return append(parts, text)
+}
+
+// openFence reports whether line opens a code fence, returning the fence and language
+func openFence(line string) (string, string, bool) {
+	trimmed := strings.TrimSpace(line)
+	for _, marker := range []string{"```", "~~~"} {
+		if strings.HasPrefix(trimmed, marker) {
+			n := len(trimmed) - len(strings.TrimLeft(trimmed, marker[:1]))
+			return trimmed[:n], strings.TrimSpace(trimmed[n:]), true
+		}
+	}
+	return "", "", false
+}
+
+func isCloseFence(line, fence string) bool {
+	trimmed := strings.TrimSpace(line)
+	return strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == ""
+}
+
+func isTableRow(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	return strings.HasPrefix(trimmed, "|") && strings.Count(trimmed, "|") >= 2
+}
+
+func splitTableRow(line string) []string {
+	trimmed := strings.Trim(strings.TrimSpace(line), "|")
+	cells := strings.Split(trimmed, "|")
+	for i, cell := range cells {
+		cells[i] = strings.TrimSpace(cell)
+	}
+	return cells
+}
+
+// formatTable lays out table rows as aligned plain text
+func formatTable(rows [][]string) string {
+	var widths []int
+	for _, row := range rows {
+		for i, cell := range row {
+			if i >= len(widths) {
+				widths = append(widths, 0)
+			}
+			if w := utf8.RuneCountInString(cell); w > widths[i] {
+				widths[i] = w
+			}
+		}
+	}
+
+	var sb strings.Builder
+	for r, row := range rows {
+		var cells []string
+		for i, cell := range row {
+			cells = append(cells, cell+strings.Repeat(" ", widths[i]-utf8.RuneCountInString(cell)))
+		}
+		sb.WriteString(strings.TrimRight(strings.Join(cells, " | "), " "))
+		if r == 0 {
+			var rule []string
+			for _, w := range widths {
+				rule = append(rule, strings.Repeat("-", w))
+			}
+			sb.WriteString("\n" + strings.Join(rule, "-+-"))
+		}
+		if r < len(rows)-1 {
+			sb.WriteString("\n")
+		}
+	}
+	return sb.String()
+}
+
+func codeBlock(lang, code string) map[string]interface{} {
+	return map[string]interface{}{"tag": "code_block", "language": strings.ToUpper(lang), "text": code}
+}
+
+func textElement(text string, style []string) map[string]interface{} {
+	el := map[string]interface{}{"tag": "text", "text": text}
+	if len(style) > 0 {
+		el["style"] = style
+	}
+	return el
+}
+
+func withStyle(style []string, s string) []string {
+	return append(append([]string(nil), style...), s)
+}
+
+func prefixed(prefix string, elements []map[string]interface{}) []map[string]interface{} {
+	return append([]map[string]interface{}{textElement(prefix, nil)}, elements...)
+}
+
+// indentOf converts list indentation into nesting-friendly spaces
+func indentOf(indent string) string {
+	n := len(strings.ReplaceAll(indent, "\t", "    ")) / 2
+	return strings.Repeat("  ", n)
+}
@@ -0,0 +1,145 @@
+package feishu
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestRenderPostBlocks(t *testing.T) {
+	md := "# Title\n\nSome **bold** and *italic* with [docs](https://example.com).\n\n" +
+		"- item one\n- [x] done\n\n```go\nfmt.Println(\"hi\")\n```\n\n---\n\n> quoted"
+
+	content := RenderPost(md)
+
+	var tags []string
+	for _, paragraph := range content {
+		if len(paragraph) == 0 {
+			tags = append(tags, "blank")
+			continue
+		}
+		tags = append(tags, paragraph[0]["tag"].(string))
+	}
+	want := []string{"text", "blank", "text", "blank", "text", "text", "blank", "code_block", "blank", "hr", "blank", "text"}
+	if strings.Join(tags, ",") != strings.Join(want, ",") {
+		t.Fatalf("paragraph tags = %v, want %v", tags, want)
+	}
+
+	title := content[0][0]
+	if title["text"] != "Title" || title["style"].([]string)[0] != "bold" {
+		t.Errorf("heading rendered as %v", title)
+	}
+
+	code := content[7][0]
+	if code["language"] != "GO" || code["text"] != `fmt.Println("hi")` {
+		t.Errorf("code block rendered as %v", code)
+	}
+
+	if content[5][0]["text"] != "☑ " {
+		t.Errorf("task item marker = %v", content[5][0]["text"])
+	}
+}
+
+func TestRenderInline(t *testing.T) {
+	elements := renderInline(`Hi <at user_id="ou_1">Tom</at>, see **[the PR](https://x.io/1)** or https://x.io/2.`, nil)
+
+	var got []string
+	for _, el := range elements {
+		switch el["tag"] {
+		case "at":
+			got = append(got, "@"+el["user_id"].(string))
+		case "a":
+			got = append(got, "a:"+el["href"].(string))
+		default:
+			got = append(got, el["text"].(string))
+		}
+	}
+	want := []string{"Hi ", "@ou_1", ", see ", "a:https://x.io/1", " or ", "a:https://x.io/2", "."}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("inline elements = %q, want %q", got, want)
+	}
+}
+
+func TestRenderPostTable(t *testing.T) {
+	content := RenderPost("| Name | Count |\n|---|---:|\n| a | 10 |\n| long name | 2 |")
+	if len(content) != 1 || content[0][0]["tag"] != "code_block" {
+		t.Fatalf("table should render as one code block, got %v", content)
+	}
+	want := "Name      | Count\n----------+------\na         | 10\nlong name | 2"
+	if content[0][0]["text"] != want {
+		t.Errorf("table text = %q, want %q", content[0][0]["text"], want)
+	}
+}
+
+func TestSplitMarkdownPrefersParagraphs(t *testing.T) {
+	para := strings.Repeat("word ", 15) // 75 bytes
+	text := para + "\n" + para + "\n\n" + para
+
+	chunks := SplitMarkdown(text, 200)
+	if len(chunks) != 2 {
+		t.Fatalf("got %d chunks, want 2", len(chunks))
+	}
+	if strings.Count(chunks[0], "\n") != 1 {
+		t.Errorf("first chunk should hold the first paragraph, got %q", chunks[0])
+	}
+}
+
+func TestSplitMarkdownReopensCodeFences(t *testing.T) {
+	var lines []string
+	for i := 0; i < 40; i++ {
+		lines = append(lines, "line of code number")
+	}
+	text := "intro\n```python\n" + strings.Join(lines, "\n") + "\n```\noutro"
+
+	chunks := SplitMarkdown(text, 300)
+	if len(chunks) < 3 {
+		t.Fatalf("expected the code block to be split, got %d chunks", len(chunks))
+	}
+	for _, chunk := range chunks {
+		if len(chunk) > 300 {
+			t.Errorf("chunk exceeds limit: %d bytes", len(chunk))
+		}
+		if strings.Count(chunk, "```")%2 != 0 {
+			t.Errorf("chunk has an unbalanced fence: %q", chunk)
+		}
+	}
+	if !strings.HasPrefix(chunks[1], "```python\n") {
+		t.Errorf("continued code should reopen the fence, got %q", chunks[1])
+	}
+	if chunks[0] != "intro" || chunks[len(chunks)-1] != "outro" {
+		t.Errorf("text around the block should be kept in order: %q ... %q", chunks[0], chunks[len(chunks)-1])
+	}
+}
+
+func TestSplitMarkdownUTF8(t *testing.T) {
+	text := strings.Repeat("你好", 100) // One 600-byte line
+	chunks := SplitMarkdown(text, 100)
+	if strings.Join(chunks, "") != text {
+		t.Fatal("chunks should reassemble to the original text")
+	}
+	for _, chunk := range chunks {
+		if !json.Valid([]byte(`"`+chunk+`"`)) || strings.ContainsRune(chunk, '�') {
+			t.Errorf("chunk cut inside a character: %q", chunk)
+		}
+	}
+}
+
+func TestRenderPostsFitLimit(t *testing.T) {
+	// Many short lines render far larger than their source
+	text := strings.Repeat("- a\n", 5000)
+	posts := RenderPosts(text)
+	if len(posts) < 2 {
+		t.Fatalf("expected multiple posts, got %d", len(posts))
+	}
+	items := 0
+	for _, content := range posts {
+		data, _ := json.Marshal(content)
+		if len(data) > maxPostBytes {
+			t.Errorf("post is %d bytes, over the limit", len(data))
+		}
+		items += len(content)
+	}
+	if items != 5000 {
+		t.Errorf("rendered %d items, want 5000", items)
+	}
+}
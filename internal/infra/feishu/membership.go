@@ -0,0 +1,139 @@
+package feishu
+
+import (
+	"context"
+	"fmt"
+
+	larkim "github.com/larksuite/oapi-sdk-go/v3/service/im/v1"
+)
+
+// ChatEvent represents a change to the members of a chat
+type ChatEvent struct {
+	ChatID     string
+	ChatName   string
+	OperatorID string   // open_id of the user who made the change
+	UserIDs    []string // open_ids of the users added or removed, empty for bot events
+}
+
+// ChatEventHandler is the callback for chat member events
+type ChatEventHandler func(event *ChatEvent)
+
+// OnBotAdded sets the handler for the bot being added to a group
+func (c *Client) OnBotAdded(handler ChatEventHandler) {
+	c.onBotAdded = handler
+}
+
+// OnBotRemoved sets the handler for the bot leaving a group, removed or disbanded
+func (c *Client) OnBotRemoved(handler ChatEventHandler) {
+	c.onBotRemoved = handler
+}
+
+// OnMembersChanged sets the handler for users joining or leaving a group
+func (c *Client) OnMembersChanged(handler ChatEventHandler) {
+	c.onMembers = handler
+}
+
+func (c *Client) handleBotAdded(ctx context.Context, event *larkim.P2ChatMemberBotAddedV1) error {
+	if event.Event == nil {
+		return nil
+	}
+	c.dispatchChatEvent("Bot added to", c.onBotAdded, &ChatEvent{
+		ChatID:     stringValue(event.Event.ChatId),
+		ChatName:   stringValue(event.Event.Name),
+		OperatorID: openID(event.Event.OperatorId),
+	})
+	return nil
+}
+
+func (c *Client) handleBotDeleted(ctx context.Context, event *larkim.P2ChatMemberBotDeletedV1) error {
+	if event.Event == nil {
+		return nil
+	}
+	c.dispatchChatEvent("Bot removed from", c.onBotRemoved, &ChatEvent{
+		ChatID:     stringValue(event.Event.ChatId),
+		ChatName:   stringValue(event.Event.Name),
+		OperatorID: openID(event.Event.OperatorId),
+	})
+	return nil
+}
+
+func (c *Client) handleChatDisbanded(ctx context.Context, event *larkim.P2ChatDisbandedV1) error {
+	if event.Event == nil {
+		return nil
+	}
+	c.dispatchChatEvent("Disbanded", c.onBotRemoved, &ChatEvent{
+		ChatID:     stringValue(event.Event.ChatId),
+		ChatName:   stringValue(event.Event.Name),
+		OperatorID: openID(event.Event.OperatorId),
+	})
+	return nil
+}
+
+func (c *Client) handleUsersAdded(ctx context.Context, event *larkim.P2ChatMemberUserAddedV1) error {
+	if event.Event == nil {
+		return nil
+	}
+	c.dispatchChatEvent("Users added to", c.onMembers, &ChatEvent{
+		ChatID:     stringValue(event.Event.ChatId),
+		ChatName:   stringValue(event.Event.Name),
+		OperatorID: openID(event.Event.OperatorId),
+		UserIDs:    memberIDs(event.Event.Users),
+	})
+	return nil
+}
+
+func (c *Client) handleUsersDeleted(ctx context.Context, event *larkim.P2ChatMemberUserDeletedV1) error {
+	if event.Event == nil {
+		return nil
+	}
+	c.dispatchChatEvent("Users removed from", c.onMembers, &ChatEvent{
+		ChatID:     stringValue(event.Event.ChatId),
+		ChatName:   stringValue(event.Event.Name),
+		OperatorID: openID(event.Event.OperatorId),
+		UserIDs:    memberIDs(event.Event.Users),
+	})
+	return nil
+}
+
+func (c *Client) handleUsersWithdrawn(ctx context.Context, event *larkim.P2ChatMemberUserWithdrawnV1) error {
+	if event.Event == nil {
+		return nil
+	}
+	c.dispatchChatEvent("Invitation withdrawn in", c.onMembers, &ChatEvent{
+		ChatID:     stringValue(event.Event.ChatId),
+		ChatName:   stringValue(event.Event.Name),
+		OperatorID: openID(event.Event.OperatorId),
+		UserIDs:    memberIDs(event.Event.Users),
+	})
+	return nil
+}
+
+// dispatchChatEvent logs a chat event and runs its handler in the background
+func (c *Client) dispatchChatEvent(what string, handler ChatEventHandler, event *ChatEvent) {
+	if event.ChatID == "" {
+		return
+	}
+	fmt.Printf("[Feishu] %s %s (%s)\n", what, event.ChatID, event.ChatName)
+	if handler == nil {
+		return
+	}
+	// Return immediately to let SDK send ACK
+	go handler(event)
+}
+
+func openID(id *larkim.UserId) string {
+	if id == nil {
+		return ""
+	}
+	return stringValue(id.OpenId)
+}
+
+func memberIDs(users []*larkim.ChatMemberUser) []string {
+	ids := make([]string, 0, len(users))
+	for _, user := range users {
+		if id := openID(user.UserId); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
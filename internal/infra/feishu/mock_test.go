@@ -0,0 +1,133 @@
+package feishu
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anthropics/feishu-codex-bridge/internal/infra/feishu/feishutest"
+)
+
+// newMockClient connects a client to a mock open platform, with events delivered to its webhook
+func newMockClient(t *testing.T) (*Client, *feishutest.Server) {
+	t.Helper()
+	mock := feishutest.New()
+	api := httptest.NewServer(mock)
+	t.Cleanup(api.Close)
+
+	client := NewClient("cli_mock", "secret")
+	client.SetBaseURL(api.URL)
+	client.SetWebhook(WebhookConfig{VerificationToken: "vtoken"})
+	client.outbound.sleep = func(ctx context.Context, d time.Duration) error { return nil }
+	client.larkCli = client.newLarkClient()
+	if err := client.fetchBotOpenID(); err != nil {
+		t.Fatalf("fetchBotOpenID failed: %v", err)
+	}
+
+	webhook := httptest.NewServer(client.webhookHandler(client.newEventDispatcher()))
+	t.Cleanup(webhook.Close)
+	mock.SetWebhook(webhook.URL, "vtoken")
+
+	mock.AddChat(feishutest.Chat{
+		ChatID:  "oc_team",
+		Name:    "Team",
+		Members: []feishutest.Member{{OpenID: "ou_alice", Name: "Alice"}, {OpenID: "ou_bob", Name: "Bob"}},
+	})
+	return client, mock
+}
+
+func TestMock_MessageToReply(t *testing.T) {
+	client, mock := newMockClient(t)
+	client.OnMessage(func(msg *Message) {
+		if !msg.MentionsBot {
+			return
+		}
+		_ = client.AddReaction(msg.MsgID, "OnIt")
+		_ = client.ReplyText(msg.MsgID, "echo: "+strings.TrimSpace(msg.Content), false)
+	})
+
+	// Messages without a mention are ignored by the handler
+	if _, err := mock.Receive(feishutest.Inbound{ChatID: "oc_team", SenderID: "ou_bob", Text: "chatter"}); err != nil {
+		t.Fatalf("Receive failed: %v", err)
+	}
+	in, err := mock.Receive(feishutest.Inbound{ChatID: "oc_team", SenderID: "ou_alice", Text: "hello", MentionBot: true})
+	if err != nil {
+		t.Fatalf("Receive failed: %v", err)
+	}
+
+	reply, err := mock.WaitSent(2*time.Second, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply.ParentID != in.MessageID || reply.ChatID != "oc_team" || reply.Text() != "echo: @Mock Bot hello" {
+		t.Errorf("Unexpected reply: %+v", reply)
+	}
+	if reactions := mock.Reactions(); len(reactions) != 1 || reactions[0].MessageID != in.MessageID {
+		t.Errorf("Expected a reaction on the message, got %+v", reactions)
+	}
+	if sent := mock.Sent(); len(sent) != 1 {
+		t.Errorf("Expected exactly one reply, got %+v", sent)
+	}
+
+	history, err := client.GetChatHistory("oc_team", 10)
+	if err != nil || len(history) != 3 {
+		t.Fatalf("Expected three messages in the history, got %d, %v", len(history), err)
+	}
+}
+
+func TestMock_Directory(t *testing.T) {
+	client, _ := newMockClient(t)
+
+	members, err := client.GetChatMembers("oc_team")
+	if err != nil || len(members) != 2 || members[0].Name != "Alice" {
+		t.Fatalf("Unexpected members: %+v, %v", members, err)
+	}
+	info, err := client.GetChatInfo("oc_team")
+	if err != nil || info.Name != "Team" {
+		t.Fatalf("Unexpected chat info: %+v, %v", info, err)
+	}
+	user, err := client.GetUser("ou_bob")
+	if err != nil || user.Name != "Bob" {
+		t.Fatalf("Unexpected user: %+v, %v", user, err)
+	}
+}
+
+func TestMock_InjectedErrors(t *testing.T) {
+	client, mock := newMockClient(t)
+
+	// Frequency limits are retried with the same uuid, so one message is sent
+	mock.FailNext("POST", "/open-apis/im/v1/messages", 99991400, 2)
+	if err := client.SendText("oc_team", "hi"); err != nil {
+		t.Fatalf("SendText failed: %v", err)
+	}
+	if sent := mock.Sent(); len(sent) != 1 || sent[0].Text() != "hi" {
+		t.Errorf("Unexpected sent messages: %+v", sent)
+	}
+
+	// Other errors surface to the caller
+	mock.FailNext("POST", "/open-apis/im/v1/messages", 230002, 1)
+	if err := client.SendText("oc_team", "again"); err == nil {
+		t.Error("Expected the injected error")
+	}
+}
+
+func TestMock_FetchMessageSentWhileOffline(t *testing.T) {
+	client, mock := newMockClient(t)
+	posted := mock.Post(feishutest.Inbound{ChatID: "oc_team", SenderID: "ou_alice", Text: "anyone?", MentionBot: true})
+
+	msg, err := client.GetMessage(posted.MessageID)
+	if err != nil {
+		t.Fatalf("GetMessage failed: %v", err)
+	}
+	if !msg.MentionsBot || len(msg.Mentions) != 1 || msg.Mentions[0] != feishutest.DefaultBotOpenID {
+		t.Errorf("Expected the fetched message to mention the bot, got %+v", msg)
+	}
+	if msg.Content != "@Mock Bot anyone?" || msg.Sender == nil || msg.Sender.SenderID != "ou_alice" {
+		t.Errorf("Unexpected fetched message: %+v", msg)
+	}
+	if len(mock.Sent()) != 0 {
+		t.Error("Expected no event to be delivered for an offline message")
+	}
+}
@@ -0,0 +1,348 @@
+package feishu
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	mathrand "math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	larkcore "github.com/larksuite/oapi-sdk-go/v3/core"
+)
+
+// API families, each with its own rate limit
+const (
+	FamilySend     = "send"     // Sending and replying to messages
+	FamilyUpdate   = "update"   // Editing and recalling messages
+	FamilyReaction = "reaction" // Adding and removing reactions
+	FamilyRead     = "read"     // Reading messages, chats and members
+	FamilyResource = "resource" // Uploading and downloading images and files
+)
+
+// APIFamilies lists the rate limited API families
+var APIFamilies = []string{FamilySend, FamilyUpdate, FamilyReaction, FamilyRead, FamilyResource}
+
+// OutboundConfig tunes rate limiting and retries of Feishu API calls
+type OutboundConfig struct {
+	MaxRetries int                // Retries after the first attempt, 0 to disable
+	BaseDelay  time.Duration      // First backoff, doubled on every retry
+	MaxDelay   time.Duration      // Backoff cap
+	RateLimits map[string]float64 // Requests per second by API family, unlisted families are unlimited
+}
+
+// DefaultOutboundConfig returns limits well below Feishu's 50 requests per second per app
+func DefaultOutboundConfig() OutboundConfig {
+	return OutboundConfig{
+		MaxRetries: 3,
+		BaseDelay:  500 * time.Millisecond,
+		MaxDelay:   10 * time.Second,
+		RateLimits: map[string]float64{
+			FamilySend:     20,
+			FamilyUpdate:   20,
+			FamilyReaction: 20,
+			FamilyRead:     20,
+			FamilyResource: 10,
+		},
+	}
+}
+
+// OutboundStats counts Feishu API traffic since start
+type OutboundStats struct {
+	Requests  int64 `json:"requests"`  // Attempts sent, retries included
+	Limited   int64 `json:"limited"`   // Calls delayed by the local rate limiter
+	Throttled int64 `json:"throttled"` // Responses rejected by Feishu's frequency control
+	Retries   int64 `json:"retries"`
+	Failures  int64 `json:"failures"` // Calls still failing when retries ran out
+}
+
+// rateLimitCodes are Feishu error codes for frequency control
+var rateLimitCodes = map[int]bool{
+	99991400: true, // App request frequency limit
+	230020:   true, // IM operation frequency limit
+	11232:    true, // Message send frequency limit
+}
+
+// SetOutbound replaces the rate limits and retry policy of API calls
+// Must be called before Start
+func (c *Client) SetOutbound(cfg OutboundConfig) {
+	c.outbound = newOutbound(c.outbound.next, cfg)
+}
+
+// OutboundStats returns the API traffic counters
+func (c *Client) OutboundStats() OutboundStats {
+	return c.outbound.stats()
+}
+
+// outbound is the HTTP client under every Feishu API call
+// It waits for the family's token bucket, then retries frequency limits with
+// jittered exponential backoff. Network and server errors leave the outcome
+// unknown, they are only retried for requests that are safe to repeat
+type outbound struct {
+	next    larkcore.HttpClient
+	cfg     OutboundConfig
+	buckets map[string]*tokenBucket
+	sleep   func(ctx context.Context, d time.Duration) error
+
+	requests  atomic.Int64
+	limited   atomic.Int64
+	throttled atomic.Int64
+	retries   atomic.Int64
+	failures  atomic.Int64
+}
+
+func newOutbound(next larkcore.HttpClient, cfg OutboundConfig) *outbound {
+	o := &outbound{
+		next:    next,
+		cfg:     cfg,
+		buckets: make(map[string]*tokenBucket),
+		sleep:   sleepContext,
+	}
+	for family, rate := range cfg.RateLimits {
+		if rate > 0 {
+			o.buckets[family] = newTokenBucket(rate)
+		}
+	}
+	return o
+}
+
+// Do sends a request, implementing larkcore.HttpClient
+func (o *outbound) Do(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	if bucket := o.buckets[apiFamily(req)]; bucket != nil {
+		if wait := bucket.reserve(time.Now()); wait > 0 {
+			o.limited.Add(1)
+			if err := o.sleep(ctx, wait); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// Keep the body so every attempt can send it again
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("read request body: %w", err)
+		}
+	}
+
+	repeatable := safeToRepeat(req, body)
+	for attempt := 0; ; attempt++ {
+		try := req.Clone(ctx)
+		if req.Body != nil {
+			try.Body = io.NopCloser(bytes.NewReader(body))
+		}
+		o.requests.Add(1)
+		resp, err := o.next.Do(try)
+
+		var reason string
+		var wait time.Duration
+		retry := repeatable
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, err
+			}
+			reason = err.Error()
+		} else {
+			code, readErr := responseCode(resp)
+			switch {
+			case readErr != nil:
+				reason = readErr.Error()
+				err = readErr
+				resp = nil
+			case resp.StatusCode == http.StatusTooManyRequests || rateLimitCodes[code]:
+				o.throttled.Add(1)
+				reason = fmt.Sprintf("frequency limited (HTTP %d, code %d)", resp.StatusCode, code)
+				wait = rateLimitReset(resp.Header)
+				retry = true // Rejected before running, always safe to send again
+			case resp.StatusCode >= http.StatusInternalServerError:
+				reason = fmt.Sprintf("HTTP %d", resp.StatusCode)
+			default:
+				return resp, nil
+			}
+		}
+
+		if !retry {
+			o.failures.Add(1)
+			fmt.Printf("[Feishu] %s %s failed, not retried as it may have taken effect: %s\n", req.Method, req.URL.Path, reason)
+			return resp, err
+		}
+		if attempt >= o.cfg.MaxRetries {
+			o.failures.Add(1)
+			fmt.Printf("[Feishu] %s %s failed after %d attempts: %s\n", req.Method, req.URL.Path, attempt+1, reason)
+			return resp, err
+		}
+
+		delay := o.backoff(attempt)
+		if wait > delay {
+			delay = wait
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+		o.retries.Add(1)
+		fmt.Printf("[Feishu] %s %s %s, retrying in %v (%d/%d)\n", req.Method, req.URL.Path, reason, delay.Round(time.Millisecond), attempt+1, o.cfg.MaxRetries)
+		if err := o.sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// backoff returns the jittered delay before a retry, between half and all of
+// the exponential delay
+func (o *outbound) backoff(attempt int) time.Duration {
+	delay := o.cfg.BaseDelay << attempt
+	if delay <= 0 || delay > o.cfg.MaxDelay {
+		delay = o.cfg.MaxDelay
+	}
+	if delay <= 1 {
+		return delay
+	}
+	half := delay / 2
+	return half + time.Duration(mathrand.Int63n(int64(half)+1))
+}
+
+func (o *outbound) stats() OutboundStats {
+	return OutboundStats{
+		Requests:  o.requests.Load(),
+		Limited:   o.limited.Load(),
+		Throttled: o.throttled.Load(),
+		Retries:   o.retries.Load(),
+		Failures:  o.failures.Load(),
+	}
+}
+
+// apiFamily returns the rate limit family of a request, empty for unlimited ones
+func apiFamily(req *http.Request) string {
+	path := strings.TrimPrefix(req.URL.Path, "/open-apis/")
+	switch {
+	case strings.HasPrefix(path, "im/v1/images"), strings.HasPrefix(path, "im/v1/files"),
+		strings.Contains(path, "/resources/"):
+		return FamilyResource
+	case strings.Contains(path, "/reactions"):
+		return FamilyReaction
+	case strings.HasPrefix(path, "im/v1/messages"):
+		switch {
+		case req.Method == http.MethodGet:
+			return FamilyRead
+		case req.Method == http.MethodPost && (path == "im/v1/messages" || strings.HasSuffix(path, "/reply")):
+			return FamilySend
+		default:
+			return FamilyUpdate
+		}
+	case strings.HasPrefix(path, "im/"), strings.HasPrefix(path, "contact/"):
+		if req.Method == http.MethodGet {
+			return FamilyRead
+		}
+	}
+	return ""
+}
+
+// safeToRepeat reports whether a request can be sent again when its outcome
+// is unknown. Reads and token requests have no side effects, and Feishu runs
+// message sends carrying a uuid at most once. Other writes, such as reactions,
+// uploads, edits and recalls, could be applied twice
+func safeToRepeat(req *http.Request, body []byte) bool {
+	if req.Method == http.MethodGet || req.Method == http.MethodHead {
+		return true
+	}
+	if strings.HasPrefix(req.URL.Path, "/open-apis/auth/") {
+		return true
+	}
+	if req.Method != http.MethodPost || apiFamily(req) != FamilySend {
+		return false
+	}
+	var send struct {
+		UUID string `json:"uuid"`
+	}
+	return json.Unmarshal(body, &send) == nil && send.UUID != ""
+}
+
+// responseCode returns the error code of a JSON response, 0 for other responses
+// The body is buffered so the SDK can still read it
+func responseCode(resp *http.Response) (int, error) {
+	if !strings.Contains(resp.Header.Get("Content-Type"), "json") {
+		return 0, nil
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return 0, fmt.Errorf("read response body: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	var result struct {
+		Code int `json:"code"`
+	}
+	_ = json.Unmarshal(body, &result)
+	return result.Code, nil
+}
+
+// rateLimitReset returns how long Feishu asked to wait before the next request
+func rateLimitReset(header http.Header) time.Duration {
+	seconds, err := strconv.Atoi(header.Get("x-ogw-ratelimit-reset"))
+	if err != nil || seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// tokenBucket allows rate requests per second with bursts of up to one second's worth
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64) *tokenBucket {
+	burst := math.Max(1, rate)
+	return &tokenBucket{rate: rate, burst: burst, tokens: burst, last: time.Now()}
+}
+
+// reserve takes a token and returns how long to wait before using it
+func (b *tokenBucket) reserve(now time.Time) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(b.burst, b.tokens+elapsed*b.rate)
+		b.last = now
+	}
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// newUUID returns a random request ID, Feishu runs sends sharing one at most once
+func newUUID() string {
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,260 @@
+package feishu
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// scriptedHTTP answers requests with queued responses and records the bodies it got
+type scriptedHTTP struct {
+	responses []func() (*http.Response, error)
+	bodies    []string
+}
+
+func (s *scriptedHTTP) Do(req *http.Request) (*http.Response, error) {
+	body, _ := io.ReadAll(req.Body)
+	s.bodies = append(s.bodies, string(body))
+	next := s.responses[0]
+	if len(s.responses) > 1 {
+		s.responses = s.responses[1:]
+	}
+	return next()
+}
+
+func jsonResponse(status int, body string) func() (*http.Response, error) {
+	return func() (*http.Response, error) {
+		return &http.Response{
+			StatusCode: status,
+			Header:     http.Header{"Content-Type": []string{"application/json; charset=utf-8"}},
+			Body:       io.NopCloser(strings.NewReader(body)),
+		}, nil
+	}
+}
+
+func newTestOutbound(next *scriptedHTTP, maxRetries int) (*outbound, *[]time.Duration) {
+	cfg := DefaultOutboundConfig()
+	cfg.MaxRetries = maxRetries
+	o := newOutbound(next, cfg)
+	var slept []time.Duration
+	o.sleep = func(ctx context.Context, d time.Duration) error {
+		slept = append(slept, d)
+		return nil
+	}
+	return o, &slept
+}
+
+func newSendRequest() *http.Request {
+	req, _ := http.NewRequest(http.MethodPost, "https://open.feishu.cn/open-apis/im/v1/messages", strings.NewReader(`{"uuid":"u-1"}`))
+	return req
+}
+
+func TestOutbound_RetriesFrequencyLimit(t *testing.T) {
+	next := &scriptedHTTP{responses: []func() (*http.Response, error){
+		jsonResponse(http.StatusOK, `{"code":230020,"msg":"frequency limit"}`),
+		jsonResponse(http.StatusBadGateway, `{"code":0}`),
+		jsonResponse(http.StatusOK, `{"code":0,"data":{"message_id":"om_1"}}`),
+	}}
+	o, slept := newTestOutbound(next, 3)
+
+	resp, err := o.Do(newSendRequest())
+	if err != nil {
+		t.Fatalf("Do failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if !strings.Contains(string(body), "om_1") {
+		t.Errorf("Expected the successful response body, got %s", body)
+	}
+	if len(next.bodies) != 3 || next.bodies[2] != `{"uuid":"u-1"}` {
+		t.Errorf("Expected every attempt to resend the body, got %q", next.bodies)
+	}
+	if len(*slept) != 2 {
+		t.Errorf("Expected two backoffs, got %v", *slept)
+	}
+
+	stats := o.stats()
+	if stats.Requests != 3 || stats.Retries != 2 || stats.Throttled != 1 || stats.Failures != 0 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
+
+func TestOutbound_GivesUp(t *testing.T) {
+	next := &scriptedHTTP{responses: []func() (*http.Response, error){
+		func() (*http.Response, error) { return nil, errors.New("connection reset") },
+	}}
+	o, _ := newTestOutbound(next, 2)
+
+	if _, err := o.Do(newSendRequest()); err == nil || !strings.Contains(err.Error(), "connection reset") {
+		t.Errorf("Expected the network error, got %v", err)
+	}
+	if stats := o.stats(); stats.Requests != 3 || stats.Retries != 2 || stats.Failures != 1 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
+
+func TestOutbound_KeepsOtherErrors(t *testing.T) {
+	next := &scriptedHTTP{responses: []func() (*http.Response, error){
+		jsonResponse(http.StatusBadRequest, `{"code":230002,"msg":"bot not in chat"}`),
+	}}
+	o, _ := newTestOutbound(next, 3)
+
+	resp, err := o.Do(newSendRequest())
+	if err != nil || resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("Expected the error response to be returned as is, got %v, %v", resp, err)
+	}
+	if stats := o.stats(); stats.Requests != 1 || stats.Retries != 0 {
+		t.Errorf("Expected no retry, got %+v", stats)
+	}
+}
+
+func TestOutbound_RetriesOnlyRepeatableWrites(t *testing.T) {
+	tests := []struct {
+		name, method, path, body string
+		wantRequests             int64
+	}{
+		{"send with uuid", "POST", "/open-apis/im/v1/messages", `{"uuid":"u-1"}`, 3},
+		{"send without uuid", "POST", "/open-apis/im/v1/messages", `{}`, 1},
+		{"read", "GET", "/open-apis/im/v1/chats/oc_1/members", "", 3},
+		{"reaction", "POST", "/open-apis/im/v1/messages/om_1/reactions", `{"reaction_type":{"emoji_type":"OK"}}`, 1},
+		{"upload", "POST", "/open-apis/im/v1/images", "image", 1},
+		{"edit", "PATCH", "/open-apis/im/v1/messages/om_1", `{"content":"{}"}`, 1},
+		{"recall", "DELETE", "/open-apis/im/v1/messages/om_1", "", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := &scriptedHTTP{responses: []func() (*http.Response, error){
+				jsonResponse(http.StatusBadGateway, `{"code":0}`),
+				func() (*http.Response, error) { return nil, errors.New("connection reset") },
+				jsonResponse(http.StatusOK, `{"code":0}`),
+			}}
+			o, _ := newTestOutbound(next, 3)
+
+			req, _ := http.NewRequest(tt.method, "https://open.feishu.cn"+tt.path, strings.NewReader(tt.body))
+			_, _ = o.Do(req)
+			if stats := o.stats(); stats.Requests != tt.wantRequests {
+				t.Errorf("Expected %d attempts, got %+v", tt.wantRequests, stats)
+			}
+		})
+	}
+
+	// Frequency limits are rejected before running, every write is retried
+	next := &scriptedHTTP{responses: []func() (*http.Response, error){
+		jsonResponse(http.StatusOK, `{"code":230020}`),
+		jsonResponse(http.StatusOK, `{"code":0}`),
+	}}
+	o, _ := newTestOutbound(next, 3)
+	req, _ := http.NewRequest(http.MethodPost, "https://open.feishu.cn/open-apis/im/v1/messages/om_1/reactions", strings.NewReader(`{}`))
+	if _, err := o.Do(req); err != nil || o.stats().Requests != 2 {
+		t.Errorf("Expected a frequency limited reaction to be retried, got %v, %+v", err, o.stats())
+	}
+}
+
+func TestOutbound_WaitsForRateLimitReset(t *testing.T) {
+	limited := jsonResponse(http.StatusTooManyRequests, `{"code":99991400}`)
+	next := &scriptedHTTP{responses: []func() (*http.Response, error){
+		func() (*http.Response, error) {
+			resp, _ := limited()
+			resp.Header.Set("x-ogw-ratelimit-reset", "30")
+			return resp, nil
+		},
+		jsonResponse(http.StatusOK, `{"code":0}`),
+	}}
+	o, slept := newTestOutbound(next, 3)
+
+	if _, err := o.Do(newSendRequest()); err != nil {
+		t.Fatalf("Do failed: %v", err)
+	}
+	if len(*slept) != 1 || (*slept)[0] != 30*time.Second {
+		t.Errorf("Expected to wait for the rate limit reset, got %v", *slept)
+	}
+}
+
+func TestTokenBucket(t *testing.T) {
+	bucket := newTokenBucket(2)
+	now := bucket.last
+
+	if bucket.reserve(now) != 0 || bucket.reserve(now) != 0 {
+		t.Fatal("Expected a burst of two to pass")
+	}
+	if wait := bucket.reserve(now); wait != 500*time.Millisecond {
+		t.Errorf("Expected the third call to wait 500ms, got %v", wait)
+	}
+	if wait := bucket.reserve(now.Add(time.Second)); wait != 0 {
+		t.Errorf("Expected tokens to refill, got %v", wait)
+	}
+}
+
+func TestAPIFamily(t *testing.T) {
+	tests := []struct {
+		method, path, want string
+	}{
+		{"POST", "/open-apis/im/v1/messages", FamilySend},
+		{"POST", "/open-apis/im/v1/messages/om_1/reply", FamilySend},
+		{"PATCH", "/open-apis/im/v1/messages/om_1", FamilyUpdate},
+		{"DELETE", "/open-apis/im/v1/messages/om_1", FamilyUpdate},
+		{"POST", "/open-apis/im/v1/messages/om_1/reactions", FamilyReaction},
+		{"GET", "/open-apis/im/v1/messages", FamilyRead},
+		{"GET", "/open-apis/im/v1/chats/oc_1/members", FamilyRead},
+		{"GET", "/open-apis/im/v1/messages/om_1/resources/img_1", FamilyResource},
+		{"POST", "/open-apis/im/v1/images", FamilyResource},
+		{"POST", "/open-apis/auth/v3/tenant_access_token/internal", ""},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		if got := apiFamily(req); got != tt.want {
+			t.Errorf("apiFamily(%s %s) = %q, want %q", tt.method, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestSendText_RetriesWithSameUUID(t *testing.T) {
+	var mu sync.Mutex
+	var uuids []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/open-apis/auth/v3/tenant_access_token/internal":
+			fmt.Fprint(w, `{"code":0,"tenant_access_token":"t-123","expire":7200}`)
+		case "/open-apis/im/v1/messages":
+			var body struct {
+				UUID string `json:"uuid"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			mu.Lock()
+			uuids = append(uuids, body.UUID)
+			first := len(uuids) == 1
+			mu.Unlock()
+			if first {
+				fmt.Fprint(w, `{"code":230020,"msg":"frequency limit"}`)
+				return
+			}
+			fmt.Fprint(w, `{"code":0,"data":{"message_id":"om_1"}}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	client := NewClient("app_id", "app_secret")
+	client.SetBaseURL(srv.URL)
+	client.outbound.sleep = func(ctx context.Context, d time.Duration) error { return nil }
+	client.larkCli = client.newLarkClient()
+
+	if err := client.SendText("oc_1", "hello"); err != nil {
+		t.Fatalf("SendText failed: %v", err)
+	}
+	if len(uuids) != 2 || uuids[0] == "" || uuids[0] != uuids[1] {
+		t.Errorf("Expected the retry to reuse the send's uuid, got %q", uuids)
+	}
+	if stats := client.OutboundStats(); stats.Throttled != 1 || stats.Retries != 1 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
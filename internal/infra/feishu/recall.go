@@ -0,0 +1,141 @@
+package feishu
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	larkevent "github.com/larksuite/oapi-sdk-go/v3/event"
+	larkim "github.com/larksuite/oapi-sdk-go/v3/service/im/v1"
+)
+
+// messageUpdatedEvent is the event type of edited messages
+// The SDK has no typed handler for it, the payload matches im.message.receive_v1
+const messageUpdatedEvent = "im.message.updated_v1"
+
+// replyRetention is how long sent replies can be recalled with their trigger
+const replyRetention = 24 * time.Hour
+
+// MessageRecall represents a recalled message
+type MessageRecall struct {
+	ChatID string
+	MsgID  string
+}
+
+// RecallHandler is the callback for recalled messages
+type RecallHandler func(recall *MessageRecall)
+
+// OnMessageRecalled sets the handler for recalled messages
+func (c *Client) OnMessageRecalled(handler RecallHandler) {
+	c.onRecall = handler
+}
+
+// OnMessageEdited sets the handler for edited messages, called with the new content
+func (c *Client) OnMessageEdited(handler MessageHandler) {
+	c.onEdit = handler
+}
+
+func (c *Client) handleRecall(ctx context.Context, event *larkim.P2MessageRecalledV1) error {
+	if c.onRecall == nil || event.Event == nil || event.Event.MessageId == nil {
+		return nil
+	}
+	recall := &MessageRecall{MsgID: *event.Event.MessageId}
+	if event.Event.ChatId != nil {
+		recall.ChatID = *event.Event.ChatId
+	}
+	fmt.Printf("[Feishu] Message %s recalled in %s\n", recall.MsgID, recall.ChatID)
+	// Return immediately to let SDK send ACK
+	go c.onRecall(recall)
+	return nil
+}
+
+func (c *Client) handleEdit(ctx context.Context, event *larkevent.EventReq) error {
+	if c.onEdit == nil {
+		return nil
+	}
+	var payload larkim.P2MessageReceiveV1
+	if err := json.Unmarshal(event.Body, &payload); err != nil {
+		return fmt.Errorf("decode %s: %w", messageUpdatedEvent, err)
+	}
+	msg := c.parseMessageEvent(payload.Event)
+	if msg == nil {
+		return nil
+	}
+	fmt.Printf("[Feishu] Message %s edited in %s: %s\n", msg.MsgID, msg.ChatID, truncate(msg.Content, 50))
+	go c.onEdit(msg)
+	return nil
+}
+
+// replyLog remembers the replies sent to each message so they can be recalled
+type replyLog struct {
+	mu      sync.Mutex
+	replies map[string][]sentReply // Triggering message ID -> replies
+}
+
+type sentReply struct {
+	msgID  string
+	sentAt time.Time
+}
+
+func newReplyLog() *replyLog {
+	return &replyLog{replies: make(map[string][]sentReply)}
+}
+
+// add records a reply and drops entries past the retention
+func (l *replyLog) add(parentID, msgID string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	for id, sent := range l.replies {
+		if now.Sub(sent[len(sent)-1].sentAt) > replyRetention {
+			delete(l.replies, id)
+		}
+	}
+	l.replies[parentID] = append(l.replies[parentID], sentReply{msgID: msgID, sentAt: now})
+}
+
+// take removes and returns the replies to a message
+func (l *replyLog) take(parentID string) []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	sent := l.replies[parentID]
+	delete(l.replies, parentID)
+	msgIDs := make([]string, len(sent))
+	for i, r := range sent {
+		msgIDs[i] = r.msgID
+	}
+	return msgIDs
+}
+
+// RecallReplies recalls the bot's replies to a message and returns how many
+// Only replies sent with Reply* in the last day are known
+func (c *Client) RecallReplies(messageID string) (int, error) {
+	recalled := 0
+	for _, msgID := range c.replies.take(messageID) {
+		if err := c.DeleteMessage(msgID); err != nil {
+			return recalled, err
+		}
+		recalled++
+	}
+	return recalled, nil
+}
+
+// DeleteMessage recalls a message sent by the bot
+func (c *Client) DeleteMessage(messageID string) error {
+	req := larkim.NewDeleteMessageReqBuilder().
+		MessageId(messageID).
+		Build()
+
+	resp, err := c.larkCli.Im.Message.Delete(context.Background(), req)
+	if err != nil {
+		return fmt.Errorf("delete message failed: %w", err)
+	}
+	if !resp.Success() {
+		return fmt.Errorf("delete message error: %s", resp.Msg)
+	}
+	return nil
+}
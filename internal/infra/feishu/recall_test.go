@@ -0,0 +1,28 @@
+package feishu
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReplyLog(t *testing.T) {
+	log := newReplyLog()
+	log.add("om_q1", "om_r1")
+	log.add("om_q1", "om_r2")
+	log.add("om_q2", "om_r3")
+
+	if got := strings.Join(log.take("om_q1"), ","); got != "om_r1,om_r2" {
+		t.Errorf("take(om_q1) = %s, want om_r1,om_r2", got)
+	}
+	if got := log.take("om_q1"); len(got) != 0 {
+		t.Errorf("Expected replies to be taken once, got %v", got)
+	}
+
+	// Old entries are pruned when a reply is added
+	log.replies["om_q2"][0].sentAt = time.Now().Add(-replyRetention - time.Minute)
+	log.add("om_q3", "om_r4")
+	if got := log.take("om_q2"); len(got) != 0 {
+		t.Errorf("Expected expired replies to be dropped, got %v", got)
+	}
+}
@@ -0,0 +1,136 @@
+package feishu
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	larkim "github.com/larksuite/oapi-sdk-go/v3/service/im/v1"
+)
+
+// SendImage uploads a local image and posts it to a chat
+func (c *Client) SendImage(chatID, path string) error {
+	imageKey, err := c.UploadImage(path)
+	if err != nil {
+		return err
+	}
+	content, _ := json.Marshal(map[string]string{"image_key": imageKey})
+	if err := c.create(chatID, larkim.MsgTypeImage, string(content)); err != nil {
+		return fmt.Errorf("send image: %w", err)
+	}
+	fmt.Printf("[Feishu] Image %s sent to %s\n", filepath.Base(path), chatID)
+	return nil
+}
+
+// SendFile uploads a local file and posts it to a chat
+func (c *Client) SendFile(chatID, path string) error {
+	fileKey, err := c.UploadFile(path)
+	if err != nil {
+		return err
+	}
+	content, _ := json.Marshal(map[string]string{"file_key": fileKey})
+	if err := c.create(chatID, larkim.MsgTypeFile, string(content)); err != nil {
+		return fmt.Errorf("send file: %w", err)
+	}
+	fmt.Printf("[Feishu] File %s sent to %s\n", filepath.Base(path), chatID)
+	return nil
+}
+
+// UploadImage uploads an image for use in messages and returns its image key
+func (c *Client) UploadImage(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open image: %w", err)
+	}
+	defer file.Close()
+
+	req := larkim.NewCreateImageReqBuilder().
+		Body(larkim.NewCreateImageReqBodyBuilder().
+			ImageType("message").
+			Image(file).
+			Build()).
+		Build()
+
+	resp, err := c.larkCli.Im.Image.Create(context.Background(), req)
+	if err != nil {
+		return "", fmt.Errorf("upload image failed: %w", err)
+	}
+	if !resp.Success() {
+		return "", fmt.Errorf("upload image error: %s", resp.Msg)
+	}
+	if resp.Data == nil || resp.Data.ImageKey == nil {
+		return "", fmt.Errorf("upload image returned no image key")
+	}
+	return *resp.Data.ImageKey, nil
+}
+
+// UploadFile uploads a file for use in messages and returns its file key
+func (c *Client) UploadFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	req := larkim.NewCreateFileReqBuilder().
+		Body(larkim.NewCreateFileReqBodyBuilder().
+			FileType(uploadFileType(path)).
+			FileName(filepath.Base(path)).
+			File(file).
+			Build()).
+		Build()
+
+	resp, err := c.larkCli.Im.File.Create(context.Background(), req)
+	if err != nil {
+		return "", fmt.Errorf("upload file failed: %w", err)
+	}
+	if !resp.Success() {
+		return "", fmt.Errorf("upload file error: %s", resp.Msg)
+	}
+	if resp.Data == nil || resp.Data.FileKey == nil {
+		return "", fmt.Errorf("upload file returned no file key")
+	}
+	return *resp.Data.FileKey, nil
+}
+
+// create sends a message of any type to a chat
+func (c *Client) create(chatID, msgType, content string) error {
+	req := larkim.NewCreateMessageReqBuilder().
+		ReceiveIdType(larkim.ReceiveIdTypeChatId).
+		Body(larkim.NewCreateMessageReqBodyBuilder().
+			ReceiveId(chatID).
+			Uuid(newUUID()).
+			MsgType(msgType).
+			Content(content).
+			Build()).
+		Build()
+
+	resp, err := c.larkCli.Im.Message.Create(context.Background(), req)
+	if err != nil {
+		return fmt.Errorf("send message failed: %w", err)
+	}
+	if !resp.Success() {
+		return fmt.Errorf("send message error: %s", resp.Msg)
+	}
+	return nil
+}
+
+// uploadFileType maps a file name to a Feishu upload file type
+// Everything without a dedicated type is uploaded as a generic stream
+func uploadFileType(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".pdf":
+		return "pdf"
+	case ".doc", ".docx":
+		return "doc"
+	case ".xls", ".xlsx":
+		return "xls"
+	case ".ppt", ".pptx":
+		return "ppt"
+	default:
+		return "stream"
+	}
+}
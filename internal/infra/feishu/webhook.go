@@ -0,0 +1,106 @@
+package feishu
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	larkcore "github.com/larksuite/oapi-sdk-go/v3/core"
+	"github.com/larksuite/oapi-sdk-go/v3/core/httpserverext"
+	larkevent "github.com/larksuite/oapi-sdk-go/v3/event"
+	"github.com/larksuite/oapi-sdk-go/v3/event/dispatcher"
+)
+
+// WebhookConfig configures receiving events over HTTP
+type WebhookConfig struct {
+	Addr              string // Listen address, e.g. ":8080"
+	Path              string // Request path of the event subscription URL
+	VerificationToken string // Verification Token of the app's event subscription
+	EncryptKey        string // Encrypt Key, empty if events are sent in plain text
+}
+
+// SetWebhook receives events on an HTTP endpoint instead of the WebSocket connection
+// Must be called before Start
+func (c *Client) SetWebhook(cfg WebhookConfig) {
+	if cfg.Path == "" {
+		cfg.Path = "/"
+	}
+	c.webhook = &cfg
+}
+
+// serveWebhook serves the event endpoint until Stop is called
+func (c *Client) serveWebhook(eventHandler *dispatcher.EventDispatcher) error {
+	mux := http.NewServeMux()
+	mux.Handle(c.webhook.Path, c.webhookHandler(eventHandler))
+	c.httpSrv = &http.Server{
+		Addr:              c.webhook.Addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-c.ctx.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = c.httpSrv.Shutdown(ctx)
+	}()
+
+	fmt.Printf("[Feishu] Listening for webhook events on %s%s\n", c.webhook.Addr, c.webhook.Path)
+	if err := c.httpSrv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("webhook server: %w", err)
+	}
+	return nil
+}
+
+// webhookHandler answers URL verification challenges and dispatches events
+// With an Encrypt Key the SDK decrypts payloads and checks their signature.
+// Plain text events are checked against the verification token instead
+func (c *Client) webhookHandler(eventHandler *dispatcher.EventDispatcher) http.Handler {
+	handle := httpserverext.NewEventHandlerFunc(eventHandler, larkevent.WithLogLevel(larkcore.LogLevelInfo))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if c.webhook.EncryptKey == "" && c.webhook.VerificationToken != "" {
+			body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBody))
+			if err != nil {
+				http.Error(w, "read body failed", http.StatusBadRequest)
+				return
+			}
+			if webhookToken(body) != c.webhook.VerificationToken {
+				fmt.Printf("[Feishu] Rejected webhook request with a wrong verification token\n")
+				http.Error(w, "invalid verification token", http.StatusUnauthorized)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+		}
+		handle(w, r)
+	})
+}
+
+// maxWebhookBody limits the size of plain text event requests
+const maxWebhookBody = 10 << 20
+
+// webhookToken returns the verification token of a plain text event
+// Schema 2.0 events carry it in the header, challenges and older events at the top
+func webhookToken(body []byte) string {
+	var payload struct {
+		Token  string `json:"token"`
+		Header *struct {
+			Token string `json:"token"`
+		} `json:"header"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return ""
+	}
+	if payload.Header != nil {
+		return payload.Header.Token
+	}
+	return payload.Token
+}
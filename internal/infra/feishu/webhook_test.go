@@ -0,0 +1,135 @@
+package feishu
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	larkevent "github.com/larksuite/oapi-sdk-go/v3/event"
+)
+
+const testMessageEvent = `{
+	"schema": "2.0",
+	"header": {"event_id": "ev_1", "event_type": "im.message.receive_v1", "token": "%TOKEN%"},
+	"event": {
+		"sender": {"sender_id": {"open_id": "ou_user"}, "sender_type": "user"},
+		"message": {
+			"message_id": "om_1", "chat_id": "oc_1", "chat_type": "p2p", "message_type": "text",
+			"create_time": "1700000000000", "content": "{\"text\":\"hello\"}"
+		}
+	}
+}`
+
+// encryptEvent encrypts a payload the way Feishu does for apps with an Encrypt Key
+func encryptEvent(t *testing.T, plain, key string) string {
+	t.Helper()
+	sum := sha256.Sum256([]byte(key))
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	padding := aes.BlockSize - len(plain)%aes.BlockSize
+	data := append([]byte(plain), bytes.Repeat([]byte{byte(padding)}, padding)...)
+	buf := make([]byte, aes.BlockSize+len(data))
+	iv := buf[:aes.BlockSize]
+	if _, err := rand.Read(iv); err != nil {
+		t.Fatal(err)
+	}
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(buf[aes.BlockSize:], data)
+	body, _ := json.Marshal(map[string]string{"encrypt": base64.StdEncoding.EncodeToString(buf)})
+	return string(body)
+}
+
+// newWebhookTestClient returns a webhook handler and a channel of received messages
+func newWebhookTestClient(cfg WebhookConfig) (http.Handler, chan *Message) {
+	received := make(chan *Message, 1)
+	c := NewClient("app", "secret")
+	c.SetWebhook(cfg)
+	c.OnMessage(func(msg *Message) { received <- msg })
+	return c.webhookHandler(c.newEventDispatcher()), received
+}
+
+func post(handler http.Handler, body string, header map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/webhook/event", strings.NewReader(body))
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func waitMessage(t *testing.T, received chan *Message) *Message {
+	t.Helper()
+	select {
+	case msg := <-received:
+		return msg
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the event to reach the message handler")
+		return nil
+	}
+}
+
+func TestWebhook_PlainText(t *testing.T) {
+	handler, received := newWebhookTestClient(WebhookConfig{VerificationToken: "vtoken"})
+
+	rec := post(handler, `{"type":"url_verification","challenge":"ch-1","token":"vtoken"}`, nil)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"challenge":"ch-1"`) {
+		t.Fatalf("Expected the challenge to be echoed, got %d %s", rec.Code, rec.Body.String())
+	}
+
+	rec = post(handler, strings.ReplaceAll(testMessageEvent, "%TOKEN%", "wrong"), nil)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected a wrong token to be rejected, got %d", rec.Code)
+	}
+
+	rec = post(handler, strings.ReplaceAll(testMessageEvent, "%TOKEN%", "vtoken"), nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected the event to be accepted, got %d %s", rec.Code, rec.Body.String())
+	}
+	if msg := waitMessage(t, received); msg.MsgID != "om_1" || msg.Content != "hello" {
+		t.Errorf("Unexpected message: %+v", msg)
+	}
+}
+
+func TestWebhook_Encrypted(t *testing.T) {
+	const key = "encrypt-key"
+	handler, received := newWebhookTestClient(WebhookConfig{VerificationToken: "vtoken", EncryptKey: key})
+
+	challenge := encryptEvent(t, `{"type":"url_verification","challenge":"ch-2","token":"vtoken"}`, key)
+	rec := post(handler, challenge, nil)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"challenge":"ch-2"`) {
+		t.Fatalf("Expected the decrypted challenge to be echoed, got %d %s", rec.Code, rec.Body.String())
+	}
+
+	body := encryptEvent(t, strings.ReplaceAll(testMessageEvent, "%TOKEN%", "vtoken"), key)
+	rec = post(handler, body, map[string]string{
+		larkevent.EventRequestTimestamp: "1700000000",
+		larkevent.EventRequestNonce:     "nonce",
+		larkevent.EventSignature:        "forged",
+	})
+	if rec.Code == http.StatusOK {
+		t.Error("Expected a bad signature to be rejected")
+	}
+
+	rec = post(handler, body, map[string]string{
+		larkevent.EventRequestTimestamp: "1700000000",
+		larkevent.EventRequestNonce:     "nonce",
+		larkevent.EventSignature:        larkevent.Signature("1700000000", "nonce", key, body),
+	})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected the signed event to be accepted, got %d %s", rec.Code, rec.Body.String())
+	}
+	if msg := waitMessage(t, received); msg.MsgID != "om_1" {
+		t.Errorf("Unexpected message: %+v", msg)
+	}
+}
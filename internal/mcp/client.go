@@ -382,6 +382,31 @@ func (c *Client) DeleteHeartbeat(chatID string) error {
 	return c.delete(fmt.Sprintf("/api/heartbeat/%s", url.PathEscape(chatID)))
 }
 
+// ============ Files ============
+
+// Artifact describes a workspace file posted to a chat
+type Artifact struct {
+	Path string `json:"path"`
+	Name string `json:"name"`
+	Kind string `json:"kind"` // image or file
+	Size int64  `json:"size"`
+}
+
+// SendFile posts a file from the Codex workspace to a chat
+func (c *Client) SendFile(chatID, path string) (*Artifact, error) {
+	var result struct {
+		Artifact *Artifact `json:"artifact"`
+	}
+	body := map[string]string{
+		"chat_id": chatID,
+		"path":    path,
+	}
+	if err := c.post("/api/files", body, &result); err != nil {
+		return nil, err
+	}
+	return result.Artifact, nil
+}
+
 // ============ HTTP Helpers ============
 
 func (c *Client) get(path string, result interface{}) error {
@@ -29,6 +29,8 @@ func (h *Handler) HandleToolCall(name string, args map[string]interface{}) (inte
 		return h.handleGetChatMembers(ctx, args)
 	case "feishu_get_chat_history":
 		return h.handleGetChatHistory(ctx, args)
+	case "feishu_send_file":
+		return h.handleSendFile(ctx, args)
 	case "feishu_add_to_whitelist":
 		return h.handleAddToWhitelist(ctx, args)
 	case "feishu_remove_from_whitelist":
@@ -118,6 +120,28 @@ func (h *Handler) handleGetChatHistory(ctx *ChatContext, args map[string]interfa
 	}, nil
 }
 
+func (h *Handler) handleSendFile(ctx *ChatContext, args map[string]interface{}) (interface{}, error) {
+	chatID := getStringArg(args, "chat_id", ctx.ChatID)
+	if chatID == "" {
+		return nil, fmt.Errorf("no chat context available")
+	}
+
+	path := getStringArg(args, "path", "")
+	if path == "" {
+		return nil, fmt.Errorf("path is required")
+	}
+
+	artifact, err := h.client.SendFile(chatID, path)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"success":  true,
+		"artifact": artifact,
+	}, nil
+}
+
 // ============ Whitelist Handlers ============
 
 func (h *Handler) handleAddToWhitelist(ctx *ChatContext, args map[string]interface{}) (interface{}, error) {
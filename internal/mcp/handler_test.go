@@ -119,6 +119,45 @@ func TestHandleToolCall_AddToWhitelist(t *testing.T) {
 	}
 }
 
+func TestHandleToolCall_SendFile(t *testing.T) {
+	var gotBody map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/context":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"chat_id": "test-chat",
+			})
+		case "/api/files":
+			json.NewDecoder(r.Body).Decode(&gotBody)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"success":  true,
+				"artifact": Artifact{Path: "/work/out.csv", Name: "out.csv", Kind: "file", Size: 12},
+			})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	handler := NewHandler(NewClient(server.URL))
+
+	result, err := handler.HandleToolCall("feishu_send_file", map[string]interface{}{"path": "out.csv"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if gotBody["chat_id"] != "test-chat" || gotBody["path"] != "out.csv" {
+		t.Errorf("Unexpected request body %v", gotBody)
+	}
+	artifact := result.(map[string]interface{})["artifact"].(*Artifact)
+	if artifact.Name != "out.csv" {
+		t.Errorf("Expected artifact out.csv, got %+v", artifact)
+	}
+
+	if _, err := handler.HandleToolCall("feishu_send_file", nil); err == nil {
+		t.Error("Expected an error without a path")
+	}
+}
+
 func TestHandleToolCall_AddKeyword(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		switch r.URL.Path {
@@ -326,6 +365,7 @@ func TestGetToolDefinitions(t *testing.T) {
 	expectedTools := []string{
 		"feishu_get_chat_members",
 		"feishu_get_chat_history",
+		"feishu_send_file",
 		"feishu_add_to_whitelist",
 		"feishu_remove_from_whitelist",
 		"feishu_list_whitelist",
@@ -31,6 +31,20 @@ func GetToolDefinitions() []ToolDefinition {
 				},
 			},
 		},
+		{
+			Name:        "feishu_send_file",
+			Description: "Post a file from your workspace into the current chat, such as a generated chart, patch, CSV or log. Images are shown inline, other files are attached. The file must be inside the working directory.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Path of the file, relative to the working directory or absolute",
+					},
+				},
+				"required": []string{"path"},
+			},
+		},
 		// Whitelist management tools
 		{
 			Name:        "feishu_add_to_whitelist",
@@ -2,7 +2,10 @@ package server
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
@@ -21,13 +24,28 @@ type FeishuServer struct {
 	convSvc      *service.ConversationService
 	bufferUC     *usecase.BufferUsecase
 	scheduler    *service.DigestScheduler
+	commands     *service.CommandRouter
+	cards        *service.CardRouter
+	lifecycle    *service.LifecycleService
+	userRepo     repo.UserRepo
+
+	// Directory attachments are downloaded into, one subdirectory per message
+	attachmentDir string
+
+	// Recall the bot's replies when their triggering message is recalled
+	recallReplies bool
 
 	// API server for setting context
 	apiServer *api.Server
 
-	// Message deduplication cache
+	// Message deduplication cache, used when the journal is disabled or failing
 	seenMsgsMu sync.RWMutex
 	seenMsgs   map[string]time.Time // msgID -> timestamp
+
+	// Journal of received messages, for catching up after restarts and reconnects
+	journalUC *usecase.JournalUsecase
+	catchUpMu sync.Mutex
+	resumed   bool // Messages interrupted by the last shutdown were resumed
 }
 
 // NewFeishuServer creates a new Feishu server
@@ -67,11 +85,57 @@ func NewFeishuServer(
 	return s
 }
 
+// SetCardRouter enables card buttons, routed to the handler of their action
+func (s *FeishuServer) SetCardRouter(router *service.CardRouter) {
+	s.cards = router
+}
+
+// SetLifecycleService enables onboarding and cleanup when the bot joins or leaves chats
+func (s *FeishuServer) SetLifecycleService(lifecycle *service.LifecycleService) {
+	s.lifecycle = lifecycle
+}
+
+// SetUserRepo resolves senders who are not chat members, e.g. in P2P chats
+func (s *FeishuServer) SetUserRepo(userRepo repo.UserRepo) {
+	s.userRepo = userRepo
+}
+
+// SetJournal records received messages durably, deduplicating across restarts
+// On every (re)connection the bridge catches up on messages it missed
+func (s *FeishuServer) SetJournal(journalUC *usecase.JournalUsecase) {
+	s.journalUC = journalUC
+	s.convSvc.SetJournal(journalUC)
+}
+
+// DigestScheduler returns the digest scheduler, nil without a buffer
+func (s *FeishuServer) DigestScheduler() *service.DigestScheduler {
+	return s.scheduler
+}
+
+// SetCommandRouter enables slash commands, answered without Codex
+func (s *FeishuServer) SetCommandRouter(router *service.CommandRouter) {
+	s.commands = router
+}
+
+// SetAttachmentDir sets where files sent to the bot are downloaded
+// It should be inside the Codex workspace so Codex can read them
+func (s *FeishuServer) SetAttachmentDir(dir string) {
+	s.attachmentDir = dir
+}
+
+// SetRecallReplies makes recalling a message also recall the bot's replies to it
+func (s *FeishuServer) SetRecallReplies(enabled bool) {
+	s.recallReplies = enabled
+}
+
 // Start starts the server
 func (s *FeishuServer) Start() error {
 	// Start event loop
 	s.convSvc.StartEventLoop()
 
+	// Run messages that were still queued when the bridge stopped
+	s.convSvc.ResumeQueues()
+
 	// Start digest scheduler
 	if s.scheduler != nil {
 		s.scheduler.Start(context.Background())
@@ -79,6 +143,13 @@ func (s *FeishuServer) Start() error {
 
 	// Set message handler and start Feishu client
 	s.feishuClient.OnMessage(s.handleMessage)
+	s.feishuClient.OnCardAction(s.handleCardAction)
+	s.feishuClient.OnMessageRecalled(s.handleRecall)
+	s.feishuClient.OnMessageEdited(s.handleEdit)
+	s.feishuClient.OnBotAdded(s.handleBotAdded)
+	s.feishuClient.OnBotRemoved(s.handleBotRemoved)
+	s.feishuClient.OnMembersChanged(s.handleMembersChanged)
+	s.feishuClient.OnConnected(s.handleConnected)
 	return s.feishuClient.Start()
 }
 
@@ -96,39 +167,26 @@ func (s *FeishuServer) handleMessage(msg *feishu.Message) {
 		msg.MsgType, msg.ChatID, msg.ChatType, truncate(msg.Content, 50))
 
 	// Message deduplication: check if already processed
-	if s.isMessageSeen(msg.MsgID) {
+	ctx := context.Background()
+	if !s.receive(ctx, msg) {
 		fmt.Printf("[Server] Duplicate message ignored: %s\n", msg.MsgID)
 		return
 	}
-	s.markMessageSeen(msg.MsgID)
-
-	ctx := context.Background()
+	s.process(ctx, msg)
+}
 
-	// Convert chat type
-	chatType := domain.ChatTypeP2P
-	if msg.ChatType == "group" {
-		chatType = domain.ChatTypeGroup
-	}
+// process routes a received message to a command, the buffer or Codex
+func (s *FeishuServer) process(ctx context.Context, msg *feishu.Message) {
+	req := s.newRequest(ctx, msg)
 
-	// Get sender name
-	senderName := ""
-	senderID := ""
-	if msg.Sender != nil {
-		senderID = msg.Sender.SenderID
-		// Try to get name from member list
-		members, err := s.messageRepo.GetChatMembers(ctx, msg.ChatID)
-		if err == nil {
-			for _, m := range members {
-				if m.UserID == senderID {
-					senderName = m.Name
-					break
-				}
-			}
-		}
+	// Slash commands are answered by the bridge and never buffered
+	if s.commands != nil && s.commands.Dispatch(ctx, req) {
+		s.journalMark(ctx, msg.MsgID, domain.JournalReplied)
+		return
 	}
 
 	// Check if should process immediately (group chat uses Buffer system)
-	if chatType == domain.ChatTypeGroup && s.bufferUC != nil {
+	if req.ChatType == domain.ChatTypeGroup && s.bufferUC != nil {
 		shouldProcess, reason := s.bufferUC.ShouldProcessImmediately(ctx, msg.ChatID, msg.Content, msg.MentionsBot)
 		if !shouldProcess {
 			// Add to buffer
@@ -136,14 +194,16 @@ func (s *FeishuServer) handleMessage(msg *feishu.Message) {
 				ChatID:     msg.ChatID,
 				MsgID:      msg.MsgID,
 				Content:    msg.Content,
-				SenderID:   senderID,
-				SenderName: senderName,
+				SenderID:   req.SenderID,
+				SenderName: req.SenderName,
 				CreatedAt:  time.Now(),
 			}
 			if err := s.bufferUC.AddToBuffer(ctx, bufferedMsg); err != nil {
 				fmt.Printf("[Server] Failed to buffer message: %v\n", err)
+				s.journalFail(ctx, msg.MsgID, "buffer: "+err.Error())
 			} else {
 				fmt.Printf("[Server] Message buffered for later digest\n")
+				s.journalMark(ctx, msg.MsgID, domain.JournalReplied)
 			}
 			return
 		}
@@ -162,7 +222,7 @@ func (s *FeishuServer) handleMessage(msg *feishu.Message) {
 		}
 
 		chatTypeStr := "p2p"
-		if chatType == domain.ChatTypeGroup {
+		if req.ChatType == domain.ChatTypeGroup {
 			chatTypeStr = "group"
 		}
 
@@ -176,57 +236,322 @@ func (s *FeishuServer) handleMessage(msg *feishu.Message) {
 			msg.ChatID, chatTypeStr, len(contextMembers))
 	}
 
-	// Download images
-	var imagePaths []string
-	for _, imageKey := range msg.ImageKeys {
-		path, err := s.feishuClient.DownloadImage(msg.MsgID, imageKey)
+	// Download images and attachments, and fetch the message it replies to
+	s.downloadMedia(msg, req)
+	s.attachParent(ctx, msg, req)
+
+	// Process message
+	if err := s.convSvc.HandleMessage(ctx, req); err != nil {
+		if errors.Is(err, service.ErrAlreadyProcessing) {
+			_ = s.messageRepo.SendText(ctx, msg.ChatID, "Processing previous request, please wait...")
+		} else {
+			fmt.Printf("[Server] Handle message error: %v\n", err)
+			s.journalFail(ctx, msg.MsgID, err.Error())
+		}
+	}
+}
+
+// handleRecall cancels the turn of a recalled message
+func (s *FeishuServer) handleRecall(recall *feishu.MessageRecall) {
+	ctx := context.Background()
+	if _, err := s.convSvc.HandleRecall(ctx, recall.ChatID, recall.MsgID); err != nil {
+		fmt.Printf("[Server] Failed to cancel recalled message %s: %v\n", recall.MsgID, err)
+	}
+	if !s.recallReplies {
+		return
+	}
+	recalled, err := s.messageRepo.RecallReplies(ctx, recall.MsgID)
+	if err != nil {
+		fmt.Printf("[Server] Failed to recall replies to %s: %v\n", recall.MsgID, err)
+	}
+	if recalled > 0 {
+		fmt.Printf("[Server] Recalled %d replies to %s\n", recalled, recall.MsgID)
+	}
+}
+
+// handleEdit re-runs a message edited while it was running or queued
+func (s *FeishuServer) handleEdit(msg *feishu.Message) {
+	// The same edit may be delivered more than once
+	editID := fmt.Sprintf("%s@%d", msg.MsgID, msg.UpdateTime)
+	if s.isMessageSeen(editID) {
+		return
+	}
+	s.markMessageSeen(editID)
+
+	ctx := context.Background()
+	req := s.newRequest(ctx, msg)
+	s.downloadMedia(msg, req)
+	s.attachParent(ctx, msg, req)
+	handled, err := s.convSvc.HandleEdit(ctx, req)
+	if err != nil {
+		fmt.Printf("[Server] Failed to handle edit of %s: %v\n", msg.MsgID, err)
+	} else if !handled {
+		fmt.Printf("[Server] Ignored edit of %s, it is not pending\n", msg.MsgID)
+	}
+}
+
+// handleBotAdded greets a group the bot was added to
+func (s *FeishuServer) handleBotAdded(event *feishu.ChatEvent) {
+	if s.lifecycle == nil {
+		return
+	}
+	if err := s.lifecycle.HandleBotAdded(context.Background(), event.ChatID); err != nil {
+		fmt.Printf("[Server] Failed to onboard %s: %v\n", event.ChatID, err)
+	}
+}
+
+// handleBotRemoved cleans up a group the bot left
+func (s *FeishuServer) handleBotRemoved(event *feishu.ChatEvent) {
+	if s.lifecycle == nil {
+		return
+	}
+	if err := s.lifecycle.HandleBotRemoved(context.Background(), event.ChatID); err != nil {
+		fmt.Printf("[Server] Failed to clean up %s: %v\n", event.ChatID, err)
+	}
+}
+
+// handleMembersChanged refreshes what the bridge knows about a group's members
+func (s *FeishuServer) handleMembersChanged(event *feishu.ChatEvent) {
+	if s.lifecycle == nil {
+		return
+	}
+	if err := s.lifecycle.HandleMembersChanged(context.Background(), event.ChatID); err != nil {
+		fmt.Printf("[Server] Failed to update members of %s: %v\n", event.ChatID, err)
+	}
+}
+
+// handleConnected catches up on messages sent while events were not flowing
+// The first connection after startup also resumes messages the shutdown interrupted
+func (s *FeishuServer) handleConnected() {
+	if s.journalUC == nil {
+		return
+	}
+	s.catchUpMu.Lock()
+	defer s.catchUpMu.Unlock()
+
+	ctx := context.Background()
+	if !s.resumed {
+		s.resumed = true
+		s.resumeUnfinished(ctx)
+	}
+	s.replayMissed(ctx)
+}
+
+// resumeUnfinished handles again the messages whose handling a shutdown interrupted
+// Queued messages are resumed by the persistent queue instead
+func (s *FeishuServer) resumeUnfinished(ctx context.Context) {
+	if pruned, err := s.journalUC.Prune(ctx); err != nil {
+		fmt.Printf("[Server] Failed to prune the message journal: %v\n", err)
+	} else if pruned > 0 {
+		fmt.Printf("[Server] Pruned %d old journal entries\n", pruned)
+	}
+
+	entries, err := s.journalUC.Unfinished(ctx)
+	if err != nil {
+		fmt.Printf("[Server] Failed to list unfinished messages: %v\n", err)
+		return
+	}
+	for _, entry := range entries {
+		msg, err := s.fetchMessage(ctx, entry.MsgID)
 		if err != nil {
-			fmt.Printf("[Server] Failed to download image %s: %v\n", imageKey, err)
+			fmt.Printf("[Server] Failed to fetch unfinished message %s: %v\n", entry.MsgID, err)
+			s.journalFail(ctx, entry.MsgID, "fetch: "+err.Error())
+			continue
+		}
+		if msg.Recalled {
+			s.journalFail(ctx, entry.MsgID, "recalled")
 			continue
 		}
-		imagePaths = append(imagePaths, path)
+		fmt.Printf("[Server] Resuming message %s in %s, interrupted while %s\n", entry.MsgID, entry.Key(), entry.State)
+		s.process(ctx, msg)
 	}
+}
 
-	// Build request
+// replayMissed handles the messages of active sessions that never arrived as events
+func (s *FeishuServer) replayMissed(ctx context.Context) {
+	missed, err := s.journalUC.Missed(ctx)
+	if err != nil {
+		fmt.Printf("[Server] Failed to look for missed messages: %v\n", err)
+		return
+	}
+	for _, m := range missed {
+		msg, err := s.fetchMessage(ctx, m.ID)
+		if err != nil {
+			fmt.Printf("[Server] Failed to fetch missed message %s: %v\n", m.ID, err)
+			continue
+		}
+		// Other bots' messages and recalled ones are journaled so they are not fetched again
+		if msg.Recalled || (msg.Sender != nil && msg.Sender.SenderType == "app") {
+			if s.receive(ctx, msg) {
+				s.journalMark(ctx, msg.MsgID, domain.JournalReplied)
+			}
+			continue
+		}
+		fmt.Printf("[Server] Catching up on message %s in %s\n", msg.MsgID, msg.ChatID)
+		s.handleMessage(msg)
+	}
+}
+
+// fetchMessage fetches a message for catching up, filling in what only events carry
+func (s *FeishuServer) fetchMessage(ctx context.Context, msgID string) (*feishu.Message, error) {
+	msg, err := s.feishuClient.GetMessage(msgID)
+	if err != nil {
+		return nil, err
+	}
+	msg.ChatType = "group"
+	if info, err := s.messageRepo.GetChatInfo(ctx, msg.ChatID); err == nil && info.ChatType == domain.ChatTypeP2P {
+		msg.ChatType = "p2p"
+	}
+	return msg, nil
+}
+
+// newRequest converts a Feishu message, resolving the sender's name
+// Images and attachments are added by downloadMedia
+func (s *FeishuServer) newRequest(ctx context.Context, msg *feishu.Message) *service.MessageRequest {
 	req := &service.MessageRequest{
 		ChatID:        msg.ChatID,
 		MsgID:         msg.MsgID,
 		Content:       msg.Content,
-		SenderID:      senderID,
-		SenderName:    senderName,
-		ChatType:      chatType,
+		ChatType:      domain.ChatTypeP2P,
 		MentionsBot:   msg.MentionsBot,
-		ImagePaths:    imagePaths,
 		MsgCreateTime: msg.CreateTime,
+		TopicID:       msg.ThreadID,
+	}
+	if msg.ChatType == "group" {
+		req.ChatType = domain.ChatTypeGroup
 	}
 
-	// Process message
-	if err := s.convSvc.HandleMessage(ctx, req); err != nil {
-		if err.Error() == "already processing" {
-			_ = s.messageRepo.SendText(ctx, msg.ChatID, "Processing previous request, please wait...")
-		} else {
-			fmt.Printf("[Server] Handle message error: %v\n", err)
+	if msg.Sender != nil {
+		req.SenderID = msg.Sender.SenderID
+		// Try to get name from member list
+		members, err := s.messageRepo.GetChatMembers(ctx, msg.ChatID)
+		if err == nil {
+			for _, m := range members {
+				if m.UserID == req.SenderID {
+					req.SenderName = m.Name
+					break
+				}
+			}
+		}
+		// Fall back to the contact API for P2P chats and non-members
+		if req.SenderName == "" && s.userRepo != nil && msg.Sender.SenderType == "user" {
+			if profile, _ := s.userRepo.GetUser(ctx, req.SenderID); profile != nil {
+				req.SenderName = profile.Name
+			}
+		}
+	}
+	return req
+}
+
+// downloadMedia downloads a message's images and attachments for Codex
+// Attachment paths are listed at the end of the content
+func (s *FeishuServer) downloadMedia(msg *feishu.Message, req *service.MessageRequest) {
+	for _, imageKey := range msg.ImageKeys {
+		path, err := s.feishuClient.DownloadImage(msg.MsgID, imageKey)
+		if err != nil {
+			fmt.Printf("[Server] Failed to download image %s: %v\n", imageKey, err)
+			continue
 		}
+		req.ImagePaths = append(req.ImagePaths, path)
+	}
+
+	if paths := s.downloadAttachments(msg); len(paths) > 0 {
+		req.Content += "\n\nAttached files (saved in your workspace):\n- " + strings.Join(paths, "\n- ")
+	}
+}
+
+// attachParent fetches the message msg quote-replies to, with its images and files
+// Topic messages reply to the topic's first message, which is in the topic history already
+func (s *FeishuServer) attachParent(ctx context.Context, msg *feishu.Message, req *service.MessageRequest) {
+	if msg.ParentID == "" || (msg.ThreadID != "" && msg.ParentID == msg.RootID) {
+		return
+	}
+	parent, err := s.feishuClient.GetMessage(msg.ParentID)
+	if err != nil {
+		fmt.Printf("[Server] Failed to fetch parent %s of %s: %v\n", msg.ParentID, msg.MsgID, err)
+		return
+	}
+
+	parentReq := s.newRequest(ctx, parent)
+	s.downloadMedia(parent, parentReq)
+	req.ImagePaths = append(req.ImagePaths, parentReq.ImagePaths...)
+	req.Parent = &domain.Message{
+		ID:         parent.MsgID,
+		ChatID:     parent.ChatID,
+		Content:    parentReq.Content,
+		SenderID:   parentReq.SenderID,
+		SenderName: parentReq.SenderName,
+		MsgType:    parent.MsgType,
+		CreateTime: time.UnixMilli(parent.CreateTime),
+		IsBot:      parent.Sender != nil && parent.Sender.SenderType == "app",
+		ParentID:   parent.ParentID,
+		RootID:     parent.RootID,
 	}
 }
 
-// sendReply sends a reply
-func (s *FeishuServer) sendReply(chatID, msgID, text string, mentions []domain.Member) {
+// handleCardAction routes interactive card button clicks
+func (s *FeishuServer) handleCardAction(action *feishu.CardAction) (*feishu.CardResponse, error) {
+	if s.cards == nil {
+		return nil, nil
+	}
+	result, err := s.cards.Dispatch(context.Background(), &service.CardAction{
+		Value:      action.Value,
+		OperatorID: action.OperatorID,
+		MessageID:  action.MessageID,
+		ChatID:     action.ChatID,
+	})
+	if err != nil || result == nil {
+		return nil, err
+	}
+	return &feishu.CardResponse{Toast: result.Toast, Card: result.Card}, nil
+}
+
+// sendReply sends a reply as rendered Markdown, split into ordered messages if long
+// With a target the reply quotes the triggering message, otherwise it goes to the chat
+func (s *FeishuServer) sendReply(chatID string, to *repo.ReplyTo, text string, mentions []domain.Member) {
 	ctx := context.Background()
 
-	if len(mentions) > 0 {
-		err := s.messageRepo.SendTextWithMentions(ctx, chatID, text, mentions)
-		if err != nil {
-			fmt.Printf("[Server] Failed to send reply with mentions: %v\n", err)
-			// Fallback to plain message
-			_ = s.messageRepo.SendText(ctx, chatID, text)
+	if to != nil {
+		err := s.messageRepo.ReplyMarkdown(ctx, *to, text, mentions)
+		if err == nil {
+			return
 		}
+		fmt.Printf("[Server] Failed to reply to %s: %v\n", to.MsgID, err)
+	}
+
+	err := s.messageRepo.SendMarkdown(ctx, chatID, text, mentions)
+	if err == nil {
+		return
+	}
+	fmt.Printf("[Server] Failed to send rich reply: %v\n", err)
+
+	// Fallback to plain text
+	if len(mentions) > 0 {
+		err = s.messageRepo.SendTextWithMentions(ctx, chatID, text, mentions)
 	} else {
-		err := s.messageRepo.SendText(ctx, chatID, text)
+		err = s.messageRepo.SendText(ctx, chatID, text)
+	}
+	if err != nil {
+		fmt.Printf("[Server] Failed to send reply: %v\n", err)
+	}
+}
+
+// downloadAttachments saves a message's attachments into its own directory
+func (s *FeishuServer) downloadAttachments(msg *feishu.Message) []string {
+	if len(msg.Attachments) == 0 || s.attachmentDir == "" {
+		return nil
+	}
+	dir := filepath.Join(s.attachmentDir, msg.MsgID)
+	var paths []string
+	for _, attachment := range msg.Attachments {
+		path, err := s.feishuClient.DownloadFile(msg.MsgID, attachment, dir)
 		if err != nil {
-			fmt.Printf("[Server] Failed to send reply: %v\n", err)
+			fmt.Printf("[Server] Failed to download %s %s: %v\n", attachment.Kind, attachment.Name, err)
+			continue
 		}
+		paths = append(paths, path)
 	}
+	return paths
 }
 
 func truncate(s string, n int) string {
@@ -236,6 +561,38 @@ func truncate(s string, n int) string {
 	return s[:n] + "..."
 }
 
+// receive records a received message, false if it was received before
+// The journal remembers messages across restarts, the in-memory cache for a few minutes
+func (s *FeishuServer) receive(ctx context.Context, msg *feishu.Message) bool {
+	if s.journalUC != nil {
+		key := domain.SessionKey{ChatID: msg.ChatID, TopicID: msg.ThreadID}
+		fresh, err := s.journalUC.Receive(ctx, key, msg.MsgID, time.UnixMilli(msg.CreateTime))
+		if err == nil {
+			return fresh
+		}
+		fmt.Printf("[Server] Failed to journal %s: %v\n", msg.MsgID, err)
+	}
+	if s.isMessageSeen(msg.MsgID) {
+		return false
+	}
+	s.markMessageSeen(msg.MsgID)
+	return true
+}
+
+// journalMark records how far the handling of a message got, if the journal is enabled
+func (s *FeishuServer) journalMark(ctx context.Context, msgID string, state domain.JournalState) {
+	if s.journalUC != nil {
+		s.journalUC.Mark(ctx, state, msgID)
+	}
+}
+
+// journalFail records that a message was given up on, if the journal is enabled
+func (s *FeishuServer) journalFail(ctx context.Context, msgID, reason string) {
+	if s.journalUC != nil {
+		s.journalUC.Fail(ctx, reason, msgID)
+	}
+}
+
 // isMessageSeen checks if a message has been processed
 func (s *FeishuServer) isMessageSeen(msgID string) bool {
 	s.seenMsgsMu.RLock()
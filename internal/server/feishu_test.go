@@ -0,0 +1,158 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anthropics/feishu-codex-bridge/internal/biz/domain"
+	"github.com/anthropics/feishu-codex-bridge/internal/biz/repo"
+	"github.com/anthropics/feishu-codex-bridge/internal/biz/usecase"
+	"github.com/anthropics/feishu-codex-bridge/internal/data"
+	"github.com/anthropics/feishu-codex-bridge/internal/infra/acp"
+	"github.com/anthropics/feishu-codex-bridge/internal/infra/acp/acptest"
+	"github.com/anthropics/feishu-codex-bridge/internal/infra/feishu"
+	"github.com/anthropics/feishu-codex-bridge/internal/infra/feishu/feishutest"
+	"github.com/anthropics/feishu-codex-bridge/internal/service"
+)
+
+// TestMain lets the test binary stand in for codex: acp.Client runs it as "app-server"
+func TestMain(m *testing.M) {
+	if len(os.Args) > 1 && (os.Args[1] == "app-server" || os.Args[1] == "mcp") {
+		acptest.Main()
+	}
+	os.Exit(m.Run())
+}
+
+// startFakeCodex starts a Codex client talking to the fake playing script
+func startFakeCodex(t *testing.T, script *acptest.Script) repo.CodexRepo {
+	t.Helper()
+	scriptJSON, _ := json.Marshal(script)
+	path := filepath.Join(t.TempDir(), "script.json")
+	if err := os.WriteFile(path, scriptJSON, 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(acptest.EnvScript, path)
+
+	executable, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := acp.NewClient(t.TempDir(), "")
+	client.SetExecutable(executable)
+	if err := client.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	t.Cleanup(func() { client.Stop() })
+	return data.NewCodexRepo(client)
+}
+
+// freeAddr returns a local address nothing listens on
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	return l.Addr().String()
+}
+
+// startBridge runs a FeishuServer against a mock open platform and the fake Codex
+func startBridge(t *testing.T, script *acptest.Script) *feishutest.Server {
+	t.Helper()
+	mock := feishutest.New()
+	platform := httptest.NewServer(mock)
+	t.Cleanup(platform.Close)
+	mock.AddChat(feishutest.Chat{
+		ChatID:  "oc_team",
+		Name:    "Team",
+		Members: []feishutest.Member{{OpenID: "ou_alice", Name: "Alice"}, {OpenID: "ou_bob", Name: "Bob"}},
+	})
+
+	addr := freeAddr(t)
+	feishuClient := feishu.NewClient("cli_mock", "secret")
+	feishuClient.SetBaseURL(platform.URL)
+	feishuClient.SetWebhook(feishu.WebhookConfig{Addr: addr, VerificationToken: "vtoken"})
+	mock.SetWebhook("http://"+addr+"/", "vtoken")
+
+	codexRepo := startFakeCodex(t, script)
+	messageRepo := data.NewFeishuRepo(feishuClient)
+	sessionRepo, err := data.NewSessionRepo(filepath.Join(t.TempDir(), "sessions.db"), domain.DefaultBotID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	contextUC := usecase.NewContextBuilderUsecase(messageRepo)
+	sessionUC := usecase.NewSessionUsecase(sessionRepo, codexRepo, domain.SessionConfig{IdleTimeout: time.Hour, ResetHour: -1})
+	filterUC := usecase.NewFilterUsecase(nil, messageRepo, contextUC)
+	convUC := usecase.NewConversationUsecase(sessionUC, contextUC, codexRepo, usecase.PromptConfig{})
+	convSvc := service.NewConversationService(convUC, filterUC, messageRepo, codexRepo)
+
+	srv := NewFeishuServer(feishuClient, messageRepo, convSvc, nil, codexRepo, filterUC, nil)
+	go func() {
+		if err := srv.Start(); err != nil {
+			t.Errorf("Start failed: %v", err)
+		}
+	}()
+	t.Cleanup(srv.Stop)
+
+	// Wait for the webhook to listen
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		conn, err := net.Dial("tcp", addr)
+		if err == nil {
+			conn.Close()
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Webhook never came up: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return mock
+}
+
+func TestFeishuServer_RepliesThroughMockPlatform(t *testing.T) {
+	mock := startBridge(t, &acptest.Script{Turns: []acptest.Turn{{
+		Match: "is the build green",
+		Steps: []acptest.Step{{Delta: "Yes, "}, {Delta: "**all** checks passed."}},
+	}}})
+
+	// Without a filter, group messages that do not mention the bot are skipped
+	if _, err := mock.Receive(feishutest.Inbound{ChatID: "oc_team", SenderID: "ou_bob", Text: "lunch?"}); err != nil {
+		t.Fatalf("Receive failed: %v", err)
+	}
+	in, err := mock.Receive(feishutest.Inbound{ChatID: "oc_team", SenderID: "ou_alice", Text: "is the build green?", MentionBot: true})
+	if err != nil {
+		t.Fatalf("Receive failed: %v", err)
+	}
+
+	reply, err := mock.WaitSent(10*time.Second, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply.ChatID != "oc_team" || reply.ParentID != in.MessageID {
+		t.Errorf("Expected a reply quoting the message, got %+v", reply)
+	}
+	if reply.MsgType != "post" || !strings.Contains(reply.Text(), "Yes, all checks passed.") {
+		t.Errorf("Expected the Markdown reply of the scripted turn, got %s %q", reply.MsgType, reply.Text())
+	}
+
+	reactions := mock.Reactions()
+	if len(reactions) == 0 || reactions[0].MessageID != in.MessageID {
+		t.Errorf("Expected a reaction on the message being answered, got %+v", reactions)
+	}
+
+	// Only the mention was answered
+	time.Sleep(100 * time.Millisecond)
+	if sent := mock.Sent(); len(sent) != 1 {
+		t.Errorf("Expected exactly one reply, got %+v", sent)
+	}
+}
@@ -0,0 +1,318 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/anthropics/feishu-codex-bridge/internal/biz/domain"
+	"github.com/anthropics/feishu-codex-bridge/internal/biz/repo"
+	"github.com/anthropics/feishu-codex-bridge/internal/biz/usecase"
+)
+
+// ApprovalCardAction is the card action value that routes button clicks to the approval service
+const ApprovalCardAction = "approval"
+
+// ApprovalService decides Codex approval requests by policy,
+// asking the originating chat via interactive cards when policy has no rule
+type ApprovalService struct {
+	approvalUC  *usecase.ApprovalUsecase
+	messageRepo repo.MessageRepo
+	codexRepo   repo.CodexRepo
+
+	// resolveSession maps a Codex thread to its originating session
+	// and, for a topic, the message in the topic to reply to
+	resolveSession func(threadID string) (domain.SessionKey, *repo.ReplyTo)
+
+	// Keyed by a token the bridge generates, Codex request IDs restart with the process
+	pending   map[string]*pendingApproval
+	pendingMu sync.Mutex
+}
+
+// pendingApproval is a request waiting for a human decision
+type pendingApproval struct {
+	token     string // Carried by the card buttons
+	req       *domain.ApprovalRequest
+	cardMsgID string
+	timer     *time.Timer
+}
+
+// NewApprovalService creates a new approval service
+func NewApprovalService(
+	approvalUC *usecase.ApprovalUsecase,
+	messageRepo repo.MessageRepo,
+	codexRepo repo.CodexRepo,
+) *ApprovalService {
+	return &ApprovalService{
+		approvalUC:  approvalUC,
+		messageRepo: messageRepo,
+		codexRepo:   codexRepo,
+		pending:     make(map[string]*pendingApproval),
+	}
+}
+
+// SetSessionResolver sets the function mapping Codex threads to sessions
+func (s *ApprovalService) SetSessionResolver(resolver func(threadID string) (domain.SessionKey, *repo.ReplyTo)) {
+	s.resolveSession = resolver
+}
+
+// Start registers the service as the Codex approval handler
+// Requests still pending when Codex exits are expired, their cards can no longer decide anything
+func (s *ApprovalService) Start() {
+	s.codexRepo.SetApprovalHandler(s.HandleRequest)
+	sub := s.codexRepo.Subscribe(repo.EventFilter{Types: []repo.EventType{repo.EventTypeCodexExited}})
+	go func() {
+		for range sub.Events() {
+			s.ExpireAll(context.Background())
+		}
+	}()
+	fmt.Println("[Approval] Approval flow enabled")
+}
+
+// HandleRequest decides an approval request, asking the chat if policy has no rule
+func (s *ApprovalService) HandleRequest(req *domain.ApprovalRequest) {
+	ctx := context.Background()
+
+	// Cards of topic sessions are posted in the topic
+	var replyTo *repo.ReplyTo
+	if req.ChatID == "" && s.resolveSession != nil {
+		var key domain.SessionKey
+		key, replyTo = s.resolveSession(req.ThreadID)
+		req.ChatID = key.ChatID
+	}
+
+	decision := s.approvalUC.Evaluate(req)
+	if decision != domain.ApprovalAsk {
+		s.respond(ctx, req, decision, domain.ApprovalSourcePolicy, "")
+		return
+	}
+
+	policy := s.approvalUC.Policy()
+	if req.ChatID == "" {
+		// Nobody to ask (e.g. scheduled task threads)
+		fmt.Printf("[Approval] No chat for thread %s, applying default decision\n", req.ThreadID)
+		s.respond(ctx, req, policy.DefaultDecision, domain.ApprovalSourcePolicy, "")
+		return
+	}
+
+	// Register before sending the card so an immediate click finds it
+	p := &pendingApproval{token: newApprovalToken(), req: req}
+	s.pendingMu.Lock()
+	s.pending[p.token] = p
+	if policy.Timeout > 0 {
+		p.timer = time.AfterFunc(policy.Timeout, func() {
+			s.resolve(context.Background(), p.token, policy.DefaultDecision, domain.ApprovalSourceTimeout, "")
+		})
+	}
+	s.pendingMu.Unlock()
+
+	msgID, err := s.sendCard(ctx, req.ChatID, replyTo, buildApprovalCard(p.token, req, policy.Timeout))
+	if err != nil {
+		fmt.Printf("[Approval] Failed to send approval card: %v\n", err)
+		s.resolve(ctx, p.token, policy.DefaultDecision, domain.ApprovalSourcePolicy, "")
+		return
+	}
+
+	s.pendingMu.Lock()
+	p.cardMsgID = msgID
+	s.pendingMu.Unlock()
+}
+
+// sendCard posts a card, as a reply when to is set
+func (s *ApprovalService) sendCard(ctx context.Context, chatID string, to *repo.ReplyTo, card string) (string, error) {
+	if to != nil {
+		msgID, err := s.messageRepo.ReplyCard(ctx, *to, card)
+		if err == nil {
+			return msgID, nil
+		}
+		// The message may have been recalled, post to the chat instead
+		fmt.Printf("[Approval] Failed to reply to %s: %v\n", to.MsgID, err)
+	}
+	return s.messageRepo.SendCard(ctx, chatID, card)
+}
+
+// Resolve applies a human decision from a card button, token identifies the card's request
+func (s *ApprovalService) Resolve(ctx context.Context, token string, decision domain.ApprovalDecision, operatorID string) error {
+	if decision != domain.ApprovalAccept && decision != domain.ApprovalDecline {
+		return fmt.Errorf("invalid decision: %s", decision)
+	}
+	if !s.resolve(ctx, token, decision, domain.ApprovalSourceUser, operatorID) {
+		return fmt.Errorf("approval request is no longer pending")
+	}
+	return nil
+}
+
+// HandleCardAction resolves a request from an Approve or Deny button
+func (s *ApprovalService) HandleCardAction(ctx context.Context, action *CardAction) (*CardResult, error) {
+	token := action.String("token")
+	if token == "" {
+		return nil, fmt.Errorf("missing approval token")
+	}
+	decision := domain.ApprovalDecision(action.String("decision"))
+	if err := s.Resolve(ctx, token, decision, action.OperatorID); err != nil {
+		return nil, err
+	}
+	// The card itself is updated by resolve, also for timeouts
+	if decision == domain.ApprovalAccept {
+		return &CardResult{Toast: "Approved"}, nil
+	}
+	return &CardResult{Toast: "Denied"}, nil
+}
+
+// PendingCount returns the number of requests waiting for a decision
+func (s *ApprovalService) PendingCount() int {
+	s.pendingMu.Lock()
+	defer s.pendingMu.Unlock()
+	return len(s.pending)
+}
+
+// ExpireAll drops every pending request, e.g. because the Codex process that sent them exited
+// Their cards are updated so nobody clicks them, nothing is sent to Codex
+func (s *ApprovalService) ExpireAll(ctx context.Context) {
+	s.pendingMu.Lock()
+	expired := make([]*pendingApproval, 0, len(s.pending))
+	for token, p := range s.pending {
+		delete(s.pending, token)
+		if p.timer != nil {
+			p.timer.Stop()
+		}
+		expired = append(expired, p)
+	}
+	s.pendingMu.Unlock()
+
+	for _, p := range expired {
+		if err := s.approvalUC.Record(ctx, p.req, domain.ApprovalDecline, domain.ApprovalSourceExpired, ""); err != nil {
+			fmt.Printf("[Approval] Failed to record expired request: %v\n", err)
+		}
+		if p.cardMsgID != "" {
+			if err := s.messageRepo.UpdateCard(ctx, p.cardMsgID, buildApprovalExpiredCard(p.req)); err != nil {
+				fmt.Printf("[Approval] Failed to update approval card: %v\n", err)
+			}
+		}
+	}
+	if len(expired) > 0 {
+		fmt.Printf("[Approval] Expired %d pending requests after Codex exited\n", len(expired))
+	}
+}
+
+// resolve finishes a pending request. Returns false if it was already resolved.
+func (s *ApprovalService) resolve(ctx context.Context, token string, decision domain.ApprovalDecision, source, decidedBy string) bool {
+	s.pendingMu.Lock()
+	p, ok := s.pending[token]
+	if ok {
+		delete(s.pending, token)
+		if p.timer != nil {
+			p.timer.Stop()
+		}
+	}
+	s.pendingMu.Unlock()
+
+	if !ok {
+		return false
+	}
+
+	s.respond(ctx, p.req, decision, source, decidedBy)
+
+	if p.cardMsgID != "" {
+		if err := s.messageRepo.UpdateCard(ctx, p.cardMsgID, buildApprovalResultCard(p.req, decision, source, decidedBy)); err != nil {
+			fmt.Printf("[Approval] Failed to update approval card: %v\n", err)
+		}
+	}
+	return true
+}
+
+// respond sends the decision to Codex and records it
+func (s *ApprovalService) respond(ctx context.Context, req *domain.ApprovalRequest, decision domain.ApprovalDecision, source, decidedBy string) {
+	if err := s.codexRepo.RespondToApproval(req.RequestID, decision); err != nil {
+		fmt.Printf("[Approval] Failed to respond to request %d: %v\n", req.RequestID, err)
+	}
+	if err := s.approvalUC.Record(ctx, req, decision, source, decidedBy); err != nil {
+		fmt.Printf("[Approval] Failed to record decision: %v\n", err)
+	}
+}
+
+// newApprovalToken returns a random token identifying a pending request on its card
+func newApprovalToken() string {
+	buf := make([]byte, 12)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// buildApprovalCard builds the interactive card asking for a decision
+func buildApprovalCard(token string, req *domain.ApprovalRequest, timeout time.Duration) string {
+	body := fmt.Sprintf("Codex wants to **%s**", approvalKindLabel(req.Kind))
+	switch req.Kind {
+	case domain.ApprovalKindCommand:
+		body += fmt.Sprintf("\n```\n%s\n```", req.Command)
+		if req.Cwd != "" {
+			body += fmt.Sprintf("\nWorking directory: `%s`", req.Cwd)
+		}
+	case domain.ApprovalKindFileChange:
+		for _, f := range req.Files {
+			body += fmt.Sprintf("\n- `%s`", f)
+		}
+	}
+	if timeout > 0 {
+		body += fmt.Sprintf("\n\nNo answer within %s applies the default decision.", timeout)
+	}
+
+	button := func(label, style string, decision domain.ApprovalDecision) map[string]interface{} {
+		return cardButton(label, style, ApprovalCardAction, map[string]interface{}{
+			"token":    token,
+			"decision": string(decision),
+		})
+	}
+
+	return buildCard("Approval required", "orange", []interface{}{
+		markdownElement(body),
+		map[string]interface{}{
+			"tag": "action",
+			"actions": []interface{}{
+				button("Approve", "primary", domain.ApprovalAccept),
+				button("Deny", "danger", domain.ApprovalDecline),
+			},
+		},
+	})
+}
+
+// buildApprovalResultCard builds the card shown after a decision (no buttons)
+func buildApprovalResultCard(req *domain.ApprovalRequest, decision domain.ApprovalDecision, source, decidedBy string) string {
+	title, template := "Approved", "green"
+	if decision != domain.ApprovalAccept {
+		title, template = "Denied", "red"
+	}
+
+	by := ""
+	switch source {
+	case domain.ApprovalSourceUser:
+		by = fmt.Sprintf("by <at id=%s></at>", decidedBy)
+	case domain.ApprovalSourceTimeout:
+		by = "automatically after timeout"
+	default:
+		by = "by policy"
+	}
+
+	return buildCard(title, template, []interface{}{
+		markdownElement(fmt.Sprintf("%s\n%s %s", req.Summary(), title, by)),
+	})
+}
+
+// buildApprovalExpiredCard builds the card shown when the request died with its Codex process
+func buildApprovalExpiredCard(req *domain.ApprovalRequest) string {
+	return buildCard("Expired", "grey", []interface{}{
+		markdownElement(fmt.Sprintf("%s\nCodex restarted before a decision, the request was cancelled.", req.Summary())),
+	})
+}
+
+func approvalKindLabel(kind domain.ApprovalKind) string {
+	switch kind {
+	case domain.ApprovalKindCommand:
+		return "run a command"
+	case domain.ApprovalKindFileChange:
+		return "change files"
+	}
+	return string(kind)
+}
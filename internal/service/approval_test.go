@@ -0,0 +1,237 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/anthropics/feishu-codex-bridge/internal/biz/domain"
+	"github.com/anthropics/feishu-codex-bridge/internal/biz/repo"
+	"github.com/anthropics/feishu-codex-bridge/internal/biz/usecase"
+)
+
+type mockApprovalRepo struct {
+	records []*domain.ApprovalRecord
+	mu      sync.Mutex
+}
+
+func (m *mockApprovalRepo) SaveRecord(ctx context.Context, record *domain.ApprovalRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.records = append(m.records, record)
+	return nil
+}
+
+func (m *mockApprovalRepo) ListByChat(ctx context.Context, chatID string, limit int) ([]*domain.ApprovalRecord, error) {
+	return nil, nil
+}
+
+func (m *mockApprovalRepo) ListByUser(ctx context.Context, userID string, limit int) ([]*domain.ApprovalRecord, error) {
+	return nil, nil
+}
+
+func (m *mockApprovalRepo) Close() error {
+	return nil
+}
+
+func (m *mockApprovalRepo) lastRecord() *domain.ApprovalRecord {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.records) == 0 {
+		return nil
+	}
+	return m.records[len(m.records)-1]
+}
+
+func newTestApprovalService(policy *domain.ApprovalPolicy) (*ApprovalService, *mockMessageRepo, *mockCodexRepo, *mockApprovalRepo) {
+	msgRepo := &mockMessageRepo{}
+	codexRepo := &mockCodexRepo{}
+	approvalRepo := &mockApprovalRepo{}
+	svc := NewApprovalService(usecase.NewApprovalUsecase(approvalRepo, policy), msgRepo, codexRepo)
+	svc.SetSessionResolver(func(threadID string) (domain.SessionKey, *repo.ReplyTo) {
+		switch threadID {
+		case "thread-1":
+			return domain.SessionKey{ChatID: "chat-1"}, nil
+		case "thread-topic":
+			return domain.SessionKey{ChatID: "chat-1", TopicID: "omt_1"}, &repo.ReplyTo{MsgID: "om_topic", InTopic: true}
+		}
+		return domain.SessionKey{}, nil
+	})
+	return svc, msgRepo, codexRepo, approvalRepo
+}
+
+func (m *mockCodexRepo) response(requestID int64) (domain.ApprovalDecision, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	d, ok := m.responses[requestID]
+	return d, ok
+}
+
+// pendingToken returns the card token of a pending request
+func (s *ApprovalService) pendingToken(requestID int64) string {
+	s.pendingMu.Lock()
+	defer s.pendingMu.Unlock()
+	for token, p := range s.pending {
+		if p.req.RequestID == requestID {
+			return token
+		}
+	}
+	return ""
+}
+
+func TestApprovalService_PolicyAccept(t *testing.T) {
+	svc, msgRepo, codexRepo, approvalRepo := newTestApprovalService(&domain.ApprovalPolicy{
+		AutoAcceptCommands: []string{"ls"},
+	})
+
+	svc.HandleRequest(&domain.ApprovalRequest{RequestID: 1, Kind: domain.ApprovalKindCommand, ThreadID: "thread-1", Command: "ls -la"})
+
+	if d, _ := codexRepo.response(1); d != domain.ApprovalAccept {
+		t.Errorf("Expected accept, got %q", d)
+	}
+	if len(msgRepo.sentCards) != 0 {
+		t.Error("Expected no card for policy decision")
+	}
+	rec := approvalRepo.lastRecord()
+	if rec == nil || rec.Source != domain.ApprovalSourcePolicy || rec.ChatID != "chat-1" {
+		t.Errorf("Unexpected record: %+v", rec)
+	}
+}
+
+func TestApprovalService_AskThenUserDecides(t *testing.T) {
+	svc, msgRepo, codexRepo, approvalRepo := newTestApprovalService(&domain.ApprovalPolicy{Timeout: time.Minute})
+
+	svc.HandleRequest(&domain.ApprovalRequest{RequestID: 2, Kind: domain.ApprovalKindCommand, ThreadID: "thread-1", Command: "make deploy"})
+
+	if len(msgRepo.sentCards) != 1 {
+		t.Fatalf("Expected 1 card, got %d", len(msgRepo.sentCards))
+	}
+	if !strings.Contains(msgRepo.sentCards[0], "make deploy") {
+		t.Errorf("Card should contain the command: %s", msgRepo.sentCards[0])
+	}
+	if _, ok := codexRepo.response(2); ok {
+		t.Fatal("Expected no response before decision")
+	}
+
+	token := svc.pendingToken(2)
+	if token == "" || !strings.Contains(msgRepo.sentCards[0], token) {
+		t.Fatalf("Expected the card to carry the request's token %q", token)
+	}
+	if err := svc.Resolve(context.Background(), token, domain.ApprovalAccept, "ou_alice"); err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+
+	if d, _ := codexRepo.response(2); d != domain.ApprovalAccept {
+		t.Errorf("Expected accept, got %q", d)
+	}
+	rec := approvalRepo.lastRecord()
+	if rec == nil || rec.Source != domain.ApprovalSourceUser || rec.DecidedBy != "ou_alice" {
+		t.Errorf("Unexpected record: %+v", rec)
+	}
+	if _, ok := msgRepo.updated["card_1"]; !ok {
+		t.Error("Expected card to be updated")
+	}
+	if svc.PendingCount() != 0 {
+		t.Errorf("Expected no pending requests, got %d", svc.PendingCount())
+	}
+
+	// Second click is rejected
+	if err := svc.Resolve(context.Background(), token, domain.ApprovalDecline, "ou_bob"); err == nil {
+		t.Error("Expected error for already resolved request")
+	}
+}
+
+func TestApprovalService_TopicCardRepliesInTopic(t *testing.T) {
+	svc, msgRepo, _, _ := newTestApprovalService(&domain.ApprovalPolicy{Timeout: time.Minute})
+
+	svc.HandleRequest(&domain.ApprovalRequest{RequestID: 5, Kind: domain.ApprovalKindCommand, ThreadID: "thread-topic", Command: "make deploy"})
+
+	if len(msgRepo.sentCards) != 1 {
+		t.Fatalf("Expected 1 card, got %d", len(msgRepo.sentCards))
+	}
+	if len(msgRepo.replies) != 1 || msgRepo.replies[0] != (repo.ReplyTo{MsgID: "om_topic", InTopic: true}) {
+		t.Errorf("Expected the card as a reply in the topic, got %+v", msgRepo.replies)
+	}
+	if token := svc.pendingToken(5); token == "" {
+		t.Error("Expected the request to wait for the card")
+	}
+}
+
+func TestApprovalService_Timeout(t *testing.T) {
+	svc, _, codexRepo, approvalRepo := newTestApprovalService(&domain.ApprovalPolicy{
+		Timeout:         20 * time.Millisecond,
+		DefaultDecision: domain.ApprovalDecline,
+	})
+
+	svc.HandleRequest(&domain.ApprovalRequest{RequestID: 3, Kind: domain.ApprovalKindFileChange, ThreadID: "thread-1", Files: []string{"a.go"}})
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if _, ok := codexRepo.response(3); ok {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if d, _ := codexRepo.response(3); d != domain.ApprovalDecline {
+		t.Errorf("Expected decline after timeout, got %q", d)
+	}
+	rec := approvalRepo.lastRecord()
+	if rec == nil || rec.Source != domain.ApprovalSourceTimeout {
+		t.Errorf("Unexpected record: %+v", rec)
+	}
+}
+
+func TestApprovalService_NoChatUsesDefault(t *testing.T) {
+	svc, msgRepo, codexRepo, _ := newTestApprovalService(&domain.ApprovalPolicy{DefaultDecision: domain.ApprovalDecline})
+
+	svc.HandleRequest(&domain.ApprovalRequest{RequestID: 4, Kind: domain.ApprovalKindCommand, ThreadID: "unknown", Command: "make"})
+
+	if d, _ := codexRepo.response(4); d != domain.ApprovalDecline {
+		t.Errorf("Expected decline, got %q", d)
+	}
+	if len(msgRepo.sentCards) != 0 {
+		t.Error("Expected no card without a chat")
+	}
+}
+
+func TestApprovalService_CodexExitExpiresCards(t *testing.T) {
+	svc, msgRepo, codexRepo, approvalRepo := newTestApprovalService(&domain.ApprovalPolicy{Timeout: time.Minute})
+	svc.Start()
+
+	svc.HandleRequest(&domain.ApprovalRequest{RequestID: 1, Kind: domain.ApprovalKindCommand, ThreadID: "thread-1", Command: "make deploy"})
+	oldToken := svc.pendingToken(1)
+
+	// Codex exits, the restarted process numbers its requests from 1 again
+	codexRepo.lastSub().ch <- repo.Event{Type: repo.EventTypeCodexExited}
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) && svc.PendingCount() != 0 {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if svc.PendingCount() != 0 {
+		t.Fatal("Expected the pending request to expire")
+	}
+	msgRepo.mu.Lock()
+	card, ok := msgRepo.updated["card_1"]
+	msgRepo.mu.Unlock()
+	if !ok || !strings.Contains(card, "Expired") {
+		t.Errorf("Expected the card to show it expired, got %q", card)
+	}
+	if rec := approvalRepo.lastRecord(); rec == nil || rec.Source != domain.ApprovalSourceExpired {
+		t.Errorf("Unexpected record: %+v", rec)
+	}
+
+	svc.HandleRequest(&domain.ApprovalRequest{RequestID: 1, Kind: domain.ApprovalKindCommand, ThreadID: "thread-1", Command: "rm -rf /"})
+	_, err := svc.HandleCardAction(context.Background(), &CardAction{
+		Value:      map[string]interface{}{"token": oldToken, "decision": string(domain.ApprovalAccept)},
+		OperatorID: "ou_alice",
+	})
+	if err == nil {
+		t.Error("Expected the old card to be rejected")
+	}
+	if _, ok := codexRepo.response(1); ok {
+		t.Error("Expected the new request to stay undecided")
+	}
+}
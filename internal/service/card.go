@@ -0,0 +1,161 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// Card action names, the "action" key of a button's value selects the handler
+const (
+	TaskCardAction     = "task"
+	DigestCardAction   = "digest"
+	FeedbackCardAction = "feedback"
+)
+
+// CardAction is a click on a button of a card the bridge sent
+type CardAction struct {
+	Name       string                 // Value of the "action" key
+	Value      map[string]interface{} // Value attached to the clicked button
+	OperatorID string                 // open_id of the user who clicked
+	MessageID  string                 // Card message ID
+	ChatID     string                 // Chat the card was posted in
+}
+
+// String returns a string field of the action value
+func (a *CardAction) String(key string) string {
+	s, _ := a.Value[key].(string)
+	return s
+}
+
+// Int64 returns a numeric field of the action value
+func (a *CardAction) Int64(key string) (int64, bool) {
+	// JSON numbers arrive as float64
+	n, ok := a.Value[key].(float64)
+	return int64(n), ok
+}
+
+// CardResult answers a card action
+type CardResult struct {
+	Toast string // Shown to the operator, empty for none
+	Card  string // Replaces the clicked card in place, empty to keep it
+}
+
+// CardHandler handles the actions of one kind of card
+type CardHandler interface {
+	HandleCardAction(ctx context.Context, action *CardAction) (*CardResult, error)
+}
+
+// CardHandlerFunc adapts a function to CardHandler
+type CardHandlerFunc func(ctx context.Context, action *CardAction) (*CardResult, error)
+
+// HandleCardAction calls f
+func (f CardHandlerFunc) HandleCardAction(ctx context.Context, action *CardAction) (*CardResult, error) {
+	return f(ctx, action)
+}
+
+// cardRoute is a registered card handler
+type cardRoute struct {
+	handler    CardHandler
+	permission CommandPermission
+}
+
+// CardRouter routes card button clicks to the handler registered for their action
+type CardRouter struct {
+	mu     sync.RWMutex
+	routes map[string]cardRoute
+	admins map[string]bool
+}
+
+// NewCardRouter creates an empty card router
+func NewCardRouter() *CardRouter {
+	return &CardRouter{routes: make(map[string]cardRoute)}
+}
+
+// Register adds or replaces the handler of an action
+func (r *CardRouter) Register(action string, permission CommandPermission, handler CardHandler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.routes[action] = cardRoute{handler: handler, permission: permission}
+}
+
+// SetAdmins sets the user IDs allowed to click admin buttons
+// With no admins every user counts as one, like CommandRouter.SetAdmins
+func (r *CardRouter) SetAdmins(userIDs []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.admins = make(map[string]bool, len(userIDs))
+	for _, id := range userIDs {
+		r.admins[id] = true
+	}
+}
+
+// Dispatch runs the handler of a card action
+// Actions without a handler are ignored, returning a nil result
+func (r *CardRouter) Dispatch(ctx context.Context, action *CardAction) (*CardResult, error) {
+	if action.Name == "" {
+		action.Name, _ = action.Value["action"].(string)
+	}
+
+	r.mu.RLock()
+	route, ok := r.routes[action.Name]
+	allowed := route.permission == PermissionAnyone || len(r.admins) == 0 || r.admins[action.OperatorID]
+	r.mu.RUnlock()
+
+	if !ok {
+		fmt.Printf("[Card] Unknown card action: %v\n", action.Value)
+		return nil, nil
+	}
+	if !allowed {
+		return nil, fmt.Errorf("only bridge admins can use this button")
+	}
+	fmt.Printf("[Card] %s action from %s on %s\n", action.Name, action.OperatorID, action.MessageID)
+	return route.handler.HandleCardAction(ctx, action)
+}
+
+// cardButton builds a button whose clicks are routed to the handler of action
+func cardButton(label, style, action string, value map[string]interface{}) map[string]interface{} {
+	v := map[string]interface{}{"action": action}
+	for key, val := range value {
+		v[key] = val
+	}
+	return map[string]interface{}{
+		"tag":   "button",
+		"text":  map[string]interface{}{"tag": "plain_text", "content": label},
+		"type":  style,
+		"value": v,
+	}
+}
+
+// buildCard builds a card with a header and the given elements
+func buildCard(title, template string, elements []interface{}) string {
+	card := map[string]interface{}{
+		"config": map[string]interface{}{"wide_screen_mode": true, "update_multi": true},
+		"header": map[string]interface{}{
+			"title":    map[string]interface{}{"tag": "plain_text", "content": title},
+			"template": template,
+		},
+		"elements": elements,
+	}
+	cardJSON, _ := json.Marshal(card)
+	return string(cardJSON)
+}
+
+// noteElement is a card element showing a small grey note
+func noteElement(content string) map[string]interface{} {
+	return map[string]interface{}{
+		"tag": "note",
+		"elements": []interface{}{
+			map[string]interface{}{"tag": "plain_text", "content": content},
+		},
+	}
+}
+
+// markdownElement is a card element showing lark_md text
+func markdownElement(content string) map[string]interface{} {
+	return map[string]interface{}{
+		"tag":  "div",
+		"text": map[string]interface{}{"tag": "lark_md", "content": content},
+	}
+}
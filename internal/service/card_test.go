@@ -0,0 +1,146 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anthropics/feishu-codex-bridge/internal/biz/domain"
+)
+
+func TestCardRouter_Dispatch(t *testing.T) {
+	router := NewCardRouter()
+	router.SetAdmins([]string{"ou_admin"})
+
+	var got []*CardAction
+	handler := CardHandlerFunc(func(ctx context.Context, action *CardAction) (*CardResult, error) {
+		got = append(got, action)
+		return &CardResult{Toast: "done", Card: `{"elements":[]}`}, nil
+	})
+	router.Register("ping", PermissionAnyone, handler)
+	router.Register("wipe", PermissionAdmin, handler)
+
+	ctx := context.Background()
+	result, err := router.Dispatch(ctx, &CardAction{
+		Value:      map[string]interface{}{"action": "ping", "n": float64(3)},
+		OperatorID: "ou_user",
+		MessageID:  "om_card",
+	})
+	if err != nil || result == nil || result.Toast != "done" || result.Card == "" {
+		t.Fatalf("Unexpected result %+v, err %v", result, err)
+	}
+	if n, ok := got[0].Int64("n"); got[0].Name != "ping" || !ok || n != 3 {
+		t.Errorf("Unexpected action passed to the handler: %+v", got[0])
+	}
+
+	if _, err := router.Dispatch(ctx, &CardAction{Value: map[string]interface{}{"action": "wipe"}, OperatorID: "ou_user"}); err == nil {
+		t.Error("Expected admin buttons to be refused to other users")
+	}
+	if _, err := router.Dispatch(ctx, &CardAction{Value: map[string]interface{}{"action": "wipe"}, OperatorID: "ou_admin"}); err != nil {
+		t.Errorf("Expected admins to use admin buttons, got %v", err)
+	}
+	if result, err := router.Dispatch(ctx, &CardAction{Value: map[string]interface{}{"action": "nope"}}); result != nil || err != nil {
+		t.Errorf("Expected unknown actions to be ignored, got %+v %v", result, err)
+	}
+	if len(got) != 2 {
+		t.Errorf("Expected 2 handled actions, got %d", len(got))
+	}
+}
+
+func TestApprovalService_HandleCardAction(t *testing.T) {
+	svc, _, codexRepo, _ := newTestApprovalService(&domain.ApprovalPolicy{DefaultDecision: domain.ApprovalDecline})
+	svc.HandleRequest(&domain.ApprovalRequest{RequestID: 7, Kind: domain.ApprovalKindCommand, ThreadID: "thread-1", Command: "make deploy"})
+
+	router := NewCardRouter()
+	router.Register(ApprovalCardAction, PermissionAnyone, svc)
+	result, err := router.Dispatch(context.Background(), &CardAction{
+		Value:      map[string]interface{}{"action": ApprovalCardAction, "token": svc.pendingToken(7), "decision": "accept"},
+		OperatorID: "ou_alice",
+	})
+	if err != nil || result.Toast != "Approved" {
+		t.Fatalf("Unexpected result %+v, err %v", result, err)
+	}
+	if d, _ := codexRepo.response(7); d != domain.ApprovalAccept {
+		t.Errorf("Expected accept, got %q", d)
+	}
+}
+
+func TestBuildTaskCard(t *testing.T) {
+	tasks := []*domain.ScheduledTask{
+		{ID: 1, Name: "standup", ScheduleType: "cron", ScheduleValue: "0 9 * * 1-5", Enabled: true, NextRun: time.Now()},
+		{ID: 2, Name: "report", ScheduleType: "interval", ScheduleValue: "3600000"},
+	}
+	card := buildTaskCard(tasks, "Paused report")
+
+	var parsed map[string]interface{}
+	if err := json.Unmarshal([]byte(card), &parsed); err != nil {
+		t.Fatalf("Invalid card JSON: %v", err)
+	}
+	for _, want := range []string{`"op":"pause","task_id":1`, `"op":"resume","task_id":2`, `"op":"run","task_id":2`, "Paused report"} {
+		if !strings.Contains(card, want) {
+			t.Errorf("Expected %s in card: %s", want, card)
+		}
+	}
+}
+
+func TestReplyStream_FeedbackButtons(t *testing.T) {
+	msgRepo := &mockMessageRepo{}
+	cfg := testStreamConfig()
+	cfg.Feedback = true
+	st := newReplyStream(msgRepo, "chat-1", nil, cfg, identity)
+
+	st.Append("Hello")
+	if strings.Contains(msgRepo.sentCards[0], FeedbackCardAction) {
+		t.Error("Expected no rating buttons while streaming")
+	}
+	st.Finish("Hello!")
+	if !strings.Contains(msgRepo.updated["card_1"], `"rating":"up"`) {
+		t.Errorf("Expected rating buttons on the final card: %s", msgRepo.updated["card_1"])
+	}
+
+}
+
+type mockFeedbackRepo struct {
+	saved []*domain.Feedback
+}
+
+func (m *mockFeedbackRepo) Save(ctx context.Context, feedback *domain.Feedback) error {
+	m.saved = append(m.saved, feedback)
+	return nil
+}
+
+func (m *mockFeedbackRepo) ListByChat(ctx context.Context, chatID string, limit int) ([]*domain.Feedback, error) {
+	return m.saved, nil
+}
+
+func (m *mockFeedbackRepo) Close() error {
+	return nil
+}
+
+func TestFeedbackHandler_StoresRating(t *testing.T) {
+	feedbackRepo := &mockFeedbackRepo{}
+	handler := NewFeedbackHandler(feedbackRepo)
+	ctx := context.Background()
+
+	if _, err := handler.HandleCardAction(ctx, &CardAction{Value: map[string]interface{}{"rating": "meh"}}); err == nil {
+		t.Error("Expected invalid ratings to be rejected")
+	}
+	action := &CardAction{
+		OperatorID: "ou_alice",
+		MessageID:  "om_reply",
+		ChatID:     "chat-1",
+		Value:      map[string]interface{}{"rating": "down"},
+	}
+	if _, err := handler.HandleCardAction(ctx, action); err != nil {
+		t.Fatal(err)
+	}
+	if len(feedbackRepo.saved) != 1 {
+		t.Fatalf("Expected one stored rating, got %d", len(feedbackRepo.saved))
+	}
+	got := feedbackRepo.saved[0]
+	if got.MsgID != "om_reply" || got.OperatorID != "ou_alice" || got.ChatID != "chat-1" || got.Rating != domain.FeedbackDown {
+		t.Errorf("Unexpected rating: %+v", got)
+	}
+}
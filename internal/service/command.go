@@ -0,0 +1,465 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/anthropics/feishu-codex-bridge/internal/biz/domain"
+	"github.com/anthropics/feishu-codex-bridge/internal/biz/repo"
+	"github.com/anthropics/feishu-codex-bridge/internal/biz/usecase"
+)
+
+// commandRegex matches a /name at the start of a message, after any @mentions
+// A name must end at whitespace, so paths like /usr/bin are not commands
+var commandRegex = regexp.MustCompile(`(?s)^(?:@\S+\s+)*/([A-Za-z][A-Za-z0-9_-]*)(?:\s+(.*))?$`)
+
+// CommandPermission is who may run a command
+type CommandPermission int
+
+const (
+	PermissionAnyone CommandPermission = iota // Anyone who can message the bot
+	PermissionAdmin                           // Bridge admins, see CommandRouter.SetAdmins
+)
+
+// CommandRequest is a slash command sent in a chat
+type CommandRequest struct {
+	ChatID     string
+	MsgID      string
+	SenderID   string
+	SenderName string
+	ChatType   domain.ChatType
+	TopicID    string
+	Name       string   // Lower-cased, without the slash
+	Args       []string // Whitespace-separated arguments
+}
+
+// Key returns the session the command acts on, the topic's when sent in one
+func (r *CommandRequest) Key() domain.SessionKey {
+	return domain.SessionKey{ChatID: r.ChatID, TopicID: r.TopicID}
+}
+
+// CommandHandler runs a command and returns the text to reply with
+type CommandHandler func(ctx context.Context, req *CommandRequest) (string, error)
+
+// Command is a slash command the bridge answers itself, without Codex
+type Command struct {
+	Name        string // Without the slash, e.g. "reset"
+	Args        string // Argument usage for /help, e.g. "[name]"
+	Description string
+	Permission  CommandPermission
+	Handler     CommandHandler
+}
+
+// CommandRouter intercepts slash commands before messages reach Codex
+type CommandRouter struct {
+	messageRepo repo.MessageRepo
+
+	mu       sync.RWMutex
+	commands map[string]*Command
+	admins   map[string]bool
+}
+
+// NewCommandRouter creates a command router with /help registered
+func NewCommandRouter(messageRepo repo.MessageRepo) *CommandRouter {
+	r := &CommandRouter{
+		messageRepo: messageRepo,
+		commands:    make(map[string]*Command),
+	}
+	r.Register(&Command{
+		Name:        "help",
+		Description: "List the commands you can use",
+		Permission:  PermissionAnyone,
+		Handler:     r.help,
+	})
+	return r
+}
+
+// Register adds or replaces a command
+func (r *CommandRouter) Register(cmd *Command) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.commands[strings.ToLower(cmd.Name)] = cmd
+}
+
+// SetAdmins sets the user IDs allowed to run admin commands
+// With no admins every user counts as one, e.g. for a personal bot
+func (r *CommandRouter) SetAdmins(userIDs []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.admins = make(map[string]bool, len(userIDs))
+	for _, id := range userIDs {
+		r.admins[id] = true
+	}
+}
+
+// Commands returns the registered commands sorted by name
+func (r *CommandRouter) Commands() []*Command {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	cmds := make([]*Command, 0, len(r.commands))
+	for _, cmd := range r.commands {
+		cmds = append(cmds, cmd)
+	}
+	sort.Slice(cmds, func(i, j int) bool { return cmds[i].Name < cmds[j].Name })
+	return cmds
+}
+
+// ParseCommand extracts a slash command from message content
+func ParseCommand(content string) (name string, args []string, ok bool) {
+	m := commandRegex.FindStringSubmatch(strings.TrimSpace(content))
+	if m == nil {
+		return "", nil, false
+	}
+	return strings.ToLower(m[1]), strings.Fields(m[2]), true
+}
+
+// Dispatch runs the command in a message and replies with its result
+// Returns false if the message is not a command and should go to Codex.
+// In groups only messages that @mention the bot are treated as commands
+func (r *CommandRouter) Dispatch(ctx context.Context, msg *MessageRequest) bool {
+	if msg.ChatType == domain.ChatTypeGroup && !msg.MentionsBot {
+		return false
+	}
+	name, args, ok := ParseCommand(msg.Content)
+	if !ok {
+		return false
+	}
+
+	req := &CommandRequest{
+		ChatID:     msg.ChatID,
+		MsgID:      msg.MsgID,
+		SenderID:   msg.SenderID,
+		SenderName: msg.SenderName,
+		ChatType:   msg.ChatType,
+		TopicID:    msg.TopicID,
+		Name:       name,
+		Args:       args,
+	}
+	fmt.Printf("[Command] /%s %v from %s in %s\n", name, args, msg.SenderID, msg.ChatID)
+	r.reply(ctx, req, r.run(ctx, req))
+	return true
+}
+
+// run looks up, checks and runs a command, returning the reply text
+func (r *CommandRouter) run(ctx context.Context, req *CommandRequest) string {
+	r.mu.RLock()
+	cmd, ok := r.commands[req.Name]
+	r.mu.RUnlock()
+	if !ok {
+		return fmt.Sprintf("Unknown command /%s, send /help for the list of commands.", req.Name)
+	}
+	if !r.allowed(cmd, req.SenderID) {
+		return fmt.Sprintf("Only bridge admins can use /%s.", cmd.Name)
+	}
+
+	text, err := cmd.Handler(ctx, req)
+	if err != nil {
+		fmt.Printf("[Command] /%s failed: %v\n", req.Name, err)
+		return fmt.Sprintf("/%s failed: %v", req.Name, err)
+	}
+	return text
+}
+
+// allowed checks whether a user may run a command
+func (r *CommandRouter) allowed(cmd *Command, userID string) bool {
+	if cmd.Permission == PermissionAnyone {
+		return true
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.admins) == 0 || r.admins[userID]
+}
+
+// reply answers the command message, or posts to the chat if that fails
+func (r *CommandRouter) reply(ctx context.Context, req *CommandRequest, text string) {
+	if text == "" {
+		return
+	}
+	if req.MsgID != "" {
+		err := r.messageRepo.ReplyText(ctx, repo.ReplyTo{MsgID: req.MsgID, InTopic: req.TopicID != ""}, text)
+		if err == nil {
+			return
+		}
+		fmt.Printf("[Command] Failed to reply to %s: %v\n", req.MsgID, err)
+	}
+	if err := r.messageRepo.SendText(ctx, req.ChatID, text); err != nil {
+		fmt.Printf("[Command] Failed to send reply: %v\n", err)
+	}
+}
+
+// ReplyCard answers a command with a card, or posts it to the chat if that fails
+// Handlers that reply with a card return an empty text
+func (r *CommandRouter) ReplyCard(ctx context.Context, req *CommandRequest, card string) error {
+	if req.MsgID != "" {
+		_, err := r.messageRepo.ReplyCard(ctx, repo.ReplyTo{MsgID: req.MsgID, InTopic: req.TopicID != ""}, card)
+		if err == nil {
+			return nil
+		}
+		fmt.Printf("[Command] Failed to reply to %s: %v\n", req.MsgID, err)
+	}
+	_, err := r.messageRepo.SendCard(ctx, req.ChatID, card)
+	return err
+}
+
+// help handles /help, listing only the commands the sender may run
+func (r *CommandRouter) help(ctx context.Context, req *CommandRequest) (string, error) {
+	var sb strings.Builder
+	sb.WriteString("Commands:")
+	for _, cmd := range r.Commands() {
+		if !r.allowed(cmd, req.SenderID) {
+			continue
+		}
+		sb.WriteString("\n/" + cmd.Name)
+		if cmd.Args != "" {
+			sb.WriteString(" " + cmd.Args)
+		}
+		sb.WriteString(" - " + cmd.Description)
+	}
+	return sb.String(), nil
+}
+
+// RegisterCommands adds the session commands: /reset, /status, /stop and /model
+func (s *ConversationService) RegisterCommands(router *CommandRouter) {
+	router.Register(&Command{
+		Name:        "reset",
+		Description: "Stop the current reply and start a new conversation",
+		Permission:  PermissionAdmin,
+		Handler:     s.resetCommand,
+	})
+	router.Register(&Command{
+		Name:        "status",
+		Description: "Show the session, queue and Codex status",
+		Permission:  PermissionAnyone,
+		Handler:     s.statusCommand,
+	})
+	router.Register(&Command{
+		Name:        "stop",
+		Description: "Stop the reply Codex is writing",
+		Permission:  PermissionAdmin,
+		Handler:     s.stopCommand,
+	})
+	router.Register(&Command{
+		Name:        "model",
+		Args:        "[name|default]",
+		Description: "Show or change the Codex model of this chat",
+		Permission:  PermissionAdmin,
+		Handler:     s.modelCommand,
+	})
+}
+
+// RegisterBufferCommands adds /buffer, /whitelist and /keywords
+// With a scheduler, /buffer posts a card with "Reply now" buttons
+func RegisterBufferCommands(router *CommandRouter, bufferUC *usecase.BufferUsecase, scheduler *DigestScheduler) {
+	router.Register(&Command{
+		Name:        "buffer",
+		Description: "Show unread buffered messages per chat",
+		Permission:  PermissionAnyone,
+		Handler: func(ctx context.Context, req *CommandRequest) (string, error) {
+			summaries, err := bufferUC.GetBufferSummary(ctx)
+			if err != nil {
+				return "", err
+			}
+			if len(summaries) == 0 {
+				return "No buffered messages.", nil
+			}
+			if scheduler != nil {
+				return "", router.ReplyCard(ctx, req, buildBufferCard(summaries, ""))
+			}
+			var sb strings.Builder
+			sb.WriteString("Buffered messages:")
+			for _, sum := range summaries {
+				name := sum.ChatName
+				if name == "" {
+					name = sum.ChatID
+				}
+				fmt.Fprintf(&sb, "\n- %s: %d (last %s)", name, sum.MessageCount, sum.LastMessage.Format("01-02 15:04"))
+			}
+			return sb.String(), nil
+		},
+	})
+	router.Register(&Command{
+		Name:        "whitelist",
+		Description: "List chats whose messages are answered immediately",
+		Permission:  PermissionAnyone,
+		Handler: func(ctx context.Context, req *CommandRequest) (string, error) {
+			entries, err := bufferUC.GetWhitelist(ctx)
+			if err != nil {
+				return "", err
+			}
+			if len(entries) == 0 {
+				return "The whitelist is empty.", nil
+			}
+			var sb strings.Builder
+			sb.WriteString("Whitelisted chats:")
+			for _, entry := range entries {
+				current := ""
+				if entry.ChatID == req.ChatID {
+					current = " (this chat)"
+				}
+				fmt.Fprintf(&sb, "\n- %s%s: %s", entry.ChatID, current, entry.Reason)
+			}
+			return sb.String(), nil
+		},
+	})
+	router.Register(&Command{
+		Name:        "keywords",
+		Description: "List trigger keywords",
+		Permission:  PermissionAnyone,
+		Handler: func(ctx context.Context, req *CommandRequest) (string, error) {
+			keywords, err := bufferUC.GetKeywords(ctx)
+			if err != nil {
+				return "", err
+			}
+			if len(keywords) == 0 {
+				return "No trigger keywords.", nil
+			}
+			var sb strings.Builder
+			sb.WriteString("Trigger keywords:")
+			for _, kw := range keywords {
+				priority := "normal"
+				if kw.Priority >= 2 {
+					priority = "high"
+				}
+				fmt.Fprintf(&sb, "\n- %s (%s)", kw.Keyword, priority)
+			}
+			return sb.String(), nil
+		},
+	})
+}
+
+// resetCommand handles /reset
+func (s *ConversationService) resetCommand(ctx context.Context, req *CommandRequest) (string, error) {
+	stopped, err := s.StopTurn(ctx, req.Key())
+	if err != nil {
+		return "", err
+	}
+	if err := s.convUC.ResetSession(ctx, req.Key()); err != nil {
+		return "", fmt.Errorf("reset session: %w", err)
+	}
+	text := "Started a new conversation, earlier messages are forgotten."
+	if stopped {
+		text = "Stopped the current reply. " + text
+	}
+	return text, nil
+}
+
+// stopCommand handles /stop
+func (s *ConversationService) stopCommand(ctx context.Context, req *CommandRequest) (string, error) {
+	stopped, err := s.StopTurn(ctx, req.Key())
+	if err != nil {
+		return "", err
+	}
+	if !stopped {
+		return "Nothing is running.", nil
+	}
+	text := "Stopped the current reply."
+	if queued := s.queuedCount(ctx, req.Key()); queued > 0 {
+		text += fmt.Sprintf(" %d queued messages will run next.", queued)
+	}
+	return text, nil
+}
+
+// statusCommand handles /status
+func (s *ConversationService) statusCommand(ctx context.Context, req *CommandRequest) (string, error) {
+	var sb strings.Builder
+
+	session, err := s.convUC.GetSession(ctx, req.Key())
+	if err != nil {
+		return "", err
+	}
+	if session == nil {
+		sb.WriteString("Session: none, the next message starts a new conversation")
+	} else {
+		fmt.Fprintf(&sb, "Session: thread %s, started %s", session.ThreadID, session.CreatedAt.Format("01-02 15:04"))
+		if !session.LastReplyAt.IsZero() {
+			fmt.Fprintf(&sb, ", last reply %s", session.LastReplyAt.Format("01-02 15:04"))
+		}
+	}
+
+	state := s.getChatState(req.Key())
+	state.mu.Lock()
+	busy := state.Processing || state.InFlight
+	state.mu.Unlock()
+	if busy {
+		sb.WriteString("\nReply: in progress")
+	} else {
+		sb.WriteString("\nReply: idle")
+	}
+	fmt.Fprintf(&sb, "\nQueue: %d waiting", s.queuedCount(ctx, req.Key()))
+
+	status := s.codexRepo.Status()
+	switch {
+	case status.Restarting:
+		fmt.Fprintf(&sb, "\nCodex: restarting (%d restarts)", status.Restarts)
+	case status.Running:
+		fmt.Fprintf(&sb, "\nCodex: running (%d restarts)", status.Restarts)
+	default:
+		sb.WriteString("\nCodex: stopped")
+	}
+
+	if s.settingsUC != nil {
+		settings, err := s.settingsUC.Get(ctx, req.ChatID)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&sb, "\nSettings: model %s, reply mode %s, streaming %v",
+			modelName(settings.Model), settings.ReplyMode, settings.StreamReplies)
+	}
+	return sb.String(), nil
+}
+
+// modelCommand handles /model, a new model takes effect in a new conversation
+func (s *ConversationService) modelCommand(ctx context.Context, req *CommandRequest) (string, error) {
+	if s.settingsUC == nil {
+		return "", fmt.Errorf("chat settings are not enabled")
+	}
+	settings, err := s.settingsUC.Get(ctx, req.ChatID)
+	if err != nil {
+		return "", err
+	}
+	if len(req.Args) == 0 {
+		return "Model: " + modelName(settings.Model), nil
+	}
+
+	model := req.Args[0]
+	if strings.EqualFold(model, "default") {
+		model = ""
+	}
+	settings.Model = model
+	if err := s.settingsUC.Save(ctx, settings); err != nil {
+		return "", err
+	}
+	if err := s.convUC.ResetSession(ctx, req.Key()); err != nil {
+		return "", fmt.Errorf("reset session: %w", err)
+	}
+
+	// Saving an empty model falls back to the configured default
+	settings, err = s.settingsUC.Get(ctx, req.ChatID)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Switched to %s, the next message starts a new conversation.", modelName(settings.Model)), nil
+}
+
+// queuedCount returns the number of queued messages of a session
+func (s *ConversationService) queuedCount(ctx context.Context, key domain.SessionKey) int {
+	if s.queueUC == nil {
+		return 0
+	}
+	count, err := s.queueUC.Count(ctx, key)
+	if err != nil {
+		fmt.Printf("[Service] Failed to count queued messages for %s: %v\n", key, err)
+	}
+	return count
+}
+
+func modelName(model string) string {
+	if model == "" {
+		return "Codex default"
+	}
+	return model
+}
@@ -0,0 +1,190 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anthropics/feishu-codex-bridge/internal/biz/domain"
+	"github.com/anthropics/feishu-codex-bridge/internal/biz/repo"
+	"github.com/anthropics/feishu-codex-bridge/internal/biz/usecase"
+)
+
+func TestParseCommand(t *testing.T) {
+	tests := []struct {
+		content string
+		name    string
+		args    []string
+		ok      bool
+	}{
+		{"/reset", "reset", nil, true},
+		{"  /Model gpt-5-codex ", "model", []string{"gpt-5-codex"}, true},
+		{"@Codex /status", "status", nil, true},
+		{"/usr/bin is missing", "", nil, false},
+		{"please /reset", "", nil, false},
+		{"/", "", nil, false},
+	}
+	for _, tt := range tests {
+		name, args, ok := ParseCommand(tt.content)
+		if ok != tt.ok || name != tt.name || strings.Join(args, " ") != strings.Join(tt.args, " ") {
+			t.Errorf("ParseCommand(%q) = %q %q %v, want %q %q %v", tt.content, name, args, ok, tt.name, tt.args, tt.ok)
+		}
+	}
+}
+
+func TestCommandRouter_Dispatch(t *testing.T) {
+	msgRepo := &mockMessageRepo{}
+	router := NewCommandRouter(msgRepo)
+	router.SetAdmins([]string{"ou_admin"})
+
+	var ran []string
+	router.Register(&Command{
+		Name:       "ping",
+		Permission: PermissionAnyone,
+		Handler: func(ctx context.Context, req *CommandRequest) (string, error) {
+			ran = append(ran, "ping:"+strings.Join(req.Args, ","))
+			return "pong", nil
+		},
+	})
+	router.Register(&Command{
+		Name:       "wipe",
+		Permission: PermissionAdmin,
+		Handler: func(ctx context.Context, req *CommandRequest) (string, error) {
+			ran = append(ran, "wipe")
+			return "wiped", nil
+		},
+	})
+
+	ctx := context.Background()
+	dispatch := func(content, sender string, chatType domain.ChatType, mentionsBot bool) bool {
+		return router.Dispatch(ctx, &MessageRequest{
+			ChatID: "chat-1", MsgID: "msg-1", Content: content,
+			SenderID: sender, ChatType: chatType, MentionsBot: mentionsBot,
+		})
+	}
+
+	if !dispatch("/ping a b", "ou_user", domain.ChatTypeP2P, false) {
+		t.Fatal("Expected /ping to be handled")
+	}
+	if dispatch("hello", "ou_user", domain.ChatTypeP2P, false) {
+		t.Error("Plain messages should go to Codex")
+	}
+	if dispatch("/ping", "ou_user", domain.ChatTypeGroup, false) {
+		t.Error("Group commands without an @mention should be ignored")
+	}
+	dispatch("/wipe", "ou_user", domain.ChatTypeP2P, false)
+	dispatch("@Codex /wipe", "ou_admin", domain.ChatTypeGroup, true)
+	dispatch("/nope", "ou_user", domain.ChatTypeP2P, false)
+
+	if strings.Join(ran, "|") != "ping:a,b|wipe" {
+		t.Errorf("Unexpected commands run: %v", ran)
+	}
+	want := []string{"pong", "Only bridge admins can use /wipe.", "wiped", "Unknown command /nope, send /help for the list of commands."}
+	if strings.Join(msgRepo.sentText, "|") != strings.Join(want, "|") {
+		t.Errorf("Replies = %q, want %q", msgRepo.sentText, want)
+	}
+	if len(msgRepo.replies) != len(want) || msgRepo.replies[0].MsgID != "msg-1" {
+		t.Errorf("Expected every answer to quote the command, got %+v", msgRepo.replies)
+	}
+}
+
+func TestCommandRouter_HelpHidesAdminCommands(t *testing.T) {
+	router := NewCommandRouter(&mockMessageRepo{})
+	router.SetAdmins([]string{"ou_admin"})
+	router.Register(&Command{Name: "status", Description: "Show status", Permission: PermissionAnyone})
+	router.Register(&Command{Name: "reset", Description: "Reset", Permission: PermissionAdmin})
+
+	text, _ := router.help(context.Background(), &CommandRequest{SenderID: "ou_user"})
+	if !strings.Contains(text, "/status - Show status") || strings.Contains(text, "/reset") {
+		t.Errorf("Unexpected help for a user: %q", text)
+	}
+	text, _ = router.help(context.Background(), &CommandRequest{SenderID: "ou_admin"})
+	if !strings.Contains(text, "/reset - Reset") {
+		t.Errorf("Expected admins to see /reset: %q", text)
+	}
+}
+
+func TestStopCommand_InterruptsAndDropsReply(t *testing.T) {
+	msgRepo := &mockMessageRepo{}
+	codexRepo := &mockCodexRepo{}
+	sessionRepo := &mockSessionRepo{sessions: make(map[string]*domain.Session)}
+	sessionUC := usecase.NewSessionUsecase(sessionRepo, codexRepo, domain.SessionConfig{ResetHour: -1})
+	svc := &ConversationService{
+		chatStates:  make(map[domain.SessionKey]*ChatState),
+		messageRepo: msgRepo,
+		codexRepo:   codexRepo,
+		convUC:      usecase.NewConversationUsecase(sessionUC, nil, codexRepo, usecase.PromptConfig{}),
+	}
+	var replies []string
+	svc.SetReplyCallback(func(chatID string, to *repo.ReplyTo, text string, mentions []domain.Member) {
+		replies = append(replies, text)
+	})
+
+	ctx := context.Background()
+	req := &CommandRequest{ChatID: "chat-1"}
+	if text, _ := svc.stopCommand(ctx, req); text != "Nothing is running." {
+		t.Errorf("Unexpected reply when idle: %q", text)
+	}
+
+	state := svc.getChatState(domain.SessionKey{ChatID: "chat-1"})
+	state.ThreadID = "thread-1"
+	state.InFlight = true
+	state.Buffer.WriteString("half an answ")
+	sessionRepo.sessions["chat-1"] = &domain.Session{ChatID: "chat-1", ThreadID: "thread-1", UpdatedAt: time.Now()}
+
+	text, err := svc.resetCommand(ctx, req)
+	if err != nil || !strings.HasPrefix(text, "Stopped the current reply.") {
+		t.Fatalf("Unexpected reset reply %q, err %v", text, err)
+	}
+	if len(codexRepo.interrupted) != 1 || codexRepo.interrupted[0] != "thread-1" {
+		t.Errorf("Expected thread-1 to be interrupted, got %v", codexRepo.interrupted)
+	}
+	if sessionRepo.sessions["chat-1"] != nil {
+		t.Error("Expected the session to be deleted")
+	}
+
+	// The interrupted turn completes without a reply
+	svc.HandleCodexEvent(repo.Event{Type: repo.EventTypeTurnComplete, ThreadID: "thread-1"})
+	if len(replies) != 0 {
+		t.Errorf("Expected the stopped reply to be dropped, got %q", replies)
+	}
+	if state.InFlight || state.Stopped {
+		t.Error("Expected the chat to be idle after the stopped turn completed")
+	}
+}
+
+func TestModelCommand(t *testing.T) {
+	codexRepo := &mockCodexRepo{}
+	sessionRepo := &mockSessionRepo{sessions: map[string]*domain.Session{
+		"chat-1": {ChatID: "chat-1", ThreadID: "thread-1", UpdatedAt: time.Now()},
+	}}
+	settingsUC := usecase.NewSettingsUsecase(&mockSettingsRepo{settings: make(map[string]*domain.ChatSettings)}, domain.ChatSettings{Model: "gpt-5"})
+	sessionUC := usecase.NewSessionUsecase(sessionRepo, codexRepo, domain.SessionConfig{ResetHour: -1})
+	svc := &ConversationService{
+		chatStates: make(map[domain.SessionKey]*ChatState),
+		codexRepo:  codexRepo,
+		convUC:     usecase.NewConversationUsecase(sessionUC, nil, codexRepo, usecase.PromptConfig{}),
+	}
+	svc.SetStreaming(settingsUC, DefaultStreamConfig())
+
+	ctx := context.Background()
+	if text, _ := svc.modelCommand(ctx, &CommandRequest{ChatID: "chat-1"}); text != "Model: gpt-5" {
+		t.Errorf("Unexpected model reply %q", text)
+	}
+
+	if _, err := svc.modelCommand(ctx, &CommandRequest{ChatID: "chat-1", Args: []string{"o3"}}); err != nil {
+		t.Fatalf("Set model failed: %v", err)
+	}
+	if settings, _ := settingsUC.Get(ctx, "chat-1"); settings.Model != "o3" {
+		t.Errorf("Expected model o3, got %q", settings.Model)
+	}
+	if sessionRepo.sessions["chat-1"] != nil {
+		t.Error("Expected the session to be reset so the model applies")
+	}
+
+	text, _ := svc.modelCommand(ctx, &CommandRequest{ChatID: "chat-1", Args: []string{"default"}})
+	if text != "Switched to gpt-5, the next message starts a new conversation." {
+		t.Errorf("Unexpected reply %q", text)
+	}
+}
@@ -2,8 +2,8 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"regexp"
 	"strings"
 	"sync"
 
@@ -12,29 +12,44 @@ import (
 	"github.com/anthropics/feishu-codex-bridge/internal/biz/usecase"
 )
 
+// ErrAlreadyProcessing is returned when a session is busy and no queue is configured
+var ErrAlreadyProcessing = errors.New("already processing")
+
 // ConversationService handles conversation logic
 type ConversationService struct {
 	convUC      *usecase.ConversationUsecase
 	filterUC    *usecase.FilterUsecase
+	queueUC     *usecase.QueueUsecase
+	settingsUC  *usecase.SettingsUsecase
+	journalUC   *usecase.JournalUsecase
+	streamCfg   StreamConfig
 	messageRepo repo.MessageRepo
 	codexRepo   repo.CodexRepo
+	directives  *DirectiveSet
 
-	// Chat states
-	chatStates map[string]*ChatState
+	// Chat states, one per session so topic threads of a chat run concurrently
+	chatStates map[domain.SessionKey]*ChatState
 	statesMu   sync.RWMutex
 
 	// Callback
-	onReply func(chatID, msgID, text string, mentions []domain.Member)
+	onReply ReplyFunc
 }
 
-// ChatState represents the state of a chat
+// ChatState represents the state of a chat or one of its topic threads
 type ChatState struct {
 	mu         sync.Mutex
 	ThreadID   string
 	TurnID     string
 	MsgID      string
+	ReplyTo    *repo.ReplyTo // Where the reply goes, nil to post to the chat
 	Processing bool
+	InFlight   bool            // A turn was started and has not completed yet
+	Request    *MessageRequest // Request of the in-flight turn (for retry after a Codex restart)
+	Retried    bool            // The in-flight request was already retried once
+	Stopped    bool            // The in-flight turn was interrupted, drop its reply
+	Rerun      *MessageRequest // Edited message to run once the stopped turn ends
 	Buffer     strings.Builder
+	Stream     *replyStream // Set when the reply of the in-flight turn is streamed
 }
 
 // NewConversationService creates a new conversation service
@@ -49,15 +64,44 @@ func NewConversationService(
 		filterUC:    filterUC,
 		messageRepo: messageRepo,
 		codexRepo:   codexRepo,
-		chatStates:  make(map[string]*ChatState),
+		directives:  NewDirectiveSet(),
+		chatStates:  make(map[domain.SessionKey]*ChatState),
+	}
+}
+
+// Directives returns the directive set, to register additional directives
+func (s *ConversationService) Directives() *DirectiveSet {
+	if s.directives == nil {
+		return defaultDirectives
 	}
+	return s.directives
 }
 
+// ReplyFunc sends a finished reply; to is nil when the reply should be posted to the chat
+type ReplyFunc func(chatID string, to *repo.ReplyTo, text string, mentions []domain.Member)
+
 // SetReplyCallback sets the reply callback
-func (s *ConversationService) SetReplyCallback(callback func(chatID, msgID, text string, mentions []domain.Member)) {
+func (s *ConversationService) SetReplyCallback(callback ReplyFunc) {
 	s.onReply = callback
 }
 
+// SetQueueUsecase enables queueing messages that arrive while a turn is in flight
+func (s *ConversationService) SetQueueUsecase(queueUC *usecase.QueueUsecase) {
+	s.queueUC = queueUC
+}
+
+// SetStreaming enables streaming replies for chats whose settings ask for it
+// The settings also choose between quote replies and plain messages
+func (s *ConversationService) SetStreaming(settingsUC *usecase.SettingsUsecase, cfg StreamConfig) {
+	s.settingsUC = settingsUC
+	s.streamCfg = cfg
+}
+
+// SetJournal records in the message journal how far the handling of each message got
+func (s *ConversationService) SetJournal(journalUC *usecase.JournalUsecase) {
+	s.journalUC = journalUC
+}
+
 // MessageRequest represents a message request
 type MessageRequest struct {
 	ChatID        string
@@ -68,13 +112,21 @@ type MessageRequest struct {
 	ChatType      domain.ChatType
 	MentionsBot   bool
 	ImagePaths    []string
-	MsgCreateTime int64 // Message creation time (milliseconds Unix timestamp from Feishu)
+	MsgCreateTime int64           // Message creation time (milliseconds Unix timestamp from Feishu)
+	TopicID       string          // Feishu topic thread the message was posted in, if any
+	Parent        *domain.Message // Message it quote-replies to, if any
+	MergedMsgIDs  []string        // Earlier queued messages coalesced into this one
+}
+
+// Key returns the key of the session the message belongs to
+func (r *MessageRequest) Key() domain.SessionKey {
+	return domain.SessionKey{ChatID: r.ChatID, TopicID: r.TopicID}
 }
 
 // HandleMessage processes a message
 func (s *ConversationService) HandleMessage(ctx context.Context, req *MessageRequest) error {
 	// 1. Get or create chat state
-	state := s.getChatState(req.ChatID)
+	state := s.getChatState(req.Key())
 
 	// 2. Check if filtering is needed (group chat without @mention)
 	if req.ChatType == domain.ChatTypeGroup && !req.MentionsBot {
@@ -85,27 +137,49 @@ func (s *ConversationService) HandleMessage(ctx context.Context, req *MessageReq
 			}
 			if !should {
 				fmt.Printf("[Service] Skipping irrelevant message\n")
+				s.mark(ctx, req, domain.JournalReplied)
 				return nil
 			}
 		} else {
 			// No filter configured, skip non-@ messages
 			fmt.Printf("[Service] No filter, skipping non-@ group message\n")
+			s.mark(ctx, req, domain.JournalReplied)
 			return nil
 		}
 	}
 
-	// 3. Check if already processing
+	// 3. Queue behind the current turn (or earlier queued messages) to keep FIFO order
 	state.mu.Lock()
-	if state.Processing {
+	busy := state.Processing || state.InFlight
+	if s.queueUC != nil && (busy || s.queueUC.HasPending(ctx, req.Key())) {
+		position, err := s.queueUC.Enqueue(ctx, toQueuedMessage(req))
+		state.mu.Unlock()
+		if err != nil {
+			return fmt.Errorf("enqueue message: %w", err)
+		}
+		fmt.Printf("[Service] Queued message %s in %s at position %d\n", req.MsgID, req.Key(), position)
+		s.mark(ctx, req, domain.JournalQueued)
+		if busy && (req.MentionsBot || req.ChatType == domain.ChatTypeP2P) {
+			s.notify(ctx, req.ChatID, s.replyTarget(ctx, req),
+				fmt.Sprintf("Queued your message (position %d), I'll get to it after the current reply.", position))
+		}
+		s.runNext(req.Key())
+		return nil
+	}
+	if busy {
 		state.mu.Unlock()
-		return fmt.Errorf("already processing")
+		s.fail(ctx, req, "session busy")
+		return ErrAlreadyProcessing
 	}
 	state.Processing = true
 	state.MsgID = req.MsgID
+	state.Retried = false
+	state.Stopped = false
 	state.Buffer.Reset()
 	state.mu.Unlock()
 
 	// 4. Add processing reaction
+	s.mark(ctx, req, domain.JournalProcessing)
 	_ = s.messageRepo.AddReaction(ctx, req.MsgID, "OnIt")
 
 	// 5. Trigger conversation
@@ -118,7 +192,13 @@ func (s *ConversationService) processMessage(ctx context.Context, req *MessageRe
 	defer func() {
 		state.mu.Lock()
 		state.Processing = false
+		inFlight := state.InFlight
 		state.mu.Unlock()
+
+		// Trigger failed or the turn already finished
+		if !inFlight {
+			s.runNext(req.Key())
+		}
 	}()
 
 	triggerReq := &usecase.TriggerRequest{
@@ -130,20 +210,44 @@ func (s *ConversationService) processMessage(ctx context.Context, req *MessageRe
 		ChatType:      req.ChatType,
 		ImagePaths:    req.ImagePaths,
 		MsgCreateTime: req.MsgCreateTime,
+		TopicID:       req.TopicID,
+		Parent:        req.Parent,
 	}
 
+	replyTo := s.replyTarget(ctx, req)
+
 	resp, err := s.convUC.Trigger(ctx, triggerReq)
 	if err != nil {
 		fmt.Printf("[Service] Trigger error: %v\n", err)
-		_ = s.messageRepo.SendText(ctx, req.ChatID, fmt.Sprintf("Error processing: %v", err))
+		s.fail(ctx, req, err.Error())
+		s.notify(ctx, req.ChatID, replyTo, fmt.Sprintf("Error processing: %v", err))
 		return
 	}
 
+	var stream *replyStream
+	if s.streamEnabled(ctx, req.ChatID) {
+		stream = newReplyStream(s.messageRepo, req.ChatID, replyTo, s.streamCfg, s.renderPartial)
+	}
+
 	state.mu.Lock()
+	state.ReplyTo = replyTo
 	state.ThreadID = resp.ThreadID
 	state.TurnID = resp.TurnID
+	state.InFlight = true
+	state.Request = req
+	state.Stream = stream
+	stopped := state.Stopped
 	state.mu.Unlock()
 
+	go s.consumeTurn(resp.Events)
+
+	// Stopped while the turn was starting
+	if stopped {
+		if err := s.codexRepo.InterruptTurn(ctx, resp.ThreadID); err != nil {
+			fmt.Printf("[Service] Failed to interrupt turn %s: %v\n", resp.TurnID, err)
+		}
+	}
+
 	fmt.Printf("[Service] Started turn %s in thread %s (isNew=%v)\n", resp.TurnID, resp.ThreadID, resp.IsNew)
 }
 
@@ -162,118 +266,621 @@ func (s *ConversationService) HandleCodexEvent(event repo.Event) {
 		if data, ok := event.Data.(*repo.ErrorData); ok {
 			fmt.Printf("[Service] Codex error: %v\n", data.Error)
 		}
+
+	case repo.EventTypeCodexExited:
+		if data, ok := event.Data.(*repo.ErrorData); ok {
+			fmt.Printf("[Service] %v\n", data.Error)
+		}
+
+	case repo.EventTypeCodexRestarted:
+		go s.handleCodexRestart()
+	}
+}
+
+// handleCodexRestart re-attaches threads and retries turns lost with the old process
+func (s *ConversationService) handleCodexRestart() {
+	ctx := context.Background()
+
+	resumed, err := s.convUC.ResumeActiveThreads(ctx)
+	if err != nil {
+		fmt.Printf("[Service] Failed to resume threads after restart: %v\n", err)
+	} else {
+		fmt.Printf("[Service] Resumed %d threads after Codex restart\n", resumed)
+	}
+
+	s.statesMu.RLock()
+	keys := make([]domain.SessionKey, 0, len(s.chatStates))
+	for key := range s.chatStates {
+		keys = append(keys, key)
+	}
+	s.statesMu.RUnlock()
+
+	for _, key := range keys {
+		state := s.getChatState(key)
+
+		state.mu.Lock()
+		if !state.InFlight || state.Request == nil || state.Processing {
+			state.mu.Unlock()
+			continue
+		}
+		req := state.Request
+		stream := state.Stream
+		replyTo := state.ReplyTo
+		state.InFlight = false
+		state.Stream = nil
+		if state.Stopped {
+			// Stopped turns are not retried
+			state.Stopped = false
+			edited := state.Rerun != nil
+			state.mu.Unlock()
+			if stream != nil {
+				stream.Abort("Stopped")
+			}
+			if !edited {
+				s.fail(ctx, req, "stopped")
+			}
+			s.runNext(key)
+			continue
+		}
+		if state.Retried {
+			state.mu.Unlock()
+			if stream != nil {
+				stream.Abort("Interrupted by a Codex restart")
+			}
+			s.fail(ctx, req, "codex restarted twice")
+			s.notify(ctx, key.ChatID, replyTo, "Codex restarted again while handling this message, please try again later.")
+			s.runNext(key)
+			continue
+		}
+		state.Retried = true
+		state.Processing = true
+		state.Buffer.Reset()
+		state.mu.Unlock()
+
+		if stream != nil {
+			stream.Abort("Interrupted by a Codex restart")
+		}
+		fmt.Printf("[Service] Retrying in-flight message %s in %s after restart\n", req.MsgID, key)
+		s.notify(ctx, key.ChatID, replyTo, "Codex restarted, retrying your last message...")
+		go s.processMessage(ctx, req, state)
 	}
 }
 
 func (s *ConversationService) handleAgentDelta(threadID, delta string) {
-	chatID := s.findChatByThread(threadID)
-	if chatID == "" {
+	key, ok := s.findSessionByThread(threadID)
+	if !ok {
 		return
 	}
 
-	state := s.getChatState(chatID)
+	state := s.getChatState(key)
 	state.mu.Lock()
 	state.Buffer.WriteString(delta)
+	stream := state.Stream
 	state.mu.Unlock()
+
+	if stream != nil {
+		stream.Append(delta)
+	}
 }
 
 func (s *ConversationService) handleTurnComplete(threadID string) {
-	chatID := s.findChatByThread(threadID)
-	if chatID == "" {
+	key, ok := s.findSessionByThread(threadID)
+	if !ok {
 		return
 	}
+	chatID := key.ChatID
+
+	// Start the next queued message once this reply is out
+	defer s.runNext(key)
 
-	state := s.getChatState(chatID)
+	state := s.getChatState(key)
 	state.mu.Lock()
 	response := state.Buffer.String()
 	msgID := state.MsgID
+	req := state.Request
+	replyTo := state.ReplyTo
+	stream := state.Stream
+	stopped := state.Stopped
+	edited := state.Rerun != nil
+	state.InFlight = false
+	state.Stream = nil
+	state.Stopped = false
 	state.mu.Unlock()
 
+	ctx := context.Background()
+	if stopped {
+		if stream != nil && edited {
+			stream.Abort("Message edited, answering the new version")
+		} else if stream != nil {
+			stream.Abort("Stopped")
+		}
+		if !edited {
+			s.fail(ctx, req, "stopped")
+		}
+		fmt.Printf("[Service] Dropped reply of stopped turn in %s\n", key)
+		return
+	}
+
+	// Nothing is retried past this point, even if sending the reply fails
+	s.mark(ctx, req, domain.JournalReplied)
+
 	if response == "" {
+		if stream != nil {
+			stream.stop()
+		}
 		return
 	}
 
-	// Parse response
-	text, mentions := s.parseResponse(response)
+	// Interpret directives
+	plan := s.Directives().Interpret(response)
+	for _, err := range plan.Errors {
+		fmt.Printf("[Service] Ignored directive in %s: %v\n", chatID, err)
+	}
+	text, mentions := plan.Text, plan.Mentions
 
-	// Add completion reaction
-	ctx := context.Background()
+	// Add completion reaction, plus any Codex asked for
 	_ = s.messageRepo.AddReaction(ctx, msgID, "DONE")
+	for _, reaction := range plan.Reactions {
+		if err := s.messageRepo.AddReaction(ctx, msgID, reaction); err != nil {
+			fmt.Printf("[Service] Failed to add reaction %s to %s: %v\n", reaction, msgID, err)
+		}
+	}
 
-	// Send reply
-	if s.onReply != nil {
-		s.onReply(chatID, msgID, text, mentions)
+	// Send reply (a streamed reply only needs its final update)
+	streamed := stream != nil && stream.Finish(formatMentions(text, mentions))
+	switch {
+	case plan.MentionAll:
+		s.sendMentionAll(ctx, chatID, replyTo, text, mentions, streamed)
+	case streamed || text == "":
+		// Nothing left to send, e.g. a reaction-only response
+	case s.onReply != nil:
+		s.onReply(chatID, replyTo, text, mentions)
 	}
 
 	// Mark as replied
-	_ = s.convUC.OnReplyComplete(ctx, chatID)
+	_ = s.convUC.OnReplyComplete(ctx, key)
 
-	fmt.Printf("[Service] Turn completed, sent %d chars to %s\n", len(text), chatID)
+	fmt.Printf("[Service] Turn completed, sent %d chars to %s\n", len(text), key)
 }
 
-// parseResponse parses the response, extracting text and directives
-func (s *ConversationService) parseResponse(response string) (string, []domain.Member) {
-	text := response
-	var mentions []domain.Member
+// StopTurn interrupts the turn running in a session and drops its reply
+// Returns false if no turn was running. Queued messages run afterwards as usual
+func (s *ConversationService) StopTurn(ctx context.Context, key domain.SessionKey) (bool, error) {
+	state := s.getChatState(key)
+	state.mu.Lock()
+	if !(state.InFlight || state.Processing) || state.Stopped {
+		state.mu.Unlock()
+		return false, nil
+	}
+	state.Stopped = true
+	if !state.InFlight {
+		// The turn is still starting, processMessage interrupts it once started
+		state.mu.Unlock()
+		return true, nil
+	}
+	threadID := state.ThreadID
+	state.mu.Unlock()
+
+	if err := s.codexRepo.InterruptTurn(ctx, threadID); err != nil {
+		state.mu.Lock()
+		state.Stopped = false
+		state.mu.Unlock()
+		return false, fmt.Errorf("interrupt turn: %w", err)
+	}
+	fmt.Printf("[Service] Interrupted turn in thread %s for %s\n", threadID, key)
+	return true, nil
+}
+
+// HandleRecall cancels a recalled message: its turn is interrupted, or it leaves the queue
+// Returns false if the message was neither running nor queued, e.g. already answered
+// Recall events do not name the topic, so every session of the chat is checked
+func (s *ConversationService) HandleRecall(ctx context.Context, chatID, msgID string) (bool, error) {
+	var running bool
+	var key domain.SessionKey
+	for _, k := range s.chatSessions(chatID) {
+		state := s.getChatState(k)
+		state.mu.Lock()
+		if state.MsgID == msgID && (state.Processing || state.InFlight) {
+			// An edit before the recall must not run either
+			state.Rerun = nil
+			running, key = true, k
+		}
+		state.mu.Unlock()
+		if running {
+			break
+		}
+	}
+
+	if running {
+		if _, err := s.StopTurn(ctx, key); err != nil {
+			return false, err
+		}
+		fmt.Printf("[Service] Cancelled recalled message %s in %s\n", msgID, chatID)
+		return true, nil
+	}
+	if s.queueUC == nil {
+		return false, nil
+	}
+	dropped, err := s.queueUC.Drop(ctx, msgID)
+	if err != nil {
+		return false, fmt.Errorf("drop queued message: %w", err)
+	}
+	if dropped {
+		fmt.Printf("[Service] Dropped recalled message %s from the queue of %s\n", msgID, chatID)
+		if s.journalUC != nil {
+			s.journalUC.Fail(ctx, "recalled", msgID)
+		}
+	}
+	return dropped, nil
+}
+
+// ForgetChat stops the turns of a chat and its topics and drops their queued messages
+// Used when the bot leaves a chat, so nothing runs or resumes there later
+func (s *ConversationService) ForgetChat(ctx context.Context, chatID string) {
+	keys := s.chatSessions(chatID)
+	for _, key := range keys {
+		state := s.getChatState(key)
+		state.mu.Lock()
+		state.Rerun = nil
+		req := state.Request
+		running := state.InFlight || state.Processing
+		state.mu.Unlock()
+		if running && req != nil {
+			s.fail(ctx, req, "bot left the chat")
+		}
+		if _, err := s.StopTurn(ctx, key); err != nil {
+			fmt.Printf("[Service] Failed to stop turn in %s: %v\n", key, err)
+		}
+	}
+
+	if s.queueUC != nil {
+		dropped, err := s.queueUC.DropChat(ctx, chatID)
+		if err != nil {
+			fmt.Printf("[Service] Failed to drop queued messages of %s: %v\n", chatID, err)
+		} else if dropped > 0 {
+			fmt.Printf("[Service] Dropped %d queued messages of %s\n", dropped, chatID)
+		}
+	}
+
+	// Turns completing after this find no session and send nothing
+	s.statesMu.Lock()
+	for _, key := range keys {
+		delete(s.chatStates, key)
+	}
+	s.statesMu.Unlock()
+}
+
+// HandleEdit answers the new version of an edited message that is running or queued
+// A running turn is stopped and re-run with the new content, a queued message is updated.
+// Returns false if the message was neither, answered messages are left alone
+func (s *ConversationService) HandleEdit(ctx context.Context, req *MessageRequest) (bool, error) {
+	state := s.getChatState(req.Key())
+	state.mu.Lock()
+	running := state.MsgID == req.MsgID && (state.Processing || state.InFlight)
+	if running {
+		state.Rerun = req
+	}
+	state.mu.Unlock()
+
+	if running {
+		if _, err := s.StopTurn(ctx, req.Key()); err != nil {
+			state.mu.Lock()
+			state.Rerun = nil
+			state.mu.Unlock()
+			return false, err
+		}
+		fmt.Printf("[Service] Re-running edited message %s in %s\n", req.MsgID, req.ChatID)
+		return true, nil
+	}
+	if s.queueUC == nil {
+		return false, nil
+	}
+	updated, err := s.queueUC.Edit(ctx, req.MsgID, req.Content, req.ImagePaths)
+	if err != nil {
+		return false, fmt.Errorf("update queued message: %w", err)
+	}
+	if updated {
+		fmt.Printf("[Service] Updated edited message %s in the queue of %s\n", req.MsgID, req.ChatID)
+	}
+	return updated, nil
+}
 
-	// Extract REACTION directives (match any non-] character for error tolerance)
-	reactionRegex := regexp.MustCompile(`\[REACTION:[^\]]+\]`)
-	text = reactionRegex.ReplaceAllString(text, "")
+// streamEnabled checks whether a chat wants streaming replies
+func (s *ConversationService) streamEnabled(ctx context.Context, chatID string) bool {
+	if s.settingsUC == nil {
+		return false
+	}
+	settings, err := s.settingsUC.Get(ctx, chatID)
+	if err != nil {
+		fmt.Printf("[Service] Failed to load settings for %s: %v\n", chatID, err)
+		return false
+	}
+	return settings.StreamReplies
+}
 
-	// Extract MENTION directives
-	mentionRegex := regexp.MustCompile(`\[MENTION:([^:]+):([^\]]+)\]`)
-	mentionMatches := mentionRegex.FindAllStringSubmatch(text, -1)
-	for _, match := range mentionMatches {
-		if len(match) == 3 {
-			mentions = append(mentions, domain.Member{
-				UserID: match[1],
-				Name:   match[2],
-			})
+// replyTarget decides whether replies to req quote the message or go to the chat
+// Replies to topic messages always stay in their topic
+func (s *ConversationService) replyTarget(ctx context.Context, req *MessageRequest) *repo.ReplyTo {
+	if req.MsgID == "" {
+		return nil
+	}
+	if req.TopicID != "" {
+		return &repo.ReplyTo{MsgID: req.MsgID, InTopic: true}
+	}
+	if s.settingsUC != nil {
+		settings, err := s.settingsUC.Get(ctx, req.ChatID)
+		if err != nil {
+			fmt.Printf("[Service] Failed to load settings for %s: %v\n", req.ChatID, err)
+		} else if settings.ReplyMode == domain.ReplyModePlain {
+			return nil
 		}
 	}
-	text = mentionRegex.ReplaceAllString(text, "")
+	return &repo.ReplyTo{MsgID: req.MsgID}
+}
 
-	// Extract MENTION_ALL directives
-	mentionAllRegex := regexp.MustCompile(`\[MENTION_ALL\]`)
-	text = mentionAllRegex.ReplaceAllString(text, "")
+// notify sends a short status message, as a reply when to is set
+func (s *ConversationService) notify(ctx context.Context, chatID string, to *repo.ReplyTo, text string) {
+	if to != nil {
+		err := s.messageRepo.ReplyText(ctx, *to, text)
+		if err == nil {
+			return
+		}
+		// The message may have been recalled, post to the chat instead
+		fmt.Printf("[Service] Failed to reply to %s: %v\n", to.MsgID, err)
+	}
+	_ = s.messageRepo.SendText(ctx, chatID, text)
+}
 
-	// Clean up extra whitespace
-	text = strings.TrimSpace(text)
+// renderPartial renders an incomplete response for streaming
+func (s *ConversationService) renderPartial(raw string) string {
+	text, _ := s.parseResponse(raw)
+	return text
+}
 
-	return text, mentions
+// parseResponse parses the response, extracting text and mentions
+// Other directives are stripped, see DirectiveSet.Interpret
+func (s *ConversationService) parseResponse(response string) (string, []domain.Member) {
+	plan := s.Directives().Interpret(response)
+	return plan.Text, plan.Mentions
 }
 
-func (s *ConversationService) getChatState(chatID string) *ChatState {
+// sendMentionAll sends a reply that @ mentions everyone
+// A streamed reply is already posted, so only a short ping is sent
+func (s *ConversationService) sendMentionAll(ctx context.Context, chatID string, to *repo.ReplyTo, text string, mentions []domain.Member, streamed bool) {
+	notice := text
+	if streamed {
+		notice = "Please see the reply above."
+	}
+	err := s.messageRepo.SendTextMentionAll(ctx, chatID, notice)
+	if err == nil {
+		return
+	}
+
+	// Usually the bot may not @all in this chat, send the reply without it
+	fmt.Printf("[Service] Failed to mention all in %s: %v\n", chatID, err)
+	if !streamed && text != "" && s.onReply != nil {
+		s.onReply(chatID, to, text, mentions)
+	}
+}
+
+func (s *ConversationService) getChatState(key domain.SessionKey) *ChatState {
 	s.statesMu.Lock()
 	defer s.statesMu.Unlock()
 
-	state, ok := s.chatStates[chatID]
+	state, ok := s.chatStates[key]
 	if !ok {
 		state = &ChatState{}
-		s.chatStates[chatID] = state
+		s.chatStates[key] = state
 	}
 	return state
 }
 
-func (s *ConversationService) findChatByThread(threadID string) string {
+// chatSessions lists the sessions of a chat that have state: the chat and its topics
+func (s *ConversationService) chatSessions(chatID string) []domain.SessionKey {
 	s.statesMu.RLock()
 	defer s.statesMu.RUnlock()
 
-	for chatID, state := range s.chatStates {
+	var keys []domain.SessionKey
+	for key := range s.chatStates {
+		if key.ChatID == chatID {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// SessionForThread returns the session whose conversation runs in the given thread
+// and, for a topic session, the message to reply to so that posts stay in the topic
+// A turn's first approval request can arrive before the turn is recorded on
+// its chat state, the stored session names the session then
+func (s *ConversationService) SessionForThread(threadID string) (domain.SessionKey, *repo.ReplyTo) {
+	key, ok := s.findSessionByThread(threadID)
+	var lastMsgID string
+	if !ok && s.convUC != nil {
+		session, err := s.convUC.FindSessionByThread(context.Background(), threadID)
+		if err != nil {
+			fmt.Printf("[Service] Failed to find the session of thread %s: %v\n", threadID, err)
+		} else if session != nil {
+			key, ok = session.Key(), true
+			lastMsgID = session.LastProcessedMsgID
+		}
+	}
+	if !ok {
+		return domain.SessionKey{}, nil
+	}
+	if key.TopicID == "" {
+		return key, nil
+	}
+
+	// Reply to the message being answered, the topic ID is not a message
+	s.statesMu.RLock()
+	state := s.chatStates[key]
+	s.statesMu.RUnlock()
+	msgID := lastMsgID
+	if state != nil {
+		state.mu.Lock()
+		if state.MsgID != "" {
+			msgID = state.MsgID
+		}
+		state.mu.Unlock()
+	}
+	if msgID == "" {
+		return key, nil
+	}
+	return key, &repo.ReplyTo{MsgID: msgID, InTopic: true}
+}
+
+func (s *ConversationService) findSessionByThread(threadID string) (domain.SessionKey, bool) {
+	s.statesMu.RLock()
+	defer s.statesMu.RUnlock()
+
+	for key, state := range s.chatStates {
 		state.mu.Lock()
 		tid := state.ThreadID
 		state.mu.Unlock()
 		if tid == threadID {
-			return chatID
+			return key, true
+		}
+	}
+	return domain.SessionKey{}, false
+}
+
+// runNext starts the next queued message of a session if the session is idle
+// An edited message whose turn was stopped goes first
+func (s *ConversationService) runNext(key domain.SessionKey) {
+	ctx := context.Background()
+
+	state := s.getChatState(key)
+	state.mu.Lock()
+	if state.Processing || state.InFlight {
+		state.mu.Unlock()
+		return
+	}
+	if rerun := state.Rerun; rerun != nil {
+		state.Rerun = nil
+		state.Processing = true
+		state.MsgID = rerun.MsgID
+		state.Retried = false
+		state.Stopped = false
+		state.Buffer.Reset()
+		state.mu.Unlock()
+		s.mark(ctx, rerun, domain.JournalProcessing)
+		go s.processMessage(ctx, rerun, state)
+		return
+	}
+	if s.queueUC == nil {
+		state.mu.Unlock()
+		return
+	}
+	next, err := s.queueUC.Next(ctx, key)
+	if err != nil || next == nil {
+		state.mu.Unlock()
+		if err != nil {
+			fmt.Printf("[Service] Failed to dequeue message for %s: %v\n", key, err)
+		}
+		return
+	}
+	state.Processing = true
+	state.MsgID = next.MsgID
+	state.Retried = false
+	state.Stopped = false
+	state.Buffer.Reset()
+	state.mu.Unlock()
+
+	req := &MessageRequest{
+		ChatID:        next.ChatID,
+		MsgID:         next.MsgID,
+		Content:       next.Content,
+		SenderID:      next.SenderID,
+		SenderName:    next.SenderName,
+		ChatType:      next.ChatType,
+		MentionsBot:   next.MentionsBot,
+		ImagePaths:    next.ImagePaths,
+		MsgCreateTime: next.MsgCreateTime,
+		TopicID:       next.TopicID,
+		Parent:        next.Parent,
+		MergedMsgIDs:  next.MergedMsgIDs,
+	}
+
+	fmt.Printf("[Service] Running queued message %s in %s\n", req.MsgID, key)
+	s.mark(ctx, req, domain.JournalProcessing)
+	_ = s.messageRepo.AddReaction(ctx, req.MsgID, "OnIt")
+	go s.processMessage(ctx, req, state)
+}
+
+// ResumeQueues starts sessions whose queued messages survived a bridge restart
+func (s *ConversationService) ResumeQueues() {
+	if s.queueUC == nil {
+		return
+	}
+	keys, err := s.queueUC.PendingSessions(context.Background())
+	if err != nil {
+		fmt.Printf("[Service] Failed to list queued sessions: %v\n", err)
+		return
+	}
+	for _, key := range keys {
+		s.runNext(key)
+	}
+	if len(keys) > 0 {
+		fmt.Printf("[Service] Resumed message queues for %d sessions\n", len(keys))
+	}
+}
+
+// mark records how far the handling of req got, if a journal is set
+func (s *ConversationService) mark(ctx context.Context, req *MessageRequest, state domain.JournalState) {
+	if s.journalUC != nil && req != nil {
+		s.journalUC.Mark(ctx, state, req.msgIDs()...)
+	}
+}
+
+// fail records in the journal that req was given up on
+func (s *ConversationService) fail(ctx context.Context, req *MessageRequest, reason string) {
+	if s.journalUC != nil && req != nil {
+		s.journalUC.Fail(ctx, reason, req.msgIDs()...)
+	}
+}
+
+// msgIDs lists the messages a request answers, coalesced ones included
+func (r *MessageRequest) msgIDs() []string {
+	return append([]string{r.MsgID}, r.MergedMsgIDs...)
+}
+
+func toQueuedMessage(req *MessageRequest) *domain.QueuedMessage {
+	return &domain.QueuedMessage{
+		ChatID:        req.ChatID,
+		MsgID:         req.MsgID,
+		Content:       req.Content,
+		SenderID:      req.SenderID,
+		SenderName:    req.SenderName,
+		ChatType:      req.ChatType,
+		MentionsBot:   req.MentionsBot,
+		ImagePaths:    req.ImagePaths,
+		MsgCreateTime: req.MsgCreateTime,
+		TopicID:       req.TopicID,
+		Parent:        req.Parent,
+	}
+}
+
+// consumeTurn feeds the events of one turn to the handlers until the turn ends
+func (s *ConversationService) consumeTurn(sub repo.Subscription) {
+	defer sub.Close()
+	for event := range sub.Events() {
+		if event.Type == repo.EventTypeCodexExited {
+			// Lifecycle events are handled by the event loop
+			continue
 		}
+		s.HandleCodexEvent(event)
 	}
-	return ""
 }
 
-// StartEventLoop starts the event loop
+// StartEventLoop starts the event loop for app-server lifecycle events
+// Turn events are consumed per turn, see consumeTurn
 func (s *ConversationService) StartEventLoop() {
+	sub := s.codexRepo.Subscribe(repo.EventFilter{
+		Types: []repo.EventType{repo.EventTypeCodexExited, repo.EventTypeCodexRestarted},
+	})
 	go func() {
-		for event := range s.codexRepo.Events() {
+		for event := range sub.Events() {
 			s.HandleCodexEvent(event)
 		}
 	}()
@@ -2,6 +2,8 @@ package service
 
 import (
 	"context"
+	"fmt"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -14,16 +16,27 @@ import (
 // Mock implementations
 
 type mockMessageRepo struct {
-	history  []domain.Message
-	members  []domain.Member
-	sentText []string
-	mu       sync.Mutex
+	history    []domain.Message
+	members    []domain.Member
+	sentText   []string
+	sentCards  []string
+	updated    map[string]string // msgID -> card
+	cardErr    error             // Returned by SendCard when set
+	replies    []repo.ReplyTo    // Targets of Reply* calls
+	reactions  []string          // Emoji types added with AddReaction
+	mentionAll []string          // Texts sent with @all
+	recalled   []string          // Messages whose replies were recalled
+	mu         sync.Mutex
 }
 
 func (m *mockMessageRepo) GetChatHistory(ctx context.Context, chatID string, limit int) ([]domain.Message, error) {
 	return m.history, nil
 }
 
+func (m *mockMessageRepo) GetThreadHistory(ctx context.Context, chatID, threadID string, limit int) ([]domain.Message, error) {
+	return m.history, nil
+}
+
 func (m *mockMessageRepo) GetChatMembers(ctx context.Context, chatID string) ([]domain.Member, error) {
 	return m.members, nil
 }
@@ -46,17 +59,97 @@ func (m *mockMessageRepo) SendTextWithMentions(ctx context.Context, chatID, text
 	return nil
 }
 
+func (m *mockMessageRepo) SendTextMentionAll(ctx context.Context, chatID, text string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sentText = append(m.sentText, text)
+	m.mentionAll = append(m.mentionAll, text)
+	return nil
+}
+
+func (m *mockMessageRepo) SendMarkdown(ctx context.Context, chatID, markdown string, mentions []domain.Member) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sentText = append(m.sentText, markdown)
+	return nil
+}
+
+func (m *mockMessageRepo) ReplyText(ctx context.Context, to repo.ReplyTo, text string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sentText = append(m.sentText, text)
+	m.replies = append(m.replies, to)
+	return nil
+}
+
+func (m *mockMessageRepo) ReplyMarkdown(ctx context.Context, to repo.ReplyTo, markdown string, mentions []domain.Member) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sentText = append(m.sentText, markdown)
+	m.replies = append(m.replies, to)
+	return nil
+}
+
+func (m *mockMessageRepo) ReplyCard(ctx context.Context, to repo.ReplyTo, card string) (string, error) {
+	m.mu.Lock()
+	m.replies = append(m.replies, to)
+	m.mu.Unlock()
+	return m.SendCard(ctx, "", card)
+}
+
+func (m *mockMessageRepo) SendImage(ctx context.Context, chatID, path string) error {
+	return nil
+}
+
+func (m *mockMessageRepo) SendFile(ctx context.Context, chatID, path string) error {
+	return nil
+}
+
 func (m *mockMessageRepo) AddReaction(ctx context.Context, msgID, reactionType string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.reactions = append(m.reactions, reactionType)
+	return nil
+}
+
+func (m *mockMessageRepo) RecallReplies(ctx context.Context, msgID string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.recalled = append(m.recalled, msgID)
+	return 1, nil
+}
+
+func (m *mockMessageRepo) SendCard(ctx context.Context, chatID, card string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.cardErr != nil {
+		return "", m.cardErr
+	}
+	m.sentCards = append(m.sentCards, card)
+	return fmt.Sprintf("card_%d", len(m.sentCards)), nil
+}
+
+func (m *mockMessageRepo) UpdateCard(ctx context.Context, msgID, card string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.updated == nil {
+		m.updated = make(map[string]string)
+	}
+	m.updated[msgID] = card
 	return nil
 }
 
 type mockCodexRepo struct {
-	threadID string
-	turnID   string
-	events   chan repo.Event
+	threadID        string
+	turnID          string
+	subs            []*mockSubscription
+	approvalHandler repo.ApprovalHandler
+	responses       map[int64]domain.ApprovalDecision
+	interrupted     []string
+	mu              sync.Mutex
 }
 
-func (m *mockCodexRepo) CreateThread(ctx context.Context) (string, error) {
+func (m *mockCodexRepo) CreateThread(ctx context.Context, model string) (string, error) {
 	return m.threadID, nil
 }
 
@@ -68,50 +161,117 @@ func (m *mockCodexRepo) ResumeThread(ctx context.Context, threadID string) error
 	return nil
 }
 
+func (m *mockCodexRepo) InterruptTurn(ctx context.Context, threadID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.interrupted = append(m.interrupted, threadID)
+	return nil
+}
+
 func (m *mockCodexRepo) Stop() {}
 
-func (m *mockCodexRepo) Events() <-chan repo.Event {
-	return m.events
+func (m *mockCodexRepo) Subscribe(filter repo.EventFilter) repo.Subscription {
+	return m.subscribe()
+}
+
+func (m *mockCodexRepo) SubscribeTurn(threadID, turnID string) repo.Subscription {
+	return m.subscribe()
+}
+
+func (m *mockCodexRepo) subscribe() *mockSubscription {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sub := &mockSubscription{ch: make(chan repo.Event, 10)}
+	m.subs = append(m.subs, sub)
+	return sub
+}
+
+// lastSub returns the most recent subscription, or nil
+func (m *mockCodexRepo) lastSub() *mockSubscription {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.subs) == 0 {
+		return nil
+	}
+	return m.subs[len(m.subs)-1]
+}
+
+type mockSubscription struct {
+	ch   chan repo.Event
+	once sync.Once
+}
+
+func (s *mockSubscription) Events() <-chan repo.Event {
+	return s.ch
+}
+
+func (s *mockSubscription) Close() {
+	s.once.Do(func() { close(s.ch) })
+}
+
+func (m *mockCodexRepo) Status() repo.CodexStatus {
+	return repo.CodexStatus{Running: true}
 }
 
 func (m *mockCodexRepo) DebugConversation(ctx context.Context, prompt string, timeout time.Duration) (string, string, error) {
 	return "mock response", m.threadID, nil
 }
 
+func (m *mockCodexRepo) SetApprovalHandler(handler repo.ApprovalHandler) {
+	m.approvalHandler = handler
+}
+
+func (m *mockCodexRepo) RespondToApproval(requestID int64, decision domain.ApprovalDecision) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.responses == nil {
+		m.responses = make(map[int64]domain.ApprovalDecision)
+	}
+	m.responses[requestID] = decision
+	return nil
+}
+
 type mockSessionRepo struct {
 	sessions map[string]*domain.Session
 	mu       sync.Mutex
 }
 
-func (m *mockSessionRepo) GetByChat(ctx context.Context, chatID string) (*domain.Session, error) {
+func (m *mockSessionRepo) Get(ctx context.Context, key domain.SessionKey) (*domain.Session, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	return m.sessions[chatID], nil
+	return m.sessions[key.String()], nil
 }
 
 func (m *mockSessionRepo) Save(ctx context.Context, session *domain.Session) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	m.sessions[chatID(session)] = session
+	m.sessions[session.Key().String()] = session
 	return nil
 }
 
-func chatID(s *domain.Session) string {
-	return s.ChatID
+func (m *mockSessionRepo) Delete(ctx context.Context, key domain.SessionKey) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.sessions, key.String())
+	return nil
 }
 
-func (m *mockSessionRepo) Delete(ctx context.Context, chatID string) error {
+func (m *mockSessionRepo) DeleteChat(ctx context.Context, chatID string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	delete(m.sessions, chatID)
+	for k, s := range m.sessions {
+		if s.ChatID == chatID {
+			delete(m.sessions, k)
+		}
+	}
 	return nil
 }
 
-func (m *mockSessionRepo) Touch(ctx context.Context, chatID string) error {
+func (m *mockSessionRepo) Touch(ctx context.Context, key domain.SessionKey) error {
 	return nil
 }
 
-func (m *mockSessionRepo) MarkReplied(ctx context.Context, chatID string) error {
+func (m *mockSessionRepo) MarkReplied(ctx context.Context, key domain.SessionKey) error {
 	return nil
 }
 
@@ -119,16 +279,22 @@ func (m *mockSessionRepo) CleanupStale(ctx context.Context, before time.Time) (i
 	return 0, nil
 }
 
-func (m *mockSessionRepo) UpdateLastMsgTime(ctx context.Context, chatID string, msgTime time.Time) error {
+func (m *mockSessionRepo) UpdateLastMsgTime(ctx context.Context, key domain.SessionKey, msgTime time.Time) error {
 	return nil
 }
 
-func (m *mockSessionRepo) UpdateLastProcessedMsg(ctx context.Context, chatID string, msgID string, msgTime time.Time) error {
+func (m *mockSessionRepo) UpdateLastProcessedMsg(ctx context.Context, key domain.SessionKey, msgID string, msgTime time.Time) error {
 	return nil
 }
 
 func (m *mockSessionRepo) ListAll(ctx context.Context) ([]*domain.Session, error) {
-	return nil, nil
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var result []*domain.Session
+	for _, session := range m.sessions {
+		result = append(result, session)
+	}
+	return result, nil
 }
 
 func (m *mockSessionRepo) Close() error {
@@ -212,36 +378,75 @@ func TestParseResponse_Complex(t *testing.T) {
 	}
 }
 
-func TestFindChatByThread(t *testing.T) {
+func TestFindSessionByThread(t *testing.T) {
 	svc := &ConversationService{
-		chatStates: make(map[string]*ChatState),
+		chatStates: make(map[domain.SessionKey]*ChatState),
 	}
 
-	// Add a state
-	svc.chatStates["chat-123"] = &ChatState{
+	// Add a chat and a topic state
+	svc.chatStates[domain.SessionKey{ChatID: "chat-123"}] = &ChatState{
 		ThreadID: "thread-abc",
 	}
+	topic := domain.SessionKey{ChatID: "chat-123", TopicID: "omt-1"}
+	svc.chatStates[topic] = &ChatState{
+		ThreadID: "thread-topic",
+		MsgID:    "om_topic",
+	}
 
 	// Test finding existing
-	chatID := svc.findChatByThread("thread-abc")
-	if chatID != "chat-123" {
-		t.Errorf("Expected 'chat-123', got '%s'", chatID)
+	key, ok := svc.findSessionByThread("thread-abc")
+	if !ok || key != (domain.SessionKey{ChatID: "chat-123"}) {
+		t.Errorf("Expected chat-123, got %v", key)
+	}
+	if key, _ := svc.findSessionByThread("thread-topic"); key != topic {
+		t.Errorf("Expected %v, got %v", topic, key)
+	}
+	if key, to := svc.SessionForThread("thread-topic"); key != topic || to == nil || *to != (repo.ReplyTo{MsgID: "om_topic", InTopic: true}) {
+		t.Errorf("Expected the topic and a reply in it, got %v %+v", key, to)
+	}
+	if _, to := svc.SessionForThread("thread-abc"); to != nil {
+		t.Errorf("Expected no reply target for the chat session, got %+v", to)
 	}
 
 	// Test finding non-existing
-	chatID = svc.findChatByThread("thread-xyz")
-	if chatID != "" {
-		t.Errorf("Expected empty string, got '%s'", chatID)
+	if key, ok := svc.findSessionByThread("thread-xyz"); ok {
+		t.Errorf("Expected no session, got %v", key)
+	}
+}
+
+func TestSessionForThread_BeforeTurnIsRecorded(t *testing.T) {
+	sessionRepo := &mockSessionRepo{sessions: map[string]*domain.Session{
+		"chat-123":       {ChatID: "chat-123", ThreadID: "thread-new"},
+		"chat-123/omt-1": {ChatID: "chat-123", TopicID: "omt-1", ThreadID: "thread-topic", LastProcessedMsgID: "om_last"},
+	}}
+	sessionUC := usecase.NewSessionUsecase(sessionRepo, &mockCodexRepo{}, domain.SessionConfig{ResetHour: -1})
+	svc := &ConversationService{
+		chatStates: make(map[domain.SessionKey]*ChatState),
+		convUC:     usecase.NewConversationUsecase(sessionUC, nil, &mockCodexRepo{}, usecase.PromptConfig{}),
+	}
+
+	// The thread was just created, the turn has not reached the chat state yet
+	if key, _ := svc.SessionForThread("thread-new"); key != (domain.SessionKey{ChatID: "chat-123"}) {
+		t.Errorf("Expected the stored session's chat, got %v", key)
+	}
+	if key, _ := svc.SessionForThread("thread-cron"); key.ChatID != "" {
+		t.Errorf("Expected no chat for an unknown thread, got %v", key)
+	}
+
+	// A topic session without chat state replies to the last message it processed
+	key, to := svc.SessionForThread("thread-topic")
+	if key.TopicID != "omt-1" || to == nil || to.MsgID != "om_last" || !to.InTopic {
+		t.Errorf("Expected a reply in the stored topic, got %v %+v", key, to)
 	}
 }
 
 func TestHandleCodexEvent_AgentDelta(t *testing.T) {
 	svc := &ConversationService{
-		chatStates: make(map[string]*ChatState),
+		chatStates: make(map[domain.SessionKey]*ChatState),
 	}
 
 	// Setup a chat state
-	svc.chatStates["chat-123"] = &ChatState{
+	svc.chatStates[domain.SessionKey{ChatID: "chat-123"}] = &ChatState{
 		ThreadID: "thread-abc",
 	}
 
@@ -266,7 +471,7 @@ func TestHandleCodexEvent_AgentDelta(t *testing.T) {
 	svc.HandleCodexEvent(event2)
 
 	// Check buffer
-	state := svc.chatStates["chat-123"]
+	state := svc.chatStates[domain.SessionKey{ChatID: "chat-123"}]
 	if state.Buffer.String() != "Hello World" {
 		t.Errorf("Expected 'Hello World', got '%s'", state.Buffer.String())
 	}
@@ -275,7 +480,7 @@ func TestHandleCodexEvent_AgentDelta(t *testing.T) {
 func TestHandleCodexEvent_TurnComplete_SendsReply(t *testing.T) {
 	msgRepo := &mockMessageRepo{}
 	sessionRepo := &mockSessionRepo{sessions: make(map[string]*domain.Session)}
-	codexRepo := &mockCodexRepo{events: make(chan repo.Event, 10)}
+	codexRepo := &mockCodexRepo{}
 
 	// Create properly initialized usecase
 	sessionCfg := domain.SessionConfig{IdleTimeout: 60 * time.Minute, ResetHour: 4}
@@ -285,14 +490,14 @@ func TestHandleCodexEvent_TurnComplete_SendsReply(t *testing.T) {
 	convUC := usecase.NewConversationUsecase(sessionUC, contextUC, codexRepo, promptCfg)
 
 	svc := &ConversationService{
-		chatStates:  make(map[string]*ChatState),
+		chatStates:  make(map[domain.SessionKey]*ChatState),
 		messageRepo: msgRepo,
 		convUC:      convUC,
 	}
 
 	var replyCalled bool
 	var replyText string
-	svc.SetReplyCallback(func(chatID, msgID, text string, mentions []domain.Member) {
+	svc.SetReplyCallback(func(chatID string, to *repo.ReplyTo, text string, mentions []domain.Member) {
 		replyCalled = true
 		replyText = text
 	})
@@ -303,7 +508,7 @@ func TestHandleCodexEvent_TurnComplete_SendsReply(t *testing.T) {
 		MsgID:    "msg-123",
 	}
 	state.Buffer.WriteString("Test response")
-	svc.chatStates["chat-123"] = state
+	svc.chatStates[domain.SessionKey{ChatID: "chat-123"}] = state
 
 	// Send turn complete event
 	event := repo.Event{
@@ -325,16 +530,16 @@ func TestHandleCodexEvent_TurnComplete_SendsReply(t *testing.T) {
 
 func TestHandleCodexEvent_TurnComplete_EmptyBuffer(t *testing.T) {
 	svc := &ConversationService{
-		chatStates: make(map[string]*ChatState),
+		chatStates: make(map[domain.SessionKey]*ChatState),
 	}
 
 	var replyCalled bool
-	svc.SetReplyCallback(func(chatID, msgID, text string, mentions []domain.Member) {
+	svc.SetReplyCallback(func(chatID string, to *repo.ReplyTo, text string, mentions []domain.Member) {
 		replyCalled = true
 	})
 
 	// Setup a chat state with EMPTY buffer
-	svc.chatStates["chat-123"] = &ChatState{
+	svc.chatStates[domain.SessionKey{ChatID: "chat-123"}] = &ChatState{
 		ThreadID: "thread-abc",
 		MsgID:    "msg-123",
 	}
@@ -355,7 +560,7 @@ func TestHandleCodexEvent_TurnComplete_EmptyBuffer(t *testing.T) {
 
 func TestHandleCodexEvent_UnknownThread(t *testing.T) {
 	svc := &ConversationService{
-		chatStates: make(map[string]*ChatState),
+		chatStates: make(map[domain.SessionKey]*ChatState),
 	}
 
 	// Send event for unknown thread - should not panic
@@ -372,18 +577,610 @@ func TestHandleCodexEvent_UnknownThread(t *testing.T) {
 
 func TestGetChatState_CreatesNew(t *testing.T) {
 	svc := &ConversationService{
-		chatStates: make(map[string]*ChatState),
+		chatStates: make(map[domain.SessionKey]*ChatState),
 	}
 
-	state := svc.getChatState("new-chat")
+	state := svc.getChatState(domain.SessionKey{ChatID: "new-chat"})
 
 	if state == nil {
 		t.Fatal("Expected non-nil state")
 	}
 
 	// Getting again should return same instance
-	state2 := svc.getChatState("new-chat")
+	state2 := svc.getChatState(domain.SessionKey{ChatID: "new-chat"})
 	if state != state2 {
 		t.Error("Expected same state instance")
 	}
 }
+
+func TestHandleCodexRestart_RetriesInFlightTurn(t *testing.T) {
+	msgRepo := &mockMessageRepo{}
+	sessionRepo := &mockSessionRepo{sessions: make(map[string]*domain.Session)}
+	codexRepo := &mockCodexRepo{threadID: "thread-new", turnID: "turn-new"}
+
+	sessionCfg := domain.SessionConfig{IdleTimeout: 60 * time.Minute, ResetHour: -1}
+	sessionUC := usecase.NewSessionUsecase(sessionRepo, codexRepo, sessionCfg)
+	contextUC := usecase.NewContextBuilderUsecase(msgRepo)
+	convUC := usecase.NewConversationUsecase(sessionUC, contextUC, codexRepo, usecase.PromptConfig{})
+
+	svc := &ConversationService{
+		chatStates:  make(map[domain.SessionKey]*ChatState),
+		messageRepo: msgRepo,
+		convUC:      convUC,
+	}
+
+	req := &MessageRequest{ChatID: "chat-1", MsgID: "msg-1", Content: "hello", ChatType: domain.ChatTypeP2P}
+	state := &ChatState{ThreadID: "thread-old", MsgID: "msg-1", InFlight: true, Request: req}
+	state.Buffer.WriteString("partial")
+	svc.chatStates[domain.SessionKey{ChatID: "chat-1"}] = state
+
+	svc.handleCodexRestart()
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		state.mu.Lock()
+		done := !state.Processing && state.TurnID == "turn-new"
+		state.mu.Unlock()
+		if done {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	if state.TurnID != "turn-new" {
+		t.Errorf("Expected retried turn 'turn-new', got '%s'", state.TurnID)
+	}
+	if !state.Retried || !state.InFlight {
+		t.Errorf("Expected retried in-flight state, got retried=%v inFlight=%v", state.Retried, state.InFlight)
+	}
+	if state.Buffer.Len() != 0 {
+		t.Error("Expected partial buffer to be reset")
+	}
+}
+
+func TestHandleCodexRestart_GivesUpAfterOneRetry(t *testing.T) {
+	msgRepo := &mockMessageRepo{}
+	sessionRepo := &mockSessionRepo{sessions: make(map[string]*domain.Session)}
+	codexRepo := &mockCodexRepo{}
+
+	sessionUC := usecase.NewSessionUsecase(sessionRepo, codexRepo, domain.SessionConfig{})
+	convUC := usecase.NewConversationUsecase(sessionUC, usecase.NewContextBuilderUsecase(msgRepo), codexRepo, usecase.PromptConfig{})
+
+	svc := &ConversationService{
+		chatStates:  make(map[domain.SessionKey]*ChatState),
+		messageRepo: msgRepo,
+		convUC:      convUC,
+	}
+
+	req := &MessageRequest{ChatID: "chat-1", MsgID: "msg-1"}
+	svc.chatStates[domain.SessionKey{ChatID: "chat-1"}] = &ChatState{InFlight: true, Request: req, Retried: true}
+
+	svc.handleCodexRestart()
+
+	if len(msgRepo.sentText) != 1 {
+		t.Fatalf("Expected 1 notice, got %d", len(msgRepo.sentText))
+	}
+	if svc.chatStates[domain.SessionKey{ChatID: "chat-1"}].InFlight {
+		t.Error("Expected in-flight flag to be cleared")
+	}
+}
+
+// waitForSub waits until the service subscribed to a turn
+func waitForSub(t *testing.T, codexRepo *mockCodexRepo) *mockSubscription {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if sub := codexRepo.lastSub(); sub != nil {
+			return sub
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatal("Expected a turn subscription")
+	return nil
+}
+
+func TestProcessMessage_ConsumesOwnTurnEvents(t *testing.T) {
+	msgRepo := &mockMessageRepo{}
+	sessionRepo := &mockSessionRepo{sessions: make(map[string]*domain.Session)}
+	codexRepo := &mockCodexRepo{threadID: "thread-1", turnID: "turn-1"}
+
+	sessionUC := usecase.NewSessionUsecase(sessionRepo, codexRepo, domain.SessionConfig{IdleTimeout: time.Hour, ResetHour: -1})
+	convUC := usecase.NewConversationUsecase(sessionUC, usecase.NewContextBuilderUsecase(msgRepo), codexRepo, usecase.PromptConfig{})
+	svc := NewConversationService(convUC, nil, msgRepo, codexRepo)
+
+	replies := make(chan string, 1)
+	svc.SetReplyCallback(func(chatID string, to *repo.ReplyTo, text string, mentions []domain.Member) {
+		replies <- text
+	})
+
+	req := &MessageRequest{ChatID: "chat-1", MsgID: "msg-1", Content: "hi", ChatType: domain.ChatTypeP2P}
+	if err := svc.HandleMessage(context.Background(), req); err != nil {
+		t.Fatalf("HandleMessage failed: %v", err)
+	}
+
+	sub := waitForSub(t, codexRepo)
+	sub.ch <- repo.Event{Type: repo.EventTypeAgentDelta, ThreadID: "thread-1", TurnID: "turn-1", Data: &repo.AgentDeltaData{Delta: "Hello"}}
+	sub.ch <- repo.Event{Type: repo.EventTypeTurnComplete, ThreadID: "thread-1", TurnID: "turn-1"}
+
+	select {
+	case text := <-replies:
+		if text != "Hello" {
+			t.Errorf("Expected 'Hello', got %q", text)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected a reply")
+	}
+}
+
+type mockQueueRepo struct {
+	msgs   []*domain.QueuedMessage
+	nextID int64
+	mu     sync.Mutex
+}
+
+func (m *mockQueueRepo) Enqueue(ctx context.Context, msg *domain.QueuedMessage) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nextID++
+	msg.ID = m.nextID
+	m.msgs = append(m.msgs, msg)
+	return nil
+}
+
+func (m *mockQueueRepo) ListPending(ctx context.Context, key domain.SessionKey, limit int) ([]*domain.QueuedMessage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var result []*domain.QueuedMessage
+	for _, msg := range m.msgs {
+		if msg.Key() == key && len(result) < limit {
+			result = append(result, msg)
+		}
+	}
+	return result, nil
+}
+
+func (m *mockQueueRepo) Count(ctx context.Context, key domain.SessionKey) (int, error) {
+	msgs, _ := m.ListPending(ctx, key, len(m.msgs)+1)
+	return len(msgs), nil
+}
+
+func (m *mockQueueRepo) Remove(ctx context.Context, ids []int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var kept []*domain.QueuedMessage
+	for _, msg := range m.msgs {
+		removed := false
+		for _, id := range ids {
+			removed = removed || msg.ID == id
+		}
+		if !removed {
+			kept = append(kept, msg)
+		}
+	}
+	m.msgs = kept
+	return nil
+}
+
+func (m *mockQueueRepo) RemoveChat(ctx context.Context, chatID string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var kept []*domain.QueuedMessage
+	for _, msg := range m.msgs {
+		if msg.ChatID != chatID {
+			kept = append(kept, msg)
+		}
+	}
+	removed := int64(len(m.msgs) - len(kept))
+	m.msgs = kept
+	return removed, nil
+}
+
+func (m *mockQueueRepo) RemoveMessage(ctx context.Context, msgID string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, msg := range m.msgs {
+		if msg.MsgID == msgID {
+			m.msgs = append(m.msgs[:i], m.msgs[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *mockQueueRepo) UpdateMessage(ctx context.Context, msgID, content string, imagePaths []string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, msg := range m.msgs {
+		if msg.MsgID == msgID {
+			msg.Content = content
+			msg.ImagePaths = imagePaths
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *mockQueueRepo) ListSessions(ctx context.Context) ([]domain.SessionKey, error) {
+	return nil, nil
+}
+
+func (m *mockQueueRepo) Close() error {
+	return nil
+}
+
+func TestHandleMessage_QueuesWhileTurnInFlight(t *testing.T) {
+	msgRepo := &mockMessageRepo{}
+	sessionRepo := &mockSessionRepo{sessions: make(map[string]*domain.Session)}
+	codexRepo := &mockCodexRepo{threadID: "thread-1", turnID: "turn-2"}
+	queueRepo := &mockQueueRepo{}
+
+	sessionUC := usecase.NewSessionUsecase(sessionRepo, codexRepo, domain.SessionConfig{IdleTimeout: time.Hour, ResetHour: -1})
+	convUC := usecase.NewConversationUsecase(sessionUC, usecase.NewContextBuilderUsecase(msgRepo), codexRepo, usecase.PromptConfig{})
+	svc := NewConversationService(convUC, nil, msgRepo, codexRepo)
+	svc.SetQueueUsecase(usecase.NewQueueUsecase(queueRepo, usecase.DefaultQueueConfig()))
+
+	// A turn is already running in this chat
+	state := &ChatState{ThreadID: "thread-1", TurnID: "turn-1", MsgID: "msg-1", InFlight: true}
+	state.Buffer.WriteString("first answer")
+	svc.chatStates[domain.SessionKey{ChatID: "chat-1"}] = state
+
+	req := &MessageRequest{ChatID: "chat-1", MsgID: "msg-2", Content: "follow-up", ChatType: domain.ChatTypeP2P}
+	if err := svc.HandleMessage(context.Background(), req); err != nil {
+		t.Fatalf("Expected message to be queued, got %v", err)
+	}
+	if count, _ := queueRepo.Count(context.Background(), domain.SessionKey{ChatID: "chat-1"}); count != 1 {
+		t.Fatalf("Expected 1 queued message, got %d", count)
+	}
+	if len(msgRepo.sentText) != 1 || !strings.Contains(msgRepo.sentText[0], "position 1") {
+		t.Errorf("Expected queue position notice, got %v", msgRepo.sentText)
+	}
+
+	// Finishing the current turn starts the queued message
+	svc.HandleCodexEvent(repo.Event{Type: repo.EventTypeTurnComplete, ThreadID: "thread-1", TurnID: "turn-1"})
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		state.mu.Lock()
+		started := state.TurnID == "turn-2"
+		state.mu.Unlock()
+		if started {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	if state.TurnID != "turn-2" || state.MsgID != "msg-2" {
+		t.Errorf("Expected queued message to start turn-2, got turn=%s msg=%s", state.TurnID, state.MsgID)
+	}
+	if count, _ := queueRepo.Count(context.Background(), domain.SessionKey{ChatID: "chat-1"}); count != 0 {
+		t.Errorf("Expected queue to be drained, got %d", count)
+	}
+}
+
+func TestHandleMessage_TopicsRunConcurrently(t *testing.T) {
+	msgRepo := &mockMessageRepo{}
+	sessionRepo := &mockSessionRepo{sessions: make(map[string]*domain.Session)}
+	codexRepo := &mockCodexRepo{threadID: "thread-2", turnID: "turn-2"}
+	queueRepo := &mockQueueRepo{}
+
+	sessionUC := usecase.NewSessionUsecase(sessionRepo, codexRepo, domain.SessionConfig{IdleTimeout: time.Hour, ResetHour: -1})
+	convUC := usecase.NewConversationUsecase(sessionUC, usecase.NewContextBuilderUsecase(msgRepo), codexRepo, usecase.PromptConfig{})
+	svc := NewConversationService(convUC, nil, msgRepo, codexRepo)
+	svc.SetQueueUsecase(usecase.NewQueueUsecase(queueRepo, usecase.DefaultQueueConfig()))
+
+	// A turn is running in the chat itself
+	svc.chatStates[domain.SessionKey{ChatID: "chat-1"}] = &ChatState{ThreadID: "thread-1", MsgID: "msg-1", InFlight: true}
+
+	// A message in one of its topics starts right away in its own session
+	req := &MessageRequest{ChatID: "chat-1", MsgID: "msg-2", Content: "topic question", ChatType: domain.ChatTypeP2P, TopicID: "omt_1"}
+	if err := svc.HandleMessage(context.Background(), req); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	topic := svc.getChatState(req.Key())
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		topic.mu.Lock()
+		started := topic.InFlight
+		topic.mu.Unlock()
+		if started {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	topic.mu.Lock()
+	defer topic.mu.Unlock()
+	if topic.TurnID != "turn-2" || topic.ThreadID != "thread-2" {
+		t.Errorf("Expected the topic to start turn-2 in thread-2, got turn=%s thread=%s", topic.TurnID, topic.ThreadID)
+	}
+	if topic.ReplyTo == nil || !topic.ReplyTo.InTopic {
+		t.Errorf("Expected the reply to go into the topic, got %+v", topic.ReplyTo)
+	}
+	if len(queueRepo.msgs) != 0 {
+		t.Errorf("Expected nothing queued, got %d", len(queueRepo.msgs))
+	}
+	sessionRepo.mu.Lock()
+	defer sessionRepo.mu.Unlock()
+	if session := sessionRepo.sessions["chat-1/omt_1"]; session == nil || session.TopicID != "omt_1" {
+		t.Errorf("Expected a session for the topic, got %+v", session)
+	}
+}
+
+type mockSettingsRepo struct {
+	settings map[string]*domain.ChatSettings
+}
+
+func (m *mockSettingsRepo) Get(ctx context.Context, chatID string) (*domain.ChatSettings, error) {
+	return m.settings[chatID], nil
+}
+
+func (m *mockSettingsRepo) Save(ctx context.Context, settings *domain.ChatSettings) error {
+	m.settings[settings.ChatID] = settings
+	return nil
+}
+
+func (m *mockSettingsRepo) Close() error {
+	return nil
+}
+
+func TestReplyTarget_FollowsChatSettings(t *testing.T) {
+	settingsRepo := &mockSettingsRepo{settings: map[string]*domain.ChatSettings{
+		"chat-plain": {ChatID: "chat-plain", ReplyMode: domain.ReplyModePlain},
+	}}
+	svc := &ConversationService{
+		chatStates:  make(map[domain.SessionKey]*ChatState),
+		messageRepo: &mockMessageRepo{},
+	}
+	svc.SetStreaming(usecase.NewSettingsUsecase(settingsRepo, domain.ChatSettings{ReplyMode: domain.ReplyModeQuote}), DefaultStreamConfig())
+
+	ctx := context.Background()
+	to := svc.replyTarget(ctx, &MessageRequest{ChatID: "chat-1", MsgID: "msg-1", TopicID: "omt_1"})
+	if to == nil || to.MsgID != "msg-1" || !to.InTopic {
+		t.Errorf("Expected a quote reply inside the topic, got %+v", to)
+	}
+
+	if to := svc.replyTarget(ctx, &MessageRequest{ChatID: "chat-plain", MsgID: "msg-2"}); to != nil {
+		t.Errorf("Expected a plain message for chat-plain, got %+v", to)
+	}
+	// Topic replies stay in the topic even in plain mode
+	to = svc.replyTarget(ctx, &MessageRequest{ChatID: "chat-plain", MsgID: "msg-3", TopicID: "omt_1"})
+	if to == nil || !to.InTopic {
+		t.Errorf("Expected a reply inside the topic for chat-plain, got %+v", to)
+	}
+}
+
+// failingSessionRepo fails every lookup, so triggers error out
+type failingSessionRepo struct {
+	mockSessionRepo
+}
+
+func (m *failingSessionRepo) Get(ctx context.Context, key domain.SessionKey) (*domain.Session, error) {
+	return nil, fmt.Errorf("database locked")
+}
+
+func TestProcessMessage_ErrorRepliesToMessage(t *testing.T) {
+	msgRepo := &mockMessageRepo{}
+	svc := &ConversationService{
+		chatStates:  make(map[domain.SessionKey]*ChatState),
+		messageRepo: msgRepo,
+		convUC:      usecase.NewConversationUsecase(usecase.NewSessionUsecase(&failingSessionRepo{}, &mockCodexRepo{}, domain.SessionConfig{}), nil, &mockCodexRepo{}, usecase.PromptConfig{}),
+	}
+
+	req := &MessageRequest{ChatID: "chat-1", MsgID: "msg-1", Content: "hi"}
+	svc.processMessage(context.Background(), req, svc.getChatState(domain.SessionKey{ChatID: "chat-1"}))
+
+	msgRepo.mu.Lock()
+	defer msgRepo.mu.Unlock()
+	if len(msgRepo.replies) != 1 || msgRepo.replies[0].MsgID != "msg-1" {
+		t.Fatalf("Expected the error to reply to msg-1, got %+v", msgRepo.replies)
+	}
+	if !strings.HasPrefix(msgRepo.sentText[0], "Error processing:") {
+		t.Errorf("Unexpected error notice %q", msgRepo.sentText[0])
+	}
+}
+
+func TestHandleRecall_CancelsRunningAndQueuedMessages(t *testing.T) {
+	msgRepo := &mockMessageRepo{}
+	codexRepo := &mockCodexRepo{}
+	queueRepo := &mockQueueRepo{}
+	svc := NewConversationService(nil, nil, msgRepo, codexRepo)
+	svc.SetQueueUsecase(usecase.NewQueueUsecase(queueRepo, usecase.DefaultQueueConfig()))
+
+	state := &ChatState{ThreadID: "thread-1", MsgID: "msg-1", InFlight: true}
+	state.Buffer.WriteString("half an answ")
+	svc.chatStates[domain.SessionKey{ChatID: "chat-1"}] = state
+	_ = queueRepo.Enqueue(context.Background(), &domain.QueuedMessage{ChatID: "chat-1", MsgID: "msg-2"})
+
+	ctx := context.Background()
+	if cancelled, err := svc.HandleRecall(ctx, "chat-1", "msg-2"); err != nil || !cancelled {
+		t.Fatalf("Expected the queued message to be dropped, got %v %v", cancelled, err)
+	}
+	if count, _ := queueRepo.Count(ctx, domain.SessionKey{ChatID: "chat-1"}); count != 0 {
+		t.Errorf("Expected an empty queue, got %d", count)
+	}
+	if len(codexRepo.interrupted) != 0 {
+		t.Error("Recalling a queued message must not interrupt the running turn")
+	}
+
+	if cancelled, _ := svc.HandleRecall(ctx, "chat-1", "msg-1"); !cancelled {
+		t.Fatal("Expected the running message to be cancelled")
+	}
+	if len(codexRepo.interrupted) != 1 || codexRepo.interrupted[0] != "thread-1" {
+		t.Errorf("Expected thread-1 to be interrupted, got %v", codexRepo.interrupted)
+	}
+
+	var replies []string
+	svc.SetReplyCallback(func(chatID string, to *repo.ReplyTo, text string, mentions []domain.Member) {
+		replies = append(replies, text)
+	})
+	svc.HandleCodexEvent(repo.Event{Type: repo.EventTypeTurnComplete, ThreadID: "thread-1"})
+	if len(replies) != 0 {
+		t.Errorf("Expected no reply to a recalled message, got %q", replies)
+	}
+
+	if cancelled, _ := svc.HandleRecall(ctx, "chat-1", "msg-1"); cancelled {
+		t.Error("An answered message has nothing to cancel")
+	}
+}
+
+func TestHandleEdit_RerunsRunningMessage(t *testing.T) {
+	msgRepo := &mockMessageRepo{}
+	sessionRepo := &mockSessionRepo{sessions: make(map[string]*domain.Session)}
+	codexRepo := &mockCodexRepo{threadID: "thread-1", turnID: "turn-2"}
+	queueRepo := &mockQueueRepo{}
+
+	sessionUC := usecase.NewSessionUsecase(sessionRepo, codexRepo, domain.SessionConfig{IdleTimeout: time.Hour, ResetHour: -1})
+	convUC := usecase.NewConversationUsecase(sessionUC, usecase.NewContextBuilderUsecase(msgRepo), codexRepo, usecase.PromptConfig{})
+	svc := NewConversationService(convUC, nil, msgRepo, codexRepo)
+	svc.SetQueueUsecase(usecase.NewQueueUsecase(queueRepo, usecase.DefaultQueueConfig()))
+
+	state := &ChatState{ThreadID: "thread-1", TurnID: "turn-1", MsgID: "msg-1", InFlight: true}
+	svc.chatStates[domain.SessionKey{ChatID: "chat-1"}] = state
+	_ = queueRepo.Enqueue(context.Background(), &domain.QueuedMessage{ChatID: "chat-1", MsgID: "msg-2", Content: "old follow-up"})
+
+	ctx := context.Background()
+	edited := &MessageRequest{ChatID: "chat-1", MsgID: "msg-2", Content: "new follow-up", ChatType: domain.ChatTypeP2P}
+	if updated, err := svc.HandleEdit(ctx, edited); err != nil || !updated {
+		t.Fatalf("Expected the queued message to be updated, got %v %v", updated, err)
+	}
+	if pending, _ := queueRepo.ListPending(ctx, domain.SessionKey{ChatID: "chat-1"}, 10); pending[0].Content != "new follow-up" {
+		t.Errorf("Expected the queued content to change, got %q", pending[0].Content)
+	}
+
+	edited = &MessageRequest{ChatID: "chat-1", MsgID: "msg-1", Content: "new question", ChatType: domain.ChatTypeP2P}
+	if rerun, _ := svc.HandleEdit(ctx, edited); !rerun {
+		t.Fatal("Expected the running message to be re-run")
+	}
+	if len(codexRepo.interrupted) != 1 {
+		t.Errorf("Expected the stale turn to be interrupted, got %v", codexRepo.interrupted)
+	}
+
+	// The stopped turn ends, the edited message runs before the queue
+	svc.HandleCodexEvent(repo.Event{Type: repo.EventTypeTurnComplete, ThreadID: "thread-1", TurnID: "turn-1"})
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		state.mu.Lock()
+		started := state.TurnID == "turn-2"
+		state.mu.Unlock()
+		if started {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	if state.TurnID != "turn-2" || state.Request == nil || state.Request.Content != "new question" {
+		t.Errorf("Expected the new version to run as turn-2, got turn=%s req=%+v", state.TurnID, state.Request)
+	}
+	if count, _ := queueRepo.Count(ctx, domain.SessionKey{ChatID: "chat-1"}); count != 1 {
+		t.Errorf("Expected the follow-up to stay queued, got %d", count)
+	}
+}
+
+type mockJournalRepo struct {
+	mu     sync.Mutex
+	states map[string]domain.JournalState
+}
+
+func (m *mockJournalRepo) Receive(ctx context.Context, entry *domain.JournalEntry) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.states[entry.MsgID]; ok {
+		return false, nil
+	}
+	m.states[entry.MsgID] = domain.JournalReceived
+	return true, nil
+}
+
+func (m *mockJournalRepo) Get(ctx context.Context, msgID string) (*domain.JournalEntry, error) {
+	return nil, nil
+}
+
+func (m *mockJournalRepo) SetState(ctx context.Context, msgIDs []string, state domain.JournalState, errMsg string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, id := range msgIDs {
+		m.states[id] = state
+	}
+	return nil
+}
+
+func (m *mockJournalRepo) ListUnfinished(ctx context.Context) ([]*domain.JournalEntry, error) {
+	return nil, nil
+}
+
+func (m *mockJournalRepo) ClaimRecovery(ctx context.Context, msgID string) (bool, error) {
+	return false, nil
+}
+
+func (m *mockJournalRepo) Prune(ctx context.Context, before time.Time) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockJournalRepo) Close() error {
+	return nil
+}
+
+func (m *mockJournalRepo) state(msgID string) domain.JournalState {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.states[msgID]
+}
+
+func TestHandleMessage_JournalsStates(t *testing.T) {
+	msgRepo := &mockMessageRepo{}
+	sessionRepo := &mockSessionRepo{sessions: make(map[string]*domain.Session)}
+	codexRepo := &mockCodexRepo{threadID: "thread-1", turnID: "turn-2"}
+	queueRepo := &mockQueueRepo{}
+	journalRepo := &mockJournalRepo{states: make(map[string]domain.JournalState)}
+
+	sessionUC := usecase.NewSessionUsecase(sessionRepo, codexRepo, domain.SessionConfig{IdleTimeout: time.Hour, ResetHour: -1})
+	convUC := usecase.NewConversationUsecase(sessionUC, usecase.NewContextBuilderUsecase(msgRepo), codexRepo, usecase.PromptConfig{})
+	svc := NewConversationService(convUC, nil, msgRepo, codexRepo)
+	svc.SetQueueUsecase(usecase.NewQueueUsecase(queueRepo, usecase.QueueConfig{Coalesce: true, MaxCoalesce: 5}))
+	journalUC := usecase.NewJournalUsecase(journalRepo, sessionRepo, msgRepo, usecase.DefaultJournalConfig())
+	svc.SetJournal(journalUC)
+
+	ctx := context.Background()
+	key := domain.SessionKey{ChatID: "chat-1"}
+	for _, id := range []string{"msg-1", "msg-2", "msg-3"} {
+		_, _ = journalUC.Receive(ctx, key, id, time.Now())
+	}
+	journalUC.Mark(ctx, domain.JournalProcessing, "msg-1")
+
+	// msg-1 is running, msg-2 and msg-3 queue behind it
+	state := &ChatState{ThreadID: "thread-1", TurnID: "turn-1", MsgID: "msg-1", InFlight: true,
+		Request: &MessageRequest{ChatID: "chat-1", MsgID: "msg-1"}}
+	state.Buffer.WriteString("first answer")
+	svc.chatStates[key] = state
+	for _, id := range []string{"msg-2", "msg-3"} {
+		req := &MessageRequest{ChatID: "chat-1", MsgID: id, Content: id, ChatType: domain.ChatTypeP2P}
+		if err := svc.HandleMessage(ctx, req); err != nil {
+			t.Fatal(err)
+		}
+		if got := journalRepo.state(id); got != domain.JournalQueued {
+			t.Fatalf("Expected %s to be queued, got %s", id, got)
+		}
+	}
+
+	// Completing msg-1 runs the coalesced queue, both messages are processing
+	svc.HandleCodexEvent(repo.Event{Type: repo.EventTypeTurnComplete, ThreadID: "thread-1", TurnID: "turn-1"})
+	if got := journalRepo.state("msg-1"); got != domain.JournalReplied {
+		t.Errorf("Expected msg-1 to be replied, got %s", got)
+	}
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) && journalRepo.state("msg-2") != domain.JournalProcessing {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if journalRepo.state("msg-2") != domain.JournalProcessing || journalRepo.state("msg-3") != domain.JournalProcessing {
+		t.Errorf("Expected the coalesced messages to be processing, got %s and %s",
+			journalRepo.state("msg-2"), journalRepo.state("msg-3"))
+	}
+}
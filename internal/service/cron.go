@@ -19,9 +19,14 @@ type CronRunner struct {
 	codexRepo   repo.CodexRepo
 
 	pollInterval time.Duration
+	turnTimeout  time.Duration
 	running      bool
 	stopCh       chan struct{}
 	wg           sync.WaitGroup
+
+	// Tasks being run, so a "Run now" click cannot overlap a scheduled run
+	activeMu sync.Mutex
+	active   map[int64]bool
 }
 
 // NewCronRunner creates a new cron runner
@@ -31,7 +36,9 @@ func NewCronRunner(memoryUC *usecase.MemoryUsecase, messageRepo repo.MessageRepo
 		messageRepo:  messageRepo,
 		codexRepo:    codexRepo,
 		pollInterval: 60 * time.Second, // Check every 60 seconds
+		turnTimeout:  10 * time.Minute, // Give up on a task turn after 10 minutes
 		stopCh:       make(chan struct{}),
+		active:       make(map[int64]bool),
 	}
 }
 
@@ -95,12 +102,18 @@ func (r *CronRunner) runDueTasks() {
 
 // runTask runs a single scheduled task
 func (r *CronRunner) runTask(ctx context.Context, task *domain.ScheduledTask) {
+	if !r.claim(task.ID) {
+		fmt.Printf("[CronRunner] Task %s is already running\n", task.Name)
+		return
+	}
+	defer r.release(task.ID)
+
 	fmt.Printf("[CronRunner] Running task: %s\n", task.Name)
 
 	startTime := time.Now()
 
 	// Create a new thread for this task
-	threadID, err := r.codexRepo.CreateThread(ctx)
+	threadID, err := r.codexRepo.CreateThread(ctx, "")
 	if err != nil {
 		r.memoryUC.UpdateTaskAfterRun(ctx, task, "error", "failed to create thread: "+err.Error())
 		fmt.Printf("[CronRunner] Error creating thread for task %s: %v\n", task.Name, err)
@@ -118,37 +131,16 @@ Chat ID: %s
 `, task.Name, task.Prompt, task.ChatID)
 
 	// Start turn
-	_, err = r.codexRepo.StartTurn(ctx, threadID, prompt, nil)
+	response, err := r.runTurn(ctx, threadID, prompt)
 	if err != nil {
-		r.memoryUC.UpdateTaskAfterRun(ctx, task, "error", "failed to start turn: "+err.Error())
-		fmt.Printf("[CronRunner] Error starting turn for task %s: %v\n", task.Name, err)
+		r.memoryUC.UpdateTaskAfterRun(ctx, task, "error", err.Error())
+		fmt.Printf("[CronRunner] Error in task %s: %v\n", task.Name, err)
 		return
 	}
 
-	// Collect response
-	response := ""
-	for event := range r.codexRepo.Events() {
-		switch event.Type {
-		case repo.EventTypeAgentDelta:
-			if data, ok := event.Data.(*repo.AgentDeltaData); ok {
-				response += data.Delta
-			}
-		case repo.EventTypeTurnComplete:
-			// Turn is complete
-		case repo.EventTypeError:
-			errMsg := "unknown error"
-			if data, ok := event.Data.(*repo.ErrorData); ok && data.Error != nil {
-				errMsg = data.Error.Error()
-			}
-			r.memoryUC.UpdateTaskAfterRun(ctx, task, "error", errMsg)
-			fmt.Printf("[CronRunner] Error in task %s: %s\n", task.Name, errMsg)
-			return
-		}
-	}
-
 	// Send response to chat if we have one
 	if response != "" && task.ChatID != "" {
-		err = r.messageRepo.SendText(ctx, task.ChatID, response)
+		err = r.messageRepo.SendMarkdown(ctx, task.ChatID, response, nil)
 		if err != nil {
 			fmt.Printf("[CronRunner] Error sending task result to chat %s: %v\n", task.ChatID, err)
 		}
@@ -159,6 +151,142 @@ Chat ID: %s
 	fmt.Printf("[CronRunner] Task %s completed in %v\n", task.Name, duration)
 }
 
+// claim marks a task as running, returning false if it already is
+func (r *CronRunner) claim(taskID int64) bool {
+	r.activeMu.Lock()
+	defer r.activeMu.Unlock()
+	if r.active[taskID] {
+		return false
+	}
+	r.active[taskID] = true
+	return true
+}
+
+func (r *CronRunner) release(taskID int64) {
+	r.activeMu.Lock()
+	defer r.activeMu.Unlock()
+	delete(r.active, taskID)
+}
+
+// RegisterCommands adds /tasks, which posts the chat's scheduled tasks as a card
+func (r *CronRunner) RegisterCommands(router *CommandRouter) {
+	router.Register(&Command{
+		Name:        "tasks",
+		Description: "Show this chat's scheduled tasks with run and pause buttons",
+		Permission:  PermissionAnyone,
+		Handler: func(ctx context.Context, req *CommandRequest) (string, error) {
+			tasks, err := r.chatTasks(ctx, req.ChatID)
+			if err != nil {
+				return "", err
+			}
+			if len(tasks) == 0 {
+				return "No scheduled tasks in this chat.", nil
+			}
+			return "", router.ReplyCard(ctx, req, buildTaskCard(tasks, ""))
+		},
+	})
+}
+
+// HandleCardAction runs, pauses or resumes a task from the /tasks card
+// The card is redrawn with the new task states
+func (r *CronRunner) HandleCardAction(ctx context.Context, action *CardAction) (*CardResult, error) {
+	taskID, ok := action.Int64("task_id")
+	if !ok {
+		return nil, fmt.Errorf("missing task_id")
+	}
+	task, err := r.memoryUC.GetTask(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+	if task == nil {
+		return nil, fmt.Errorf("task %d no longer exists", taskID)
+	}
+	// Task cards only control the tasks of the chat they were posted in
+	if task.ChatID != action.ChatID {
+		return nil, fmt.Errorf("task %d belongs to another chat", taskID)
+	}
+
+	var toast string
+	switch op := action.String("op"); op {
+	case "run":
+		// Runs take minutes, answer the click right away
+		go r.runTask(context.Background(), task)
+		toast = "Running " + task.Name
+	case "pause", "resume":
+		if err := r.memoryUC.EnableTask(ctx, taskID, op == "resume"); err != nil {
+			return nil, err
+		}
+		toast = "Paused " + task.Name
+		if op == "resume" {
+			toast = "Resumed " + task.Name
+		}
+	default:
+		return nil, fmt.Errorf("unknown task operation: %s", op)
+	}
+
+	tasks, err := r.chatTasks(ctx, task.ChatID)
+	if err != nil {
+		return &CardResult{Toast: toast}, nil
+	}
+	return &CardResult{Toast: toast, Card: buildTaskCard(tasks, toast)}, nil
+}
+
+// chatTasks lists the scheduled tasks that post to a chat
+func (r *CronRunner) chatTasks(ctx context.Context, chatID string) ([]*domain.ScheduledTask, error) {
+	all, err := r.memoryUC.ListTasks(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	var tasks []*domain.ScheduledTask
+	for _, task := range all {
+		if task.ChatID == chatID {
+			tasks = append(tasks, task)
+		}
+	}
+	return tasks, nil
+}
+
+// buildTaskCard builds the task list card, one row of buttons per task
+func buildTaskCard(tasks []*domain.ScheduledTask, notice string) string {
+	var elements []interface{}
+	for _, task := range tasks {
+		status := "paused"
+		if task.Enabled {
+			status = "next run " + task.NextRun.Format("01-02 15:04")
+		}
+		text := fmt.Sprintf("**%s** (%s %s)\n%s", task.Name, task.ScheduleType, task.ScheduleValue, status)
+		if !task.LastRun.IsZero() {
+			text += fmt.Sprintf(", last run %s: %s", task.LastRun.Format("01-02 15:04"), task.LastStatus)
+		}
+
+		value := map[string]interface{}{"task_id": task.ID}
+		toggle := cardButton("Pause", "default", TaskCardAction, withOp(value, "pause"))
+		if !task.Enabled {
+			toggle = cardButton("Resume", "default", TaskCardAction, withOp(value, "resume"))
+		}
+		elements = append(elements, markdownElement(text), map[string]interface{}{
+			"tag": "action",
+			"actions": []interface{}{
+				cardButton("Run now", "primary", TaskCardAction, withOp(value, "run")),
+				toggle,
+			},
+		})
+	}
+	if notice != "" {
+		elements = append(elements, noteElement(notice))
+	}
+	return buildCard("Scheduled tasks", "blue", elements)
+}
+
+// withOp copies a button value and adds the operation
+func withOp(value map[string]interface{}, op string) map[string]interface{} {
+	v := map[string]interface{}{"op": op}
+	for key, val := range value {
+		v[key] = val
+	}
+	return v
+}
+
 // runDueHeartbeats runs all due heartbeats
 func (r *CronRunner) runDueHeartbeats() {
 	ctx := context.Background()
@@ -194,7 +322,7 @@ func (r *CronRunner) runHeartbeat(ctx context.Context, config *domain.HeartbeatC
 	startTime := time.Now()
 
 	// Create a new thread for this heartbeat
-	threadID, err := r.codexRepo.CreateThread(ctx)
+	threadID, err := r.codexRepo.CreateThread(ctx, "")
 	if err != nil {
 		fmt.Printf("[CronRunner] Error creating thread for heartbeat %s: %v\n", config.ChatID, err)
 		return
@@ -215,33 +343,13 @@ If nothing needs attention, reply with just: HEARTBEAT_OK
 Chat ID: %s`, prompt, config.ChatID)
 	}
 
-	// Start turn with the heartbeat prompt
-	_, err = r.codexRepo.StartTurn(ctx, threadID, prompt, nil)
+	// Start turn with the heartbeat prompt and collect the response
+	response, err := r.runTurn(ctx, threadID, prompt)
 	if err != nil {
-		fmt.Printf("[CronRunner] Error starting turn for heartbeat %s: %v\n", config.ChatID, err)
+		fmt.Printf("[CronRunner] Error in heartbeat %s: %v\n", config.ChatID, err)
 		return
 	}
 
-	// Collect response from Codex
-	response := ""
-	for event := range r.codexRepo.Events() {
-		switch event.Type {
-		case repo.EventTypeAgentDelta:
-			if data, ok := event.Data.(*repo.AgentDeltaData); ok {
-				response += data.Delta
-			}
-		case repo.EventTypeTurnComplete:
-			// Turn is complete
-		case repo.EventTypeError:
-			errMsg := "unknown error"
-			if data, ok := event.Data.(*repo.ErrorData); ok && data.Error != nil {
-				errMsg = data.Error.Error()
-			}
-			fmt.Printf("[CronRunner] Error in heartbeat %s: %s\n", config.ChatID, errMsg)
-			return
-		}
-	}
-
 	// Update last heartbeat time
 	r.memoryUC.UpdateHeartbeatTime(ctx, config.ChatID)
 
@@ -257,7 +365,7 @@ Chat ID: %s`, prompt, config.ChatID)
 
 	// Send the agent's response to chat if there's meaningful content
 	if cleanResponse != "" && config.ChatID != "" {
-		err = r.messageRepo.SendText(ctx, config.ChatID, cleanResponse)
+		err = r.messageRepo.SendMarkdown(ctx, config.ChatID, cleanResponse, nil)
 		if err != nil {
 			fmt.Printf("[CronRunner] Error sending heartbeat response to chat %s: %v\n", config.ChatID, err)
 			return
@@ -268,6 +376,46 @@ Chat ID: %s`, prompt, config.ChatID)
 	fmt.Printf("[CronRunner] Heartbeat for chat %s completed in %v\n", config.ChatID, duration)
 }
 
+// runTurn starts a turn and collects its response from the turn's own event stream
+func (r *CronRunner) runTurn(ctx context.Context, threadID, prompt string) (string, error) {
+	sub := r.codexRepo.SubscribeTurn(threadID, "")
+	defer sub.Close()
+
+	if _, err := r.codexRepo.StartTurn(ctx, threadID, prompt, nil); err != nil {
+		return "", fmt.Errorf("failed to start turn: %w", err)
+	}
+
+	timeout := time.NewTimer(r.turnTimeout)
+	defer timeout.Stop()
+
+	var response strings.Builder
+	for {
+		select {
+		case event, ok := <-sub.Events():
+			if !ok {
+				return "", fmt.Errorf("event stream closed before turn completed")
+			}
+			switch event.Type {
+			case repo.EventTypeAgentDelta:
+				if data, ok := event.Data.(*repo.AgentDeltaData); ok {
+					response.WriteString(data.Delta)
+				}
+			case repo.EventTypeTurnComplete:
+				return response.String(), nil
+			case repo.EventTypeError, repo.EventTypeCodexExited:
+				if data, ok := event.Data.(*repo.ErrorData); ok && data.Error != nil {
+					return "", data.Error
+				}
+				return "", fmt.Errorf("unknown error")
+			}
+		case <-timeout.C:
+			return "", fmt.Errorf("timeout waiting for turn to complete")
+		case <-r.stopCh:
+			return "", fmt.Errorf("cron runner stopped")
+		}
+	}
+}
+
 // isHeartbeatOK checks if the response indicates nothing needs attention
 func isHeartbeatOK(response string) bool {
 	normalized := strings.ToUpper(strings.TrimSpace(response))
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/anthropics/feishu-codex-bridge/internal/biz/domain"
+	"github.com/anthropics/feishu-codex-bridge/internal/biz/repo"
+	"github.com/anthropics/feishu-codex-bridge/internal/biz/usecase"
+)
+
+func TestCronRunner_RunTurnCollectsOwnTurn(t *testing.T) {
+	codexRepo := &mockCodexRepo{turnID: "turn-1"}
+	r := NewCronRunner(nil, &mockMessageRepo{}, codexRepo)
+
+	type result struct {
+		response string
+		err      error
+	}
+	done := make(chan result, 1)
+	go func() {
+		response, err := r.runTurn(context.Background(), "thread-1", "prompt")
+		done <- result{response, err}
+	}()
+
+	sub := waitForSub(t, codexRepo)
+	sub.ch <- repo.Event{Type: repo.EventTypeAgentDelta, ThreadID: "thread-1", Data: &repo.AgentDeltaData{Delta: "HEARTBEAT"}}
+	sub.ch <- repo.Event{Type: repo.EventTypeAgentDelta, ThreadID: "thread-1", Data: &repo.AgentDeltaData{Delta: "_OK"}}
+	sub.ch <- repo.Event{Type: repo.EventTypeTurnComplete, ThreadID: "thread-1"}
+
+	select {
+	case res := <-done:
+		if res.err != nil {
+			t.Fatalf("Unexpected error: %v", res.err)
+		}
+		if res.response != "HEARTBEAT_OK" {
+			t.Errorf("Expected 'HEARTBEAT_OK', got %q", res.response)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("runTurn did not return after turn completed")
+	}
+}
+
+func TestCronRunner_RunTurnTimeout(t *testing.T) {
+	codexRepo := &mockCodexRepo{turnID: "turn-1"}
+	r := NewCronRunner(nil, &mockMessageRepo{}, codexRepo)
+	r.turnTimeout = 20 * time.Millisecond
+
+	if _, err := r.runTurn(context.Background(), "thread-1", "prompt"); err == nil {
+		t.Error("Expected timeout error")
+	}
+}
+
+// mockTaskRepo serves scheduled tasks, other memory operations are not used
+type mockTaskRepo struct {
+	repo.MemoryRepo
+	tasks map[int64]*domain.ScheduledTask
+}
+
+func (m *mockTaskRepo) GetTask(ctx context.Context, id int64) (*domain.ScheduledTask, error) {
+	return m.tasks[id], nil
+}
+
+func (m *mockTaskRepo) ListTasks(ctx context.Context, enabledOnly bool) ([]*domain.ScheduledTask, error) {
+	var tasks []*domain.ScheduledTask
+	for _, task := range m.tasks {
+		tasks = append(tasks, task)
+	}
+	return tasks, nil
+}
+
+func (m *mockTaskRepo) EnableTask(ctx context.Context, id int64, enabled bool) error {
+	m.tasks[id].Enabled = enabled
+	return nil
+}
+
+func TestCronRunner_CardActionStaysInItsChat(t *testing.T) {
+	tasks := &mockTaskRepo{tasks: map[int64]*domain.ScheduledTask{
+		1: {ID: 1, Name: "standup", ChatID: "chat-1", Enabled: true},
+	}}
+	r := NewCronRunner(usecase.NewMemoryUsecase(tasks), &mockMessageRepo{}, &mockCodexRepo{})
+	ctx := context.Background()
+	pause := map[string]interface{}{"task_id": float64(1), "op": "pause"}
+
+	if _, err := r.HandleCardAction(ctx, &CardAction{Value: pause, ChatID: "chat-2"}); err == nil {
+		t.Error("Expected a card from another chat to be rejected")
+	}
+	if !tasks.tasks[1].Enabled {
+		t.Fatal("Expected the task to keep running")
+	}
+
+	result, err := r.HandleCardAction(ctx, &CardAction{Value: pause, ChatID: "chat-1"})
+	if err != nil || result.Toast != "Paused standup" || tasks.tasks[1].Enabled {
+		t.Errorf("Expected the task to be paused, got %+v %v", result, err)
+	}
+}
@@ -0,0 +1,141 @@
+package service
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/anthropics/feishu-codex-bridge/internal/biz/domain"
+)
+
+// directiveRegex matches [NAME] and [NAME:args] with an upper-case name
+var directiveRegex = regexp.MustCompile(`\[([A-Z][A-Z0-9_]*)(?::([^\]]*))?\]`)
+
+// ReplyPlan is a response with its directives interpreted
+type ReplyPlan struct {
+	Text       string
+	Mentions   []domain.Member
+	MentionAll bool
+	Reactions  []string // Emoji types to add to the triggering message
+	Errors     []error  // Directives that could not be applied
+}
+
+// DirectiveHandler applies one directive's arguments to the plan and returns
+// the text that replaces the directive (usually none). Handlers only record
+// intent; side effects happen once the reply is sent
+type DirectiveHandler func(plan *ReplyPlan, args string) (string, error)
+
+// DirectiveSet interprets the [NAME:args] commands Codex writes in replies
+type DirectiveSet struct {
+	mu       sync.RWMutex
+	handlers map[string]DirectiveHandler
+}
+
+// NewDirectiveSet creates a directive set with the built-in directives
+func NewDirectiveSet() *DirectiveSet {
+	d := &DirectiveSet{handlers: make(map[string]DirectiveHandler)}
+	d.Register("REACTION", reactionDirective)
+	d.Register("MENTION", mentionDirective)
+	d.Register("MENTION_ALL", mentionAllDirective)
+	return d
+}
+
+// defaultDirectives is used by services that were not given their own set
+var defaultDirectives = NewDirectiveSet()
+
+// Register adds or replaces a directive
+func (d *DirectiveSet) Register(name string, handler DirectiveHandler) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.handlers[name] = handler
+}
+
+// Names returns the registered directive names
+func (d *DirectiveSet) Names() []string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.namesLocked()
+}
+
+// Interpret strips directives from a response and collects what they ask for
+// Unknown directives with arguments are dropped and reported; unknown ones
+// without arguments are left alone since they are likely plain text like [WIP]
+func (d *DirectiveSet) Interpret(response string) *ReplyPlan {
+	plan := &ReplyPlan{}
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	text := directiveRegex.ReplaceAllStringFunc(response, func(raw string) string {
+		m := directiveRegex.FindStringSubmatch(raw)
+		name, args := m[1], m[2]
+		hasArgs := strings.Contains(raw, ":")
+
+		handler, ok := d.handlers[name]
+		if !ok {
+			if !hasArgs {
+				return raw
+			}
+			plan.Errors = append(plan.Errors, fmt.Errorf("unknown directive %s (known: %s)", raw, strings.Join(d.namesLocked(), ", ")))
+			return ""
+		}
+		replacement, err := handler(plan, args)
+		if err != nil {
+			plan.Errors = append(plan.Errors, fmt.Errorf("directive %s: %w", raw, err))
+			return ""
+		}
+		return replacement
+	})
+
+	// Clean up extra whitespace
+	plan.Text = strings.TrimSpace(text)
+	return plan
+}
+
+func (d *DirectiveSet) namesLocked() []string {
+	names := make([]string, 0, len(d.handlers))
+	for name := range d.handlers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// reactionDirective handles [REACTION:TYPE]
+func reactionDirective(plan *ReplyPlan, args string) (string, error) {
+	emojiType, ok := domain.NormalizeReaction(strings.TrimSpace(args))
+	if !ok {
+		return "", fmt.Errorf("unknown emoji type %q", args)
+	}
+	for _, r := range plan.Reactions {
+		if r == emojiType {
+			return "", nil
+		}
+	}
+	plan.Reactions = append(plan.Reactions, emojiType)
+	return "", nil
+}
+
+// mentionDirective handles [MENTION:user_id:name]
+func mentionDirective(plan *ReplyPlan, args string) (string, error) {
+	parts := strings.SplitN(args, ":", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", fmt.Errorf("expected [MENTION:user_id:name]")
+	}
+	plan.Mentions = append(plan.Mentions, domain.Member{
+		UserID: parts[0],
+		Name:   parts[1],
+	})
+	return "", nil
+}
+
+// mentionAllDirective handles [MENTION_ALL]
+func mentionAllDirective(plan *ReplyPlan, args string) (string, error) {
+	if args != "" {
+		return "", fmt.Errorf("takes no arguments")
+	}
+	plan.MentionAll = true
+	return "", nil
+}
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/anthropics/feishu-codex-bridge/internal/biz/domain"
+	"github.com/anthropics/feishu-codex-bridge/internal/biz/repo"
+	"github.com/anthropics/feishu-codex-bridge/internal/biz/usecase"
+)
+
+func TestInterpret_Reactions(t *testing.T) {
+	plan := NewDirectiveSet().Interpret("[REACTION:thumbsup] Sure! [REACTION:THUMBSUP] [REACTION:Sparkles]")
+
+	if plan.Text != "Sure!" {
+		t.Errorf("Expected 'Sure!', got %q", plan.Text)
+	}
+	if len(plan.Reactions) != 1 || plan.Reactions[0] != "THUMBSUP" {
+		t.Errorf("Expected one THUMBSUP reaction, got %v", plan.Reactions)
+	}
+	if len(plan.Errors) != 1 || !strings.Contains(plan.Errors[0].Error(), `unknown emoji type "Sparkles"`) {
+		t.Errorf("Expected an unknown emoji error, got %v", plan.Errors)
+	}
+}
+
+func TestInterpret_UnknownDirectives(t *testing.T) {
+	plan := NewDirectiveSet().Interpret("[WIP] done [PIN:msg-1] see [docs](http://x)")
+
+	// Bare brackets are plain text, unknown directives with arguments are reported
+	if plan.Text != "[WIP] done  see [docs](http://x)" {
+		t.Errorf("Unexpected text %q", plan.Text)
+	}
+	if len(plan.Errors) != 1 || !strings.Contains(plan.Errors[0].Error(), "unknown directive [PIN:msg-1]") {
+		t.Errorf("Expected an unknown directive error, got %v", plan.Errors)
+	}
+}
+
+func TestInterpret_CustomDirective(t *testing.T) {
+	directives := NewDirectiveSet()
+	directives.Register("SHOUT", func(plan *ReplyPlan, args string) (string, error) {
+		if args == "" {
+			return "", fmt.Errorf("needs text")
+		}
+		return strings.ToUpper(args), nil
+	})
+
+	plan := directives.Interpret("[SHOUT:hey] [SHOUT]")
+	if plan.Text != "HEY" {
+		t.Errorf("Expected custom directive to apply, got %q", plan.Text)
+	}
+	if len(plan.Errors) != 1 {
+		t.Errorf("Expected an error for the empty directive, got %v", plan.Errors)
+	}
+}
+
+func newTurnCompleteService(msgRepo *mockMessageRepo, response string) (*ConversationService, *[]string) {
+	sessionRepo := &mockSessionRepo{sessions: make(map[string]*domain.Session)}
+	sessionUC := usecase.NewSessionUsecase(sessionRepo, &mockCodexRepo{}, domain.SessionConfig{})
+	convUC := usecase.NewConversationUsecase(sessionUC, usecase.NewContextBuilderUsecase(msgRepo), &mockCodexRepo{}, usecase.PromptConfig{})
+
+	svc := &ConversationService{
+		chatStates:  make(map[domain.SessionKey]*ChatState),
+		messageRepo: msgRepo,
+		convUC:      convUC,
+	}
+	var replies []string
+	svc.SetReplyCallback(func(chatID string, to *repo.ReplyTo, text string, mentions []domain.Member) {
+		replies = append(replies, text)
+	})
+
+	state := &ChatState{ThreadID: "thread-1", MsgID: "msg-1", InFlight: true}
+	state.Buffer.WriteString(response)
+	svc.chatStates[domain.SessionKey{ChatID: "chat-1"}] = state
+	return svc, &replies
+}
+
+func TestHandleTurnComplete_AppliesReactions(t *testing.T) {
+	msgRepo := &mockMessageRepo{}
+	svc, replies := newTurnCompleteService(msgRepo, "[REACTION:HEART]")
+
+	svc.handleTurnComplete("thread-1")
+
+	if strings.Join(msgRepo.reactions, ",") != "DONE,HEART" {
+		t.Errorf("Expected DONE and HEART reactions, got %v", msgRepo.reactions)
+	}
+	if len(*replies) != 0 {
+		t.Errorf("Expected no text reply for a reaction-only response, got %v", *replies)
+	}
+}
+
+func TestHandleTurnComplete_MentionAll(t *testing.T) {
+	msgRepo := &mockMessageRepo{}
+	svc, replies := newTurnCompleteService(msgRepo, "[MENTION_ALL] Release is out!")
+
+	svc.handleTurnComplete("thread-1")
+
+	if len(msgRepo.mentionAll) != 1 || msgRepo.mentionAll[0] != "Release is out!" {
+		t.Errorf("Expected the reply to go out with @all, got %v", msgRepo.mentionAll)
+	}
+	if len(*replies) != 0 {
+		t.Errorf("Expected no second reply, got %v", *replies)
+	}
+}
+
+func TestSendMentionAll_FallsBackWhenRejected(t *testing.T) {
+	msgRepo := &rejectingMentionAllRepo{}
+	svc, replies := newTurnCompleteService(&msgRepo.mockMessageRepo, "")
+	svc.messageRepo = msgRepo
+
+	svc.sendMentionAll(context.Background(), "chat-1", nil, "Heads up", nil, false)
+
+	if len(*replies) != 1 || (*replies)[0] != "Heads up" {
+		t.Errorf("Expected a plain reply after @all was rejected, got %v", *replies)
+	}
+}
+
+// rejectingMentionAllRepo fails @all, like a chat where the bot may not use it
+type rejectingMentionAllRepo struct {
+	mockMessageRepo
+}
+
+func (m *rejectingMentionAllRepo) SendTextMentionAll(ctx context.Context, chatID, text string) error {
+	return fmt.Errorf("no permission to @all")
+}
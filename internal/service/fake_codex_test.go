@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anthropics/feishu-codex-bridge/internal/biz/domain"
+	"github.com/anthropics/feishu-codex-bridge/internal/biz/repo"
+	"github.com/anthropics/feishu-codex-bridge/internal/biz/usecase"
+	"github.com/anthropics/feishu-codex-bridge/internal/data"
+	"github.com/anthropics/feishu-codex-bridge/internal/infra/acp"
+	"github.com/anthropics/feishu-codex-bridge/internal/infra/acp/acptest"
+)
+
+// TestMain lets the test binary stand in for codex: acp.Client runs it as "app-server"
+func TestMain(m *testing.M) {
+	if len(os.Args) > 1 && (os.Args[1] == "app-server" || os.Args[1] == "mcp") {
+		acptest.Main()
+	}
+	os.Exit(m.Run())
+}
+
+// startFakeCodex starts a Codex client talking to the fake playing script
+func startFakeCodex(t *testing.T, script *acptest.Script) repo.CodexRepo {
+	t.Helper()
+	scriptJSON, _ := json.Marshal(script)
+	path := filepath.Join(t.TempDir(), "script.json")
+	if err := os.WriteFile(path, scriptJSON, 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(acptest.EnvScript, path)
+
+	executable, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := acp.NewClient(t.TempDir(), "")
+	client.SetExecutable(executable)
+	if err := client.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	t.Cleanup(func() { client.Stop() })
+	return data.NewCodexRepo(client)
+}
+
+// waitFor polls cond until it holds
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestConversationService_FakeCodexTurn(t *testing.T) {
+	codexRepo := startFakeCodex(t, &acptest.Script{Turns: []acptest.Turn{
+		{Match: "deploy", Steps: []acptest.Step{
+			{Delta: "Checking the build."},
+			{Approval: &acptest.Approval{Command: "make deploy"}},
+			{Delta: " Deployed."},
+			{Error: "model overloaded"},
+			{Delta: " never sent"},
+		}},
+		{Match: "thanks", Steps: []acptest.Step{{Delta: "Any time!"}}},
+	}})
+
+	msgRepo := &mockMessageRepo{}
+	sessionRepo := &mockSessionRepo{sessions: make(map[string]*domain.Session)}
+	sessionUC := usecase.NewSessionUsecase(sessionRepo, codexRepo, domain.SessionConfig{IdleTimeout: time.Hour, ResetHour: -1})
+	contextUC := usecase.NewContextBuilderUsecase(msgRepo)
+	convUC := usecase.NewConversationUsecase(sessionUC, contextUC, codexRepo, usecase.PromptConfig{})
+	svc := NewConversationService(convUC, usecase.NewFilterUsecase(nil, msgRepo, contextUC), msgRepo, codexRepo)
+	svc.StartEventLoop()
+
+	replies := make(chan string, 2)
+	svc.SetReplyCallback(func(chatID string, to *repo.ReplyTo, text string, mentions []domain.Member) {
+		replies <- text
+	})
+
+	approvalRepo := &mockApprovalRepo{}
+	approvals := NewApprovalService(usecase.NewApprovalUsecase(approvalRepo, &domain.ApprovalPolicy{Timeout: time.Minute}), msgRepo, codexRepo)
+	approvals.SetSessionResolver(svc.SessionForThread)
+	approvals.Start()
+
+	ctx := context.Background()
+	err := svc.HandleMessage(ctx, &MessageRequest{ChatID: "oc_1", MsgID: "om_1", Content: "please deploy", ChatType: domain.ChatTypeP2P, SenderID: "ou_alice"})
+	if err != nil {
+		t.Fatalf("HandleMessage failed: %v", err)
+	}
+
+	// The scripted command waits for a decision from the approval card
+	waitFor(t, "the approval card", func() bool { return approvals.PendingCount() == 1 })
+	msgRepo.mu.Lock()
+	card := msgRepo.sentCards[0]
+	msgRepo.mu.Unlock()
+	if !strings.Contains(card, "make deploy") {
+		t.Errorf("Expected the command on the approval card: %s", card)
+	}
+	var token string
+	approvals.pendingMu.Lock()
+	for tok := range approvals.pending {
+		token = tok
+	}
+	approvals.pendingMu.Unlock()
+	action := &CardAction{OperatorID: "ou_alice", ChatID: "oc_1", Value: map[string]interface{}{"token": token, "decision": "accept"}}
+	if _, err := approvals.HandleCardAction(ctx, action); err != nil {
+		t.Fatalf("HandleCardAction failed: %v", err)
+	}
+
+	// The turn fails part way, what was written before the error is still sent
+	select {
+	case reply := <-replies:
+		if reply != "Checking the build. Deployed." {
+			t.Errorf("Unexpected reply: %q", reply)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Timed out waiting for the reply")
+	}
+	if rec := approvalRepo.lastRecord(); rec == nil || rec.Decision != domain.ApprovalAccept || rec.DecidedBy != "ou_alice" || rec.ChatID != "oc_1" {
+		t.Errorf("Expected Alice's approval to be logged, got %+v", rec)
+	}
+
+	// The failed turn does not leave the chat busy
+	waitFor(t, "the chat to be idle", func() bool {
+		state := svc.getChatState(domain.SessionKey{ChatID: "oc_1"})
+		state.mu.Lock()
+		defer state.mu.Unlock()
+		return !state.Processing && !state.InFlight
+	})
+	err = svc.HandleMessage(ctx, &MessageRequest{ChatID: "oc_1", MsgID: "om_2", Content: "thanks", ChatType: domain.ChatTypeP2P, SenderID: "ou_alice"})
+	if err != nil {
+		t.Fatalf("HandleMessage failed: %v", err)
+	}
+	select {
+	case reply := <-replies:
+		if reply != "Any time!" {
+			t.Errorf("Unexpected reply: %q", reply)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Timed out waiting for the second reply")
+	}
+}
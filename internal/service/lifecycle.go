@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/anthropics/feishu-codex-bridge/internal/biz/repo"
+	"github.com/anthropics/feishu-codex-bridge/internal/biz/usecase"
+)
+
+// LifecycleService answers the bot joining and leaving chats, and member changes
+type LifecycleService struct {
+	lifecycleUC *usecase.LifecycleUsecase
+	convSvc     *ConversationService
+	messageRepo repo.MessageRepo
+	onboarding  string
+}
+
+// NewLifecycleService creates a new lifecycle service
+// onboarding is posted to groups the bot is added to, empty to stay quiet
+func NewLifecycleService(
+	lifecycleUC *usecase.LifecycleUsecase,
+	convSvc *ConversationService,
+	messageRepo repo.MessageRepo,
+	onboarding string,
+) *LifecycleService {
+	return &LifecycleService{
+		lifecycleUC: lifecycleUC,
+		convSvc:     convSvc,
+		messageRepo: messageRepo,
+		onboarding:  onboarding,
+	}
+}
+
+// HandleBotAdded posts the onboarding message to a group the bot joined
+func (s *LifecycleService) HandleBotAdded(ctx context.Context, chatID string) error {
+	if s.onboarding == "" {
+		return nil
+	}
+	if err := s.messageRepo.SendMarkdown(ctx, chatID, s.onboarding, nil); err != nil {
+		return fmt.Errorf("send onboarding message: %w", err)
+	}
+	fmt.Printf("[Lifecycle] Sent onboarding message to %s\n", chatID)
+	return nil
+}
+
+// HandleBotRemoved forgets a chat the bot was removed from or that was disbanded
+// Running turns are stopped, queued messages dropped and stored state deleted
+func (s *LifecycleService) HandleBotRemoved(ctx context.Context, chatID string) error {
+	if s.convSvc != nil {
+		s.convSvc.ForgetChat(ctx, chatID)
+	}
+	s.invalidateMembers(chatID)
+	if err := s.lifecycleUC.CleanupChat(ctx, chatID); err != nil {
+		return fmt.Errorf("clean up chat: %w", err)
+	}
+	return nil
+}
+
+// HandleMembersChanged drops the cached member list of a chat whose members changed
+func (s *LifecycleService) HandleMembersChanged(ctx context.Context, chatID string) error {
+	s.invalidateMembers(chatID)
+	return nil
+}
+
+func (s *LifecycleService) invalidateMembers(chatID string) {
+	if cache, ok := s.messageRepo.(repo.MemberCache); ok {
+		cache.InvalidateMembers(chatID)
+	}
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/anthropics/feishu-codex-bridge/internal/biz/domain"
+	"github.com/anthropics/feishu-codex-bridge/internal/biz/repo"
+	"github.com/anthropics/feishu-codex-bridge/internal/biz/usecase"
+)
+
+// cachingMessageRepo records member cache invalidations
+type cachingMessageRepo struct {
+	mockMessageRepo
+	invalidated []string
+}
+
+func (m *cachingMessageRepo) InvalidateMembers(chatID string) {
+	m.invalidated = append(m.invalidated, chatID)
+}
+
+func TestHandleBotAdded_SendsOnboarding(t *testing.T) {
+	msgRepo := &mockMessageRepo{}
+	sessionRepo := &mockSessionRepo{sessions: make(map[string]*domain.Session)}
+	lifecycleUC := usecase.NewLifecycleUsecase(sessionRepo, nil, nil)
+
+	svc := NewLifecycleService(lifecycleUC, nil, msgRepo, "Hi, mention me!")
+	if err := svc.HandleBotAdded(context.Background(), "chat-1"); err != nil {
+		t.Fatalf("HandleBotAdded failed: %v", err)
+	}
+	if len(msgRepo.sentText) != 1 || msgRepo.sentText[0] != "Hi, mention me!" {
+		t.Errorf("Expected the onboarding message, got %q", msgRepo.sentText)
+	}
+
+	quiet := NewLifecycleService(lifecycleUC, nil, msgRepo, "")
+	_ = quiet.HandleBotAdded(context.Background(), "chat-2")
+	if len(msgRepo.sentText) != 1 {
+		t.Errorf("Expected no message without onboarding text, got %q", msgRepo.sentText)
+	}
+}
+
+func TestHandleBotRemoved_ForgetsChat(t *testing.T) {
+	msgRepo := &cachingMessageRepo{}
+	codexRepo := &mockCodexRepo{}
+	queueRepo := &mockQueueRepo{}
+	sessionRepo := &mockSessionRepo{sessions: make(map[string]*domain.Session)}
+	for _, key := range []domain.SessionKey{{ChatID: "chat-1"}, {ChatID: "chat-1", TopicID: "omt-1"}, {ChatID: "chat-2"}} {
+		_ = sessionRepo.Save(context.Background(), &domain.Session{ChatID: key.ChatID, TopicID: key.TopicID, ThreadID: "t"})
+	}
+
+	convSvc := NewConversationService(nil, nil, msgRepo, codexRepo)
+	convSvc.SetQueueUsecase(usecase.NewQueueUsecase(queueRepo, usecase.DefaultQueueConfig()))
+	topic := domain.SessionKey{ChatID: "chat-1", TopicID: "omt-1"}
+	convSvc.chatStates[topic] = &ChatState{ThreadID: "thread-1", MsgID: "msg-1", InFlight: true}
+	_ = queueRepo.Enqueue(context.Background(), &domain.QueuedMessage{ChatID: "chat-1", TopicID: "omt-1", MsgID: "msg-2"})
+	_ = queueRepo.Enqueue(context.Background(), &domain.QueuedMessage{ChatID: "chat-2", MsgID: "msg-3"})
+
+	svc := NewLifecycleService(usecase.NewLifecycleUsecase(sessionRepo, nil, nil), convSvc, msgRepo, "")
+	ctx := context.Background()
+	if err := svc.HandleBotRemoved(ctx, "chat-1"); err != nil {
+		t.Fatalf("HandleBotRemoved failed: %v", err)
+	}
+
+	if len(codexRepo.interrupted) != 1 || codexRepo.interrupted[0] != "thread-1" {
+		t.Errorf("Expected the running topic turn to be interrupted, got %v", codexRepo.interrupted)
+	}
+	if count, _ := queueRepo.Count(ctx, topic); count != 0 {
+		t.Errorf("Expected the chat's queue to be dropped, got %d", count)
+	}
+	if count, _ := queueRepo.Count(ctx, domain.SessionKey{ChatID: "chat-2"}); count != 1 {
+		t.Errorf("Expected other chats' queues to stay, got %d", count)
+	}
+	if len(sessionRepo.sessions) != 1 || sessionRepo.sessions["chat-2"] == nil {
+		t.Errorf("Expected only chat-2's session to remain, got %v", sessionRepo.sessions)
+	}
+	if len(msgRepo.invalidated) != 1 || msgRepo.invalidated[0] != "chat-1" {
+		t.Errorf("Expected chat-1's members to be invalidated, got %v", msgRepo.invalidated)
+	}
+
+	var replies []string
+	convSvc.SetReplyCallback(func(chatID string, to *repo.ReplyTo, text string, mentions []domain.Member) {
+		replies = append(replies, text)
+	})
+	convSvc.HandleCodexEvent(repo.Event{Type: repo.EventTypeTurnComplete, ThreadID: "thread-1"})
+	if len(replies) != 0 {
+		t.Errorf("Expected no reply in a chat the bot left, got %q", replies)
+	}
+}
+
+func TestHandleMembersChanged_InvalidatesCache(t *testing.T) {
+	msgRepo := &cachingMessageRepo{}
+	sessionRepo := &mockSessionRepo{sessions: make(map[string]*domain.Session)}
+	svc := NewLifecycleService(usecase.NewLifecycleUsecase(sessionRepo, nil, nil), nil, msgRepo, "")
+
+	_ = svc.HandleMembersChanged(context.Background(), "chat-1")
+	if len(msgRepo.invalidated) != 1 || msgRepo.invalidated[0] != "chat-1" {
+		t.Errorf("Expected chat-1's members to be invalidated, got %v", msgRepo.invalidated)
+	}
+}
@@ -151,6 +151,50 @@ func (s *DigestScheduler) processWithCodex(ctx context.Context, chatID string, m
 		fmt.Printf("[Scheduler] Moonshot: digest for %s needs response\n", chatID)
 	}
 
+	s.sendDigest(ctx, chatID, messages)
+}
+
+// ReplyNow sends a chat's buffered messages to Codex without waiting for the next digest
+// The relevance filter is skipped, someone asked for the reply. Returns the number of messages
+func (s *DigestScheduler) ReplyNow(ctx context.Context, chatID string) (int, error) {
+	if s.codexRepo == nil || s.convSvc == nil {
+		return 0, fmt.Errorf("digests are not enabled")
+	}
+	messages, err := s.bufferUC.GetUnprocessedMessages(ctx, chatID)
+	if err != nil {
+		return 0, err
+	}
+	if len(messages) == 0 {
+		return 0, nil
+	}
+	s.sendDigest(ctx, chatID, messages)
+	return len(messages), nil
+}
+
+// HandleCardAction handles the "Reply now" buttons of the /buffer card
+func (s *DigestScheduler) HandleCardAction(ctx context.Context, action *CardAction) (*CardResult, error) {
+	chatID := action.String("chat_id")
+	if chatID == "" {
+		return nil, fmt.Errorf("missing chat_id")
+	}
+	count, err := s.ReplyNow(ctx, chatID)
+	if err != nil {
+		return nil, err
+	}
+	notice := "No buffered messages left in " + chatID
+	if count > 0 {
+		notice = fmt.Sprintf("Replying to %d messages in %s", count, chatID)
+	}
+
+	summaries, err := s.bufferUC.GetBufferSummary(ctx)
+	if err != nil {
+		return &CardResult{Toast: notice}, nil
+	}
+	return &CardResult{Toast: notice, Card: buildBufferCard(summaries, notice)}, nil
+}
+
+// sendDigest sends buffered messages to Codex as one digest turn
+func (s *DigestScheduler) sendDigest(ctx context.Context, chatID string, messages []*domain.BufferedMessage) {
 	// 2. Build digest prompt
 	prompt := s.buildDigestPrompt(messages)
 
@@ -181,6 +225,31 @@ func (s *DigestScheduler) processWithCodex(ctx context.Context, chatID string, m
 		chatID, len(messages), lastMsg.SenderName)
 }
 
+// buildBufferCard lists buffered chats with a "Reply now" button each
+func buildBufferCard(summaries []*domain.BufferSummary, notice string) string {
+	var elements []interface{}
+	for _, sum := range summaries {
+		name := sum.ChatName
+		if name == "" {
+			name = sum.ChatID
+		}
+		elements = append(elements, map[string]interface{}{
+			"tag":  "div",
+			"text": map[string]interface{}{"tag": "lark_md", "content": fmt.Sprintf("**%s**: %d unread (last %s)", name, sum.MessageCount, sum.LastMessage.Format("01-02 15:04"))},
+			"extra": cardButton("Reply now", "primary", DigestCardAction, map[string]interface{}{
+				"chat_id": sum.ChatID,
+			}),
+		})
+	}
+	if len(summaries) == 0 {
+		elements = append(elements, markdownElement("No buffered messages."))
+	}
+	if notice != "" {
+		elements = append(elements, noteElement(notice))
+	}
+	return buildCard("Buffered messages", "blue", elements)
+}
+
 // buildHistoryForFilter builds history text for Moonshot filtering
 func (s *DigestScheduler) buildHistoryForFilter(messages []*domain.BufferedMessage) string {
 	var sb strings.Builder
@@ -0,0 +1,337 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/anthropics/feishu-codex-bridge/internal/biz/domain"
+	"github.com/anthropics/feishu-codex-bridge/internal/biz/repo"
+)
+
+// StreamConfig contains streaming reply configuration
+type StreamConfig struct {
+	Interval    time.Duration // Min time between card updates
+	MaxInterval time.Duration // Upper bound when backing off after failed updates
+	MaxBytes    int           // Max content per card, longer replies continue in a new card
+	Feedback    bool          // Add rating buttons under the finished reply
+}
+
+// DefaultStreamConfig returns default streaming configuration
+// Feishu allows about 5 updates per second per message and ~30KB per card
+func DefaultStreamConfig() StreamConfig {
+	return StreamConfig{
+		Interval:    time.Second,
+		MaxInterval: 10 * time.Second,
+		MaxBytes:    16 * 1024,
+	}
+}
+
+// replyStream posts a reply card on the first delta and patches it
+// with the accumulated text at a throttled rate
+type replyStream struct {
+	messageRepo repo.MessageRepo
+	chatID      string
+	replyTo     *repo.ReplyTo // Cards reply to this message when set
+	cfg         StreamConfig
+	render      func(raw string) string // Strips directives from partial output
+
+	mu       sync.Mutex
+	raw      strings.Builder
+	dirty    bool
+	interval time.Duration
+	msgIDs   []string // One card per segment
+	sent     []string // Last content sent per card
+	failed   bool     // Could not post a card, caller must send the reply normally
+
+	flushMu sync.Mutex // Serializes flushes so only one card is posted per segment
+	stopCh  chan struct{}
+	done    chan struct{}
+	once    sync.Once
+}
+
+func newReplyStream(messageRepo repo.MessageRepo, chatID string, replyTo *repo.ReplyTo, cfg StreamConfig, render func(string) string) *replyStream {
+	st := &replyStream{
+		messageRepo: messageRepo,
+		chatID:      chatID,
+		replyTo:     replyTo,
+		cfg:         cfg,
+		render:      render,
+		interval:    cfg.Interval,
+		stopCh:      make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+	go st.loop()
+	return st
+}
+
+// Append adds a delta; the first one posts the card right away
+func (st *replyStream) Append(delta string) {
+	st.mu.Lock()
+	st.raw.WriteString(delta)
+	st.dirty = true
+	first := len(st.msgIDs) == 0 && !st.failed
+	st.mu.Unlock()
+
+	if first {
+		st.flush(false, "")
+	}
+}
+
+// Finish stops updates and writes the final text
+// Returns false if nothing could be posted, so the caller should send the reply itself
+func (st *replyStream) Finish(text string) bool {
+	st.stop()
+
+	// The final update matters most, retry it if Feishu pushes back
+	for attempt := 0; attempt < 3; attempt++ {
+		st.flush(true, text)
+
+		st.mu.Lock()
+		retry := st.dirty && !st.failed
+		interval := st.interval
+		st.mu.Unlock()
+		if !retry {
+			break
+		}
+		time.Sleep(interval)
+	}
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return !st.failed && len(st.msgIDs) > 0
+}
+
+// Abort stops updates and marks the partial reply as interrupted
+func (st *replyStream) Abort(notice string) {
+	st.stop()
+
+	st.mu.Lock()
+	posted := len(st.msgIDs) > 0
+	st.mu.Unlock()
+	if posted {
+		st.flush(true, st.render(st.rawText())+"\n\n_"+notice+"_")
+	}
+}
+
+// stop ends the update loop without touching the cards
+func (st *replyStream) stop() {
+	st.once.Do(func() { close(st.stopCh) })
+	<-st.done
+}
+
+func (st *replyStream) rawText() string {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.raw.String()
+}
+
+func (st *replyStream) loop() {
+	defer close(st.done)
+	for {
+		st.mu.Lock()
+		interval := st.interval
+		st.mu.Unlock()
+
+		select {
+		case <-st.stopCh:
+			return
+		case <-time.After(interval):
+			st.mu.Lock()
+			dirty := st.dirty && len(st.msgIDs) > 0
+			st.mu.Unlock()
+			if dirty {
+				st.flush(false, "")
+			}
+		}
+	}
+}
+
+// flush renders the text into cards, posting new cards for overflow segments
+// and patching only cards whose content changed
+func (st *replyStream) flush(final bool, text string) {
+	st.flushMu.Lock()
+	defer st.flushMu.Unlock()
+
+	st.mu.Lock()
+	if st.failed {
+		st.mu.Unlock()
+		return
+	}
+	if !final {
+		text = st.render(st.raw.String())
+	}
+	st.dirty = false
+	st.mu.Unlock()
+
+	segments := splitAtBoundary(text, st.cfg.MaxBytes)
+	if len(segments) == 0 {
+		segments = []string{"…"}
+	}
+
+	ctx := context.Background()
+	for i, segment := range segments {
+		last := i == len(segments)-1
+		card := buildStreamCard(segment, !final && last, final && last && st.cfg.Feedback)
+
+		st.mu.Lock()
+		existing := i < len(st.msgIDs)
+		unchanged := existing && st.sent[i] == card
+		var msgID string
+		if existing {
+			msgID = st.msgIDs[i]
+		}
+		st.mu.Unlock()
+
+		if unchanged {
+			continue
+		}
+
+		if existing {
+			if err := st.messageRepo.UpdateCard(ctx, msgID, card); err != nil {
+				// Most likely rate limited, slow down and retry on the next tick
+				st.mu.Lock()
+				st.dirty = true
+				st.interval *= 2
+				if st.interval > st.cfg.MaxInterval {
+					st.interval = st.cfg.MaxInterval
+				}
+				st.mu.Unlock()
+				fmt.Printf("[Stream] Failed to update card in %s: %v\n", st.chatID, err)
+				return
+			}
+		} else {
+			newID, err := st.postCard(ctx, card)
+			if err != nil {
+				fmt.Printf("[Stream] Failed to post card in %s: %v\n", st.chatID, err)
+				st.mu.Lock()
+				if len(st.msgIDs) == 0 {
+					st.failed = true
+				} else {
+					st.dirty = true
+				}
+				st.mu.Unlock()
+				return
+			}
+			st.mu.Lock()
+			st.msgIDs = append(st.msgIDs, newID)
+			st.sent = append(st.sent, "")
+			st.mu.Unlock()
+		}
+
+		st.mu.Lock()
+		st.sent[i] = card
+		st.mu.Unlock()
+	}
+}
+
+// postCard posts a new card, as a reply when the stream has a target
+func (st *replyStream) postCard(ctx context.Context, card string) (string, error) {
+	if st.replyTo != nil {
+		return st.messageRepo.ReplyCard(ctx, *st.replyTo, card)
+	}
+	return st.messageRepo.SendCard(ctx, st.chatID, card)
+}
+
+// buildStreamCard builds a reply card, with a progress note while streaming
+// and optionally rating buttons once the reply is complete
+func buildStreamCard(content string, streaming, feedback bool) string {
+	elements := []interface{}{
+		map[string]interface{}{
+			"tag":  "div",
+			"text": map[string]interface{}{"tag": "lark_md", "content": content},
+		},
+	}
+	if streaming {
+		elements = append(elements, map[string]interface{}{
+			"tag": "note",
+			"elements": []interface{}{
+				map[string]interface{}{"tag": "plain_text", "content": "Generating..."},
+			},
+		})
+	}
+	if feedback {
+		elements = append(elements, map[string]interface{}{
+			"tag": "action",
+			"actions": []interface{}{
+				cardButton("👍 Helpful", "default", FeedbackCardAction, map[string]interface{}{"rating": string(domain.FeedbackUp)}),
+				cardButton("👎 Not helpful", "default", FeedbackCardAction, map[string]interface{}{"rating": string(domain.FeedbackDown)}),
+			},
+		})
+	}
+
+	card := map[string]interface{}{
+		"config":   map[string]interface{}{"wide_screen_mode": true, "update_multi": true},
+		"elements": elements,
+	}
+	cardJSON, _ := json.Marshal(card)
+	return string(cardJSON)
+}
+
+// FeedbackHandler records ratings from the buttons under streamed replies
+type FeedbackHandler struct {
+	feedbackRepo repo.FeedbackRepo
+}
+
+// NewFeedbackHandler creates a new feedback card handler
+func NewFeedbackHandler(feedbackRepo repo.FeedbackRepo) *FeedbackHandler {
+	return &FeedbackHandler{feedbackRepo: feedbackRepo}
+}
+
+// HandleCardAction stores a rating, keyed by the rated reply and the operator
+func (h *FeedbackHandler) HandleCardAction(ctx context.Context, action *CardAction) (*CardResult, error) {
+	rating := domain.FeedbackRating(action.String("rating"))
+	if !rating.Valid() {
+		return nil, fmt.Errorf("invalid rating: %s", rating)
+	}
+	err := h.feedbackRepo.Save(ctx, &domain.Feedback{
+		MsgID:      action.MessageID,
+		ChatID:     action.ChatID,
+		OperatorID: action.OperatorID,
+		Rating:     rating,
+	})
+	if err != nil {
+		return nil, err
+	}
+	fmt.Printf("[Feedback] %s from %s on reply %s in %s\n", rating, action.OperatorID, action.MessageID, action.ChatID)
+	return &CardResult{Toast: "Thanks for the feedback"}, nil
+}
+
+// splitAtBoundary splits text into parts of at most maxBytes,
+// preferring line breaks and never cutting inside a UTF-8 character
+func splitAtBoundary(text string, maxBytes int) []string {
+	var parts []string
+	text = strings.TrimSpace(text)
+	for len(text) > maxBytes {
+		cut := strings.LastIndex(text[:maxBytes], "\n")
+		if cut <= 0 {
+			cut = maxBytes
+			for cut > 0 && !utf8.RuneStart(text[cut]) {
+				cut--
+			}
+		}
+		parts = append(parts, strings.TrimSpace(text[:cut]))
+		text = strings.TrimSpace(text[cut:])
+	}
+	if text != "" {
+		parts = append(parts, text)
+	}
+	return parts
+}
+
+// formatMentions renders mentions for a lark_md card
+func formatMentions(text string, mentions []domain.Member) string {
+	if len(mentions) == 0 {
+		return text
+	}
+	var sb strings.Builder
+	for _, m := range mentions {
+		sb.WriteString(fmt.Sprintf("<at id=%s></at> ", m.UserID))
+	}
+	sb.WriteString(text)
+	return sb.String()
+}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testStreamConfig() StreamConfig {
+	return StreamConfig{Interval: 10 * time.Millisecond, MaxInterval: 50 * time.Millisecond, MaxBytes: 64}
+}
+
+func identity(raw string) string { return raw }
+
+func TestReplyStream_PostsThenPatches(t *testing.T) {
+	msgRepo := &mockMessageRepo{}
+	st := newReplyStream(msgRepo, "chat-1", nil, testStreamConfig(), identity)
+
+	st.Append("Hello")
+	if len(msgRepo.sentCards) != 1 || !strings.Contains(msgRepo.sentCards[0], "Hello") {
+		t.Fatalf("Expected placeholder card on first delta, got %v", msgRepo.sentCards)
+	}
+
+	st.Append(" world")
+	time.Sleep(50 * time.Millisecond)
+
+	msgRepo.mu.Lock()
+	partial := msgRepo.updated["card_1"]
+	msgRepo.mu.Unlock()
+	if !strings.Contains(partial, "Hello world") || !strings.Contains(partial, "Generating") {
+		t.Errorf("Expected throttled partial update, got %s", partial)
+	}
+
+	if !st.Finish("Hello world!") {
+		t.Fatal("Expected Finish to report a streamed reply")
+	}
+	final := msgRepo.updated["card_1"]
+	if !strings.Contains(final, "Hello world!") || strings.Contains(final, "Generating") {
+		t.Errorf("Expected final card without progress note, got %s", final)
+	}
+	if len(msgRepo.sentCards) != 1 {
+		t.Errorf("Expected a single card, got %d", len(msgRepo.sentCards))
+	}
+}
+
+func TestReplyStream_LongReplyContinuesInNewCard(t *testing.T) {
+	msgRepo := &mockMessageRepo{}
+	st := newReplyStream(msgRepo, "chat-1", nil, testStreamConfig(), identity)
+
+	st.Append("start")
+	text := strings.Repeat("line of text\n", 10) // > 64 bytes
+	if !st.Finish(text) {
+		t.Fatal("Expected Finish to succeed")
+	}
+	if len(msgRepo.sentCards) < 2 {
+		t.Errorf("Expected overflow to continue in new cards, got %d", len(msgRepo.sentCards))
+	}
+}
+
+func TestReplyStream_FallsBackWhenCardFails(t *testing.T) {
+	msgRepo := &mockMessageRepo{cardErr: errors.New("no permission")}
+	st := newReplyStream(msgRepo, "chat-1", nil, testStreamConfig(), identity)
+
+	st.Append("Hello")
+	if st.Finish("Hello") {
+		t.Error("Expected Finish to report failure so the reply is sent normally")
+	}
+}
+
+func TestSplitAtBoundary(t *testing.T) {
+	parts := splitAtBoundary("aaaa\nbbbb\ncccc", 10)
+	if len(parts) != 2 || parts[0] != "aaaa\nbbbb" || parts[1] != "cccc" {
+		t.Errorf("Expected split at line break, got %q", parts)
+	}
+
+	// Never cut inside a multi-byte character
+	for _, part := range splitAtBoundary(strings.Repeat("你好", 10), 7) {
+		if !strings.HasPrefix("你好你好", part[:3]) {
+			t.Errorf("Split inside a character: %q", part)
+		}
+	}
+
+	if parts := splitAtBoundary("  ", 10); len(parts) != 0 {
+		t.Errorf("Expected no parts for blank text, got %q", parts)
+	}
+}